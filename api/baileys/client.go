@@ -0,0 +1,212 @@
+// Package baileys es el cliente HTTP del servicio baileys-ws (sesiones de WhatsApp)
+package baileys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "http://baileys:3000"
+	DefaultTimeout = 15 * time.Second
+)
+
+// Client cubre los endpoints HTTP de baileys-ws (ver baileys-ws/src/index.ts)
+type Client interface {
+	ListSessions(ctx context.Context) (*SessionList, error)
+	CreateSession(ctx context.Context, sessionID string, req CreateSessionRequest) (*CreateSessionResponse, error)
+	DeleteSession(ctx context.Context, sessionID string) (*ActionResponse, error)
+	RestartSession(ctx context.Context, sessionID string) (*ActionResponse, error)
+	GetQR(ctx context.Context, sessionID string) (*QRResponse, error)
+	GetStatus(ctx context.Context, sessionID string) (*StatusResponse, error)
+	GetChats(ctx context.Context, sessionID string) (*ChatList, error)
+	GetMessages(ctx context.Context, sessionID, chatID string, limit int) (*MessageList, error)
+	SendMessage(ctx context.Context, sessionID string, req SendRequest) (*ActionResponse, error)
+	UpdateSessionToken(ctx context.Context, sessionID, wsToken string) (*ActionResponse, error)
+}
+
+// Config configura el cliente HTTP
+type Config struct {
+	BaseURL    string
+	Timeout    time.Duration // Timeout por solicitud si el contexto no tiene deadline
+	HTTPClient *http.Client
+}
+
+type httpClient struct {
+	baseURL string
+	timeout time.Duration
+	http    *http.Client
+}
+
+// NewClient crea un cliente para baileys-ws
+func NewClient(cfg Config) Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{}
+	}
+	return &httpClient{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		timeout: cfg.Timeout,
+		http:    cfg.HTTPClient,
+	}
+}
+
+func (c *httpClient) ListSessions(ctx context.Context) (*SessionList, error) {
+	var out SessionList
+	if err := c.do(ctx, http.MethodGet, "/sessions", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) CreateSession(ctx context.Context, sessionID string, req CreateSessionRequest) (*CreateSessionResponse, error) {
+	var out CreateSessionResponse
+	if err := c.do(ctx, http.MethodPost, sessionPath(sessionID, ""), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) DeleteSession(ctx context.Context, sessionID string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodDelete, sessionPath(sessionID, ""), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) RestartSession(ctx context.Context, sessionID string) (*ActionResponse, error) {
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, sessionPath(sessionID, "/restart"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) GetQR(ctx context.Context, sessionID string) (*QRResponse, error) {
+	var out QRResponse
+	if err := c.do(ctx, http.MethodGet, sessionPath(sessionID, "/qr"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) GetStatus(ctx context.Context, sessionID string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, http.MethodGet, sessionPath(sessionID, "/status"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) GetChats(ctx context.Context, sessionID string) (*ChatList, error) {
+	var out ChatList
+	if err := c.do(ctx, http.MethodGet, sessionPath(sessionID, "/chats"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) GetMessages(ctx context.Context, sessionID, chatID string, limit int) (*MessageList, error) {
+	path := sessionPath(sessionID, "/chats/"+url.PathEscape(chatID)+"/messages")
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+
+	var out MessageList
+	if err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) SendMessage(ctx context.Context, sessionID string, req SendRequest) (*ActionResponse, error) {
+	if req.Number == "" || req.Message == "" {
+		return nil, fmt.Errorf("%w: number y message son requeridos", ErrInvalidRequest)
+	}
+
+	var out ActionResponse
+	if err := c.do(ctx, http.MethodPost, sessionPath(sessionID, "/send"), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSessionToken entrega a la sesión una nueva credencial para /ws; baileys-ws se reconecta con ella
+func (c *httpClient) UpdateSessionToken(ctx context.Context, sessionID, wsToken string) (*ActionResponse, error) {
+	if wsToken == "" {
+		return nil, fmt.Errorf("%w: ws_token es requerido", ErrInvalidRequest)
+	}
+
+	var out ActionResponse
+	body := map[string]string{"ws_token": wsToken}
+	if err := c.do(ctx, http.MethodPut, sessionPath(sessionID, "/ws-token"), body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func sessionPath(sessionID, suffix string) string {
+	return "/sessions/" + url.PathEscape(sessionID) + suffix
+}
+
+// do ejecuta la solicitud aplicando el timeout por defecto y mapeando los errores
+func (c *httpClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error serializando solicitud: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("error creando solicitud: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s %s: %w", ErrUnavailable, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		// El cuerpo de error es opcional; si no es JSON queda el status
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(apiErr)
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decodificando respuesta de %s: %w", path, err)
+	}
+	return nil
+}
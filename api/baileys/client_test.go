@@ -0,0 +1,83 @@
+package baileys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSendMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/sessions/bot-1/send", r.URL.Path)
+
+		var req SendRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, SendRequest{Number: "573001234567", Message: "hola"}, req)
+
+		json.NewEncoder(w).Encode(ActionResponse{Success: true, SessionID: "bot-1"})
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL + "/"})
+	resp, err := client.SendMessage(context.Background(), "bot-1", SendRequest{Number: "573001234567", Message: "hola"})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, "bot-1", resp.SessionID)
+}
+
+func TestClientErrorMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sessions/missing/status":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Sesión no encontrada"})
+		case "/sessions/offline/chats":
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Sesión no conectada"})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("boom"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL})
+	ctx := context.Background()
+
+	_, err := client.GetStatus(ctx, "missing")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	_, err = client.GetChats(ctx, "offline")
+	assert.ErrorIs(t, err, ErrSessionNotConnected)
+
+	_, err = client.RestartSession(ctx, "other")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+
+	_, err = client.SendMessage(ctx, "bot-1", SendRequest{})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, Timeout: 20 * time.Millisecond})
+	_, err := client.ListSessions(context.Background())
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
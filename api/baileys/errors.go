@@ -0,0 +1,47 @@
+package baileys
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("sesión de WhatsApp no encontrada")
+	ErrSessionNotConnected = errors.New("sesión de WhatsApp no conectada")
+	ErrInvalidRequest      = errors.New("solicitud inválida para el servicio de WhatsApp")
+	ErrUnavailable         = errors.New("servicio de WhatsApp no disponible") // fallos de red o timeout
+)
+
+// APIError representa una respuesta de error de baileys-ws
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"error"`
+	Details    string `json:"details,omitempty"`
+	Hint       string `json:"message,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Details != "" {
+		return fmt.Sprintf("baileys %d: %s: %s", e.StatusCode, msg, e.Details)
+	}
+	return fmt.Sprintf("baileys %d: %s", e.StatusCode, msg)
+}
+
+// Unwrap permite usar errors.Is con los errores de la familia
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrSessionNotFound
+	case e.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(e.Message), "no conectada"):
+		return ErrSessionNotConnected
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrInvalidRequest
+	}
+	return nil
+}
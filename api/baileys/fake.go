@@ -0,0 +1,287 @@
+package baileys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake es una implementación en memoria de Client para pruebas. Reproduce las
+// respuestas y errores de baileys-ws sin levantar el servicio de Node.
+type Fake struct {
+	mu       sync.Mutex
+	sessions map[string]*fakeSession
+
+	// Sent registra los mensajes enviados con SendMessage
+	Sent []FakeSentMessage
+	// Err, si no es nil, se devuelve en todas las llamadas
+	Err error
+}
+
+// FakeSentMessage es un mensaje enviado a través del Fake
+type FakeSentMessage struct {
+	SessionID string
+	SendRequest
+}
+
+type fakeSession struct {
+	status   SessionStatus
+	wsToken  string
+	chats    []Chat
+	messages map[string][]ChatMessage
+}
+
+var _ Client = (*Fake)(nil)
+
+// NewFake crea un Fake sin sesiones
+func NewFake() *Fake {
+	return &Fake{sessions: make(map[string]*fakeSession)}
+}
+
+// AddSession agrega una sesión; si connected es true queda lista para enviar mensajes
+func (f *Fake) AddSession(sessionID string, connected bool, number string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	status := SessionStatus{Connected: connected, Number: number, CreatedAt: now, LastActivity: now}
+	if !connected {
+		status.QRCode = "fake-qr-" + sessionID
+		status.QRImage = "data:image/png;base64,ZmFrZQ=="
+	}
+	f.sessions[sessionID] = &fakeSession{status: status, messages: make(map[string][]ChatMessage)}
+}
+
+// AddChat agrega un chat con sus mensajes a una sesión existente
+func (f *Fake) AddChat(sessionID string, chat Chat, messages ...ChatMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s, ok := f.sessions[sessionID]; ok {
+		s.chats = append(s.chats, chat)
+		s.messages[chat.ID] = append(s.messages[chat.ID], messages...)
+	}
+}
+
+func (f *Fake) notFound() error {
+	return &APIError{StatusCode: http.StatusNotFound, Message: "Sesión no encontrada"}
+}
+
+func (f *Fake) notConnected() error {
+	return &APIError{StatusCode: http.StatusBadRequest, Message: "Sesión no conectada"}
+}
+
+func (f *Fake) ListSessions(ctx context.Context) (*SessionList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	out := &SessionList{Sessions: []SessionSummary{}}
+	for id, s := range f.sessions {
+		out.Sessions = append(out.Sessions, SessionSummary{ID: id, Status: s.status, HasQR: s.status.QRCode != ""})
+	}
+	sort.Slice(out.Sessions, func(i, j int) bool { return out.Sessions[i].ID < out.Sessions[j].ID })
+	out.Total = len(out.Sessions)
+	return out, nil
+}
+
+func (f *Fake) CreateSession(ctx context.Context, sessionID string, req CreateSessionRequest) (*CreateSessionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	if _, exists := f.sessions[sessionID]; exists {
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Error creando sesión",
+			Details:    fmt.Sprintf("La sesión %s ya existe", sessionID),
+		}
+	}
+
+	// Igual que baileys-ws: la sesión nueva queda esperando el escaneo del QR
+	now := time.Now()
+	status := SessionStatus{
+		QRCode:       "fake-qr-" + sessionID,
+		QRImage:      "data:image/png;base64,ZmFrZQ==",
+		CreatedAt:    now,
+		LastActivity: now,
+	}
+	f.sessions[sessionID] = &fakeSession{status: status, wsToken: req.WSToken, messages: make(map[string][]ChatMessage)}
+
+	out := &CreateSessionResponse{Success: true, Message: "Sesión creada correctamente"}
+	out.Session.ID = sessionID
+	out.Session.Status = status
+	return out, nil
+}
+
+func (f *Fake) DeleteSession(ctx context.Context, sessionID string) (*ActionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	// baileys-ws no falla si la sesión no existe
+	delete(f.sessions, sessionID)
+	return &ActionResponse{Success: true, Message: "Sesión eliminada correctamente", SessionID: sessionID}, nil
+}
+
+func (f *Fake) RestartSession(ctx context.Context, sessionID string) (*ActionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	if _, ok := f.sessions[sessionID]; !ok {
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Error reiniciando sesión",
+			Details:    fmt.Sprintf("Sesión %s no encontrada", sessionID),
+		}
+	}
+	return &ActionResponse{Success: true, Message: "Sesión reiniciada correctamente", SessionID: sessionID}, nil
+}
+
+func (f *Fake) GetQR(ctx context.Context, sessionID string) (*QRResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	s, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, f.notFound()
+	}
+
+	out := &QRResponse{Connected: s.status.Connected}
+	switch {
+	case s.status.Connected:
+		out.Status = QRStatusConnected
+		out.Message = "WhatsApp ya está conectado"
+		out.SessionInfo = &QRSessionInfo{ID: sessionID, Number: s.status.Number, Name: s.status.Name, LastActivity: s.status.LastActivity}
+	case s.status.QRCode != "":
+		out.Status = QRStatusWaitingForScan
+		out.Message = "Escanea el código QR en WhatsApp"
+		out.QRCode = s.status.QRCode
+		out.QRImage = s.status.QRImage
+		out.SessionID = sessionID
+	default:
+		out.Status = QRStatusInitializing
+		out.Message = "Iniciando sesión de WhatsApp..."
+		out.SessionID = sessionID
+	}
+	return out, nil
+}
+
+func (f *Fake) GetStatus(ctx context.Context, sessionID string) (*StatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	s, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	return &StatusResponse{SessionID: sessionID, Status: s.status}, nil
+}
+
+func (f *Fake) GetChats(ctx context.Context, sessionID string) (*ChatList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	s, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	if !s.status.Connected {
+		return nil, f.notConnected()
+	}
+
+	chats := append([]Chat{}, s.chats...)
+	return &ChatList{SessionID: sessionID, TotalChats: len(chats), Chats: chats}, nil
+}
+
+func (f *Fake) GetMessages(ctx context.Context, sessionID, chatID string, limit int) (*MessageList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	s, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	if !s.status.Connected {
+		return nil, f.notConnected()
+	}
+
+	messages := append([]ChatMessage{}, s.messages[chatID]...)
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return &MessageList{SessionID: sessionID, ChatID: chatID, TotalMessages: len(messages), Messages: messages}, nil
+}
+
+func (f *Fake) SendMessage(ctx context.Context, sessionID string, req SendRequest) (*ActionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	if req.Number == "" || req.Message == "" {
+		return nil, &APIError{StatusCode: http.StatusBadRequest, Message: "number y message son requeridos"}
+	}
+	s, ok := f.sessions[sessionID]
+	if !ok || !s.status.Connected {
+		return nil, &APIError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "Error enviando mensaje",
+			Details:    fmt.Sprintf("Sesión %s no está conectada", sessionID),
+		}
+	}
+
+	f.Sent = append(f.Sent, FakeSentMessage{SessionID: sessionID, SendRequest: req})
+	s.status.LastActivity = time.Now()
+	return &ActionResponse{Success: true, Message: "Mensaje enviado correctamente", SessionID: sessionID}, nil
+}
+
+func (f *Fake) UpdateSessionToken(ctx context.Context, sessionID, wsToken string) (*ActionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	s, ok := f.sessions[sessionID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	s.wsToken = wsToken
+	return &ActionResponse{Success: true, Message: "Credencial actualizada correctamente", SessionID: sessionID}, nil
+}
+
+// WSToken devuelve la credencial de /ws entregada a la sesión
+func (f *Fake) WSToken(sessionID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.sessions[sessionID]; ok {
+		return s.wsToken
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package baileys
+
+import "time"
+
+// SessionStatus es el estado de una sesión tal como lo reporta baileys-ws
+type SessionStatus struct {
+	Connected            bool      `json:"connected"`
+	Number               string    `json:"number"`
+	Name                 string    `json:"name"`
+	QRCode               string    `json:"qr_code"`
+	QRImage              string    `json:"qr_image"`
+	LastDisconnectReason string    `json:"last_disconnect_reason"`
+	ReconnectAttempts    int       `json:"reconnect_attempts"`
+	CreatedAt            time.Time `json:"created_at"`
+	LastActivity         time.Time `json:"last_activity"`
+}
+
+// SessionSummary es una entrada de GET /sessions
+type SessionSummary struct {
+	ID     string        `json:"id"`
+	Status SessionStatus `json:"status"`
+	HasQR  bool          `json:"has_qr"`
+}
+
+// SessionList es la respuesta de GET /sessions
+type SessionList struct {
+	Total    int              `json:"total"`
+	Sessions []SessionSummary `json:"sessions"`
+}
+
+// CreateSessionRequest es el cuerpo de POST /sessions/:sessionId
+type CreateSessionRequest struct {
+	WSToken string `json:"ws_token,omitempty"` // Credencial con la que la sesión se conecta a /ws
+}
+
+// CreateSessionResponse es la respuesta de POST /sessions/:sessionId
+type CreateSessionResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Session struct {
+		ID     string        `json:"id"`
+		Status SessionStatus `json:"status"`
+	} `json:"session"`
+}
+
+// ActionResponse es la respuesta genérica de restart, delete y send
+type ActionResponse struct {
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	SessionID string `json:"session_id"`
+}
+
+// QR states devueltos por GET /sessions/:sessionId/qr
+const (
+	QRStatusConnected      = "connected"
+	QRStatusWaitingForScan = "waiting_for_scan"
+	QRStatusInitializing   = "initializing"
+)
+
+// QRResponse es la respuesta de GET /sessions/:sessionId/qr
+type QRResponse struct {
+	Status            string         `json:"status"`
+	Message           string         `json:"message"`
+	Connected         bool           `json:"connected"`
+	QRCode            string         `json:"qr_code,omitempty"`
+	QRImage           string         `json:"qr_image,omitempty"`
+	SessionID         string         `json:"session_id,omitempty"`
+	ReconnectAttempts int            `json:"reconnect_attempts,omitempty"`
+	SessionInfo       *QRSessionInfo `json:"session_info,omitempty"`
+}
+
+// QRSessionInfo es la información de una sesión ya conectada
+type QRSessionInfo struct {
+	ID           string    `json:"id"`
+	Number       string    `json:"number"`
+	Name         string    `json:"name"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// StatusResponse es la respuesta de GET /sessions/:sessionId/status
+type StatusResponse struct {
+	SessionID string        `json:"session_id"`
+	Status    SessionStatus `json:"status"`
+}
+
+// LastMessage es el último mensaje de un chat
+type LastMessage struct {
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+	FromMe    bool   `json:"fromMe"`
+}
+
+// Chat es una entrada de GET /sessions/:sessionId/chats
+type Chat struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	IsGroup     bool         `json:"isGroup"`
+	UnreadCount int          `json:"unreadCount"`
+	LastMessage *LastMessage `json:"lastMessage"`
+	Pinned      bool         `json:"pinned"`
+	Archived    bool         `json:"archived"`
+}
+
+// ChatList es la respuesta de GET /sessions/:sessionId/chats
+type ChatList struct {
+	SessionID  string `json:"session_id"`
+	TotalChats int    `json:"total_chats"`
+	Chats      []Chat `json:"chats"`
+}
+
+// ChatMessage es una entrada de GET /sessions/:sessionId/chats/:chatId/messages
+type ChatMessage struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	FromMe    bool   `json:"fromMe"`
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+	Type      string `json:"type"`
+	HasMedia  bool   `json:"hasMedia"`
+}
+
+// MessageList es la respuesta de GET /sessions/:sessionId/chats/:chatId/messages
+type MessageList struct {
+	SessionID     string        `json:"session_id"`
+	ChatID        string        `json:"chat_id"`
+	TotalMessages int           `json:"total_messages"`
+	Messages      []ChatMessage `json:"messages"`
+}
+
+// SendRequest es el cuerpo de POST /sessions/:sessionId/send
+type SendRequest struct {
+	Number  string `json:"number"`
+	Message string `json:"message"`
+}
@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"github.com/brando1998/docubot-api/baileys"
 	"github.com/brando1998/docubot-api/config"
 	"github.com/brando1998/docubot-api/controllers"
 	database "github.com/brando1998/docubot-api/databases"
+	"github.com/brando1998/docubot-api/middleware"
 	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/rasa"
 	"github.com/brando1998/docubot-api/repositories"
 	"github.com/brando1998/docubot-api/routes"
+	"github.com/brando1998/docubot-api/scheduler"
 	"github.com/brando1998/docubot-api/services"
+	"github.com/brando1998/docubot-api/storage"
+	"github.com/brando1998/docubot-api/tokens"
 )
 
 var (
@@ -27,6 +37,24 @@ func initDependencies() (*controllers.WebSocketHub, *gin.Engine) {
 	// 1. Configuración inicial
 	config.LoadEnv()
 
+	// 1.1 Llaves de los access tokens (PASETO v4.public). Sin llave de firma solo se
+	// arranca con TOKEN_ALLOW_EPHEMERAL_KEY=true (desarrollo), con una llave temporal
+	tokenManager, err := tokens.New(tokens.Config{
+		KeyID:            os.Getenv("TOKEN_SIGNING_KEY_ID"),
+		PrivateKey:       os.Getenv("TOKEN_SIGNING_KEY"),
+		VerificationKeys: os.Getenv("TOKEN_VERIFICATION_KEYS"),
+		AllowEphemeral:   config.GetEnvBool("TOKEN_ALLOW_EPHEMERAL_KEY", false),
+	})
+	if err != nil {
+		log.Fatalf("Failed to load token keys: %v", err)
+	}
+	middleware.SetTokenManager(tokenManager)
+	controllers.SetTokenManager(tokenManager)
+
+	// 1.2 Llaves HMAC de las credenciales de los bots y de los enlaces de descarga
+	botSigningKey := requiredEnv("BOT_WS_SIGNING_KEY")
+	documentLinkSigningKey := requiredEnv("DOCUMENT_LINK_SIGNING_KEY")
+
 	// 2. Conexiones a bases de datos
 	if err := database.ConnectPostgres(); err != nil {
 		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
@@ -43,6 +71,20 @@ func initDependencies() (*controllers.WebSocketHub, *gin.Engine) {
 		log.Printf("⚠️  Warning: Error en migración de datos: %v", err)
 	}
 
+	// 4.1 Mensajes de Mongo: de arreglos por conversación a un documento por mensaje
+	mongoDB := database.MongoClient.Database(os.Getenv("MONGO_DB"))
+	if err := services.MigrateConversationsToMessages(context.Background(), mongoDB, database.GetDB()); err != nil {
+		log.Printf("⚠️  Warning: Error migrando conversaciones: %v", err)
+	}
+	if err := services.BackfillMongoOrganizations(context.Background(), mongoDB, database.GetDB()); err != nil {
+		log.Printf("⚠️  Warning: Error asignando organización a datos de Mongo: %v", err)
+	}
+
+	// 4.2 Roles: user → agent y, una sola vez, un owner por organización
+	if err := services.MigrateLegacyRoles(database.GetDB()); err != nil {
+		log.Printf("⚠️  Warning: Error migrando roles: %v", err)
+	}
+
 	// 5. 🔥 Crear organización por defecto y usuario administrador
 	if err := services.EnsureDefaultAdminUser(database.GetDB()); err != nil {
 		log.Fatalf("Failed to ensure default admin user: %v", err)
@@ -52,12 +94,251 @@ func initDependencies() (*controllers.WebSocketHub, *gin.Engine) {
 	initRepositories()
 
 	// 7. WebSocket Hub
-	wsHub := controllers.NewWebSocketHub()
+	hubConfig := controllers.DefaultHubConfig()
+	hubConfig.PingInterval = config.GetEnvDuration("WS_PING_INTERVAL", hubConfig.PingInterval)
+	hubConfig.PongWait = config.GetEnvDuration("WS_PONG_WAIT", hubConfig.PongWait)
+	hubConfig.SendQueueSize = config.GetEnvInt("WS_SEND_QUEUE_SIZE", hubConfig.SendQueueSize)
+	hubConfig.AckTimeout = config.GetEnvDuration("WS_ACK_TIMEOUT", hubConfig.AckTimeout)
+	hubConfig.MaxDeliveryAttempts = config.GetEnvInt("WS_MAX_DELIVERY_ATTEMPTS", hubConfig.MaxDeliveryAttempts)
+	hubConfig.ReconnectWait = config.GetEnvDuration("WS_RECONNECT_WAIT", hubConfig.ReconnectWait)
+	wsHub := controllers.NewWebSocketHubWithConfig(hubConfig)
+
+	// Cola durable de mensajes salientes (se reenvían al reconectarse el bot)
+	queueConfig := controllers.DefaultOutboundQueueConfig()
+	queueConfig.BaseBackoff = config.GetEnvDuration("OUTBOUND_RETRY_BASE", queueConfig.BaseBackoff)
+	queueConfig.MaxBackoff = config.GetEnvDuration("OUTBOUND_RETRY_MAX", queueConfig.MaxBackoff)
+	queueConfig.MaxAttempts = config.GetEnvInt("OUTBOUND_MAX_ATTEMPTS", queueConfig.MaxAttempts)
+	queueConfig.OfflineTimeout = config.GetEnvDuration("OUTBOUND_OFFLINE_TIMEOUT", queueConfig.OfflineTimeout)
+	queueConfig.InstanceID = config.GetEnv("INSTANCE_ID", queueConfig.InstanceID)
+	queueConfig.DispatchInterval = config.GetEnvDuration("OUTBOUND_DISPATCH_INTERVAL", queueConfig.DispatchInterval)
+	outboundQueue := controllers.NewOutboundQueue(wsHub, repositories.NewOutboundMessageRepository(database.DB), queueConfig)
+	controllers.SetOutboundQueue(outboundQueue)
+	outboundQueue.Start()
+
+	// Pool de workers para los mensajes entrantes (paralelo entre chats, ordenado dentro de cada chat)
+	dispatcherConfig := controllers.DefaultDispatcherConfig()
+	dispatcherConfig.Workers = config.GetEnvInt("INCOMING_WORKERS", dispatcherConfig.Workers)
+	dispatcherConfig.MaxPending = config.GetEnvInt("INCOMING_MAX_PENDING", dispatcherConfig.MaxPending)
+	controllers.SetMessageDispatcher(controllers.NewMessageDispatcher(wsHub, dispatcherConfig))
+
+	// 8. Credenciales de las sesiones de baileys-ws para /ws
+	botCredentials, err := services.NewBotCredentialService(
+		repositories.NewBotSessionKeyRepository(database.DB),
+		botSigningKey,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize bot credentials: %v", err)
+	}
+	controllers.SetBotCredentialService(botCredentials)
+	wsHub.SetCredentialVerifier(botCredentials)
+
+	// 9. Retorno automático a modo bot de chats inactivos (el trabajo está en el scheduler)
+	humanTakeoverIdleTimeout := config.GetEnvDuration("HUMAN_TAKEOVER_IDLE_TIMEOUT", 30*time.Minute)
+	controllers.SetHumanTakeoverIdleTimeout(humanTakeoverIdleTimeout)
+
+	// 10. Cliente del servicio baileys-ws
+	controllers.SetBaileysClient(baileys.NewClient(baileys.Config{
+		BaseURL: config.GetEnv("BAILEYS_URL", baileys.DefaultBaseURL),
+		Timeout: config.GetEnvDuration("BAILEYS_TIMEOUT", baileys.DefaultTimeout),
+	}))
+
+	// 10.1 Clientes de Rasa (bot base e instancias; comparten conexiones)
+	controllers.SetRasaClients(rasa.NewPool(rasa.Config{
+		BaseURL: config.GetEnv("RASA_URL", rasa.DefaultBaseURL),
+		Token:   os.Getenv("RASA_TOKEN"),
+		Timeout: config.GetEnvDuration("RASA_TIMEOUT", rasa.DefaultTimeout),
+	}))
+	controllers.SetRasaButtonsMode(config.GetEnv("RASA_BUTTONS_MODE", controllers.RasaButtonsList))
+
+	// 10.2 Almacenamiento de los archivos de documentos (disco local o S3/MinIO)
+	documentStorage, err := storage.New(storage.Config{
+		Driver: config.GetEnv("STORAGE_DRIVER", "filesystem"),
+		Dir:    config.GetEnv("DOCUMENTS_DIR", "/app/documents"),
+		S3: storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Prefix:    os.Getenv("S3_PREFIX"),
+			PathStyle: config.GetEnvBool("S3_PATH_STYLE", true),
+			Timeout:   config.GetEnvDuration("S3_TIMEOUT", time.Minute),
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize document storage: %v", err)
+	}
+	controllers.SetDocumentStorage(documentStorage)
+	// URL externas que la API acepta en documentos y descarga del lado del servidor
+	controllers.SetDocumentURLOrigins(append(
+		strings.Split(os.Getenv("DOCUMENT_URL_ALLOWED_ORIGINS"), ","),
+		config.GetEnv("PLAYWRIGHT_URL", playwright.DefaultBaseURL),
+		os.Getenv("S3_ENDPOINT"),
+	)...)
+
+	// Enlaces firmados de descarga (sin login) y registro de descargas
+	linkConfig := services.DefaultDocumentLinkConfig()
+	linkConfig.BaseURL = config.GetEnv("PUBLIC_API_URL", linkConfig.BaseURL)
+	linkConfig.DefaultTTL = config.GetEnvDuration("DOCUMENT_LINK_TTL", linkConfig.DefaultTTL)
+	linkConfig.MaxTTL = config.GetEnvDuration("DOCUMENT_LINK_MAX_TTL", linkConfig.MaxTTL)
+	linkConfig.MaxDownloadsLimit = config.GetEnvInt("DOCUMENT_LINK_MAX_DOWNLOADS", linkConfig.MaxDownloadsLimit)
+	documentLinks, err := services.NewDocumentLinkService(
+		repositories.NewDocumentLinkRepository(database.DB),
+		documentLinkSigningKey,
+		linkConfig,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize document links: %v", err)
+	}
+	controllers.SetDocumentLinkService(documentLinks)
+
+	// Verificación pública de documentos (código + QR)
+	documentVerification := services.NewDocumentVerification(linkConfig.BaseURL)
+	controllers.SetDocumentVerification(documentVerification)
 
-	// 8. Configuración de Gin
+	// Usuarios del dashboard de cada organización, con roles y permisos
+	roles := services.NewRoleService(
+		repositories.NewRoleRepository(database.DB),
+		repositories.NewSystemUserRepository(database.DB),
+	)
+	middleware.SetPermissionResolver(roles)
+	controllers.SetRoleService(roles)
+	controllers.SetSystemUserService(services.NewSystemUserService(
+		repositories.NewSystemUserRepository(database.DB),
+		roles,
+	))
+
+	// API keys de los clientes máquina; el middleware de autenticación las acepta
+	apiKeys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(database.DB), roles)
+	middleware.SetAPIKeyAuthenticator(apiKeys)
+	controllers.SetAPIKeyService(apiKeys)
+
+	// Sesiones del dashboard: access token corto y refresh token rotado en cada uso
+	sessionConfig := services.DefaultAuthSessionConfig()
+	sessionConfig.RefreshTTL = config.GetEnvDuration("REFRESH_TOKEN_TTL", sessionConfig.RefreshTTL)
+	authSessions := services.NewAuthSessionService(
+		repositories.NewRefreshTokenRepository(database.DB),
+		repositories.NewSystemUserRepository(database.DB),
+		repositories.NewOrganizationRepository(database.DB),
+		controllers.PasetoAccessTokenIssuer{
+			Tokens: tokenManager,
+			TTL:    config.GetEnvDuration("ACCESS_TOKEN_TTL", controllers.DefaultAccessTokenTTL),
+		},
+		sessionConfig,
+	)
+	controllers.SetAuthSessionService(authSessions)
+
+	// Registro de vehículos y conductores de cada organización
+	registry := services.NewRegistryService(
+		repositories.NewVehicleRepository(database.DB),
+		repositories.NewDriverRepository(database.DB),
+		repositories.NewClientRepository(database.DB),
+	)
+	controllers.SetRegistryService(registry)
+
+	// Trabajos programados (una sola réplica los ejecuta gracias al lease en Postgres)
+	jobs := scheduler.New(repositories.NewJobLeaseRepository(database.DB), "")
+	reminderConfig := services.DefaultExpiryReminderConfig()
+	reminderConfig.StartHour = config.GetEnvInt("EXPIRY_REMINDER_START_HOUR", reminderConfig.StartHour)
+	reminderConfig.EndHour = config.GetEnvInt("EXPIRY_REMINDER_END_HOUR", reminderConfig.EndHour)
+	expiryReminders, err := services.NewExpiryReminderService(
+		repositories.NewOrganizationRepository(database.DB),
+		repositories.NewVehicleRepository(database.DB),
+		repositories.NewDriverRepository(database.DB),
+		repositories.NewExpiryReminderRepository(database.DB),
+		controllers.NewWhatsAppReminderSender(wsHub, repositories.NewBotSessionKeyRepository(database.DB)),
+		reminderConfig,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize expiry reminders: %v", err)
+	}
+	controllers.SetExpiryReminderService(expiryReminders)
+	jobs.Add(scheduler.Job{
+		Name:     "expiry-reminders",
+		Interval: config.GetEnvDuration("EXPIRY_REMINDER_INTERVAL", time.Hour),
+		Run:      expiryReminders.Run,
+	})
+	jobs.Add(scheduler.Job{
+		Name:     "refresh-token-cleanup",
+		Interval: 24 * time.Hour,
+		Run:      authSessions.Cleanup,
+	})
+	// Sin tiempo de inactividad los chats no vuelven solos al bot
+	idleChatModeInterval := config.GetEnvDuration("HUMAN_TAKEOVER_CHECK_INTERVAL", time.Minute)
+	if humanTakeoverIdleTimeout <= 0 {
+		idleChatModeInterval = 0
+	}
+	jobs.Add(scheduler.Job{
+		Name:     "idle-chat-mode-release",
+		Interval: idleChatModeInterval,
+		Run:      controllers.ReleaseIdleChatModes,
+	})
+
+	// 10.3 Generación de manifiestos con playwright-bot
+	manifestConfig := services.DefaultManifestOrchestratorConfig()
+	manifestConfig.MaxConcurrent = config.GetEnvInt("MANIFEST_MAX_CONCURRENT", manifestConfig.MaxConcurrent)
+	manifestConfig.MaxAttempts = config.GetEnvInt("MANIFEST_MAX_ATTEMPTS", manifestConfig.MaxAttempts)
+	manifestConfig.Timeout = config.GetEnvDuration("MANIFEST_TIMEOUT", manifestConfig.Timeout)
+	manifestConfig.Defaults = services.ManifestDefaults{
+		EmpresaNIT:        os.Getenv("RNDC_EMPRESA_NIT"),
+		SedeCargue:        os.Getenv("RNDC_SEDE_CARGUE"),
+		SedeDescargue:     os.Getenv("RNDC_SEDE_DESCARGUE"),
+		TitularTipoID:     config.GetEnv("RNDC_TITULAR_TIPO_ID", "Nit"),
+		TitularNumeroID:   os.Getenv("RNDC_TITULAR_NUMERO_ID"),
+		ConsecutivoPrefix: config.GetEnv("MANIFEST_CONSECUTIVO_PREFIX", "DB"),
+	}
+	playwrightClient := playwright.NewClient(playwright.Config{
+		BaseURL: config.GetEnv("PLAYWRIGHT_URL", playwright.DefaultBaseURL),
+		Timeout: config.GetEnvDuration("PLAYWRIGHT_TIMEOUT", playwright.DefaultTimeout),
+	})
+	documentSender := controllers.NewWhatsAppDocumentSender(wsHub)
+	manifestOrchestrator := services.NewManifestOrchestrator(
+		repositories.NewConversationRepository(database.MongoClient),
+		playwrightClient,
+		documentStorage,
+		documentSender,
+		manifestConfig,
+	)
+	if config.GetEnvBool("MANIFEST_SHARE_LINK", false) {
+		manifestOrchestrator.SetDocumentLinks(documentLinks)
+	}
+	if config.GetEnvBool("MANIFEST_VERIFICATION_QR", true) {
+		manifestOrchestrator.SetDocumentVerification(documentVerification)
+	}
+	manifestOrchestrator.SetRegistry(registry)
+	controllers.SetManifestOrchestrator(manifestOrchestrator)
+	// Los manifiestos que una caída o un reinicio dejaron en generating pasan a failed
+	jobs.Add(scheduler.Job{
+		Name:     "stale-manifest-recovery",
+		Interval: config.GetEnvDuration("MANIFEST_RECOVERY_INTERVAL", 5*time.Minute),
+		Run:      manifestOrchestrator.RecoverStale,
+	})
+	controllers.SetManifestTemplateService(services.NewManifestTemplateService(
+		repositories.NewConversationRepository(database.MongoClient),
+		repositories.NewRouteTemplateRepository(database.DB),
+		manifestOrchestrator,
+	))
+
+	// Cumplidos y anulaciones; la entrega se le confirma al conductor por WhatsApp
+	manifestFollowUps := services.NewManifestFollowUpService(
+		repositories.NewConversationRepository(database.MongoClient),
+		playwrightClient,
+		documentSender,
+		manifestConfig.Timeout,
+	)
+	manifestFollowUps.SetDeliveryConfirmation(
+		repositories.NewDriverRepository(database.DB),
+		controllers.NewWhatsAppReminderSender(wsHub, repositories.NewBotSessionKeyRepository(database.DB)),
+	)
+	controllers.SetManifestFollowUpService(manifestFollowUps)
+
+	jobs.Start()
+
+	// 11. Configuración de Gin
 	routerConfig := &routes.RouterConfig{
-		WSHub:    wsHub,
-		Upgrader: &upgrader,
+		WSHub:         wsHub,
+		Upgrader:      &upgrader,
+		InternalToken: os.Getenv("INTERNAL_API_TOKEN"),
 	}
 	r := gin.Default()
 
@@ -76,6 +357,19 @@ func runMigrations() {
 		&models.WhatsAppSession{},
 		&models.SystemUser{},
 		&models.BotInstance{},
+		&models.BotSessionKey{},
+		&models.OutboundMessage{},
+		&models.DocumentLink{},
+		&models.DocumentDownload{},
+		&models.Vehicle{},
+		&models.Driver{},
+		&models.ExpiryReminder{},
+		&models.JobLease{},
+		&models.RouteTemplate{},
+		&models.Role{},
+		&models.RefreshToken{},
+		&models.APIKey{},
+		&models.DataMigration{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
@@ -91,6 +385,10 @@ func initRepositories() {
 	botInstanceRepo := repositories.NewBotInstanceRepository(database.DB)
 	organizationRepo := repositories.NewOrganizationRepository(database.DB) // 🆕
 
+	if err := conversationRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("⚠️  Warning: Error creando índices de mensajes: %v", err)
+	}
+
 	controllers.SetConversationRepo(conversationRepo)
 	controllers.SetClientRepo(clientRepo)
 	controllers.SetBotRepo(botRepo)
@@ -102,6 +400,15 @@ func initRepositories() {
 	}
 }
 
+// requiredEnv devuelve la variable de entorno o detiene el arranque si no está definida
+func requiredEnv(key string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Fatalf("%s is required", key)
+	}
+	return value
+}
+
 func getServerPort() string {
 	if port := os.Getenv("PORT"); port != "" {
 		return port
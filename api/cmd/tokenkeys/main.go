@@ -0,0 +1,27 @@
+// tokenkeys genera un par de llaves Ed25519 para firmar los access tokens.
+//
+//	go run ./cmd/tokenkeys -kid 2026-10
+//
+// Rotación: publicar la llave pública anterior en TOKEN_VERIFICATION_KEYS, cambiar
+// TOKEN_SIGNING_KEY y TOKEN_SIGNING_KEY_ID por las nuevas y, pasado el TTL de los access
+// tokens, retirar la llave anterior.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/brando1998/docubot-api/tokens"
+)
+
+func main() {
+	kid := flag.String("kid", time.Now().Format("2006-01"), "ID de la llave (va en el footer de los tokens)")
+	flag.Parse()
+
+	privateKey, publicKey := tokens.GenerateKey()
+	fmt.Printf("TOKEN_SIGNING_KEY_ID=%s\n", *kid)
+	fmt.Printf("TOKEN_SIGNING_KEY=%s\n", privateKey)
+	fmt.Printf("# Llave pública (para otros servicios o TOKEN_VERIFICATION_KEYS al rotar)\n")
+	fmt.Printf("# %s:%s\n", *kid, publicKey)
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// GetEnv devuelve el valor de la variable de entorno o el valor por defecto
+func GetEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// GetEnvInt devuelve la variable de entorno como entero o el valor por defecto
+func GetEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️  %s inválido (%q), usando %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvDuration devuelve la variable de entorno como duración (ej: "30m", "10s")
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️  %s inválido (%q), usando %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// GetEnvBool devuelve la variable de entorno como booleano o el valor por defecto
+func GetEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("⚠️  %s inválido (%q), usando %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+var apiKeyService *services.APIKeyService
+
+// SetAPIKeyService inyecta el servicio de API keys
+func SetAPIKeyService(service *services.APIKeyService) {
+	apiKeyService = service
+}
+
+// CreateAPIKeyRequest son los datos de una API key nueva
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // Opcional; sin fecha no vence
+}
+
+// ListAPIKeys lista las API keys de la organización (sin el secreto)
+// @Summary Listar API keys
+// @Tags api-keys
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/api-keys [get]
+func ListAPIKeys(c *gin.Context) {
+	actor, ok := apiKeyActor(c)
+	if !ok {
+		return
+	}
+	keys, err := apiKeyService.List(actor.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo API keys",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys, "scopes": models.APIKeyScopes})
+}
+
+// CreateAPIKey crea una API key para un cliente máquina de la organización
+// @Summary Crear API key
+// @Description La key completa solo se devuelve en esta respuesta. Se usa como "Authorization: Bearer dbk_..." o en la cabecera X-API-Key.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "Nombre, permisos y vencimiento"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	actor, ok := apiKeyActor(c)
+	if !ok {
+		return
+	}
+	var request CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	key, raw, err := apiKeyService.Create(actor, services.APIKeyInput{
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": raw})
+}
+
+// RevokeAPIKey revoca una API key; deja de aceptarse de inmediato
+// @Summary Revocar API key
+// @Tags api-keys
+// @Param id path int true "ID de la API key"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/api-keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	actor, ok := apiKeyActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	if err := apiKeyService.Revoke(actor, id); err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// apiKeyActor devuelve el usuario autenticado; una API key no administra otras keys
+func apiKeyActor(c *gin.Context) (models.SystemUser, bool) {
+	if apiKeyService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API keys no configuradas"})
+		return models.SystemUser{}, false
+	}
+	value, exists := c.Get("current_user")
+	actor, ok := value.(models.SystemUser)
+	if !exists || !ok || actor.OrganizationID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return models.SystemUser{}, false
+	}
+	return actor, true
+}
+
+// respondAPIKeyError traduce los errores de las API keys
+func respondAPIKeyError(c *gin.Context, err error) {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+			"fields":  fields,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key no encontrada"})
+	case errors.Is(err, services.ErrPermissionEscalation):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Permiso insuficiente",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error guardando la API key",
+			"details": err.Error(),
+		})
+	}
+}
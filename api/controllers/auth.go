@@ -2,32 +2,51 @@ package controllers
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/o1egl/paseto"
 
 	database "github.com/brando1998/docubot-api/databases"
 	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+	"github.com/brando1998/docubot-api/tokens"
 )
 
-const (
-	tokenDuration = 24 * time.Hour
-)
+// DefaultAccessTokenTTL es la duración de los access tokens; el dashboard los renueva
+// con el refresh token
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+var authSessions *services.AuthSessionService
+
+// SetAuthSessionService inyecta el servicio de sesiones (refresh tokens)
+func SetAuthSessionService(service *services.AuthSessionService) {
+	authSessions = service
+}
+
+var tokenManager *tokens.Manager
 
-// ✅ SIMPLIFICAR: Token solo con lo esencial
-type PasetoPayload struct {
-	UserID    uint      `json:"user_id"`
-	Username  string    `json:"username"`
-	Role      string    `json:"role"`
-	IssuedAt  time.Time `json:"issued_at"`
-	ExpiresAt time.Time `json:"expires_at"`
-	// ✅ NO incluir email - lo obtendremos del endpoint /auth/me
+// SetTokenManager inyecta las llaves de los access tokens (publica las de verificación)
+func SetTokenManager(manager *tokens.Manager) {
+	tokenManager = manager
+}
+
+// PasetoAccessTokenIssuer emite los access tokens PASETO de las sesiones
+type PasetoAccessTokenIssuer struct {
+	Tokens *tokens.Manager
+	TTL    time.Duration
+}
+
+// IssueAccessToken implementa services.AccessTokenIssuer
+func (i PasetoAccessTokenIssuer) IssueAccessToken(user models.SystemUser) (string, time.Time, error) {
+	return i.Tokens.Issue(tokens.Claims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+	}, i.TTL)
 }
 
 // LoginRequest define la estructura para el request de login
@@ -36,11 +55,18 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// RefreshRequest lleva el refresh token que entregó el login o la última renovación
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // LoginResponse define la estructura de respuesta para el login
 type LoginResponse struct {
-	AccessToken string    `json:"access_token"`
-	ExpiresAt   time.Time `json:"expires_at"`
-	User        struct {
+	AccessToken      string    `json:"access_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             struct {
 		ID       uint   `json:"id"`
 		Username string `json:"username"`
 		Email    string `json:"email"`
@@ -50,6 +76,11 @@ type LoginResponse struct {
 
 // LoginWithPaseto maneja el inicio de sesión
 func LoginWithPaseto(c *gin.Context) {
+	if authSessions == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sesiones no configuradas"})
+		return
+	}
+
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "datos inválidos"})
@@ -79,20 +110,26 @@ func LoginWithPaseto(c *gin.Context) {
 		return
 	}
 
-	now := time.Now()
-	user.LastLogin = &now
-	db.Save(&user)
-
-	// ✅ SIMPLIFICAR: Token sin email
-	token, expiresAt, err := generatePasetoToken(user.ID, user.Username, user.Role)
+	// Access token corto + refresh token de una sesión nueva
+	session, err := authSessions.Login(user, sessionClient(c))
+	if errors.Is(err, services.ErrOrganizationInactive) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error generando token"})
 		return
 	}
 
+	now := time.Now()
+	user.LastLogin = &now
+	db.Save(&user)
+
 	response := LoginResponse{
-		AccessToken: token,
-		ExpiresAt:   expiresAt,
+		AccessToken:      session.AccessToken,
+		ExpiresAt:        session.ExpiresAt,
+		RefreshToken:     session.RefreshToken,
+		RefreshExpiresAt: session.RefreshExpiresAt,
 	}
 	response.User.ID = user.ID
 	response.User.Username = user.Username
@@ -102,96 +139,114 @@ func LoginWithPaseto(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// generatePasetoToken genera un nuevo token PASETO
-func generatePasetoToken(userID uint, username, role string) (string, time.Time, error) {
-	fmt.Printf("🔍 DEBUG generatePasetoToken - UserID: %d, Username: %s, Role: %s\n", userID, username, role)
-
-	now := time.Now()
-	expiresAt := now.Add(tokenDuration)
-
-	payload := PasetoPayload{
-		UserID:    userID,
-		Username:  username,
-		Role:      role,
-		IssuedAt:  now,
-		ExpiresAt: expiresAt,
-	}
-
-	fmt.Printf("🔍 DEBUG - Payload creado: %+v\n", payload)
-
-	secretKeyFromEnv := os.Getenv("PASETO_SECRET_KEY")
-	fmt.Printf("🔍 DEBUG - Variable PASETO_SECRET_KEY completa: '%s'\n", secretKeyFromEnv)
-	fmt.Printf("🔍 DEBUG - Longitud exacta: %d caracteres\n", len(secretKeyFromEnv))
-
-	secretKey := []byte(secretKeyFromEnv)
-
-	fmt.Printf("🔍 DEBUG - Longitud después de []byte: %d\n", len(secretKey))
-
-	if len(secretKey) == 0 {
-		fmt.Printf("🔍 DEBUG - Variable vacía, usando clave por defecto\n")
-		secretKey = []byte("default-secret-key-change-in-production-32-chars")
-		fmt.Printf("🔍 DEBUG - Nueva longitud: %d\n", len(secretKey))
+// RefreshPasetoToken rota el refresh token y entrega un access token nuevo
+// @Summary Renovar sesión
+// @Description Cada refresh token sirve una sola vez. Si uno ya usado vuelve a llegar se revoca toda la sesión.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} services.AuthSession
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /auth/refresh [post]
+func RefreshPasetoToken(c *gin.Context) {
+	if authSessions == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sesiones no configuradas"})
+		return
 	}
 
-	if len(secretKey) != 32 {
-		fmt.Printf("❌ DEBUG - Longitud de clave incorrecta: %d, se requieren 32\n", len(secretKey))
-		return "", time.Time{}, errors.New("PASETO_SECRET_KEY debe tener exactamente 32 caracteres")
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "datos inválidos"})
+		return
 	}
 
-	fmt.Printf("🔍 DEBUG - Intentando encriptar token...\n")
-	v2 := paseto.NewV2()
-	token, err := v2.Encrypt(secretKey, payload, nil)
+	session, err := authSessions.Refresh(req.RefreshToken, sessionClient(c))
 	if err != nil {
-		fmt.Printf("❌ DEBUG - Error en v2.Encrypt: %v\n", err)
-		return "", time.Time{}, err
+		if errors.Is(err, services.ErrRefreshTokenInvalid) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrOrganizationInactive) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error generando nuevo token"})
+		return
 	}
 
-	fmt.Printf("✅ DEBUG - Token generado exitosamente, longitud: %d\n", len(token))
-	return token, expiresAt, nil
+	c.JSON(http.StatusOK, session)
 }
 
-// RefreshPasetoToken maneja la renovación de tokens
-func RefreshPasetoToken(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "token no proporcionado"})
+// Logout cierra la sesión del refresh token
+// @Summary Cerrar sesión
+// @Tags auth
+// @Accept json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 204
+// @Router /auth/logout [post]
+func Logout(c *gin.Context) {
+	if authSessions == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sesiones no configuradas"})
 		return
 	}
 
-	tokenString := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		tokenString = authHeader[7:]
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "datos inválidos"})
+		return
 	}
-
-	payload, err := verifyPasetoToken(tokenString)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "token inválido"})
+	if err := authSessions.Logout(req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error cerrando sesión"})
 		return
 	}
+	c.Status(http.StatusNoContent)
+}
 
-	db := database.GetDB()
-	if db == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error de conexión a base de datos"})
+// LogoutAll cierra la sesión en todos los dispositivos del usuario autenticado
+// @Summary Cerrar sesión en todos los dispositivos
+// @Description Revoca los refresh tokens e invalida los access tokens ya emitidos.
+// @Tags auth
+// @Success 204
+// @Router /auth/logout-all [post]
+func LogoutAll(c *gin.Context) {
+	if authSessions == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "sesiones no configuradas"})
 		return
 	}
 
-	var user models.SystemUser
-	if err := db.First(&user, payload.UserID).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "usuario no encontrado"})
+	value, exists := c.Get("current_user")
+	user, ok := value.(models.SystemUser)
+	if !exists || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
 		return
 	}
-
-	// ✅ SIMPLIFICAR: Nuevo token sin email
-	newToken, expiresAt, err := generatePasetoToken(user.ID, user.Username, user.Role)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error generando nuevo token"})
+	if err := authSessions.LogoutAll(user.ID, user.OrganizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error cerrando sesiones"})
 		return
 	}
+	c.Status(http.StatusNoContent)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token": newToken,
-		"expires_at":   expiresAt,
-	})
+func sessionClient(c *gin.Context) services.SessionClient {
+	return services.SessionClient{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// GetTokenKeys publica las llaves públicas con que se verifican los access tokens
+// @Summary Llaves de verificación de tokens
+// @Description Llaves Ed25519 (hex) de los tokens v4.public; el footer de cada token indica su kid. Otros servicios verifican los tokens con ellas.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/keys [get]
+func GetTokenKeys(c *gin.Context) {
+	if tokenManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Autenticación no configurada"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"issuer": tokens.Issuer, "keys": tokenManager.PublicKeys()})
 }
 
 // ✅ NUEVO: Endpoint para obtener usuario actual del sistema
@@ -222,38 +277,20 @@ func GetCurrentSystemUser(c *gin.Context) {
 		return
 	}
 
+	// Permisos del rol (los resuelve PasetoAuthMiddleware) para que el dashboard oculte acciones
+	permissions, _ := c.Get("current_user_permissions")
+	if permissions == nil {
+		permissions = []string{}
+	}
+
 	// ✅ RESPUESTA: Solo datos necesarios, sin password
 	c.JSON(http.StatusOK, gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"role":       user.Role,
-		"is_active":  user.IsActive,
-		"last_login": user.LastLogin,
+		"id":          user.ID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"role":        user.Role,
+		"permissions": permissions,
+		"is_active":   user.IsActive,
+		"last_login":  user.LastLogin,
 	})
 }
-
-// verifyPasetoToken verifica y decodifica el token
-func verifyPasetoToken(token string) (*PasetoPayload, error) {
-	v2 := paseto.NewV2()
-	var payload PasetoPayload
-
-	secretKey := []byte(os.Getenv("PASETO_SECRET_KEY"))
-	if len(secretKey) == 0 {
-		secretKey = []byte("default-secret-key-change-in-production-32-chars")
-	}
-
-	if len(secretKey) != 32 {
-		return nil, errors.New("PASETO_SECRET_KEY debe tener exactamente 32 caracteres")
-	}
-
-	if err := v2.Decrypt(token, secretKey, &payload, nil); err != nil {
-		return nil, errors.New("token inválido")
-	}
-
-	if time.Now().After(payload.ExpiresAt) {
-		return nil, errors.New("token expirado")
-	}
-
-	return &payload, nil
-}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/baileys"
+	"github.com/brando1998/docubot-api/services"
+)
+
+var botCredentialService *services.BotCredentialService
+
+// SetBotCredentialService inyecta el servicio de credenciales de sesiones de bot
+func SetBotCredentialService(service *services.BotCredentialService) {
+	botCredentialService = service
+}
+
+// createBaileysSession crea la sesión en baileys-ws entregándole la credencial
+// con la que debe conectarse a /ws. La sesión queda ligada a la organización.
+func createBaileysSession(c *gin.Context, sessionID string) (*baileys.CreateSessionResponse, error) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		return nil, errors.New("organización no encontrada")
+	}
+	var userID uint
+	if v, ok := c.Get("current_user_id"); ok {
+		userID, _ = v.(uint)
+	}
+
+	token, err := botCredentialService.EnsureToken(orgIDInterface.(uint), sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return baileysClient.CreateSession(c.Request.Context(), sessionID, baileys.CreateSessionRequest{WSToken: token})
+}
+
+// requireSessionOwner verifica que la sesión sea de la organización autenticada. Las sesiones
+// que nadie ha reclamado solo se aceptan con allowUnclaimed (el QR y el estado las crean y
+// así las reclaman). Si no, ya respondió el error y devuelve false.
+func requireSessionOwner(c *gin.Context, sessionID string, allowUnclaimed bool) bool {
+	orgID := c.GetUint("organization_id")
+	if orgID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return false
+	}
+	if botCredentialService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Credenciales de bot no configuradas"})
+		return false
+	}
+	err := botCredentialService.CheckSessionOwner(orgID, sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if allowUnclaimed {
+			return true
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sesión no encontrada"})
+		return false
+	}
+	if err != nil {
+		respondBaileysError(c, "Sesión no disponible", err)
+		return false
+	}
+	return true
+}
+
+// ListBotSessionCredentials lista las credenciales de /ws de una sesión
+// @Summary Listar credenciales de sesión
+// @Description Lista las credenciales (sin tokens) con las que la sesión se conecta al WebSocket de bots
+// @Tags whatsapp
+// @Produce json
+// @Param sessionId path string true "ID de la sesión"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/whatsapp/sessions/{sessionId}/credentials [get]
+func ListBotSessionCredentials(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+	sessionID := c.Param("sessionId")
+
+	keys, err := botCredentialService.ListBySession(orgID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo credenciales",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id":  sessionID,
+		"credentials": keys,
+	})
+}
+
+// RotateBotSessionCredential emite una nueva credencial para la sesión y revoca las anteriores
+// @Summary Rotar credencial de sesión
+// @Description Emite una nueva credencial, desconecta los bots que usaban las anteriores y la entrega a baileys-ws
+// @Tags whatsapp
+// @Produce json
+// @Param sessionId path string true "ID de la sesión"
+// @Success 201 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/whatsapp/sessions/{sessionId}/credentials/rotate [post]
+func RotateBotSessionCredential(c *gin.Context, hub *WebSocketHub) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+	var userID uint
+	if v, ok := c.Get("current_user_id"); ok {
+		userID, _ = v.(uint)
+	}
+	sessionID := c.Param("sessionId")
+
+	token, key, revoked, err := botCredentialService.Issue(orgID, sessionID, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrBotSessionTaken) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error":   "Error rotando credencial",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Primero cerrar las conexiones con credenciales viejas para que baileys-ws pueda reconectarse
+	disconnected := hub.DisconnectBotsByKeyIDs(revoked)
+
+	// Entregar la nueva credencial a baileys-ws; si la sesión no está activa la recibirá al crearse
+	delivered := true
+	if _, err := baileysClient.UpdateSessionToken(c.Request.Context(), sessionID, token); err != nil {
+		delivered = false
+		if !errors.Is(err, baileys.ErrSessionNotFound) {
+			log.Printf("⚠️ No se pudo entregar la nueva credencial a la sesión %s: %v", sessionID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":              "Credencial rotada correctamente",
+		"credential":           key,
+		"token":                token, // Solo se muestra una vez
+		"revoked_key_ids":      revoked,
+		"disconnected_bots":    disconnected,
+		"delivered_to_baileys": delivered,
+	})
+}
+
+// RevokeBotSessionCredential revoca una credencial y desconecta los bots que la usan
+// @Summary Revocar credencial de sesión
+// @Tags whatsapp
+// @Produce json
+// @Param sessionId path string true "ID de la sesión"
+// @Param keyId path string true "ID de la credencial"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/whatsapp/sessions/{sessionId}/credentials/{keyId} [delete]
+func RevokeBotSessionCredential(c *gin.Context, hub *WebSocketHub) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+	sessionID := c.Param("sessionId")
+	keyID := c.Param("keyId")
+
+	if err := botCredentialService.Revoke(orgID, sessionID, keyID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Credencial no encontrada o ya revocada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error revocando credencial",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	disconnected := hub.DisconnectBotsByKeyIDs([]string{keyID})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Credencial revocada correctamente",
+		"disconnected_bots": disconnected,
+	})
+}
@@ -42,8 +42,10 @@ func CreateBotInstance(c *gin.Context) {
 	}
 	orgID := orgIDInterface.(uint)
 
-	userID, exists := c.Get("current_user_id")
-	if !exists {
+	// Con una API key no hay usuario: queda registrada la key que la creó
+	userID := c.GetUint("current_user_id")
+	apiKeyID := c.GetUint("api_key_id")
+	if userID == 0 && apiKeyID == 0 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
 		return
 	}
@@ -82,7 +84,8 @@ func CreateBotInstance(c *gin.Context) {
 		Status:         "running",
 		BasedOnBotID:   req.BasedOnBotID,
 		WhatsAppNumber: req.WhatsAppNumber,
-		CreatedBy:      userID.(uint),
+		CreatedBy:      userID,
+		CreatedByKeyID: apiKeyID,
 	}
 
 	if err := botInstanceRepo.Create(&instance); err != nil {
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// humanTakeoverIdleTimeout es el tiempo sin actividad del agente tras el cual un chat
+// en modo usuario vuelve automáticamente al bot (0 = nunca)
+var humanTakeoverIdleTimeout = 30 * time.Minute
+
+// SetHumanTakeoverIdleTimeout configura el tiempo de inactividad para volver al modo bot
+func SetHumanTakeoverIdleTimeout(timeout time.Duration) {
+	humanTakeoverIdleTimeout = timeout
+}
+
+// chatIDCandidates devuelve las formas en que puede estar guardado el ID de un chat:
+// el JID completo de WhatsApp y el número limpio
+func chatIDCandidates(phone string) []string {
+	cleanPhone := strings.Split(phone, "@")[0]
+	if cleanPhone == phone {
+		return []string{phone, phone + "@s.whatsapp.net"}
+	}
+	return []string{phone, cleanPhone}
+}
+
+// isChatModeIdle indica si un chat en modo usuario superó el tiempo de inactividad
+func isChatModeIdle(chatMode *models.ChatMode, now time.Time) bool {
+	if humanTakeoverIdleTimeout <= 0 || chatMode.BotMode {
+		return false
+	}
+	lastActivity := chatMode.LastActivityAt
+	if lastActivity.IsZero() {
+		lastActivity = chatMode.UpdatedAt
+	}
+	return now.Sub(lastActivity) > humanTakeoverIdleTimeout
+}
+
+// isHumanTakeover indica si un agente tomó el control del chat. Si el agente dejó el chat
+// inactivo más del tiempo configurado, el chat vuelve al modo bot.
+func isHumanTakeover(ctx context.Context, orgID uint, sessionID, phone string) bool {
+	chatMode, err := conversationRepo.FindChatMode(ctx, sessionID, chatIDCandidates(phone), orgID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("⚠️ Error consultando modo del chat %s (session: %s): %v", phone, sessionID, err)
+		}
+		return false
+	}
+
+	if chatMode.BotMode {
+		return false
+	}
+
+	if isChatModeIdle(chatMode, time.Now()) {
+		log.Printf("⏰ Chat %s sin actividad del agente, volviendo a modo bot", chatMode.ChatID)
+		if err := conversationRepo.UpdateChatMode(ctx, chatMode.ClientID, chatMode.SessionID, chatMode.ChatID, true, orgID); err != nil {
+			log.Printf("⚠️ Error devolviendo chat %s a modo bot: %v", chatMode.ChatID, err)
+		}
+		return false
+	}
+
+	return true
+}
+
+// touchChatMode registra la actividad del agente para que el chat no vuelva al bot
+func touchChatMode(ctx context.Context, orgID uint, sessionID, chatID string) {
+	if err := conversationRepo.TouchChatMode(ctx, sessionID, chatIDCandidates(chatID), orgID); err != nil {
+		log.Printf("⚠️ Error registrando actividad del agente en chat %s: %v", chatID, err)
+	}
+}
+
+// ReleaseIdleChatModes devuelve al modo bot los chats en modo usuario que superaron el
+// tiempo de inactividad. Se registra en el scheduler para que solo la réplica líder lo ejecute.
+func ReleaseIdleChatModes(ctx context.Context) error {
+	if humanTakeoverIdleTimeout <= 0 {
+		return nil
+	}
+	released, err := conversationRepo.ReleaseIdleChatModes(ctx, time.Now().Add(-humanTakeoverIdleTimeout))
+	if err != nil {
+		return fmt.Errorf("error liberando chats inactivos: %w", err)
+	}
+	if released > 0 {
+		log.Printf("🤖 %d chats inactivos devueltos a modo bot", released)
+	}
+	return nil
+}
+
+// HandleAgentWebSocket conecta a un agente del dashboard para recibir los mensajes
+// entrantes de los chats de su organización en tiempo real
+// @Summary WebSocket de agentes
+// @Description Recibe en tiempo real los mensajes entrantes de la organización
+// @Tags chats
+// @Param token query string false "Token PASETO (alternativa a la cabecera Authorization)"
+// @Router /api/v1/chats/ws [get]
+func HandleAgentWebSocket(c *gin.Context, hub *WebSocketHub, upgrader websocket.Upgrader) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("WebSocket upgrade failed:", err)
+		return
+	}
+
+	hub.RegisterAgent(orgID, conn)
+
+	go func() {
+		defer hub.UnregisterAgent(orgID, conn)
+
+		// Los agentes solo escuchan; leer es necesario para detectar el cierre
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+	}()
+}
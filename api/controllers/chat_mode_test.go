@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+func TestChatIDCandidates(t *testing.T) {
+	assert.Equal(t, []string{"573001234567@s.whatsapp.net", "573001234567"}, chatIDCandidates("573001234567@s.whatsapp.net"))
+	assert.Equal(t, []string{"573001234567", "573001234567@s.whatsapp.net"}, chatIDCandidates("573001234567"))
+}
+
+func TestIsChatModeIdle(t *testing.T) {
+	defer SetHumanTakeoverIdleTimeout(humanTakeoverIdleTimeout)
+	SetHumanTakeoverIdleTimeout(30 * time.Minute)
+	now := time.Now()
+
+	recent := &models.ChatMode{BotMode: false, LastActivityAt: now.Add(-5 * time.Minute)}
+	assert.False(t, isChatModeIdle(recent, now))
+
+	idle := &models.ChatMode{BotMode: false, LastActivityAt: now.Add(-time.Hour)}
+	assert.True(t, isChatModeIdle(idle, now))
+
+	// Documentos anteriores sin last_activity_at usan updated_at
+	legacy := &models.ChatMode{BotMode: false, UpdatedAt: now.Add(-time.Hour)}
+	assert.True(t, isChatModeIdle(legacy, now))
+
+	botMode := &models.ChatMode{BotMode: true, LastActivityAt: now.Add(-time.Hour)}
+	assert.False(t, isChatModeIdle(botMode, now))
+
+	SetHumanTakeoverIdleTimeout(0)
+	assert.False(t, isChatModeIdle(idle, now))
+}
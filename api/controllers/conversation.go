@@ -1,12 +1,13 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,15 +26,34 @@ var (
 )
 
 type IncomingMessageRequest struct {
+	ID          string `json:"id,omitempty"` // ID del mensaje en WhatsApp; vuelve en el incoming_nack
 	Phone       string `json:"phone"`
 	Message     string `json:"message"`
 	BotNumber   string `json:"botNumber"`
 	SessionID   string `json:"sessionId,omitempty"`   // ID de la sesión para múltiples bots
 	MessageType string `json:"messageType,omitempty"` // Tipo de mensaje (text, audio, image, etc.)
+
+	OrganizationID uint   `json:"-"` // Organización de la credencial del bot, la asigna el servidor
+	BotKey         string `json:"-"` // Clave (sessionId:número) de la conexión autenticada que lo recibió
 }
 
-type RasaResponseItem struct {
-	Text string `json:"text"`
+// ErrFrameSessionMismatch indica un frame con una sesión o un número de bot distintos a los
+// de la conexión autenticada
+var ErrFrameSessionMismatch = errors.New("el mensaje no corresponde a la sesión de la conexión")
+
+// bindToConnection ata el mensaje a la sesión y al bot de la conexión autenticada: la
+// credencial solo vale para esa sesión, así que un frame no puede hablar por otra
+func (msg *IncomingMessageRequest) bindToConnection(sessionID, botPhone string) error {
+	if msg.SessionID != "" && msg.SessionID != sessionID {
+		return ErrFrameSessionMismatch
+	}
+	if msg.BotNumber != "" && strings.Split(msg.BotNumber, "@")[0] != strings.Split(botPhone, "@")[0] {
+		return ErrFrameSessionMismatch
+	}
+	msg.SessionID = sessionID
+	msg.BotNumber = botPhone
+	msg.BotKey = fmt.Sprintf("%s:%s", sessionID, botPhone)
+	return nil
 }
 
 // Setters para inyección de dependencias
@@ -55,19 +75,26 @@ func HandleWebSocket(c *gin.Context, hub *WebSocketHub, upgrader websocket.Upgra
 	//Obtener el numero y sessionId
 	log.Println("Nueva conexión WebSocket intentada")
 	botPhone := c.Query("phone")      // Este es el número DEL BOT
-	sessionId := c.Query("sessionId") // ID de la sesión (opcional)
-	if botPhone == "" {
-		log.Println("Bot phone number missing in WebSocket connection")
+	sessionId := c.Query("sessionId") // ID de la sesión de baileys-ws
+	if botPhone == "" || sessionId == "" {
+		log.Println("Bot phone number or sessionId missing in WebSocket connection")
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
 
-	// Usar sessionId como identificador único si está presente
-	botKey := botPhone
-	if sessionId != "" {
-		botKey = fmt.Sprintf("%s:%s", sessionId, botPhone)
+	// Credencial firmada emitida para la sesión (query ?token= o Authorization: Bearer)
+	token := c.Query("token")
+	if token == "" {
+		token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if _, err := hub.AuthenticateBot(sessionId, token); err != nil {
+		log.Printf("🔒 Conexión de bot rechazada (session: %s): %v", sessionId, err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Credencial de bot inválida o revocada"})
+		return
 	}
 
+	botKey := fmt.Sprintf("%s:%s", sessionId, botPhone)
+
 	// Verificar si el bot ya está registrado
 	if _, err := hub.GetBotConnection(botKey); err == nil {
 		log.Printf("Bot %s ya está registrado", botKey)
@@ -83,108 +110,117 @@ func HandleWebSocket(c *gin.Context, hub *WebSocketHub, upgrader websocket.Upgra
 
 	// Registrar la conexión del BOT con la clave única
 	log.Printf("Registrando bot: %s (session: %s)", botPhone, sessionId)
-	hub.RegisterBot(botKey, conn)
+	credential, err := hub.RegisterBot(botKey, sessionId, token, conn)
+	if err != nil {
+		// La credencial pudo revocarse entre la validación y el registro
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "credencial inválida"),
+			time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
 
 	// Manejar mensajes entrantes
 	go func() {
 		defer func() {
-			hub.UnregisterBot(botKey)
-			conn.Close()
+			hub.UnregisterBot(botKey, conn)
 			log.Printf("Conexión cerrada para bot: %s (session: %s)", botPhone, sessionId)
 		}()
 
 		for {
-			// Procesar mensajes, primero convertir de json a struct
-			var msg IncomingMessageRequest
-			if err := conn.ReadJSON(&msg); err != nil {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
 				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
 					log.Printf("Error reading message: %v", err)
 				}
 				break
 			}
-			// Procesar mensaje
-			if err := processIncomingMessage(msg, hub); err != nil {
-				log.Printf("Error processing message: %v", err)
+
+			// Acks y nacks de los mensajes enviados por el hub
+			var frame botFrame
+			if err := json.Unmarshal(data, &frame); err != nil {
+				log.Printf("Mensaje inválido del bot %s: %v", botKey, err)
+				continue
+			}
+			switch frame.Type {
+			case BotFrameAck:
+				hub.HandleBotAck(botKey, frame.ID)
+				continue
+			case BotFrameNack:
+				hub.HandleBotNack(botKey, frame.ID, frame.Error)
+				continue
+			}
+
+			// Procesar mensajes, primero convertir de json a struct
+			var msg IncomingMessageRequest
+			if err := json.Unmarshal(data, &msg); err != nil {
+				log.Printf("Mensaje inválido del bot %s: %v", botKey, err)
+				continue
+			}
+			// Baileys no siempre incluye la sesión en el mensaje; siempre vale la de la conexión
+			if err := msg.bindToConnection(sessionId, botPhone); err != nil {
+				log.Printf("🔒 Mensaje rechazado del bot %s (session: %s, botNumber: %s): %v",
+					botKey, msg.SessionID, msg.BotNumber, err)
+				continue
 			}
+			// La organización sale de la credencial, no del mensaje
+			msg.OrganizationID = credential.OrganizationID
+
+			// El dispatcher procesa chats distintos en paralelo y conserva el orden
+			// dentro de cada chat; con la cola llena rechaza el mensaje (incoming_nack) en
+			// vez de bloquear la lectura, que sigue atendiendo pongs y acks
+			dispatchIncoming(msg, hub)
 		}
 	}()
 }
 
+// GetConnectedBots lista los bots conectados a esta instancia (sessionId:número) de todas
+// las organizaciones
+// @Summary Bots conectados
+// @Tags debug
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/debug/bots [get]
+func GetConnectedBots(c *gin.Context, hub *WebSocketHub) {
+	bots := hub.ListBots()
+	sort.Strings(bots)
+	c.JSON(http.StatusOK, gin.H{
+		"bots":  bots,
+		"total": len(bots),
+	})
+}
+
+// botFrame identifica los frames de control que envía baileys-ws
+type botFrame struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
 // processIncomingMessage procesa los mensajes entrantes
 func processIncomingMessage(msg IncomingMessageRequest, hub *WebSocketHub) error {
 	if strings.Contains(msg.Phone, "@g.us") {
 		log.Printf("⚠️ Mensaje de grupo ignorado: %s", msg.Phone)
 		return nil
 	}
-	// Asegurar formato consistente del botNumber
-	sessionId := msg.SessionID
-	if sessionId == "" {
-		sessionId = "default"
+	// Las respuestas salen por la conexión autenticada que recibió el mensaje
+	botKey := msg.BotKey
+	if botKey == "" {
+		return errors.New("mensaje sin conexión de bot autenticada")
 	}
-	botKey := fmt.Sprintf("%s:%s", sessionId, msg.BotNumber)
+	sessionId := msg.SessionID
 
 	log.Printf("Procesando mensaje de %s a bot %s (session: %s): %s (tipo: %s)", msg.Phone, msg.BotNumber, sessionId, msg.Message, msg.MessageType)
 
-	// Verificar si es un mensaje de audio
-	if msg.MessageType == "audio" {
-		log.Printf("Mensaje de audio recibido de %s - respondiendo automáticamente", msg.Phone)
-
-		// Responder automáticamente sin procesar con Rasa
-		responseText := "🤖 Lo siento, por ahora solo puedo procesar mensajes de texto. Por favor, envíame tu mensaje escrito. 📝"
-
-		// Enviar respuesta automática al cliente
-		if err := hub.SendToBot(botKey, map[string]interface{}{
-			"to":        msg.Phone,
-			"message":   responseText,
-			"sessionId": sessionId,
-		}); err != nil {
-			log.Printf("Failed to send audio response to bot: %v", err)
-		}
-
-		// Guardar el mensaje de audio y la respuesta en la DB
-		cleanPhone := strings.Split(msg.Phone, "@")[0]
-		orgID := getOrganizationIDFromBot(msg.BotNumber)
-		client, err := clientRepo.GetOrCreateClient(cleanPhone, "", "", orgID)
-		if err != nil {
-			return fmt.Errorf("failed to get/create client: %w", err)
-		}
-
-		cleanBotNumber := strings.Split(msg.BotNumber, "@")[0]
-		bot, err := botRepo.GetOrCreateBot(cleanBotNumber, "Default Bot")
-		if err != nil {
-			return fmt.Errorf("failed to get/create bot: %w", err)
-		}
-
-		// Guardar mensaje de audio del usuario
-		clientMsg := models.Message{
-			ClientID:  client.ID,
-			BotID:     bot.ID,
-			Sender:    msg.Phone,
-			Text:      "[Audio recibido]",
-			Timestamp: time.Now(),
-		}
-		if err := conversationRepo.SaveMessage(context.TODO(), client.ID, bot.ID, clientMsg); err != nil {
-			log.Printf("Failed to save audio message: %v", err)
-		}
-
-		// Guardar respuesta automática del bot
-		botMsg := models.Message{
-			ClientID:  client.ID,
-			BotID:     bot.ID,
-			Sender:    "bot",
-			Text:      responseText,
-			Timestamp: time.Now(),
-		}
-		if err := conversationRepo.SaveMessage(context.TODO(), client.ID, bot.ID, botMsg); err != nil {
-			log.Printf("Failed to save bot response: %v", err)
-		}
-
-		return nil
-	}
-
 	// 1. Procesar cliente (guardar en DB)
 	cleanPhone := strings.Split(msg.Phone, "@")[0]
-	orgID := getOrganizationIDFromBot(msg.BotNumber)
+	orgID := msg.OrganizationID
+	if orgID == 0 {
+		var err error
+		if orgID, err = getOrganizationIDFromBot(msg.BotNumber); err != nil {
+			return err
+		}
+	}
 	client, err := clientRepo.GetOrCreateClient(cleanPhone, "", "", orgID)
 	if err != nil {
 		return fmt.Errorf("failed to get/create client: %w", err)
@@ -198,124 +234,149 @@ func processIncomingMessage(msg IncomingMessageRequest, hub *WebSocketHub) error
 	}
 
 	// 3. Guardar mensaje del usuario
+	clientText := msg.Message
+	if msg.MessageType == "audio" {
+		clientText = "[Audio recibido]"
+	}
+	messageType := msg.MessageType
+	if messageType == "" {
+		messageType = models.MessageTypeText
+	}
 	clientMsg := models.Message{
-		ClientID:  client.ID,
-		BotID:     bot.ID,
-		Sender:    msg.Phone,
-		Text:      msg.Message,
-		Timestamp: time.Now(),
+		OrganizationID: orgID,
+		ClientID:       client.ID,
+		BotID:          bot.ID,
+		SessionID:      sessionId,
+		ChatID:         cleanPhone,
+		Direction:      models.MessageDirectionInbound,
+		Type:           messageType,
+		Status:         models.MessageStatusReceived,
+		Sender:         msg.Phone,
+		Text:           clientText,
+		Timestamp:      time.Now(),
 	}
 
-	if err := conversationRepo.SaveMessage(context.TODO(), client.ID, bot.ID, clientMsg); err != nil {
+	if err := conversationRepo.SaveMessage(context.TODO(), clientMsg); err != nil {
 		return fmt.Errorf("failed to save client message: %w", err)
 	}
 
-	// 4. Procesar con Rasa
-	// Usar sender con sessionId para aislamiento de contexto
-	senderWithSession := fmt.Sprintf("%s:%s", sessionId, msg.Phone)
-	rasaResponses, err := sendToRasaDynamic(senderWithSession, msg.Message, msg.BotNumber, hub)
-	if err != nil {
-		return fmt.Errorf("rasa processing failed: %w", err)
+	// 4. Si un agente tomó el control del chat, no responde el bot
+	botMode := !isHumanTakeover(context.TODO(), orgID, sessionId, msg.Phone)
+
+	// Notificar a los agentes conectados de la organización
+	hub.BroadcastToAgents(orgID, map[string]interface{}{
+		"type":        "incoming_message",
+		"session_id":  sessionId,
+		"chat_id":     msg.Phone,
+		"client_id":   client.ID,
+		"message":     clientText,
+		"messageType": msg.MessageType,
+		"bot_mode":    botMode,
+		"timestamp":   clientMsg.Timestamp,
+	})
+
+	if !botMode {
+		log.Printf("🙋 Chat %s (session: %s) atendido por un agente, se omite Rasa", msg.Phone, sessionId)
+		return nil
 	}
-	log.Printf("Respuestas de Rasa recibidas: %+v", rasaResponses)
 
-	// 5. Procesar respuestas
-	for _, response := range rasaResponses {
-		if response.Text == "" {
-			continue
-		}
+	// 5. Verificar si es un mensaje de audio
+	if msg.MessageType == "audio" {
+		log.Printf("Mensaje de audio recibido de %s - respondiendo automáticamente", msg.Phone)
+
+		// Responder automáticamente sin procesar con Rasa
+		responseText := "🤖 Lo siento, por ahora solo puedo procesar mensajes de texto. Por favor, envíame tu mensaje escrito. 📝"
 
-		// Guardar respuesta del bot
-		botMsg := models.Message{
-			ClientID:  client.ID,
-			BotID:     bot.ID,
-			Sender:    "bot",
-			Text:      response.Text,
-			Timestamp: time.Now(),
+		// Enviar respuesta automática al cliente
+		reply := OutboundMessage{
+			To:        msg.Phone,
+			Message:   responseText,
+			SessionID: sessionId,
 		}
-		if err := conversationRepo.SaveMessage(context.TODO(), client.ID, bot.ID, botMsg); err != nil {
-			log.Printf("Failed to save bot message: %v", err)
+		sendErr := sendOutbound(hub, orgID, botKey, reply)
+		if sendErr != nil {
+			log.Printf("Failed to send audio response to bot: %v", sendErr)
 		}
 
-		// Enviar respuesta al cliente usando la clave del bot
-		log.Printf("Enviando respuesta a bot %s (session: %s) para cliente %s: %s",
-			msg.BotNumber, sessionId, msg.Phone, response.Text)
-
-		if err := hub.SendToBot(botKey, map[string]interface{}{
-			"to":        msg.Phone, // Cliente destino
-			"message":   response.Text,
-			"sessionId": sessionId, // Incluir sessionId en la respuesta
-		}); err != nil {
-			log.Printf("Failed to send message to bot: %v", err)
+		// Guardar respuesta automática del bot
+		botMsg := botReplyMessage(clientMsg, reply, sendErr)
+		if err := conversationRepo.SaveMessage(context.TODO(), botMsg); err != nil {
+			log.Printf("Failed to save bot response: %v", err)
 		}
-	}
-
-	return nil
-}
 
-// sendToRasaDynamic envía mensajes al servidor Rasa
-func sendToRasaDynamic(sender, message, botNumber string, hub *WebSocketHub) ([]RasaResponseItem, error) {
-	// Limpiar número de bot
-	cleanBotNumber := strings.Split(botNumber, "@")[0]
-
-	// Buscar si hay una instancia específica para este bot
-	var instance models.BotInstance
-	err := database.GetDB().Where("whatsapp_number = ? AND status = ?", cleanBotNumber, "running").First(&instance).Error
-
-	rasaURL := "http://rasa:5005" // URL por defecto (bot base)
-
-	if err == nil {
-		// Si existe una instancia, usar su puerto
-		rasaURL = fmt.Sprintf("http://localhost:%d", instance.Port)
-		log.Printf("🔀 Enrutando a instancia personalizada: %s (puerto %d)", instance.Name, instance.Port)
-	} else {
-		log.Printf("🔀 Usando bot base (puerto 5005)")
-	}
-
-	return sendToRasa(rasaURL, sender, message)
-}
-
-// sendToRasa envía mensajes al servidor Rasa (versión actualizada con URL dinámica)
-func sendToRasa(rasaURL, sender, message string) ([]RasaResponseItem, error) {
-	payload := map[string]interface{}{
-		"sender":  sender,
-		"message": message,
+		return nil
 	}
 
-	body, err := json.Marshal(payload)
+	// 6. Procesar con Rasa
+	// Usar sender con sessionId para aislamiento de contexto
+	conversationID := rasaConversationID(sessionId, msg.Phone)
+	rasaText := resolveButtonChoice(conversationID, msg.Message)
+	rasaResponses, err := rasaClientForBot(msg.BotNumber).SendMessage(context.TODO(), conversationID, rasaText)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return fmt.Errorf("rasa processing failed: %w", err)
 	}
+	log.Printf("Respuestas de Rasa recibidas: %+v", rasaResponses)
 
-	webhookURL := fmt.Sprintf("%s/webhooks/rest/webhook", rasaURL)
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
+	// 7. Traducir las respuestas (texto, botones, imágenes, adjuntos, custom) y enviarlas
+	for _, response := range rasaResponses {
+		for _, reply := range rasaToOutbound(response, msg.Phone, sessionId) {
+			// Enviar respuesta al cliente usando la clave del bot
+			log.Printf("Enviando respuesta (%s) a bot %s (session: %s) para cliente %s: %s",
+				historyType(reply), msg.BotNumber, sessionId, msg.Phone, reply.Message)
+
+			sendErr := sendOutbound(hub, orgID, botKey, reply)
+			if sendErr != nil {
+				log.Printf("Failed to send message to bot: %v", sendErr)
+			} else if len(reply.Buttons) > 0 {
+				rememberButtonChoices(conversationID, reply.Buttons)
+			}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+			// Guardar respuesta del bot
+			botMsg := botReplyMessage(clientMsg, reply, sendErr)
+			if err := conversationRepo.SaveMessage(context.TODO(), botMsg); err != nil {
+				log.Printf("Failed to save bot message: %v", err)
+			}
+		}
 	}
-	defer resp.Body.Close()
 
-	var responses []RasaResponseItem
-	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return nil
+}
 
-	return responses, nil
+// botReplyMessage arma la respuesta del bot al mensaje entrante con el estado del envío
+func botReplyMessage(incoming models.Message, reply OutboundMessage, sendErr error) models.Message {
+	status := models.MessageStatusQueued
+	if sendErr != nil {
+		status = models.MessageStatusFailed
+	}
+	return models.Message{
+		OrganizationID: incoming.OrganizationID,
+		ClientID:       incoming.ClientID,
+		BotID:          incoming.BotID,
+		SessionID:      incoming.SessionID,
+		ChatID:         incoming.ChatID,
+		Direction:      models.MessageDirectionOutbound,
+		Type:           historyType(reply),
+		Status:         status,
+		Sender:         "bot",
+		Text:           historyText(reply),
+		Timestamp:      time.Now(),
+		Media:          reply.Media,
+		Buttons:        reply.Buttons,
+		Location:       reply.Location,
+	}
 }
 
-func getOrganizationIDFromBot(botNumber string) uint {
+// getOrganizationIDFromBot busca la organización de la instancia del bot; sin instancia
+// el mensaje no se procesa (no se asume ninguna organización)
+func getOrganizationIDFromBot(botNumber string) (uint, error) {
 	cleanBotNumber := strings.Split(botNumber, "@")[0]
+	db := database.GetDB()
+	if db == nil {
+		return 0, errors.New("error de conexión a base de datos")
+	}
 	var instance models.BotInstance
-	err := database.GetDB().Where("whatsapp_number = ?", cleanBotNumber).First(&instance).Error
-	if err == nil {
-		return instance.OrganizationID
+	if err := db.Where("whatsapp_number = ?", cleanBotNumber).First(&instance).Error; err != nil {
+		return 0, fmt.Errorf("bot %s sin organización: %w", cleanBotNumber, err)
 	}
-	log.Printf("⚠️ Bot %s no encontrado, usando org por defecto", cleanBotNumber)
-	return 1
+	return instance.OrganizationID, nil
 }
\ No newline at end of file
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+)
+
+var documentLinkService *services.DocumentLinkService
+
+// SetDocumentLinkService inyecta el servicio de enlaces de descarga
+func SetDocumentLinkService(service *services.DocumentLinkService) {
+	documentLinkService = service
+}
+
+// CreateDocumentLinkRequest define la vigencia y los usos de un enlace
+type CreateDocumentLinkRequest struct {
+	ExpiresIn    int `json:"expires_in"`    // Segundos; 0 = vigencia por defecto
+	MaxDownloads int `json:"max_downloads"` // 0 o 1 = un solo uso
+}
+
+// linkPreviewAgents son los clientes que abren un enlace para armar la vista previa del
+// chat; no deben gastar las descargas de un enlace de un solo uso
+var linkPreviewAgents = []string{"whatsapp", "facebookexternalhit", "telegrambot", "slackbot", "twitterbot"}
+
+// CreateDocumentLink crea un enlace firmado de descarga sin login
+// @Summary Crear enlace de descarga
+// @Description El enlace vence y admite un número limitado de descargas (por defecto uno). La URL solo se muestra al crearlo.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Param request body CreateDocumentLinkRequest false "Vigencia y descargas"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id}/links [post]
+func CreateDocumentLink(c *gin.Context) {
+	if documentLinkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Enlaces de descarga no configurados"})
+		return
+	}
+
+	var request CreateDocumentLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Datos inválidos",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if request.ExpiresIn < 0 || request.MaxDownloads < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in y max_downloads no pueden ser negativos"})
+		return
+	}
+
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	if document.Status != models.DocumentStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Solo se pueden compartir documentos completados"})
+		return
+	}
+
+	url, link, err := documentLinkService.Issue(*document, time.Duration(request.ExpiresIn)*time.Second,
+		request.MaxDownloads, c.GetUint("current_user_id"))
+	if errors.Is(err, services.ErrDocumentLinkOptions) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Opciones de enlace inválidas",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error creando enlace",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"url":  url,
+		"link": link,
+	})
+}
+
+// ListDocumentLinks lista los enlaces de un documento (sin la URL firmada)
+// @Summary Listar enlaces de descarga
+// @Tags documents
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/links [get]
+func ListDocumentLinks(c *gin.Context) {
+	if documentLinkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Enlaces de descarga no configurados"})
+		return
+	}
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+
+	links, err := documentLinkService.ListLinks(document.OrganizationID, document.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo enlaces",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"links": links,
+		"total": len(links),
+	})
+}
+
+// RevokeDocumentLink revoca un enlace de descarga
+// @Summary Revocar enlace de descarga
+// @Tags documents
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Param linkId path string true "ID del enlace"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/links/{linkId} [delete]
+func RevokeDocumentLink(c *gin.Context) {
+	if documentLinkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Enlaces de descarga no configurados"})
+		return
+	}
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+
+	err := documentLinkService.Revoke(document.OrganizationID, document.ID.Hex(), c.Param("linkId"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Enlace no encontrado o ya revocado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error revocando enlace",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Enlace revocado"})
+}
+
+// GetDocumentDownloads devuelve el registro de descargas de un documento
+// @Summary Registro de descargas
+// @Tags documents
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/downloads [get]
+func GetDocumentDownloads(c *gin.Context) {
+	if documentLinkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Enlaces de descarga no configurados"})
+		return
+	}
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+
+	downloads, err := documentLinkService.ListDownloads(document.OrganizationID, document.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo descargas",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"downloads": downloads,
+		"total":     len(downloads),
+	})
+}
+
+// DownloadSharedDocument entrega el archivo de un enlace firmado (ruta pública)
+// @Summary Descargar documento compartido
+// @Description Valida la firma, la vigencia y las descargas disponibles del enlace
+// @Tags documents
+// @Produce application/pdf
+// @Param token path string true "Token del enlace"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Router /download/{token} [get]
+func DownloadSharedDocument(c *gin.Context) {
+	if documentLinkService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Enlaces de descarga no configurados"})
+		return
+	}
+
+	link, err := documentLinkService.Verify(c.Param("token"))
+	if errors.Is(err, services.ErrDocumentLinkInvalid) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Enlace no válido"})
+		return
+	}
+	if err != nil && link == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error validando enlace",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	download := models.DocumentDownload{
+		OrganizationID: link.OrganizationID,
+		DocumentID:     link.DocumentID,
+		LinkID:         link.LinkID,
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	if err != nil {
+		respondUnavailableLink(c, download, err)
+		return
+	}
+
+	// La vista previa del chat no gasta descargas ni recibe el archivo
+	if isLinkPreviewAgent(c.Request.UserAgent()) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8",
+			[]byte("<!DOCTYPE html><html><head><title>Documento de DocuBot</title></head><body></body></html>"))
+		return
+	}
+
+	document, err := conversationRepo.GetDocumentByID(c.Request.Context(), link.DocumentID, link.OrganizationID)
+	if err != nil {
+		log.Printf("⚠️ Enlace %s apunta a un documento que no se pudo leer: %v", link.LinkID, err)
+		documentLinkService.LogDownload(withResult(download, models.DownloadResultError))
+		c.JSON(http.StatusNotFound, gin.H{"error": "Documento no encontrado"})
+		return
+	}
+
+	if err := documentLinkService.Consume(link); err != nil {
+		respondUnavailableLink(c, download, err)
+		return
+	}
+
+	content, contentType, err := documentContent(c.Request.Context(), document)
+	if err != nil {
+		// El cliente no recibió el archivo: la descarga no cuenta
+		documentLinkService.Release(link)
+		documentLinkService.LogDownload(withResult(download, models.DownloadResultError))
+		respondDocumentContentError(c, document, err)
+		return
+	}
+
+	documentLinkService.LogDownload(withResult(download, models.DownloadResultOK))
+	sendDocumentFile(c, document, content, contentType)
+}
+
+// respondUnavailableLink registra y responde un enlace vencido, agotado o revocado
+func respondUnavailableLink(c *gin.Context, download models.DocumentDownload, err error) {
+	result := models.DownloadResultError
+	message := "Error validando enlace"
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrDocumentLinkExpired):
+		result, message, status = models.DownloadResultExpired, "El enlace expiró, solicita uno nuevo", http.StatusGone
+	case errors.Is(err, services.ErrDocumentLinkExhausted):
+		result, message, status = models.DownloadResultExhausted, "El enlace ya se usó, solicita uno nuevo", http.StatusGone
+	case errors.Is(err, services.ErrDocumentLinkRevoked):
+		result, message, status = models.DownloadResultRevoked, "El enlace fue revocado", http.StatusGone
+	}
+	documentLinkService.LogDownload(withResult(download, result))
+	c.JSON(status, gin.H{"error": message})
+}
+
+func withResult(download models.DocumentDownload, result string) models.DocumentDownload {
+	download.Result = result
+	return download
+}
+
+func isLinkPreviewAgent(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range linkPreviewAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
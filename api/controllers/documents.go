@@ -0,0 +1,617 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/services"
+	"github.com/brando1998/docubot-api/storage"
+)
+
+var documentStorage storage.Storage
+
+// SetDocumentStorage inyecta el almacenamiento de archivos de documentos
+func SetDocumentStorage(s storage.Storage) {
+	documentStorage = s
+}
+
+// UpdateDocumentRequest actualiza el estado de un documento (p. ej. desde un generador externo)
+type UpdateDocumentRequest struct {
+	Status   string                 `json:"status" binding:"required"`
+	Error    string                 `json:"error"`
+	URL      string                 `json:"url"`
+	FileName string                 `json:"file_name"`
+	Metadata map[string]interface{} `json:"metadata"` // Se combina con la metadata guardada
+}
+
+// CancelDocumentRequest es el cuerpo opcional de POST /documents/:id/cancel
+type CancelDocumentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListDocuments lista los documentos de la organización con filtros y paginación
+// @Summary Listar documentos
+// @Description Filtra por tipo, estado, cliente y rango de fechas de creación (YYYY-MM-DD o RFC3339, to inclusivo para fechas)
+// @Tags documents
+// @Produce json
+// @Param type query string false "Tipo de documento"
+// @Param status query string false "generating, completed, failed o cancelled"
+// @Param client_id query int false "ID del cliente"
+// @Param stage query string false "Subestado del manifiesto (issued, awaiting_delivery, fulfilling, fulfilled, annulling, annulled)"
+// @Param parent_id query string false "Cumplidos y anulaciones de un manifiesto"
+// @Param from query string false "Desde"
+// @Param to query string false "Hasta"
+// @Param page query int false "Página (por defecto 1)"
+// @Param limit query int false "Tamaño de página (por defecto 20, máximo 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/documents [get]
+func ListDocuments(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
+	var err error
+	filter := repositories.DocumentFilter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+	}
+	if filter.Status != "" && !models.IsValidDocumentStatus(filter.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status inválido"})
+		return
+	}
+	if clientID := c.Query("client_id"); clientID != "" {
+		filter.ClientID = parseUint(clientID)
+		if filter.ClientID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "client_id inválido"})
+			return
+		}
+	}
+	if filter.Stage = c.Query("stage"); filter.Stage != "" && !models.IsValidManifestStage(filter.Stage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stage inválido"})
+		return
+	}
+	if parentID := c.Query("parent_id"); parentID != "" {
+		if filter.ParentID, err = primitive.ObjectIDFromHex(parentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id inválido"})
+			return
+		}
+	}
+
+	if filter.From, err = parseDocumentDate(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from inválido", "details": err.Error()})
+		return
+	}
+	if filter.To, err = parseDocumentDate(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to inválido", "details": err.Error()})
+		return
+	}
+
+	filter.Page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || filter.Page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page inválido"})
+		return
+	}
+	filter.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || filter.Limit < 1 || filter.Limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+		return
+	}
+
+	documents, total, err := conversationRepo.ListDocuments(c.Request.Context(), filter, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo documentos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents": documents,
+		"total":     total,
+		"page":      filter.Page,
+		"limit":     filter.Limit,
+	})
+}
+
+// GetDocument obtiene un documento con su historial de intentos
+// @Summary Obtener documento
+// @Tags documents
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Success 200 {object} models.Document
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id} [get]
+func GetDocument(c *gin.Context) {
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, document)
+}
+
+// DownloadDocument descarga el archivo de un documento verificando su checksum. Los
+// documentos registrados con una URL externa se descargan de ella.
+// @Summary Descargar documento
+// @Tags documents
+// @Produce application/pdf
+// @Param id path string true "ID del documento"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/documents/{id}/download [get]
+func DownloadDocument(c *gin.Context) {
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+
+	download := models.DocumentDownload{
+		OrganizationID: document.OrganizationID,
+		DocumentID:     document.ID.Hex(),
+		UserID:         c.GetUint("current_user_id"),
+		IP:             c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+	content, contentType, err := documentContent(c.Request.Context(), document)
+	if err != nil {
+		logDocumentDownload(download, models.DownloadResultError)
+		respondDocumentContentError(c, document, err)
+		return
+	}
+	logDocumentDownload(download, models.DownloadResultOK)
+	sendDocumentFile(c, document, content, contentType)
+}
+
+// UpdateDocument cambia el estado de un documento validando la transición
+// @Summary Actualizar estado de documento
+// @Description Permite generating → completed/failed/cancelled y failed → cancelled. Para volver a generar un documento se usa /retry. La url solo se acepta en documentos sin archivo guardado y de un origen permitido.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Param request body UpdateDocumentRequest true "Nuevo estado"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id} [patch]
+func UpdateDocument(c *gin.Context) {
+	var request UpdateDocumentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !models.IsValidDocumentStatus(request.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status inválido"})
+		return
+	}
+	if request.Status == models.DocumentStatusGenerating {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Para volver a generar el documento usa POST /documents/:id/retry"})
+		return
+	}
+
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	if !document.CanTransition(request.Status) {
+		invalidTransition(c, document, request.Status)
+		return
+	}
+
+	// La URL solo se registra en documentos sin archivo guardado por la API
+	if request.URL != "" {
+		if document.StorageKey != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "El documento ya tiene un archivo guardado; su url no se puede cambiar"})
+			return
+		}
+		if err := validateDocumentURL(request.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "url inválida",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	previousStatus := document.Status
+	if request.URL != "" {
+		document.URL = request.URL
+	}
+	if request.FileName != "" {
+		document.FileName = request.FileName
+	}
+	if len(request.Metadata) > 0 {
+		if document.Metadata == nil {
+			document.Metadata = map[string]interface{}{}
+		}
+		for key, value := range request.Metadata {
+			document.Metadata[key] = value
+		}
+	}
+	document.Finish(request.Status, request.Error, time.Now())
+
+	if !saveDocumentTransition(c, document, previousStatus) {
+		return
+	}
+	if request.Status == models.DocumentStatusCancelled && manifestOrchestrator != nil {
+		manifestOrchestrator.Abort(document.ID.Hex())
+	}
+	c.JSON(http.StatusOK, document)
+}
+
+// RetryDocument vuelve a generar un manifiesto fallido o cancelado
+// @Summary Reintentar documento
+// @Description Registra un nuevo intento con los datos guardados del formulario y lo entrega al mismo chat
+// @Tags documents
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Success 202 {object} models.Document
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id}/retry [post]
+func RetryDocument(c *gin.Context) {
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	if !document.CanTransition(models.DocumentStatusGenerating) {
+		invalidTransition(c, document, models.DocumentStatusGenerating)
+		return
+	}
+
+	var retried *models.Document
+	var err error
+	switch document.Type {
+	case services.DocumentTypeCumplido, services.DocumentTypeAnulacion:
+		if !followUpsConfigured(c) {
+			return
+		}
+		retried, err = manifestFollowUps.Retry(c.Request.Context(), *document, documentActor(c))
+	default:
+		if manifestOrchestrator == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Generación de manifiestos no configurada"})
+			return
+		}
+		retried, err = manifestOrchestrator.Retry(c.Request.Context(), *document, documentActor(c))
+	}
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, retried)
+	case errors.Is(err, services.ErrIncompleteManifest):
+		respondIncompleteManifest(c, err)
+	case errors.Is(err, services.ErrInvalidFollowUp), errors.Is(err, services.ErrManifestStage):
+		respondFollowUpError(c, err)
+	case errors.Is(err, services.ErrDocumentNotRetryable), errors.Is(err, repositories.ErrDocumentStatusChanged):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No se puede reintentar el documento",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error reintentando documento",
+			"details": err.Error(),
+		})
+	}
+}
+
+// CancelDocument cancela un documento en generación o fallido
+// @Summary Cancelar documento
+// @Description Detiene la generación en curso; un manifiesto ya enviado al RNDC puede quedar radicado
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del documento"
+// @Param request body CancelDocumentRequest false "Motivo"
+// @Success 200 {object} models.Document
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id}/cancel [post]
+func CancelDocument(c *gin.Context) {
+	var request CancelDocumentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Datos inválidos",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if request.Reason == "" {
+		request.Reason = "Cancelado por " + documentActor(c)
+	}
+
+	document, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	if !document.CanTransition(models.DocumentStatusCancelled) {
+		invalidTransition(c, document, models.DocumentStatusCancelled)
+		return
+	}
+
+	previousStatus := document.Status
+	document.Finish(models.DocumentStatusCancelled, request.Reason, time.Now())
+	if !saveDocumentTransition(c, document, previousStatus) {
+		return
+	}
+	if manifestOrchestrator != nil {
+		manifestOrchestrator.Abort(document.ID.Hex())
+	}
+	c.JSON(http.StatusOK, document)
+}
+
+// loadDocument obtiene el documento de :id dentro de la organización; responde el error si no existe
+func loadDocument(c *gin.Context) (*models.Document, bool) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return nil, false
+	}
+	orgID := orgIDInterface.(uint)
+
+	documentID := c.Param("id")
+	if !primitive.IsValidObjectID(documentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de documento inválido"})
+		return nil, false
+	}
+
+	document, err := conversationRepo.GetDocumentByID(c.Request.Context(), documentID, orgID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Documento no encontrado"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo documento",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return document, true
+}
+
+// saveDocumentTransition guarda el documento si nadie cambió su estado mientras tanto
+func saveDocumentTransition(c *gin.Context, document *models.Document, previousStatus string) bool {
+	err := conversationRepo.UpdateDocument(c.Request.Context(), *document, previousStatus)
+	if errors.Is(err, repositories.ErrDocumentStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "El documento cambió de estado, vuelve a consultarlo",
+			"details": err.Error(),
+		})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error actualizando documento",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func invalidTransition(c *gin.Context, document *models.Document, to string) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "Transición de estado no permitida",
+		"details": fmt.Sprintf("%s → %s", document.Status, to),
+	})
+}
+
+// documentActor identifica quién origina un intento o cancelación
+func documentActor(c *gin.Context) string {
+	if v, ok := c.Get("current_user_id"); ok {
+		if userID, ok := v.(uint); ok && userID != 0 {
+			return fmt.Sprintf("usuario:%d", userID)
+		}
+	}
+	if keyID := c.GetUint("api_key_id"); keyID != 0 {
+		return fmt.Sprintf("api_key:%d", keyID)
+	}
+	return "api"
+}
+
+// parseDocumentDate acepta YYYY-MM-DD o RFC3339; con endOfDay una fecha sin hora incluye todo el día
+func parseDocumentDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.New("usa el formato YYYY-MM-DD o RFC3339")
+	}
+	if endOfDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return parsed, nil
+}
+
+// errDocumentWithoutFile indica que el documento no tiene archivo guardado ni URL externa
+var errDocumentWithoutFile = errors.New("el documento no tiene archivo")
+
+// errDocumentURLNotAllowed indica que la URL externa no es de un origen permitido
+var errDocumentURLNotAllowed = errors.New("la URL del documento no es de un origen permitido")
+
+// documentURLOrigins son los orígenes (esquema://host) de los que la API acepta y descarga
+// archivos externos: playwright-bot, el almacenamiento S3 y DOCUMENT_URL_ALLOWED_ORIGINS
+var documentURLOrigins = map[string]bool{}
+
+// SetDocumentURLOrigins configura los orígenes permitidos para las URL externas de documentos
+func SetDocumentURLOrigins(origins ...string) {
+	documentURLOrigins = map[string]bool{}
+	for _, origin := range origins {
+		parsed, err := url.Parse(strings.TrimSpace(origin))
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			continue
+		}
+		documentURLOrigins[parsed.Scheme+"://"+strings.ToLower(parsed.Host)] = true
+	}
+}
+
+// validateDocumentURL acepta solo URL http(s) de un origen permitido: la API las descarga
+// del lado del servidor y no puede alcanzar servicios internos arbitrarios
+func validateDocumentURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || parsed.User != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errDocumentURLNotAllowed
+	}
+	if !documentURLOrigins[parsed.Scheme+"://"+strings.ToLower(parsed.Host)] {
+		return errDocumentURLNotAllowed
+	}
+	return nil
+}
+
+// isExternalDocumentURL indica si la URL apunta fuera de la API (las rutas internas no se descargan)
+func isExternalDocumentURL(raw string) bool {
+	return strings.HasPrefix(raw, "http://") || strings.HasPrefix(raw, "https://")
+}
+
+// documentProxyClient descarga los archivos de documentos con URL externa; las redirecciones
+// también deben quedarse en los orígenes permitidos
+var documentProxyClient = &http.Client{
+	Timeout: time.Minute,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("demasiadas redirecciones")
+		}
+		return validateDocumentURL(req.URL.String())
+	},
+}
+
+// documentContent lee el archivo del documento: del almacenamiento de la API si tiene
+// StorageKey o de la URL externa registrada (p. ej. documentos guardados con POST /documents).
+// Los errores no incluyen la URL: pueden llegar al cliente de un enlace público
+func documentContent(ctx context.Context, document *models.Document) ([]byte, string, error) {
+	var content []byte
+	contentType := document.ContentType
+
+	switch {
+	case document.StorageKey != "":
+		if documentStorage == nil {
+			return nil, "", errors.New("almacenamiento de documentos no configurado")
+		}
+		var err error
+		content, err = storage.Read(ctx, documentStorage, document.StorageKey, document.Checksum)
+		if err != nil {
+			return nil, "", err
+		}
+
+	case isExternalDocumentURL(document.URL):
+		if err := validateDocumentURL(document.URL); err != nil {
+			log.Printf("🚫 Documento %s con URL externa fuera de los orígenes permitidos", document.ID.Hex())
+			return nil, "", err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, document.URL, nil)
+		if err != nil {
+			return nil, "", errors.New("URL del documento inválida")
+		}
+		resp, err := documentProxyClient.Do(req)
+		if err != nil {
+			log.Printf("⚠️ Error descargando el archivo externo del documento %s: %v", document.ID.Hex(), err)
+			if errors.Is(err, errDocumentURLNotAllowed) {
+				return nil, "", errDocumentURLNotAllowed
+			}
+			return nil, "", errors.New("error descargando el archivo externo")
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			return nil, "", storage.ErrNotFound
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("el origen del archivo respondió %s", resp.Status)
+		}
+		content, err = io.ReadAll(io.LimitReader(resp.Body, storage.MaxFileSize+1))
+		if err != nil {
+			log.Printf("⚠️ Error leyendo el archivo externo del documento %s: %v", document.ID.Hex(), err)
+			return nil, "", errors.New("error descargando el archivo externo")
+		}
+		if len(content) > storage.MaxFileSize {
+			return nil, "", fmt.Errorf("el archivo supera el tamaño máximo (%d bytes)", storage.MaxFileSize)
+		}
+		if document.Checksum != "" && !strings.EqualFold(storage.Checksum(content), document.Checksum) {
+			return nil, "", storage.ErrChecksumMismatch
+		}
+		if contentType == "" {
+			contentType = resp.Header.Get("Content-Type")
+		}
+
+	default:
+		return nil, "", errDocumentWithoutFile
+	}
+
+	if contentType == "" {
+		contentType = storage.DetectContentType(document.FileName, content)
+	}
+	return content, contentType, nil
+}
+
+func respondDocumentContentError(c *gin.Context, document *models.Document, err error) {
+	switch {
+	case errors.Is(err, errDocumentWithoutFile):
+		c.JSON(http.StatusNotFound, gin.H{"error": "El documento no tiene archivo"})
+	case errors.Is(err, errDocumentURLNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "El archivo del documento no está en un origen permitido"})
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Archivo del documento no encontrado"})
+	case errors.Is(err, storage.ErrChecksumMismatch):
+		log.Printf("🚨 Checksum inválido en el documento %s: %v", document.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "El archivo del documento está dañado",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error leyendo documento",
+			"details": err.Error(),
+		})
+	}
+}
+
+func sendDocumentFile(c *gin.Context, document *models.Document, content []byte, contentType string) {
+	fileName := document.FileName
+	if fileName == "" {
+		fileName = document.ID.Hex()
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Header("Cache-Control", "no-store")
+	if document.Checksum != "" {
+		c.Header("X-Checksum-Sha256", document.Checksum)
+	}
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// logDocumentDownload registra la descarga si el registro de descargas está configurado
+func logDocumentDownload(download models.DocumentDownload, result string) {
+	if documentLinkService == nil {
+		return
+	}
+	documentLinkService.LogDownload(withResult(download, result))
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/storage"
+)
+
+// documentsConversationRepo guarda documentos en memoria para los endpoints de ciclo de vida
+type documentsConversationRepo struct {
+	repositories.ConversationRepository
+	documents map[string]models.Document
+	filter    repositories.DocumentFilter
+}
+
+func (r *documentsConversationRepo) GetDocumentByID(ctx context.Context, documentID string, orgID uint) (*models.Document, error) {
+	document, ok := r.documents[documentID]
+	if !ok || document.OrganizationID != orgID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &document, nil
+}
+
+func (r *documentsConversationRepo) GetDocumentByVerificationCode(ctx context.Context, code string) (*models.Document, error) {
+	for _, document := range r.documents {
+		if document.VerificationCode == code {
+			return &document, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *documentsConversationRepo) UpdateDocument(ctx context.Context, document models.Document, expectedStatus string) error {
+	if r.documents[document.ID.Hex()].Status != expectedStatus {
+		return repositories.ErrDocumentStatusChanged
+	}
+	r.documents[document.ID.Hex()] = document
+	return nil
+}
+
+func (r *documentsConversationRepo) ListDocuments(ctx context.Context, filter repositories.DocumentFilter, orgID uint) ([]models.Document, int64, error) {
+	r.filter = filter
+	return []models.Document{}, 0, nil
+}
+
+func setupDocumentsRouter(t *testing.T, documents ...models.Document) (*gin.Engine, *documentsConversationRepo) {
+	repo := &documentsConversationRepo{documents: map[string]models.Document{}}
+	for _, document := range documents {
+		repo.documents[document.ID.Hex()] = document
+	}
+	previous := conversationRepo
+	conversationRepo = repo
+	t.Cleanup(func() { conversationRepo = previous })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", uint(1))
+		c.Set("current_user_id", uint(4))
+		c.Next()
+	})
+	r.GET("/documents", ListDocuments)
+	r.GET("/documents/:id/download", DownloadDocument)
+	r.PATCH("/documents/:id", UpdateDocument)
+	r.POST("/documents/:id/cancel", CancelDocument)
+	return r, repo
+}
+
+func TestDocumentStatusTransitions(t *testing.T) {
+	generating := models.Document{ID: primitive.NewObjectID(), OrganizationID: 1, Type: "certificado"}
+	generating.StartAttempt("api", time.Now())
+	foreign := models.Document{ID: primitive.NewObjectID(), OrganizationID: 2, Status: models.DocumentStatusFailed}
+	r, repo := setupDocumentsRouter(t, generating, foreign)
+
+	patch := func(id, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/documents/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, patch(generating.ID.Hex(), `{"status":"generating"}`))
+	assert.Equal(t, http.StatusNotFound, patch(foreign.ID.Hex(), `{"status":"cancelled"}`))
+
+	require.Equal(t, http.StatusOK, patch(generating.ID.Hex(), `{"status":"failed","error":"Plantilla inválida"}`))
+	stored := repo.documents[generating.ID.Hex()]
+	assert.Equal(t, models.DocumentStatusFailed, stored.Status)
+	require.Len(t, stored.Attempts, 1)
+	assert.Equal(t, "Plantilla inválida", stored.Attempts[0].Error)
+	assert.NotNil(t, stored.Attempts[0].FinishedAt)
+
+	// failed → completed no está permitido; failed → cancelled sí
+	assert.Equal(t, http.StatusConflict, patch(generating.ID.Hex(), `{"status":"completed"}`))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/documents/"+generating.ID.Hex()+"/cancel", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	stored = repo.documents[generating.ID.Hex()]
+	assert.Equal(t, models.DocumentStatusCancelled, stored.Status)
+	assert.Equal(t, "Cancelado por usuario:4", stored.Error)
+	assert.Len(t, stored.Attempts, 1)
+}
+
+func TestListDocumentsFilters(t *testing.T) {
+	r, repo := setupDocumentsRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents?type=manifiesto&status=failed&client_id=7&from=2026-10-01&to=2026-10-15&page=2&limit=10", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "manifiesto", repo.filter.Type)
+	assert.Equal(t, models.DocumentStatusFailed, repo.filter.Status)
+	assert.Equal(t, uint(7), repo.filter.ClientID)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), repo.filter.From)
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), repo.filter.To) // to incluye el día
+	assert.Equal(t, 2, repo.filter.Page)
+	assert.Equal(t, 10, repo.filter.Limit)
+
+	for _, query := range []string{"status=borrador", "from=ayer", "limit=500"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestDownloadDocumentVerifiesChecksum(t *testing.T) {
+	files, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+	previous := documentStorage
+	SetDocumentStorage(files)
+	t.Cleanup(func() { SetDocumentStorage(previous) })
+
+	pdf := []byte("%PDF-1.4\n%%EOF\n")
+	object, err := files.Put(context.Background(), "1/manifiesto.pdf", pdf, "")
+	require.NoError(t, err)
+	document := models.Document{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: 1,
+		FileName:       "manifiesto_00000001.pdf",
+		Status:         models.DocumentStatusCompleted,
+		StorageKey:     object.Key,
+		ContentType:    object.ContentType,
+		Checksum:       object.Checksum,
+	}
+	r, _ := setupDocumentsRouter(t, document)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents/"+document.ID.Hex()+"/download", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "manifiesto_00000001.pdf")
+	assert.Equal(t, pdf, w.Body.Bytes())
+
+	// Si el archivo cambia en el almacenamiento no se entrega
+	_, err = files.Put(context.Background(), "1/manifiesto.pdf", []byte("%PDF-otro"), "")
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents/"+document.ID.Hex()+"/download", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDownloadDocumentOnlyProxiesAllowedOrigins(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n%%EOF\n")
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write(pdf)
+	}))
+	defer allowed.Close()
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("la API no debe consultar orígenes fuera de la lista")
+	}))
+	defer internal.Close()
+
+	previous := documentURLOrigins
+	SetDocumentURLOrigins(allowed.URL)
+	t.Cleanup(func() { documentURLOrigins = previous })
+
+	newDocument := func(url string) models.Document {
+		return models.Document{ID: primitive.NewObjectID(), OrganizationID: 1, Status: models.DocumentStatusCompleted, URL: url}
+	}
+	ok := newDocument(allowed.URL + "/files/manifiesto.pdf")
+	foreign := newDocument(internal.URL + "/admin")
+	redirected := newDocument(allowed.URL + "/redirect?to=" + internal.URL + "/admin")
+	r, _ := setupDocumentsRouter(t, ok, foreign, redirected)
+
+	download := func(document models.Document) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents/"+document.ID.Hex()+"/download", nil))
+		return w
+	}
+
+	w := download(ok)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, pdf, w.Body.Bytes())
+
+	for _, document := range []models.Document{foreign, redirected} {
+		w = download(document)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NotContains(t, w.Body.String(), internal.URL)
+	}
+}
+
+func TestUpdateDocumentURLRestrictions(t *testing.T) {
+	previous := documentURLOrigins
+	SetDocumentURLOrigins("http://playwright:3001")
+	t.Cleanup(func() { documentURLOrigins = previous })
+
+	external := models.Document{ID: primitive.NewObjectID(), OrganizationID: 1, Type: "certificado"}
+	external.StartAttempt("api", time.Now())
+	stored := models.Document{ID: primitive.NewObjectID(), OrganizationID: 1, Type: "manifiesto", StorageKey: "1/manifiesto.pdf"}
+	stored.StartAttempt("api", time.Now())
+	r, repo := setupDocumentsRouter(t, external, stored)
+
+	patch := func(id, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/documents/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusConflict, patch(stored.ID.Hex(), `{"status":"completed","url":"http://playwright:3001/files/a.pdf"}`))
+	assert.Equal(t, http.StatusBadRequest, patch(external.ID.Hex(), `{"status":"completed","url":"http://169.254.169.254/latest"}`))
+	assert.Equal(t, http.StatusBadRequest, patch(external.ID.Hex(), `{"status":"completed","url":"file:///etc/passwd"}`))
+	assert.Equal(t, models.DocumentStatusGenerating, repo.documents[external.ID.Hex()].Status)
+
+	require.Equal(t, http.StatusOK, patch(external.ID.Hex(), `{"status":"completed","url":"http://playwright:3001/files/a.pdf"}`))
+	assert.Equal(t, "http://playwright:3001/files/a.pdf", repo.documents[external.ID.Hex()].URL)
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/brando1998/docubot-api/baileys"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/services"
+)
+
+// maxExpirationDays limita la ventana de GET /expirations
+const maxExpirationDays = 365
+
+var expiryReminderService *services.ExpiryReminderService
+
+// SetExpiryReminderService inyecta el servicio de recordatorios de vencimientos
+func SetExpiryReminderService(service *services.ExpiryReminderService) {
+	expiryReminderService = service
+}
+
+// ExpirySettingsRequest configura los recordatorios de la organización
+type ExpirySettingsRequest struct {
+	ReminderDays *int `json:"reminder_days" binding:"required"` // 0 desactiva los recordatorios
+}
+
+// ListExpirations lista las licencias, SOAT y revisiones técnico-mecánicas que vencen
+// @Summary Próximos vencimientos
+// @Description Documentos del registro que vencen en los próximos días (incluye los vencidos) y si ya se le recordó al conductor. Por defecto usa los días de recordatorio de la organización.
+// @Tags registry
+// @Produce json
+// @Param days query int false "Días hacia adelante"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/expirations [get]
+func ListExpirations(c *gin.Context) {
+	organization, ok := expirationOrganization(c)
+	if !ok {
+		return
+	}
+
+	days := organization.ExpiryReminderDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > maxExpirationDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days debe estar entre 0 y %d", maxExpirationDays)})
+			return
+		}
+		days = parsed
+	}
+
+	expirations, err := expiryReminderService.Upcoming(organization.ID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo vencimientos",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"expirations": expirations,
+		"total":       len(expirations),
+		"days":        days,
+	})
+}
+
+// GetExpirySettings devuelve la configuración de recordatorios de la organización
+// @Summary Configuración de recordatorios
+// @Tags registry
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/expirations/settings [get]
+func GetExpirySettings(c *gin.Context) {
+	organization, ok := expirationOrganization(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reminder_days": organization.ExpiryReminderDays})
+}
+
+// UpdateExpirySettings cambia con cuántos días de anticipación se recuerdan los vencimientos
+// @Summary Configurar recordatorios
+// @Tags registry
+// @Accept json
+// @Produce json
+// @Param request body ExpirySettingsRequest true "Días de anticipación"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/expirations/settings [put]
+func UpdateExpirySettings(c *gin.Context) {
+	organization, ok := expirationOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request ExpirySettingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	if *request.ReminderDays < 0 || *request.ReminderDays > maxExpirationDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reminder_days debe estar entre 0 y %d", maxExpirationDays)})
+		return
+	}
+
+	organization.ExpiryReminderDays = *request.ReminderDays
+	if err := organizationRepo.Update(organization); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error guardando configuración",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reminder_days": organization.ExpiryReminderDays})
+}
+
+func expirationOrganization(c *gin.Context) (*models.Organization, bool) {
+	if expiryReminderService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Recordatorios de vencimientos no configurados"})
+		return nil, false
+	}
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return nil, false
+	}
+	organization, err := organizationRepo.GetByID(orgIDInterface.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organización no encontrada"})
+		return nil, false
+	}
+	return organization, true
+}
+
+// whatsappReminderSender envía los recordatorios con un bot conectado de la organización
+// (por la cola de salida) y, si no hay ninguno conectado al hub, directo con Baileys
+type whatsappReminderSender struct {
+	hub      *WebSocketHub
+	sessions repositories.BotSessionKeyRepository
+}
+
+// NewWhatsAppReminderSender crea el ReminderSender de los recordatorios de vencimientos
+func NewWhatsAppReminderSender(hub *WebSocketHub, sessions repositories.BotSessionKeyRepository) services.ReminderSender {
+	return &whatsappReminderSender{hub: hub, sessions: sessions}
+}
+
+func (s *whatsappReminderSender) SendReminder(ctx context.Context, orgID uint, phone, text string) error {
+	if botKeys := s.hub.GetBotsByOrganization(orgID); len(botKeys) > 0 {
+		sessionID, _, _ := strings.Cut(botKeys[0], ":")
+		return sendOutbound(s.hub, orgID, botKeys[0], OutboundMessage{
+			To:        phone,
+			Message:   text,
+			SessionID: sessionID,
+		})
+	}
+
+	if baileysClient == nil {
+		return errors.New("no hay bots conectados de la organización")
+	}
+	sessions, err := s.sessions.ListSessions(orgID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, sessionID := range sessions {
+		_, err := baileysClient.SendMessage(ctx, sessionID, baileys.SendRequest{Number: phone, Message: text})
+		if err == nil {
+			return nil
+		}
+		log.Printf("⚠️ Recordatorio no enviado por la sesión %s: %v", sessionID, err)
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return errors.New("la organización no tiene sesiones de WhatsApp")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/rndc"
+	"github.com/brando1998/docubot-api/services"
+)
+
+var manifestOrchestrator *services.ManifestOrchestrator
+
+// SetManifestOrchestrator inyecta el orquestador de manifiestos
+func SetManifestOrchestrator(orchestrator *services.ManifestOrchestrator) {
+	manifestOrchestrator = orchestrator
+}
+
+// StartManifestRequest es la solicitud de la acción action_generar_manifiesto de Rasa
+type StartManifestRequest struct {
+	SenderID string                 `json:"sender_id" binding:"required"` // sessionId:JID (ver rasaConversationID)
+	Slots    map[string]interface{} `json:"slots" binding:"required"`
+}
+
+// StartManifest inicia la generación de un manifiesto con los slots del formulario
+// @Summary Generar manifiesto
+// @Description Llamado por Rasa al completar manifiesto_form. Registra el documento y lo genera en segundo plano con playwright-bot; el PDF se envía al cliente por WhatsApp.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param X-Internal-Token header string true "Token interno"
+// @Param request body StartManifestRequest true "Sender de Rasa y slots"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /internal/manifests [post]
+func StartManifest(c *gin.Context, hub *WebSocketHub) {
+	if manifestOrchestrator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Generación de manifiestos no configurada"})
+		return
+	}
+
+	var request StartManifestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	target, ok := resolveManifestChat(c, hub, request.SenderID)
+	if !ok {
+		return
+	}
+
+	document, err := manifestOrchestrator.Start(c.Request.Context(), services.ManifestJob{
+		ChatTarget: target,
+		Slots:      request.Slots,
+	})
+	if errors.Is(err, services.ErrIncompleteManifest) {
+		respondIncompleteManifest(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error iniciando manifiesto",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"document_id": document.ID.Hex(),
+		"status":      document.Status,
+		"consecutivo": document.Metadata["consecutivo_remesa"],
+	})
+}
+
+// resolveManifestChat obtiene el chat, la organización y el cliente de un sender_id de Rasa.
+// Si no se puede resolver ya respondió el error y devuelve false.
+func resolveManifestChat(c *gin.Context, hub *WebSocketHub, senderID string) (services.ChatTarget, bool) {
+	sessionID, chatID, ok := strings.Cut(senderID, ":")
+	if !ok || sessionID == "" || chatID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sender_id debe tener el formato sessionId:chatId"})
+		return services.ChatTarget{}, false
+	}
+
+	// El bot que atiende la sesión define la organización y por dónde se entrega el PDF
+	botPhones := hub.GetBotsBySession(sessionID)
+	if len(botPhones) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "No hay un bot conectado para la sesión"})
+		return services.ChatTarget{}, false
+	}
+	// GetBotsBySession devuelve los números; la clave del hub es sessionId:número
+	botKey := sessionID + ":" + botPhones[0]
+	orgID, ok := hub.GetBotOrganization(botKey)
+	if !ok || orgID == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "El bot de la sesión no tiene organización"})
+		return services.ChatTarget{}, false
+	}
+
+	client, err := clientRepo.GetOrCreateClient(strings.Split(chatID, "@")[0], "", "", orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo cliente",
+			"details": err.Error(),
+		})
+		return services.ChatTarget{}, false
+	}
+
+	return services.ChatTarget{
+		OrganizationID: orgID,
+		ClientID:       client.ID,
+		SessionID:      sessionID,
+		BotKey:         botKey,
+		ChatID:         chatID,
+	}, true
+}
+
+// respondIncompleteManifest responde 400 con el detalle de cada campo inválido (fields)
+// para que Rasa o el dashboard le pidan al usuario solo lo que falta corregir
+func respondIncompleteManifest(c *gin.Context, err error) {
+	response := gin.H{
+		"error":   "Datos del manifiesto incompletos",
+		"details": err.Error(),
+	}
+	var fields rndc.ValidationErrors
+	if errors.As(err, &fields) {
+		response["fields"] = fields
+	}
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// whatsappDocumentSender entrega los documentos del orquestador por la cola de salida y
+// los registra en el historial del chat
+type whatsappDocumentSender struct {
+	hub *WebSocketHub
+}
+
+// NewWhatsAppDocumentSender crea el DocumentSender que usa el hub de bots
+func NewWhatsAppDocumentSender(hub *WebSocketHub) services.DocumentSender {
+	return &whatsappDocumentSender{hub: hub}
+}
+
+func (s *whatsappDocumentSender) SendText(ctx context.Context, target services.ChatTarget, text string) error {
+	return s.send(ctx, target, OutboundMessage{
+		To:          target.ChatID,
+		Message:     text,
+		SessionID:   target.SessionID,
+		MessageType: models.MessageTypeText,
+	})
+}
+
+// SendFile envía el archivo en base64: baileys-ws no tiene acceso a los PDF de la API.
+// Las imágenes (p. ej. el QR de verificación) se envían como imagen y no como adjunto.
+func (s *whatsappDocumentSender) SendFile(ctx context.Context, target services.ChatTarget, file playwright.File, caption string) error {
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/pdf"
+	}
+	messageType := models.MessageTypeDocument
+	if strings.HasPrefix(contentType, "image/") {
+		messageType = models.MessageTypeImage
+	}
+	return s.send(ctx, target, OutboundMessage{
+		To:          target.ChatID,
+		Message:     caption,
+		SessionID:   target.SessionID,
+		MessageType: messageType,
+		Media: &models.MessageMedia{
+			Data:     base64.StdEncoding.EncodeToString(file.Content),
+			FileName: file.FileName,
+			MimeType: contentType,
+			Caption:  caption,
+		},
+	})
+}
+
+func (s *whatsappDocumentSender) send(ctx context.Context, target services.ChatTarget, message OutboundMessage) error {
+	sendErr := sendOutbound(s.hub, target.OrganizationID, target.BotKey, message)
+
+	history := botReplyMessage(models.Message{
+		OrganizationID: target.OrganizationID,
+		ClientID:       target.ClientID,
+		BotID:          botIDForKey(target.BotKey),
+		SessionID:      target.SessionID,
+		ChatID:         strings.Split(target.ChatID, "@")[0],
+	}, message, sendErr)
+	if err := conversationRepo.SaveMessage(ctx, history); err != nil {
+		log.Printf("⚠️ Error guardando mensaje del documento: %v", err)
+	}
+	return sendErr
+}
+
+// botIDForKey devuelve el ID del bot de una clave sessionId:botPhone (0 si no se encuentra)
+func botIDForKey(botKey string) uint {
+	_, botNumber, _ := strings.Cut(botKey, ":")
+	bot, err := botRepo.GetOrCreateBot(strings.Split(botNumber, "@")[0], "Default Bot")
+	if err != nil {
+		log.Printf("⚠️ Error obteniendo bot %s: %v", botKey, err)
+		return 0
+	}
+	return bot.ID
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/brando1998/docubot-api/rndc"
+	"github.com/brando1998/docubot-api/services"
+)
+
+var manifestFollowUps *services.ManifestFollowUpService
+
+// SetManifestFollowUpService inyecta el servicio de cumplidos y anulaciones
+func SetManifestFollowUpService(service *services.ManifestFollowUpService) {
+	manifestFollowUps = service
+}
+
+// FulfilManifestRequest es el cumplido de un manifiesto
+type FulfilManifestRequest struct {
+	FechaLlegada  string `json:"fecha_llegada" binding:"required"`  // YYYY-MM-DD o ISO 8601
+	PesoEntregado int    `json:"peso_entregado" binding:"required"` // Kilogramos
+	Novedades     string `json:"novedades"`
+}
+
+// AnnulManifestRequest es la anulación de un manifiesto
+type AnnulManifestRequest struct {
+	Motivo string `json:"motivo" binding:"required"`
+}
+
+// ConfirmDeliveryRequest es la confirmación de entrega del conductor (action_confirmar_entrega de Rasa)
+type ConfirmDeliveryRequest struct {
+	SenderID      string `json:"sender_id" binding:"required"`
+	PesoEntregado string `json:"peso_entregado" binding:"required"` // "4500 kg", "4,5 toneladas"...
+	Novedades     string `json:"novedades"`
+}
+
+// FulfilManifest radica el cumplido de un manifiesto completado
+// @Summary Cumplir manifiesto
+// @Description Registra un documento de cumplido enlazado al manifiesto y lo radica en el RNDC con playwright-bot. El manifiesto queda en fulfilling hasta que termine.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del manifiesto"
+// @Param request body FulfilManifestRequest true "Llegada, peso entregado y novedades"
+// @Success 202 {object} models.Document
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id}/fulfil [post]
+func FulfilManifest(c *gin.Context) {
+	if !followUpsConfigured(c) {
+		return
+	}
+
+	var request FulfilManifestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	llegada, err := rndc.ParseFecha(request.FechaLlegada)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fecha_llegada inválida", "details": err.Error()})
+		return
+	}
+
+	manifest, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	document, err := manifestFollowUps.Fulfil(c.Request.Context(), *manifest, services.Fulfilment{
+		ArrivedAt:   llegada,
+		DeliveredKg: request.PesoEntregado,
+		Novedades:   request.Novedades,
+	}, documentActor(c))
+	if err != nil {
+		respondFollowUpError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, document)
+}
+
+// AnnulManifest radica la anulación de un manifiesto completado
+// @Summary Anular manifiesto
+// @Description Registra un documento de anulación enlazado al manifiesto y lo radica en el RNDC con playwright-bot. Un manifiesto cumplido no se puede anular.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path string true "ID del manifiesto"
+// @Param request body AnnulManifestRequest true "Motivo"
+// @Success 202 {object} models.Document
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id}/annul [post]
+func AnnulManifest(c *gin.Context) {
+	if !followUpsConfigured(c) {
+		return
+	}
+
+	var request AnnulManifestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	manifest, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	document, err := manifestFollowUps.Annul(c.Request.Context(), *manifest, request.Motivo, documentActor(c))
+	if err != nil {
+		respondFollowUpError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, document)
+}
+
+// RequestManifestDelivery le pide al conductor que confirme la entrega por WhatsApp
+// @Summary Pedir confirmación de entrega
+// @Description Envía un mensaje al celular del conductor registrado del manifiesto. Cuando responda, el bot le pide el peso entregado y las novedades y radica el cumplido.
+// @Tags documents
+// @Produce json
+// @Param id path string true "ID del manifiesto"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/documents/{id}/request-delivery [post]
+func RequestManifestDelivery(c *gin.Context) {
+	if !followUpsConfigured(c) {
+		return
+	}
+
+	manifest, ok := loadDocument(c)
+	if !ok {
+		return
+	}
+	if err := manifestFollowUps.RequestDelivery(c.Request.Context(), *manifest); err != nil {
+		respondFollowUpError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Solicitud de confirmación enviada al conductor",
+		"document_id": manifest.ID.Hex(),
+	})
+}
+
+// ConfirmManifestDelivery radica el cumplido que confirma el conductor por WhatsApp
+// @Summary Confirmar entrega (conductor)
+// @Description Llamado por Rasa al completar cumplido_form. Busca el último manifiesto del conductor del chat que espera confirmación de entrega.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param X-Internal-Token header string true "Token interno"
+// @Param request body ConfirmDeliveryRequest true "Sender de Rasa, peso y novedades"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /internal/manifests/delivery [post]
+func ConfirmManifestDelivery(c *gin.Context, hub *WebSocketHub) {
+	if !followUpsConfigured(c) {
+		return
+	}
+
+	var request ConfirmDeliveryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	target, ok := resolveManifestChat(c, hub, request.SenderID)
+	if !ok {
+		return
+	}
+	document, err := manifestFollowUps.ConfirmDelivery(c.Request.Context(), target, request.PesoEntregado, request.Novedades)
+	if err != nil {
+		respondFollowUpError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"document_id": document.ID.Hex(),
+		"manifest_id": document.ParentID.Hex(),
+		"consecutivo": document.Metadata["consecutivo_manifiesto"],
+	})
+}
+
+func followUpsConfigured(c *gin.Context) bool {
+	if manifestFollowUps == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cumplidos y anulaciones no configurados"})
+		return false
+	}
+	return true
+}
+
+// respondFollowUpError responde los errores de los cumplidos y anulaciones; los datos
+// inválidos incluyen fields igual que respondIncompleteManifest
+func respondFollowUpError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidFollowUp):
+		response := gin.H{
+			"error":   "Datos del cumplido o de la anulación inválidos",
+			"details": err.Error(),
+		}
+		var fields rndc.ValidationErrors
+		if errors.As(err, &fields) {
+			response["fields"] = fields
+		}
+		c.JSON(http.StatusBadRequest, response)
+	case errors.Is(err, services.ErrManifestStage):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "El manifiesto no admite esta operación",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrNoPendingDelivery):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No hay manifiestos pendientes de entrega para este conductor"})
+	case errors.Is(err, services.ErrNoDriverPhone):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No se puede pedir la confirmación de entrega",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error procesando el manifiesto",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+)
+
+// defaultRecentManifests es cuántos manifiestos anteriores se ofrecen si no se pide limit
+const defaultRecentManifests = 3
+
+var manifestTemplateService *services.ManifestTemplateService
+
+// SetManifestTemplateService inyecta el servicio de plantillas de manifiesto
+func SetManifestTemplateService(service *services.ManifestTemplateService) {
+	manifestTemplateService = service
+}
+
+// RepeatManifestRequest genera un manifiesto a partir de una plantilla
+type RepeatManifestRequest struct {
+	SenderID   string                 `json:"sender_id"` // Solo en /internal: sessionId:chatId de Rasa
+	TemplateID string                 `json:"template_id" binding:"required"`
+	Overrides  map[string]interface{} `json:"overrides"` // Slots confirmados o cambiados (fechas, placa...)
+}
+
+// RouteTemplateRequest son los datos de una ruta guardada del cliente
+type RouteTemplateRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Origen      string `json:"origen" binding:"required"`
+	Destino     string `json:"destino" binding:"required"`
+	Descripcion string `json:"descripcion"`
+	Peso        string `json:"peso"`
+	Flete       string `json:"flete"`
+	Tarjeta     string `json:"tarjeta"`
+	Licencia    string `json:"licencia"`
+}
+
+// GetChatManifestTemplates devuelve las plantillas del cliente del chat para repetir un manifiesto
+// @Summary Plantillas de manifiesto del chat
+// @Description Llamado por Rasa: últimos manifiestos completados del cliente (sin fechas) y sus rutas guardadas.
+// @Tags documents
+// @Produce json
+// @Param X-Internal-Token header string true "Token interno"
+// @Param sender_id query string true "Sender de Rasa (sessionId:chatId)"
+// @Param limit query int false "Manifiestos anteriores" default(3)
+// @Success 200 {object} map[string]interface{}
+// @Router /internal/manifests/templates [get]
+func GetChatManifestTemplates(c *gin.Context, hub *WebSocketHub) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	target, ok := resolveManifestChat(c, hub, c.Query("sender_id"))
+	if !ok {
+		return
+	}
+	respondManifestTemplates(c, target.OrganizationID, target.ClientID)
+}
+
+// RepeatChatManifest genera un manifiesto para el chat con una plantilla y los cambios del usuario
+// @Summary Repetir manifiesto desde el chat
+// @Description Llamado por Rasa cuando el cliente confirma una plantilla. Responde igual que POST /internal/manifests.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param X-Internal-Token header string true "Token interno"
+// @Param request body RepeatManifestRequest true "Sender, plantilla y cambios"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /internal/manifests/repeat [post]
+func RepeatChatManifest(c *gin.Context, hub *WebSocketHub) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	request, ok := bindRepeatManifest(c)
+	if !ok {
+		return
+	}
+	target, ok := resolveManifestChat(c, hub, request.SenderID)
+	if !ok {
+		return
+	}
+	repeatManifest(c, target, request)
+}
+
+// GetClientManifestTemplates devuelve las plantillas de manifiesto de un cliente
+// @Summary Plantillas de manifiesto del cliente
+// @Description Últimos manifiestos completados del cliente (sin fechas) y sus rutas guardadas.
+// @Tags documents
+// @Produce json
+// @Param id path int true "ID del cliente"
+// @Param limit query int false "Manifiestos anteriores" default(3)
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/id/{id}/manifest-templates [get]
+func GetClientManifestTemplates(c *gin.Context) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	client, ok := loadTemplateClient(c)
+	if !ok {
+		return
+	}
+	respondManifestTemplates(c, client.OrganizationID, client.ID)
+}
+
+// RepeatClientManifest genera un manifiesto para un cliente desde el dashboard
+// @Summary Repetir manifiesto del cliente
+// @Description El agente elige una plantilla y corrige los campos; el PDF se envía al WhatsApp del cliente con un bot conectado de la organización.
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del cliente"
+// @Param request body RepeatManifestRequest true "Plantilla y cambios"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/users/id/{id}/manifests/repeat [post]
+func RepeatClientManifest(c *gin.Context, hub *WebSocketHub) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	request, ok := bindRepeatManifest(c)
+	if !ok {
+		return
+	}
+	client, ok := loadTemplateClient(c)
+	if !ok {
+		return
+	}
+
+	botKeys := hub.GetBotsByOrganization(client.OrganizationID)
+	if len(botKeys) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "No hay un bot conectado para enviar el manifiesto"})
+		return
+	}
+	sessionID, _, _ := strings.Cut(botKeys[0], ":")
+
+	repeatManifest(c, services.ChatTarget{
+		OrganizationID: client.OrganizationID,
+		ClientID:       client.ID,
+		SessionID:      sessionID,
+		BotKey:         botKeys[0],
+		ChatID:         client.Phone + "@s.whatsapp.net",
+	}, request)
+}
+
+// ListRouteTemplates lista las rutas guardadas de un cliente
+// @Summary Listar rutas guardadas
+// @Tags documents
+// @Produce json
+// @Param id path int true "ID del cliente"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/users/id/{id}/route-templates [get]
+func ListRouteTemplates(c *gin.Context) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	client, ok := loadTemplateClient(c)
+	if !ok {
+		return
+	}
+	routes, err := manifestTemplateService.Routes().ListByClient(client.ID, client.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo rutas",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"route_templates": routes, "total": len(routes)})
+}
+
+// CreateRouteTemplate guarda una ruta del cliente
+// @Summary Guardar ruta
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del cliente"
+// @Param request body RouteTemplateRequest true "Datos de la ruta"
+// @Success 201 {object} models.RouteTemplate
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/users/id/{id}/route-templates [post]
+func CreateRouteTemplate(c *gin.Context) {
+	saveRouteTemplate(c, false)
+}
+
+// UpdateRouteTemplate reemplaza los datos de una ruta guardada
+// @Summary Actualizar ruta
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del cliente"
+// @Param templateId path int true "ID de la ruta"
+// @Param request body RouteTemplateRequest true "Datos de la ruta"
+// @Success 200 {object} models.RouteTemplate
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/users/id/{id}/route-templates/{templateId} [put]
+func UpdateRouteTemplate(c *gin.Context) {
+	saveRouteTemplate(c, true)
+}
+
+// DeleteRouteTemplate elimina una ruta guardada
+// @Summary Eliminar ruta
+// @Tags documents
+// @Param id path int true "ID del cliente"
+// @Param templateId path int true "ID de la ruta"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/users/id/{id}/route-templates/{templateId} [delete]
+func DeleteRouteTemplate(c *gin.Context) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	client, ok := loadTemplateClient(c)
+	if !ok {
+		return
+	}
+	templateID, err := strconv.ParseUint(c.Param("templateId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ruta inválido"})
+		return
+	}
+	err = manifestTemplateService.Routes().Delete(uint(templateID), client.ID, client.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ruta no encontrada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error eliminando ruta",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func saveRouteTemplate(c *gin.Context, update bool) {
+	if !manifestTemplatesConfigured(c) {
+		return
+	}
+	client, ok := loadTemplateClient(c)
+	if !ok {
+		return
+	}
+
+	var request RouteTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	route := models.RouteTemplate{
+		OrganizationID: client.OrganizationID,
+		ClientID:       client.ID,
+		Name:           strings.TrimSpace(request.Name),
+		Origen:         strings.TrimSpace(request.Origen),
+		Destino:        strings.TrimSpace(request.Destino),
+		Descripcion:    strings.TrimSpace(request.Descripcion),
+		Peso:           strings.TrimSpace(request.Peso),
+		Flete:          strings.TrimSpace(request.Flete),
+		Tarjeta:        strings.TrimSpace(request.Tarjeta),
+		Licencia:       strings.TrimSpace(request.Licencia),
+	}
+
+	routes := manifestTemplateService.Routes()
+	status := http.StatusCreated
+	if update {
+		templateID, err := strconv.ParseUint(c.Param("templateId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ruta inválido"})
+			return
+		}
+		existing, err := routes.GetByID(uint(templateID), client.ID, client.OrganizationID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ruta no encontrada"})
+			return
+		}
+		route.ID = existing.ID
+		route.CreatedAt = existing.CreatedAt
+		status = http.StatusOK
+	}
+
+	// El nombre es el que el cliente elige en el chat, no se puede repetir
+	saved, err := routes.ListByClient(client.ID, client.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo rutas",
+			"details": err.Error(),
+		})
+		return
+	}
+	for _, other := range saved {
+		if other.ID != route.ID && strings.EqualFold(other.Name, route.Name) {
+			c.JSON(http.StatusConflict, gin.H{"error": "El cliente ya tiene una ruta con ese nombre"})
+			return
+		}
+	}
+
+	if update {
+		err = routes.Update(&route)
+	} else {
+		err = routes.Create(&route)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error guardando ruta",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(status, route)
+}
+
+func manifestTemplatesConfigured(c *gin.Context) bool {
+	if manifestTemplateService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plantillas de manifiesto no configuradas"})
+		return false
+	}
+	return true
+}
+
+func bindRepeatManifest(c *gin.Context) (RepeatManifestRequest, bool) {
+	var request RepeatManifestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return request, false
+	}
+	return request, true
+}
+
+// loadTemplateClient obtiene el cliente de la URL dentro de la organización del usuario
+func loadTemplateClient(c *gin.Context) (*models.Client, bool) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return nil, false
+	}
+	orgID := orgIDInterface.(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return nil, false
+	}
+	client, err := clientRepo.GetClientByID(uint(id), orgID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return nil, false
+	}
+	return client, true
+}
+
+func respondManifestTemplates(c *gin.Context, orgID, clientID uint) {
+	limit := defaultRecentManifests
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > services.MaxRecentManifests {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit debe estar entre 1 y 10"})
+			return
+		}
+		limit = parsed
+	}
+
+	recent, err := manifestTemplateService.Recent(c.Request.Context(), orgID, clientID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo manifiestos anteriores",
+			"details": err.Error(),
+		})
+		return
+	}
+	routes, err := manifestTemplateService.Saved(orgID, clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo rutas",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recent": recent, "routes": routes})
+}
+
+func repeatManifest(c *gin.Context, target services.ChatTarget, request RepeatManifestRequest) {
+	document, err := manifestTemplateService.Repeat(c.Request.Context(), target, request.TemplateID, request.Overrides)
+	if errors.Is(err, services.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada"})
+		return
+	}
+	if errors.Is(err, services.ErrIncompleteManifest) {
+		respondIncompleteManifest(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error iniciando manifiesto",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"document_id": document.ID.Hex(),
+		"status":      document.Status,
+		"consecutivo": document.Metadata["consecutivo_remesa"],
+		"template_id": request.TemplateID,
+	})
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+)
+
+func TestResolveManifestChatRoutesToSessionBot(t *testing.T) {
+	previousClients := clientRepo
+	defer func() { clientRepo = previousClients }()
+	clientRepo = &mocks.MockClientRepo{
+		GetOrCreateClientFunc: func(phone, name, email string, orgID uint) (*models.Client, error) {
+			return &models.Client{ID: 5, Phone: phone, OrganizationID: orgID}, nil
+		},
+	}
+
+	server, hub, credentials := setupBotWebSocketServer(t)
+	conn := connectTestBot(t, server, credentials)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	target, ok := resolveManifestChat(c, hub, "bot-1:573009999999@s.whatsapp.net")
+	require.True(t, ok)
+	assert.Equal(t, "bot-1:573001234567", target.BotKey, "la clave del hub es sessionId:número del bot")
+	assert.Equal(t, uint(1), target.OrganizationID)
+	assert.Equal(t, uint(5), target.ClientID)
+
+	// El PDF del manifiesto completado sale por la conexión del bot de la sesión
+	id, err := hub.SendToBot(target.BotKey, OutboundMessage{To: target.ChatID, Message: "manifiesto"})
+	require.NoError(t, err)
+	envelope := readEnvelope(t, conn)
+	assert.Equal(t, id, envelope.ID)
+	assert.Equal(t, "573009999999@s.whatsapp.net", envelope.To)
+
+	// Sin bot conectado para la sesión no se resuelve
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	_, ok = resolveManifestChat(c, hub, "bot-2:573009999999@s.whatsapp.net")
+	assert.False(t, ok)
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errDispatcherClosed = errors.New("dispatcher cerrado")
+	errDispatcherFull   = errors.New("cola de mensajes entrantes llena")
+)
+
+// DispatcherConfig controla la concurrencia del procesamiento de mensajes entrantes
+type DispatcherConfig struct {
+	Workers    int // Chats que se procesan en paralelo
+	MaxPending int // Mensajes en espera antes de rechazar los nuevos
+}
+
+// DefaultDispatcherConfig devuelve la configuración por defecto del dispatcher
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		Workers:    16,
+		MaxPending: 1024,
+	}
+}
+
+// DispatcherStats resume el estado del dispatcher para monitoreo
+type DispatcherStats struct {
+	Workers     int    `json:"workers"`
+	MaxPending  int    `json:"max_pending"`
+	Pending     int    `json:"pending"`      // Mensajes en cola sin procesar
+	Busy        int    `json:"busy"`         // Workers procesando un mensaje
+	ActiveChats int    `json:"active_chats"` // Chats con mensajes en cola o en proceso
+	Processed   uint64 `json:"processed"`    // Mensajes procesados desde el arranque
+	Failed      uint64 `json:"failed"`       // Mensajes cuyo procesamiento devolvió error
+	Rejected    uint64 `json:"rejected"`     // Envíos rechazados por cola llena
+	PeakPending int    `json:"peak_pending"`
+}
+
+// chatQueue guarda los mensajes pendientes de un chat. scheduled indica que el chat
+// ya está en la lista de listos o en manos de un worker, así nunca lo procesan dos a la vez.
+type chatQueue struct {
+	messages  []IncomingMessageRequest
+	scheduled bool
+}
+
+// MessageDispatcher procesa los mensajes entrantes con un pool acotado de workers.
+// Chats distintos avanzan en paralelo; los mensajes de un mismo chat (sesión + teléfono)
+// se procesan estrictamente en el orden de llegada. Cuando la cola está llena, Submit
+// rechaza el mensaje de inmediato para que el bot lo reenvíe (backpressure sin dejar de
+// leer pongs y acks del WebSocket).
+type MessageDispatcher struct {
+	config  DispatcherConfig
+	handler func(IncomingMessageRequest) error
+
+	mu      sync.Mutex
+	work    *sync.Cond // Hay chats listos o se cerró el dispatcher
+	chats   map[string]*chatQueue
+	ready   []string // Chats con mensajes esperando un worker, en orden FIFO
+	pending int
+	closed  bool
+	stats   DispatcherStats
+	wg      sync.WaitGroup
+}
+
+var messageDispatcher *MessageDispatcher
+
+// SetMessageDispatcher inyecta el dispatcher usado por HandleWebSocket
+func SetMessageDispatcher(dispatcher *MessageDispatcher) {
+	messageDispatcher = dispatcher
+}
+
+// NewMessageDispatcher crea el dispatcher y arranca sus workers
+func NewMessageDispatcher(hub *WebSocketHub, config DispatcherConfig) *MessageDispatcher {
+	return newMessageDispatcher(config, func(msg IncomingMessageRequest) error {
+		return processIncomingMessage(msg, hub)
+	})
+}
+
+func newMessageDispatcher(config DispatcherConfig, handler func(IncomingMessageRequest) error) *MessageDispatcher {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.MaxPending < 1 {
+		config.MaxPending = 1
+	}
+
+	d := &MessageDispatcher{
+		config:  config,
+		handler: handler,
+		chats:   make(map[string]*chatQueue),
+	}
+	d.work = sync.NewCond(&d.mu)
+	d.stats.Workers = config.Workers
+	d.stats.MaxPending = config.MaxPending
+
+	d.wg.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+// chatKey identifica el chat al que pertenece un mensaje
+func chatKey(msg IncomingMessageRequest) string {
+	return msg.SessionID + ":" + msg.Phone
+}
+
+// Submit encola el mensaje sin bloquear: con la cola llena devuelve errDispatcherFull
+func (d *MessageDispatcher) Submit(msg IncomingMessageRequest) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return errDispatcherClosed
+	}
+	if d.pending >= d.config.MaxPending {
+		d.stats.Rejected++
+		return errDispatcherFull
+	}
+
+	key := chatKey(msg)
+	chat, ok := d.chats[key]
+	if !ok {
+		chat = &chatQueue{}
+		d.chats[key] = chat
+	}
+	chat.messages = append(chat.messages, msg)
+	d.pending++
+	if d.pending > d.stats.PeakPending {
+		d.stats.PeakPending = d.pending
+	}
+
+	if !chat.scheduled {
+		chat.scheduled = true
+		d.ready = append(d.ready, key)
+		d.work.Signal()
+	}
+	return nil
+}
+
+// worker toma un mensaje del primer chat listo, lo procesa y, si el chat tiene más
+// mensajes, lo devuelve al final de la lista para no acaparar el worker
+func (d *MessageDispatcher) worker() {
+	defer d.wg.Done()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		for len(d.ready) == 0 && !d.closed {
+			d.work.Wait()
+		}
+		if len(d.ready) == 0 {
+			return
+		}
+
+		key := d.ready[0]
+		d.ready = d.ready[1:]
+		chat := d.chats[key]
+		msg := chat.messages[0]
+		chat.messages = chat.messages[1:]
+		d.stats.Busy++
+
+		d.mu.Unlock()
+		err := d.handle(msg)
+		d.mu.Lock()
+
+		d.stats.Busy--
+		d.stats.Processed++
+		if err != nil {
+			d.stats.Failed++
+			log.Printf("Error processing message: %v", err)
+		}
+		d.pending--
+
+		if len(chat.messages) > 0 {
+			d.ready = append(d.ready, key)
+			d.work.Signal()
+		} else {
+			delete(d.chats, key)
+		}
+	}
+}
+
+// handle aísla los panics de un mensaje para no perder el worker
+func (d *MessageDispatcher) handle(msg IncomingMessageRequest) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("🔥 Panic procesando mensaje de %s: %v", msg.Phone, r)
+			err = errors.New("panic procesando mensaje")
+		}
+	}()
+	return d.handler(msg)
+}
+
+// Stats devuelve una foto de las métricas del dispatcher
+func (d *MessageDispatcher) Stats() DispatcherStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := d.stats
+	stats.Pending = d.pending
+	stats.ActiveChats = len(d.chats)
+	return stats
+}
+
+// Close deja de aceptar mensajes y espera a que los workers vacíen la cola
+func (d *MessageDispatcher) Close() {
+	d.mu.Lock()
+	d.closed = true
+	d.work.Broadcast()
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+// dispatchIncoming entrega el mensaje al dispatcher o, si no hay uno configurado,
+// lo procesa directamente. Si la cola sigue llena se rechaza con un incoming_nack para
+// que baileys-ws lo reenvíe más tarde.
+func dispatchIncoming(msg IncomingMessageRequest, hub *WebSocketHub) {
+	if messageDispatcher != nil {
+		err := messageDispatcher.Submit(msg)
+		if errors.Is(err, errDispatcherFull) {
+			log.Printf("⏳ Mensaje de %s rechazado, cola llena: se pide reenviarlo", msg.Phone)
+			if err := hub.RejectIncoming(msg.BotKey, msg.ID, errDispatcherFull.Error()); err != nil {
+				log.Printf("Error avisando al bot %s del rechazo: %v", msg.BotKey, err)
+			}
+		} else if err != nil {
+			log.Printf("Mensaje de %s descartado: %v", msg.Phone, err)
+		}
+		return
+	}
+	if err := processIncomingMessage(msg, hub); err != nil {
+		log.Printf("Error processing message: %v", err)
+	}
+}
+
+// GetDispatcherStats expone las métricas del dispatcher de mensajes entrantes
+// @Summary Métricas del dispatcher
+// @Tags debug
+// @Produce json
+// @Success 200 {object} DispatcherStats
+// @Router /api/v1/debug/dispatcher [get]
+func GetDispatcherStats(c *gin.Context) {
+	if messageDispatcher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dispatcher no configurado"})
+		return
+	}
+	c.JSON(http.StatusOK, messageDispatcher.Stats())
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func incomingMsg(phone string, n int) IncomingMessageRequest {
+	return IncomingMessageRequest{SessionID: "bot-1", Phone: phone, Message: fmt.Sprint(n)}
+}
+
+func TestDispatcherKeepsOrderPerChat(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	d := newMessageDispatcher(DispatcherConfig{Workers: 8, MaxPending: 1000}, func(msg IncomingMessageRequest) error {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		received[msg.Phone] = append(received[msg.Phone], msg.Message)
+		mu.Unlock()
+		return nil
+	})
+
+	phones := []string{"573001", "573002", "573003", "573004"}
+	for i := 0; i < 50; i++ {
+		for _, phone := range phones {
+			require.NoError(t, d.Submit(incomingMsg(phone, i)))
+		}
+	}
+	d.Close()
+
+	for _, phone := range phones {
+		require.Len(t, received[phone], 50)
+		for i, message := range received[phone] {
+			assert.Equal(t, fmt.Sprint(i), message, "orden roto en %s", phone)
+		}
+	}
+	stats := d.Stats()
+	assert.Equal(t, uint64(200), stats.Processed)
+	assert.Equal(t, 0, stats.Pending)
+	assert.Equal(t, 0, stats.ActiveChats)
+}
+
+func TestDispatcherSlowChatDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan string, 10)
+	d := newMessageDispatcher(DispatcherConfig{Workers: 2, MaxPending: 10}, func(msg IncomingMessageRequest) error {
+		if msg.Phone == "slow" {
+			<-release
+		}
+		done <- msg.Phone
+		return nil
+	})
+	defer d.Close()
+	defer close(release)
+
+	require.NoError(t, d.Submit(incomingMsg("slow", 1)))
+	require.NoError(t, d.Submit(incomingMsg("slow", 2)))
+	require.NoError(t, d.Submit(incomingMsg("fast", 1)))
+
+	select {
+	case phone := <-done:
+		assert.Equal(t, "fast", phone)
+	case <-time.After(2 * time.Second):
+		t.Fatal("un chat lento bloqueó a los demás")
+	}
+
+	// El segundo mensaje del chat lento espera al primero aunque haya un worker libre
+	time.Sleep(50 * time.Millisecond)
+	stats := d.Stats()
+	assert.Equal(t, 1, stats.Busy)
+	assert.Equal(t, 2, stats.Pending)
+}
+
+func TestDispatcherBoundsConcurrency(t *testing.T) {
+	var running, peak int32
+	d := newMessageDispatcher(DispatcherConfig{Workers: 3, MaxPending: 100}, func(msg IncomingMessageRequest) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, d.Submit(incomingMsg(fmt.Sprint("5730", i), i)))
+	}
+	d.Close()
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(3))
+	assert.Equal(t, uint64(30), d.Stats().Processed)
+}
+
+func TestDispatcherBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	d := newMessageDispatcher(DispatcherConfig{Workers: 1, MaxPending: 2}, func(msg IncomingMessageRequest) error {
+		<-release
+		return fmt.Errorf("rasa no disponible")
+	})
+
+	require.NoError(t, d.Submit(incomingMsg("573001", 1)))
+	require.NoError(t, d.Submit(incomingMsg("573002", 1)))
+
+	// Con la cola llena el mensaje se rechaza de inmediato
+	assert.Equal(t, errDispatcherFull, d.Submit(incomingMsg("573003", 1)))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return d.Submit(incomingMsg("573003", 1)) == nil
+	}, 2*time.Second, 5*time.Millisecond, "al liberarse espacio se acepta de nuevo")
+	d.Close()
+
+	stats := d.Stats()
+	assert.Equal(t, uint64(3), stats.Processed)
+	assert.Equal(t, uint64(3), stats.Failed)
+	assert.Equal(t, 2, stats.PeakPending)
+	assert.Equal(t, errDispatcherClosed, d.Submit(incomingMsg("573004", 1)))
+}
+
+func TestDispatcherRejectsWhenQueueStaysFull(t *testing.T) {
+	release := make(chan struct{})
+	d := newMessageDispatcher(DispatcherConfig{Workers: 1, MaxPending: 1}, func(msg IncomingMessageRequest) error {
+		<-release
+		return nil
+	})
+	defer d.Close()
+	defer close(release)
+
+	require.NoError(t, d.Submit(incomingMsg("573001", 1)))
+
+	// Submit no bloquea a quien lee el WebSocket
+	start := time.Now()
+	assert.Equal(t, errDispatcherFull, d.Submit(incomingMsg("573002", 1)))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	stats := d.Stats()
+	assert.Equal(t, uint64(1), stats.Rejected)
+	assert.Equal(t, 1, stats.Pending)
+}
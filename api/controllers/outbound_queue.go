@@ -0,0 +1,489 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// OutboundQueueConfig controla los reintentos de la cola de salida
+type OutboundQueueConfig struct {
+	BaseBackoff      time.Duration // Espera tras el primer fallo; se duplica en cada intento
+	MaxBackoff       time.Duration
+	MaxAttempts      int           // Intentos antes de pasar a dead letter
+	OfflineTimeout   time.Duration // Espera máxima sin que ninguna instancia tenga el bot conectado antes de dead letter
+	DispatchInterval time.Duration // Cada cuánto se revisan los mensajes vencidos
+	BatchSize        int
+	InstanceID       string        // Identifica esta instancia de la API en los envíos en curso
+	StaleInFlight    time.Duration // Envíos en curso de otra instancia que se consideran abandonados
+}
+
+// DefaultOutboundQueueConfig devuelve la configuración por defecto de la cola
+func DefaultOutboundQueueConfig() OutboundQueueConfig {
+	hostname, _ := os.Hostname()
+	return OutboundQueueConfig{
+		BaseBackoff:      5 * time.Second,
+		MaxBackoff:       10 * time.Minute,
+		MaxAttempts:      8,
+		OfflineTimeout:   24 * time.Hour,
+		DispatchInterval: 5 * time.Second,
+		BatchSize:        100,
+		InstanceID:       hostname,
+		StaleInFlight:    5 * time.Minute,
+	}
+}
+
+// OutboundQueue persiste los mensajes hacia WhatsApp y los reenvía hasta que baileys-ws
+// confirme la entrega. Se apoya en los acks del WebSocketHub: cada intento es un
+// envelope; si el hub lo reporta como fallido se reprograma con backoff exponencial.
+// Las filas solo se actualizan si siguen en el estado leído, así varias instancias
+// de la API pueden compartir la tabla sin enviar dos veces ni pisarse. Cada instancia
+// solo despacha los mensajes de los bots conectados a ella.
+type OutboundQueue struct {
+	hub    *WebSocketHub
+	repo   repositories.OutboundMessageRepository
+	config OutboundQueueConfig
+
+	mu   sync.Mutex // Serializa los despachos para no enviar dos veces la misma fila
+	stop chan struct{}
+	once sync.Once
+}
+
+var outboundQueue *OutboundQueue
+
+// SetOutboundQueue inyecta la cola de salida usada por el pipeline de mensajes
+func SetOutboundQueue(queue *OutboundQueue) {
+	outboundQueue = queue
+}
+
+// NewOutboundQueue crea la cola y la conecta a los eventos del hub
+func NewOutboundQueue(hub *WebSocketHub, repo repositories.OutboundMessageRepository, config OutboundQueueConfig) *OutboundQueue {
+	q := &OutboundQueue{
+		hub:    hub,
+		repo:   repo,
+		config: config,
+		stop:   make(chan struct{}),
+	}
+	hub.SetDeliveryHandler(q.handleDeliveryResult)
+	hub.SetBotRegisteredHandler(q.Flush)
+	return q
+}
+
+// Start libera los envíos que esta instancia dejó en curso en una ejecución anterior y
+// empieza a revisar periódicamente los mensajes vencidos
+func (q *OutboundQueue) Start() {
+	q.resetInFlight(q.config.InstanceID)
+
+	go func() {
+		ticker := time.NewTicker(q.config.DispatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.resetInFlight("")
+				q.expireOffline()
+				q.dispatchDue(q.hub.ListBots(), time.Now())
+			case <-q.stop:
+				return
+			}
+		}
+	}()
+}
+
+// resetInFlight devuelve a la cola los envíos en curso de la instancia indicada y los
+// abandonados por instancias caídas. El estado del hub no sobrevive a un reinicio.
+func (q *OutboundQueue) resetInFlight(instanceID string) {
+	n, err := q.repo.ResetInFlight(instanceID, time.Now().Add(-q.config.StaleInFlight))
+	if err != nil {
+		log.Printf("⚠️ Error liberando mensajes en curso: %v", err)
+	} else if n > 0 {
+		log.Printf("📤 %d mensajes en curso de una ejecución anterior vuelven a la cola", n)
+	}
+}
+
+// Stop detiene el despacho periódico
+func (q *OutboundQueue) Stop() {
+	q.once.Do(func() { close(q.stop) })
+}
+
+// Enqueue persiste el mensaje y lo intenta enviar de inmediato si el bot está conectado a
+// esta instancia. Si no, queda pendiente: lo envía la instancia que tenga el bot o esta
+// misma cuando el bot se reconecte.
+func (q *OutboundQueue) Enqueue(orgID uint, botKey string, message OutboundMessage) (*models.OutboundMessage, error) {
+	payload, err := encodeOutboundPayload(message)
+	if err != nil {
+		return nil, err
+	}
+	messageType := message.MessageType
+	if messageType == "" {
+		messageType = models.MessageTypeText
+	}
+	row := &models.OutboundMessage{
+		OrganizationID: orgID,
+		SessionID:      message.SessionID,
+		BotKey:         botKey,
+		To:             message.To,
+		Message:        message.Message,
+		MessageType:    messageType,
+		Payload:        payload,
+		Status:         models.OutboundStatusPending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := q.repo.Create(row); err != nil {
+		return nil, err
+	}
+
+	if _, connected := q.hub.GetBotOrganization(botKey); !connected {
+		return row, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dispatchLocked(row)
+	return row, nil
+}
+
+// Flush envía todos los pendientes de un bot sin esperar el backoff (se llama al reconectarse)
+func (q *OutboundQueue) Flush(botKey string) {
+	q.dispatchDue([]string{botKey}, time.Now().Add(q.config.MaxBackoff))
+}
+
+// dispatchDue envía los mensajes vencidos de los bots indicados (conectados a esta instancia)
+func (q *OutboundQueue) dispatchDue(botKeys []string, dueBefore time.Time) {
+	if len(botKeys) == 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	rows, err := q.repo.GetDispatchable(botKeys, dueBefore, q.config.BatchSize)
+	if err != nil {
+		log.Printf("⚠️ Error consultando la cola de salida: %v", err)
+		return
+	}
+	for i := range rows {
+		q.dispatchLocked(&rows[i])
+	}
+}
+
+// dispatchLocked entrega la fila al hub. Antes de enviar la reserva con el ID del envelope
+// para que otra instancia no la tome; si el bot se desconectó la libera y la reprograma con
+// backoff exponencial (expireOffline la pasa a dead letter si el bot no vuelve).
+func (q *OutboundQueue) dispatchLocked(row *models.OutboundMessage) {
+	message := OutboundMessage{
+		To:          row.To,
+		Message:     row.Message,
+		SessionID:   row.SessionID,
+		MessageType: row.MessageType,
+	}
+	if err := decodeOutboundPayload(row.Payload, &message); err != nil {
+		log.Printf("⚠️ Payload inválido en el mensaje %d de la cola: %v", row.ID, err)
+	}
+
+	envelopeID, err := newEnvelopeID()
+	if err != nil {
+		log.Printf("⚠️ Error generando envelope para el mensaje %d de la cola: %v", row.ID, err)
+		return
+	}
+	claimed := *row
+	claimed.EnvelopeID = envelopeID
+	claimed.DispatchedBy = q.config.InstanceID
+	if !q.save(&claimed, models.OutboundStatusPending, "") {
+		return
+	}
+	*row = claimed
+
+	err = q.hub.sendEnvelope(row.BotKey, envelopeID, message)
+	if err == nil {
+		return
+	}
+
+	// El bot se desconectó: no cuenta como intento de entrega; al reconectarse se envía de inmediato
+	row.EnvelopeID = ""
+	row.DispatchedBy = ""
+	row.OfflineAttempts++
+	row.LastError = err.Error()
+	row.NextAttemptAt = time.Now().Add(outboundBackoff(q.config, row.OfflineAttempts))
+	q.save(row, models.OutboundStatusPending, envelopeID)
+}
+
+// expireOffline pasa a dead letter los pendientes que ninguna instancia despachó en
+// OfflineTimeout: su bot no está conectado en ningún lado. La actualización condicional
+// hace que solo una instancia lo reporte.
+func (q *OutboundQueue) expireOffline() {
+	rows, err := q.repo.GetStalePending(time.Now().Add(-q.config.OfflineTimeout), q.config.BatchSize)
+	if err != nil {
+		log.Printf("⚠️ Error consultando mensajes sin bot conectado: %v", err)
+		return
+	}
+	for i := range rows {
+		row := &rows[i]
+		row.Status = models.OutboundStatusFailed
+		row.LastError = fmt.Sprintf("el bot %s no se conectó en %s", row.BotKey, q.config.OfflineTimeout)
+		if q.save(row, models.OutboundStatusPending, "") {
+			q.deadLetter(row)
+		}
+	}
+}
+
+// save guarda el estado de envío de la fila si nadie la cambió desde que se leyó
+func (q *OutboundQueue) save(row *models.OutboundMessage, expectedStatus, expectedEnvelopeID string) bool {
+	saved, err := q.repo.UpdateDispatch(row, expectedStatus, expectedEnvelopeID)
+	if err != nil {
+		log.Printf("⚠️ Error actualizando mensaje %d de la cola: %v", row.ID, err)
+		return false
+	}
+	return saved
+}
+
+// deadLetter avisa a los agentes de la organización que el mensaje agotó sus intentos
+func (q *OutboundQueue) deadLetter(row *models.OutboundMessage) {
+	log.Printf("💀 Mensaje %d a %s enviado a dead letter tras %d intentos (%d sin bot conectado): %s",
+		row.ID, row.To, row.Attempts, row.OfflineAttempts, row.LastError)
+	q.hub.BroadcastToAgents(row.OrganizationID, map[string]interface{}{
+		"type":                "delivery_failed",
+		"outbound_message_id": row.ID,
+		"session_id":          row.SessionID,
+		"chat_id":             row.To,
+		"message":             row.Message,
+		"attempts":            row.Attempts,
+		"error":               row.LastError,
+	})
+}
+
+// outboundPayload es el contenido enriquecido que se guarda como JSON en la cola
+type outboundPayload struct {
+	Media    *models.MessageMedia    `json:"media,omitempty"`
+	Buttons  []models.MessageButton  `json:"buttons,omitempty"`
+	Location *models.MessageLocation `json:"location,omitempty"`
+}
+
+func encodeOutboundPayload(message OutboundMessage) (string, error) {
+	if message.Media == nil && len(message.Buttons) == 0 && message.Location == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(outboundPayload{
+		Media:    message.Media,
+		Buttons:  message.Buttons,
+		Location: message.Location,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error serializando payload del mensaje: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeOutboundPayload(raw string, message *OutboundMessage) error {
+	if raw == "" {
+		return nil
+	}
+	var payload outboundPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return err
+	}
+	message.Media = payload.Media
+	message.Buttons = payload.Buttons
+	message.Location = payload.Location
+	return nil
+}
+
+// handleDeliveryResult recibe el resultado final de un envelope desde el hub
+func (q *OutboundQueue) handleDeliveryResult(result DeliveryResult) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	row, err := q.repo.GetByEnvelopeID(result.MessageID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️ Error buscando mensaje del envelope %s: %v", result.MessageID, err)
+		}
+		return
+	}
+
+	row.EnvelopeID = ""
+	row.DispatchedBy = ""
+	row.Attempts++
+	row.OfflineAttempts = 0 // El bot estaba conectado
+	now := time.Now()
+
+	switch {
+	case result.Status == DeliveryDelivered:
+		row.Status = models.OutboundStatusSent
+		row.SentAt = &now
+		row.LastError = ""
+	case row.Attempts >= q.config.MaxAttempts:
+		row.Status = models.OutboundStatusFailed
+		row.LastError = result.Error
+	default:
+		row.LastError = result.Error
+		row.NextAttemptAt = now.Add(outboundBackoff(q.config, row.Attempts))
+	}
+
+	if q.save(row, models.OutboundStatusPending, result.MessageID) && row.Status == models.OutboundStatusFailed {
+		q.deadLetter(row)
+	}
+}
+
+// outboundBackoff calcula la espera exponencial tras attempts intentos fallidos
+func outboundBackoff(config OutboundQueueConfig, attempts int) time.Duration {
+	if attempts < 1 {
+		return config.BaseBackoff
+	}
+	backoff := config.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= config.MaxBackoff {
+			return config.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// sendOutbound envía un mensaje al bot pasando por la cola durable si está configurada
+func sendOutbound(hub *WebSocketHub, orgID uint, botKey string, message OutboundMessage) error {
+	if outboundQueue != nil {
+		_, err := outboundQueue.Enqueue(orgID, botKey, message)
+		return err
+	}
+	_, err := hub.SendToBot(botKey, message)
+	return err
+}
+
+// GetOutboundDeadLetters lista los mensajes que agotaron sus intentos
+// @Summary Dead letter de mensajes salientes
+// @Description Lista los mensajes de WhatsApp que no se pudieron entregar tras todos los reintentos
+// @Tags whatsapp
+// @Produce json
+// @Param page query int false "Página" default(1)
+// @Param limit query int false "Tamaño de página" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/outbound-messages/dead-letter [get]
+func GetOutboundDeadLetters(c *gin.Context) {
+	listOutboundMessages(c, models.OutboundStatusFailed)
+}
+
+// GetOutboundMessages lista la cola de salida, opcionalmente filtrada por estado
+// @Summary Cola de mensajes salientes
+// @Tags whatsapp
+// @Produce json
+// @Param status query string false "pending, sent o failed"
+// @Param page query int false "Página" default(1)
+// @Param limit query int false "Tamaño de página" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/outbound-messages [get]
+func GetOutboundMessages(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboundStatusPending, models.OutboundStatusSent, models.OutboundStatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado inválido"})
+		return
+	}
+	listOutboundMessages(c, status)
+}
+
+func listOutboundMessages(c *gin.Context, status string) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page inválido"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+		return
+	}
+
+	messages, total, err := outboundQueue.repo.List(orgID, status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo mensajes salientes",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// RetryOutboundMessage devuelve un mensaje fallido a la cola
+// @Summary Reintentar mensaje saliente
+// @Tags whatsapp
+// @Produce json
+// @Param id path int true "ID del mensaje"
+// @Success 200 {object} models.OutboundMessage
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/outbound-messages/{id}/retry [post]
+func RetryOutboundMessage(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	q := outboundQueue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	row, err := q.repo.GetByID(uint(id), orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Mensaje no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo mensaje",
+			"details": err.Error(),
+		})
+		return
+	}
+	if row.Status != models.OutboundStatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Solo se pueden reintentar mensajes fallidos"})
+		return
+	}
+
+	row.Status = models.OutboundStatusPending
+	row.Attempts = 0
+	row.OfflineAttempts = 0
+	row.NextAttemptAt = time.Now()
+	if !q.save(row, models.OutboundStatusFailed, "") {
+		c.JSON(http.StatusConflict, gin.H{"error": "El mensaje cambió mientras se reintentaba"})
+		return
+	}
+	if _, connected := q.hub.GetBotOrganization(row.BotKey); connected {
+		q.dispatchLocked(row)
+	}
+
+	c.JSON(http.StatusOK, row)
+}
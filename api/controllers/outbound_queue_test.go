@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+)
+
+const testBotKey = "bot-1:573001234567"
+
+func TestOutboundBackoff(t *testing.T) {
+	config := OutboundQueueConfig{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	assert.Equal(t, time.Second, outboundBackoff(config, 1))
+	assert.Equal(t, 2*time.Second, outboundBackoff(config, 2))
+	assert.Equal(t, 8*time.Second, outboundBackoff(config, 4))
+	assert.Equal(t, 10*time.Second, outboundBackoff(config, 5))
+	assert.Equal(t, 10*time.Second, outboundBackoff(config, 60))
+}
+
+func TestOutboundQueueFlushesOnRegister(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	repo := &mocks.MockOutboundMessageRepo{}
+	queue := NewOutboundQueue(hub, repo, DefaultOutboundQueueConfig())
+
+	// Sin bot conectado el mensaje queda pendiente en vez de perderse
+	row, err := queue.Enqueue(1, testBotKey, OutboundMessage{To: "573009999999", Message: "hola", SessionID: "bot-1"})
+	require.NoError(t, err)
+	stored := repo.Get(row.ID)
+	assert.Equal(t, models.OutboundStatusPending, stored.Status)
+	assert.Empty(t, stored.EnvelopeID)
+	assert.Zero(t, stored.OfflineAttempts)
+
+	// Al registrarse el bot se vacía la cola
+	conn := connectTestBot(t, server, credentials)
+	envelope := readEnvelope(t, conn)
+	assert.Equal(t, "hola", envelope.Message)
+
+	require.NoError(t, conn.WriteJSON(botFrame{Type: BotFrameAck, ID: envelope.ID}))
+	assert.Eventually(t, func() bool {
+		return repo.Get(row.ID).Status == models.OutboundStatusSent
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, repo.Get(row.ID).Attempts)
+}
+
+func TestOutboundQueueDeadLetter(t *testing.T) {
+	hubConfig := testHubConfig()
+	hubConfig.MaxDeliveryAttempts = 1
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, hubConfig)
+	repo := &mocks.MockOutboundMessageRepo{}
+	queueConfig := DefaultOutboundQueueConfig()
+	queueConfig.MaxAttempts = 2
+	queueConfig.BaseBackoff = 10 * time.Millisecond
+	queueConfig.DispatchInterval = 10 * time.Millisecond
+	queue := NewOutboundQueue(hub, repo, queueConfig)
+	queue.Start()
+	defer queue.Stop()
+
+	conn := connectTestBot(t, server, credentials)
+	row, err := queue.Enqueue(1, testBotKey, OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+
+	// El bot recibe el mensaje pero nunca confirma
+	first := readEnvelope(t, conn)
+	require.NoError(t, conn.WriteJSON(botFrame{Type: BotFrameNack, ID: first.ID, Error: "número inválido"}))
+	second := readEnvelope(t, conn)
+	assert.NotEqual(t, first.ID, second.ID)
+	require.NoError(t, conn.WriteJSON(botFrame{Type: BotFrameNack, ID: second.ID, Error: "número inválido"}))
+
+	assert.Eventually(t, func() bool {
+		return repo.Get(row.ID).Status == models.OutboundStatusFailed
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "número inválido", repo.Get(row.ID).LastError)
+
+	// Dead letter visible en la API y reintentable
+	previous := outboundQueue
+	SetOutboundQueue(queue)
+	defer SetOutboundQueue(previous)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", uint(1))
+		c.Next()
+	})
+	r.GET("/outbound-messages/dead-letter", GetOutboundDeadLetters)
+	r.POST("/outbound-messages/:id/retry", RetryOutboundMessage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/outbound-messages/dead-letter", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Messages []models.OutboundMessage `json:"messages"`
+		Total    int64                    `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, row.ID, resp.Messages[0].ID)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/outbound-messages/1/retry", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.OutboundStatusPending, repo.Get(row.ID).Status)
+	assert.Equal(t, "hola", readEnvelope(t, conn).Message)
+}
+
+func TestOutboundQueueKeepsRichPayload(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	repo := &mocks.MockOutboundMessageRepo{}
+	queue := NewOutboundQueue(hub, repo, DefaultOutboundQueueConfig())
+
+	row, err := queue.Enqueue(1, testBotKey, OutboundMessage{
+		To:          "573009999999",
+		SessionID:   "bot-1",
+		MessageType: models.MessageTypeLocation,
+		Location:    &models.MessageLocation{Latitude: 4.6, Longitude: -74.08, Name: "Bodega"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.MessageTypeLocation, repo.Get(row.ID).MessageType)
+
+	// El reenvío tras reconectar se arma desde la fila guardada
+	conn := connectTestBot(t, server, credentials)
+	envelope := readEnvelope(t, conn)
+	assert.Equal(t, models.MessageTypeLocation, envelope.MessageType)
+	require.NotNil(t, envelope.Location)
+	assert.Equal(t, "Bodega", envelope.Location.Name)
+	assert.Equal(t, -74.08, envelope.Location.Longitude)
+}
+
+func TestOutboundQueueDeadLettersWhenBotStaysOffline(t *testing.T) {
+	_, hub, _ := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	repo := &mocks.MockOutboundMessageRepo{}
+	queueConfig := DefaultOutboundQueueConfig()
+	queueConfig.OfflineTimeout = 20 * time.Millisecond
+	queueConfig.DispatchInterval = 5 * time.Millisecond
+	queue := NewOutboundQueue(hub, repo, queueConfig)
+
+	row, err := queue.Enqueue(1, testBotKey, OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+
+	// Si ninguna instancia tiene el bot, el mensaje pasa a dead letter en vez de esperar para siempre
+	queue.Start()
+	defer queue.Stop()
+	assert.Eventually(t, func() bool {
+		return repo.Get(row.ID).Status == models.OutboundStatusFailed
+	}, 2*time.Second, 5*time.Millisecond)
+	stored := repo.Get(row.ID)
+	assert.Zero(t, stored.Attempts, "sin bot no se cuenta como intento de entrega")
+	assert.Empty(t, stored.EnvelopeID)
+	assert.Contains(t, stored.LastError, testBotKey)
+}
+
+func TestOutboundQueueOnlyDispatchesLocalBots(t *testing.T) {
+	_, hub, _ := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	repo := &mocks.MockOutboundMessageRepo{}
+	queueConfig := DefaultOutboundQueueConfig()
+	queueConfig.DispatchInterval = 5 * time.Millisecond
+	queue := NewOutboundQueue(hub, repo, queueConfig)
+
+	// El bot está conectado a otra instancia: esta no reserva la fila ni cuenta intentos
+	row := &models.OutboundMessage{OrganizationID: 1, BotKey: "bot-2:573007654321", To: "573009999999", Message: "hola",
+		Status: models.OutboundStatusPending, NextAttemptAt: time.Now()}
+	require.NoError(t, repo.Create(row))
+	queue.Start()
+	time.Sleep(50 * time.Millisecond)
+	queue.Stop()
+
+	stored := repo.Get(row.ID)
+	assert.Equal(t, models.OutboundStatusPending, stored.Status)
+	assert.Empty(t, stored.EnvelopeID)
+	assert.Zero(t, stored.OfflineAttempts)
+	assert.Empty(t, stored.LastError)
+}
+
+func TestOutboundQueueResetsOnlyItsOwnInFlightMessages(t *testing.T) {
+	_, hub, _ := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	repo := &mocks.MockOutboundMessageRepo{}
+	inFlight := func(instanceID string) uint {
+		row := &models.OutboundMessage{BotKey: testBotKey, To: "573009999999", Message: "hola", Status: models.OutboundStatusPending}
+		require.NoError(t, repo.Create(row))
+		row.EnvelopeID = "env-" + instanceID
+		row.DispatchedBy = instanceID
+		saved, err := repo.UpdateDispatch(row, models.OutboundStatusPending, "")
+		require.NoError(t, err)
+		require.True(t, saved)
+		return row.ID
+	}
+	own := inFlight("api-1")
+	other := inFlight("api-2")
+
+	queueConfig := DefaultOutboundQueueConfig()
+	queueConfig.InstanceID = "api-1"
+	queueConfig.StaleInFlight = time.Hour
+	queue := NewOutboundQueue(hub, repo, queueConfig)
+	queue.resetInFlight(queueConfig.InstanceID)
+
+	assert.Empty(t, repo.Get(own).EnvelopeID)
+	assert.Equal(t, "env-api-2", repo.Get(other).EnvelopeID, "el envío en curso de otra instancia no se toca")
+
+	// Si la otra instancia lo abandona, cualquiera lo recupera
+	queue.config.StaleInFlight = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	queue.resetInFlight("")
+	assert.Empty(t, repo.Get(other).EnvelopeID)
+}
+
+func TestOutboundQueueDoesNotSendRowClaimedByAnotherInstance(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	repo := &mocks.MockOutboundMessageRepo{}
+	first := NewOutboundQueue(hub, repo, DefaultOutboundQueueConfig())
+	conn := connectTestBot(t, server, credentials)
+
+	row, err := first.Enqueue(1, testBotKey, OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+	envelope := readEnvelope(t, conn)
+	assert.Equal(t, envelope.ID, repo.Get(row.ID).EnvelopeID)
+
+	// Otra instancia con una copia vieja de la fila no la reenvía ni la pisa
+	stale := repo.Get(row.ID)
+	stale.EnvelopeID = ""
+	secondConfig := DefaultOutboundQueueConfig()
+	secondConfig.InstanceID = "api-2"
+	second := &OutboundQueue{hub: hub, repo: repo, config: secondConfig}
+	second.dispatchLocked(&stale)
+
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err, "el mensaje no debe enviarse dos veces")
+	assert.Equal(t, envelope.ID, repo.Get(row.ID).EnvelopeID)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	database "github.com/brando1998/docubot-api/databases"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/rasa"
+)
+
+var rasaClients = rasa.NewPool(rasa.Config{})
+
+// SetRasaClients inyecta el pool de clientes de Rasa
+func SetRasaClients(pool *rasa.Pool) {
+	rasaClients = pool
+}
+
+// rasaConversationID es el sender con el que se habla con Rasa: la sesión aísla el
+// contexto cuando el mismo cliente escribe a varios bots
+func rasaConversationID(sessionID, chatID string) string {
+	if !strings.Contains(chatID, "@") {
+		chatID += "@s.whatsapp.net"
+	}
+	return fmt.Sprintf("%s:%s", sessionID, chatID)
+}
+
+// rasaClientForBot devuelve el servidor Rasa del bot: su instancia personalizada si
+// está corriendo o el bot base
+func rasaClientForBot(botNumber string) rasa.Client {
+	cleanBotNumber := strings.Split(botNumber, "@")[0]
+
+	var instance models.BotInstance
+	err := database.GetDB().Where("whatsapp_number = ? AND status = ?", cleanBotNumber, "running").First(&instance).Error
+	if err == nil {
+		log.Printf("🔀 Enrutando a instancia personalizada: %s (puerto %d)", instance.Name, instance.Port)
+		return rasaClients.For(fmt.Sprintf("http://localhost:%d", instance.Port))
+	}
+	return rasaClients.Default()
+}
+
+// rasaClientForSession resuelve el servidor Rasa a partir del número conectado a la sesión
+func rasaClientForSession(ctx context.Context, sessionID string) rasa.Client {
+	status, err := baileysClient.GetStatus(ctx, sessionID)
+	if err != nil || status.Status.Number == "" {
+		return rasaClients.Default()
+	}
+	return rasaClientForBot(status.Status.Number)
+}
+
+// respondRasaError traduce los errores del cliente de Rasa a respuestas HTTP
+func respondRasaError(c *gin.Context, message string, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, rasa.ErrInvalidRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, rasa.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+// GetChatRasaState devuelve el estado de la conversación en Rasa
+// @Summary Estado del bot en un chat
+// @Description Devuelve los slots, el formulario activo y la última intención detectada por Rasa
+// @Tags chats
+// @Produce json
+// @Param chatId path string true "ID del chat"
+// @Param sessionId query string false "ID de la sesión (opcional, usa 'default' si no se especifica)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /api/v1/whatsapp/chats/{chatId}/rasa [get]
+func GetChatRasaState(c *gin.Context) {
+	sessionId := c.DefaultQuery("sessionId", "default")
+	if !requireSessionOwner(c, sessionId, false) {
+		return
+	}
+	conversationID := rasaConversationID(sessionId, c.Param("chatId"))
+
+	tracker, err := rasaClientForSession(c.Request.Context(), sessionId).GetTracker(c.Request.Context(), conversationID)
+	if err != nil {
+		respondRasaError(c, "Error obteniendo estado de Rasa", err)
+		return
+	}
+
+	response := gin.H{
+		"conversation_id": conversationID,
+		"slots":           tracker.Slots,
+		"active_form":     tracker.ActiveLoop.Name,
+		"latest_action":   tracker.LatestActionName,
+		"paused":          tracker.Paused,
+	}
+	if tracker.LatestMessage != nil {
+		response["latest_intent"] = tracker.LatestMessage.Intent
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetChatRasaConversation reinicia la conversación de Rasa de un chat atascado
+// @Summary Reiniciar conversación del bot
+// @Description Envía un evento restart a Rasa: borra slots y formulario activo del chat
+// @Tags chats
+// @Produce json
+// @Param chatId path string true "ID del chat"
+// @Param sessionId query string false "ID de la sesión (opcional, usa 'default' si no se especifica)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /api/v1/whatsapp/chats/{chatId}/rasa/reset [post]
+func ResetChatRasaConversation(c *gin.Context) {
+	sessionId := c.DefaultQuery("sessionId", "default")
+	if !requireSessionOwner(c, sessionId, false) {
+		return
+	}
+	conversationID := rasaConversationID(sessionId, c.Param("chatId"))
+
+	if _, err := rasaClientForSession(c.Request.Context(), sessionId).Restart(c.Request.Context(), conversationID); err != nil {
+		respondRasaError(c, "Error reiniciando conversación en Rasa", err)
+		return
+	}
+
+	log.Printf("🔄 Conversación de Rasa reiniciada: %s", conversationID)
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"message":         "Conversación reiniciada",
+		"conversation_id": conversationID,
+	})
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/rasa"
+)
+
+// Modos de envío de los botones de Rasa
+const (
+	RasaButtonsList   = "list"    // Lista numerada en el texto; el cliente responde con el número
+	RasaButtonsNative = "buttons" // Botones de WhatsApp (no todos los clientes los muestran)
+)
+
+// buttonChoicesTTL es cuánto tiempo se acepta una respuesta por número a una lista de opciones
+const buttonChoicesTTL = 30 * time.Minute
+
+var rasaButtonsMode = RasaButtonsList
+
+// SetRasaButtonsMode configura cómo se envían los botones de Rasa ("list" o "buttons")
+func SetRasaButtonsMode(mode string) {
+	switch mode {
+	case RasaButtonsList, RasaButtonsNative:
+		rasaButtonsMode = mode
+	default:
+		log.Printf("⚠️ Modo de botones desconocido %q, se usa %q", mode, RasaButtonsList)
+		rasaButtonsMode = RasaButtonsList
+	}
+}
+
+// rasaAttachment es el formato aceptado para attachment y custom.document:
+// una URL o un objeto con la URL (directa o en payload.url)
+type rasaAttachment struct {
+	URL      string `json:"url"`
+	FileName string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption"`
+	Payload  *struct {
+		URL string `json:"url"`
+	} `json:"payload"`
+}
+
+// rasaCustomPayload son las claves de custom que se traducen a WhatsApp
+type rasaCustomPayload struct {
+	Text     string                  `json:"text"`
+	Image    string                  `json:"image"`
+	Document json.RawMessage         `json:"document"`
+	Location *models.MessageLocation `json:"location"`
+}
+
+// rasaToOutbound traduce una respuesta de Rasa a los mensajes de WhatsApp que la representan,
+// en orden: texto (con botones), imagen, adjunto y payload custom
+func rasaToOutbound(response rasa.Response, to, sessionID string) []OutboundMessage {
+	var out []OutboundMessage
+	base := OutboundMessage{To: to, SessionID: sessionID}
+
+	text := response.Text
+	switch {
+	case len(response.Buttons) > 0:
+		out = append(out, buttonsMessage(base, text, response.Buttons))
+	case text != "" && response.Image != "":
+		// El texto acompaña a la imagen como pie de foto
+	case text != "":
+		message := base
+		message.MessageType = models.MessageTypeText
+		message.Message = text
+		out = append(out, message)
+	}
+
+	if response.Image != "" {
+		caption := ""
+		if len(response.Buttons) == 0 {
+			caption = text
+		}
+		out = append(out, mediaMessage(base, &models.MessageMedia{URL: response.Image, Caption: caption}, models.MessageTypeImage))
+	}
+
+	if len(response.Attachment) > 0 {
+		if media, err := parseRasaAttachment(response.Attachment); err != nil {
+			log.Printf("⚠️ Adjunto de Rasa ignorado: %v", err)
+		} else {
+			out = append(out, mediaMessage(base, media, ""))
+		}
+	}
+
+	if len(response.Custom) > 0 {
+		out = append(out, customMessages(base, response.Custom)...)
+	}
+	return out
+}
+
+// buttonsMessage arma las opciones según rasaButtonsMode. En modo lista el mensaje viaja como
+// texto, pero conserva los botones para el historial y para resolver la respuesta del cliente.
+func buttonsMessage(base OutboundMessage, text string, buttons []rasa.Button) OutboundMessage {
+	message := base
+	message.Buttons = make([]models.MessageButton, 0, len(buttons))
+	for _, button := range buttons {
+		message.Buttons = append(message.Buttons, models.MessageButton{Title: button.Title, Payload: button.Payload})
+	}
+
+	if rasaButtonsMode == RasaButtonsNative {
+		message.MessageType = models.MessageTypeButtons
+		message.Message = text
+		return message
+	}
+
+	var b strings.Builder
+	if text != "" {
+		b.WriteString(text)
+		b.WriteString("\n")
+	}
+	for i, button := range message.Buttons {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, button.Title)
+	}
+	message.MessageType = models.MessageTypeText
+	message.Message = strings.TrimSpace(b.String())
+	return message
+}
+
+// mediaMessage arma el envío de una imagen o documento. Sin messageType se decide por el
+// tipo MIME: las imágenes se muestran en el chat y el resto va como documento.
+func mediaMessage(base OutboundMessage, media *models.MessageMedia, messageType string) OutboundMessage {
+	if media.MimeType == "" {
+		media.MimeType = mime.TypeByExtension(path.Ext(urlPath(media.URL)))
+	}
+	if messageType == "" {
+		messageType = models.MessageTypeDocument
+		if strings.HasPrefix(media.MimeType, "image/") {
+			messageType = models.MessageTypeImage
+		}
+	}
+	if messageType == models.MessageTypeDocument && media.FileName == "" {
+		media.FileName = path.Base(urlPath(media.URL))
+	}
+
+	message := base
+	message.MessageType = messageType
+	message.Media = media
+	message.Message = media.Caption
+	return message
+}
+
+func urlPath(raw string) string {
+	if parsed, err := url.Parse(raw); err == nil {
+		return parsed.Path
+	}
+	return raw
+}
+
+func parseRasaAttachment(raw json.RawMessage) (*models.MessageMedia, error) {
+	var link string
+	if err := json.Unmarshal(raw, &link); err == nil {
+		if link == "" {
+			return nil, fmt.Errorf("adjunto sin URL")
+		}
+		return &models.MessageMedia{URL: link}, nil
+	}
+
+	var attachment rasaAttachment
+	if err := json.Unmarshal(raw, &attachment); err != nil {
+		return nil, fmt.Errorf("formato de adjunto no soportado: %w", err)
+	}
+	if attachment.URL == "" && attachment.Payload != nil {
+		attachment.URL = attachment.Payload.URL
+	}
+	if attachment.URL == "" {
+		return nil, fmt.Errorf("adjunto sin URL")
+	}
+	return &models.MessageMedia{
+		URL:      attachment.URL,
+		FileName: attachment.FileName,
+		MimeType: attachment.MimeType,
+		Caption:  attachment.Caption,
+	}, nil
+}
+
+// customMessages traduce las claves conocidas de custom (text, image, document, location)
+func customMessages(base OutboundMessage, custom map[string]interface{}) []OutboundMessage {
+	data, err := json.Marshal(custom)
+	if err != nil {
+		log.Printf("⚠️ Payload custom de Rasa ignorado: %v", err)
+		return nil
+	}
+	var payload rasaCustomPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Printf("⚠️ Payload custom de Rasa ignorado: %v", err)
+		return nil
+	}
+
+	var out []OutboundMessage
+	if payload.Text != "" {
+		message := base
+		message.MessageType = models.MessageTypeText
+		message.Message = payload.Text
+		out = append(out, message)
+	}
+	if payload.Image != "" {
+		out = append(out, mediaMessage(base, &models.MessageMedia{URL: payload.Image}, models.MessageTypeImage))
+	}
+	if len(payload.Document) > 0 {
+		if media, err := parseRasaAttachment(payload.Document); err != nil {
+			log.Printf("⚠️ Documento custom de Rasa ignorado: %v", err)
+		} else {
+			out = append(out, mediaMessage(base, media, models.MessageTypeDocument))
+		}
+	}
+	if payload.Location != nil {
+		message := base
+		message.MessageType = models.MessageTypeLocation
+		message.Location = payload.Location
+		out = append(out, message)
+	}
+
+	if len(out) == 0 {
+		log.Printf("⚠️ Payload custom de Rasa sin claves soportadas: %s", data)
+	}
+	return out
+}
+
+// historyType es el tipo con el que se guarda en el historial un mensaje enviado
+func historyType(message OutboundMessage) string {
+	if len(message.Buttons) > 0 {
+		return models.MessageTypeButtons
+	}
+	if message.MessageType == "" {
+		return models.MessageTypeText
+	}
+	return message.MessageType
+}
+
+// historyText es el texto con el que se muestra en el historial un mensaje sin texto
+func historyText(message OutboundMessage) string {
+	if message.Message != "" {
+		return message.Message
+	}
+	switch {
+	case message.Location != nil && message.Location.Name != "":
+		return "[Ubicación: " + message.Location.Name + "]"
+	case message.Location != nil:
+		return "[Ubicación]"
+	case message.Media != nil && message.MessageType == models.MessageTypeDocument:
+		return "[Documento: " + message.Media.FileName + "]"
+	case message.Media != nil:
+		return "[Imagen]"
+	}
+	return ""
+}
+
+// buttonChoices guarda las últimas opciones enviadas a cada conversación de Rasa para
+// traducir la respuesta del cliente ("2" o el título) al payload del botón
+type buttonChoiceSet struct {
+	buttons []models.MessageButton
+	expires time.Time
+}
+
+var (
+	buttonChoicesMu sync.Mutex
+	buttonChoices   = make(map[string]buttonChoiceSet)
+)
+
+func rememberButtonChoices(conversationID string, buttons []models.MessageButton) {
+	buttonChoicesMu.Lock()
+	defer buttonChoicesMu.Unlock()
+
+	now := time.Now()
+	for id, set := range buttonChoices {
+		if now.After(set.expires) {
+			delete(buttonChoices, id)
+		}
+	}
+	buttonChoices[conversationID] = buttonChoiceSet{buttons: buttons, expires: now.Add(buttonChoicesTTL)}
+}
+
+// resolveButtonChoice devuelve el payload de la opción elegida o el texto sin cambios.
+// Las opciones se descartan con cualquier respuesta del cliente.
+func resolveButtonChoice(conversationID, text string) string {
+	buttonChoicesMu.Lock()
+	set, ok := buttonChoices[conversationID]
+	delete(buttonChoices, conversationID)
+	buttonChoicesMu.Unlock()
+
+	if !ok || time.Now().After(set.expires) {
+		return text
+	}
+
+	answer := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "."))
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(set.buttons) {
+		return set.buttons[n-1].Payload
+	}
+	for _, button := range set.buttons {
+		if strings.EqualFold(answer, button.Title) || answer == button.Payload {
+			return button.Payload
+		}
+	}
+	return text
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/rasa"
+)
+
+func TestRasaToOutbound(t *testing.T) {
+	previous := rasaButtonsMode
+	t.Cleanup(func() { rasaButtonsMode = previous })
+
+	buttons := rasa.Response{
+		Text: "¿Qué documento necesitas?",
+		Buttons: []rasa.Button{
+			{Title: "Manifiesto", Payload: "/solicitar_manifiesto"},
+			{Title: "Remesa", Payload: "/solicitar_remesa"},
+		},
+	}
+
+	SetRasaButtonsMode(RasaButtonsList)
+	out := rasaToOutbound(buttons, "573001234567", "bot-1")
+	require.Len(t, out, 1)
+	assert.Equal(t, models.MessageTypeText, out[0].MessageType)
+	assert.Equal(t, "¿Qué documento necesitas?\n\n1. Manifiesto\n2. Remesa", out[0].Message)
+	assert.Equal(t, models.MessageTypeButtons, historyType(out[0]))
+
+	SetRasaButtonsMode(RasaButtonsNative)
+	out = rasaToOutbound(buttons, "573001234567", "bot-1")
+	require.Len(t, out, 1)
+	assert.Equal(t, models.MessageTypeButtons, out[0].MessageType)
+	assert.Equal(t, "¿Qué documento necesitas?", out[0].Message)
+	assert.Len(t, out[0].Buttons, 2)
+
+	// El texto acompaña la imagen como pie de foto
+	out = rasaToOutbound(rasa.Response{Text: "Tu ruta", Image: "https://cdn.example.com/ruta"}, "573001234567", "bot-1")
+	require.Len(t, out, 1)
+	assert.Equal(t, models.MessageTypeImage, out[0].MessageType)
+	assert.Equal(t, "Tu ruta", out[0].Media.Caption)
+
+	out = rasaToOutbound(rasa.Response{
+		Attachment: json.RawMessage(`{"type":"file","payload":{"url":"https://cdn.example.com/docs/manifiesto.pdf?v=2"}}`),
+	}, "573001234567", "bot-1")
+	require.Len(t, out, 1)
+	assert.Equal(t, models.MessageTypeDocument, out[0].MessageType)
+	assert.Equal(t, "manifiesto.pdf", out[0].Media.FileName)
+	assert.Equal(t, "application/pdf", out[0].Media.MimeType)
+	assert.Equal(t, "[Documento: manifiesto.pdf]", historyText(out[0]))
+
+	out = rasaToOutbound(rasa.Response{Custom: map[string]interface{}{
+		"text":     "Recoge en:",
+		"location": map[string]interface{}{"latitude": 4.6, "longitude": -74.08, "name": "Bodega"},
+		"unknown":  true,
+	}}, "573001234567", "bot-1")
+	require.Len(t, out, 2)
+	assert.Equal(t, models.MessageTypeText, out[0].MessageType)
+	assert.Equal(t, models.MessageTypeLocation, out[1].MessageType)
+	assert.Equal(t, 4.6, out[1].Location.Latitude)
+	assert.Equal(t, "[Ubicación: Bodega]", historyText(out[1]))
+
+	assert.Empty(t, rasaToOutbound(rasa.Response{Custom: map[string]interface{}{"carousel": []int{1}}}, "573001234567", "bot-1"))
+}
+
+func TestResolveButtonChoice(t *testing.T) {
+	conversationID := "bot-1:573001234567@s.whatsapp.net"
+	buttons := []models.MessageButton{
+		{Title: "Manifiesto", Payload: "/solicitar_manifiesto"},
+		{Title: "Remesa", Payload: "/solicitar_remesa"},
+	}
+
+	rememberButtonChoices(conversationID, buttons)
+	assert.Equal(t, "/solicitar_remesa", resolveButtonChoice(conversationID, " 2 "))
+	// Las opciones solo aplican a la respuesta inmediata
+	assert.Equal(t, "2", resolveButtonChoice(conversationID, "2"))
+
+	rememberButtonChoices(conversationID, buttons)
+	assert.Equal(t, "/solicitar_manifiesto", resolveButtonChoice(conversationID, "manifiesto"))
+
+	rememberButtonChoices(conversationID, buttons)
+	assert.Equal(t, "3", resolveButtonChoice(conversationID, "3"))
+	assert.Equal(t, "hola", resolveButtonChoice("otra", "hola"))
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/baileys"
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/rasa"
+	"github.com/brando1998/docubot-api/services"
+)
+
+func setupRasaRouter(t *testing.T) *rasa.FakeServer {
+	fake := rasa.NewFakeServer()
+	t.Cleanup(fake.Close)
+
+	previousPool, previousBaileys, previousCredentials := rasaClients, baileysClient, botCredentialService
+	t.Cleanup(func() {
+		rasaClients, baileysClient, botCredentialService = previousPool, previousBaileys, previousCredentials
+	})
+	SetRasaClients(rasa.NewPool(rasa.Config{BaseURL: fake.URL}))
+	SetBaileysClient(baileys.NewFake()) // Sin sesión conocida se usa el bot base
+	credentials, err := services.NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, "test-signing-key-0123456789abcdef")
+	require.NoError(t, err)
+	SetBotCredentialService(credentials)
+	return fake
+}
+
+// rasaRouter expone los endpoints de Rasa autenticados como la organización indicada
+func rasaRouter(orgID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", orgID)
+		c.Next()
+	})
+	r.GET("/chats/:chatId/rasa", GetChatRasaState)
+	r.POST("/chats/:chatId/rasa/reset", ResetChatRasaConversation)
+	return r
+}
+
+func TestChatRasaStateAndReset(t *testing.T) {
+	fake := setupRasaRouter(t)
+	fake.SetSlots("bot-1:573001234567@s.whatsapp.net", "manifiesto_form", map[string]interface{}{
+		"origen": "Bogotá",
+		"peso":   1200.0,
+	})
+	claimSession(t, 1, "bot-1")
+	r := rasaRouter(1)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chats/573001234567/rasa?sessionId=bot-1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var state struct {
+		ConversationID string                 `json:"conversation_id"`
+		Slots          map[string]interface{} `json:"slots"`
+		ActiveForm     string                 `json:"active_form"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "bot-1:573001234567@s.whatsapp.net", state.ConversationID)
+	assert.Equal(t, "Bogotá", state.Slots["origen"])
+	assert.Equal(t, "manifiesto_form", state.ActiveForm)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chats/573001234567@s.whatsapp.net/rasa/reset?sessionId=bot-1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	tracker := fake.Tracker("bot-1:573001234567@s.whatsapp.net")
+	assert.Empty(t, tracker.Slots)
+	assert.Empty(t, tracker.ActiveLoop.Name)
+}
+
+func TestChatRasaStateUnavailable(t *testing.T) {
+	fake := setupRasaRouter(t)
+	fake.Close()
+	claimSession(t, 1, "default")
+	r := rasaRouter(1)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chats/573001234567/rasa", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/rndc"
+	"github.com/brando1998/docubot-api/services"
+)
+
+var registryService *services.RegistryService
+
+// SetRegistryService inyecta el servicio del registro de vehículos y conductores
+func SetRegistryService(service *services.RegistryService) {
+	registryService = service
+}
+
+// VehicleRequest son los datos editables de un vehículo. Las fechas aceptan YYYY-MM-DD o RFC3339.
+type VehicleRequest struct {
+	Placa                  string `json:"placa" binding:"required"`
+	Configuracion          string `json:"configuracion"`
+	OwnerTipoID            string `json:"owner_tipo_id"`
+	OwnerNumeroID          string `json:"owner_numero_id"`
+	OwnerName              string `json:"owner_name"`
+	TarjetaPropiedad       string `json:"tarjeta_propiedad"`
+	SOATExpiresAt          string `json:"soat_expires_at"`
+	TecnomecanicaExpiresAt string `json:"tecnomecanica_expires_at"`
+}
+
+// DriverRequest son los datos editables de un conductor. Las fechas aceptan YYYY-MM-DD o RFC3339.
+type DriverRequest struct {
+	Cedula           string `json:"cedula" binding:"required"`
+	TipoID           string `json:"tipo_id"`
+	Name             string `json:"name" binding:"required"`
+	LicenseCategory  string `json:"license_category"`
+	LicenseExpiresAt string `json:"license_expires_at"`
+	Phone            string `json:"phone"`
+	VehicleID        *uint  `json:"vehicle_id"`
+}
+
+// PrefillManifestRequest identifica el chat de Rasa que pide sus datos registrados
+type PrefillManifestRequest struct {
+	SenderID string `form:"sender_id" binding:"required"`
+}
+
+// ListVehicles lista los vehículos de la organización
+// @Summary Listar vehículos
+// @Tags registry
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/vehicles [get]
+func ListVehicles(c *gin.Context) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+	vehicles, err := registryService.Vehicles().List(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo vehículos",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"vehicles": vehicles, "total": len(vehicles)})
+}
+
+// GetVehicle obtiene un vehículo de la organización
+// @Summary Obtener vehículo
+// @Tags registry
+// @Produce json
+// @Param id path int true "ID del vehículo"
+// @Success 200 {object} models.Vehicle
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/vehicles/{id} [get]
+func GetVehicle(c *gin.Context) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	vehicle, err := registryService.Vehicles().GetByID(id, orgID)
+	if err != nil {
+		respondRegistryError(c, err, "Vehículo no encontrado")
+		return
+	}
+	c.JSON(http.StatusOK, vehicle)
+}
+
+// CreateVehicle registra un vehículo en la organización
+// @Summary Registrar vehículo
+// @Tags registry
+// @Accept json
+// @Produce json
+// @Param request body VehicleRequest true "Datos del vehículo"
+// @Success 201 {object} models.Vehicle
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/vehicles [post]
+func CreateVehicle(c *gin.Context) {
+	saveVehicle(c, 0)
+}
+
+// UpdateVehicle reemplaza los datos de un vehículo de la organización
+// @Summary Actualizar vehículo
+// @Tags registry
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del vehículo"
+// @Param request body VehicleRequest true "Datos del vehículo"
+// @Success 200 {object} models.Vehicle
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/vehicles/{id} [put]
+func UpdateVehicle(c *gin.Context) {
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	saveVehicle(c, id)
+}
+
+// DeleteVehicle elimina un vehículo; los conductores que lo tenían quedan sin vehículo
+// @Summary Eliminar vehículo
+// @Tags registry
+// @Param id path int true "ID del vehículo"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/vehicles/{id} [delete]
+func DeleteVehicle(c *gin.Context) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	if err := registryService.Vehicles().Delete(id, orgID); err != nil {
+		respondRegistryError(c, err, "Vehículo no encontrado")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func saveVehicle(c *gin.Context, id uint) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request VehicleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var errs rndc.ValidationErrors
+	vehicle := models.Vehicle{
+		ID:                     id,
+		OrganizationID:         orgID,
+		Placa:                  request.Placa,
+		Configuracion:          request.Configuracion,
+		OwnerTipoID:            request.OwnerTipoID,
+		OwnerNumeroID:          request.OwnerNumeroID,
+		OwnerName:              request.OwnerName,
+		TarjetaPropiedad:       request.TarjetaPropiedad,
+		SOATExpiresAt:          registryDate(&errs, "soat_expires_at", request.SOATExpiresAt),
+		TecnomecanicaExpiresAt: registryDate(&errs, "tecnomecanica_expires_at", request.TecnomecanicaExpiresAt),
+	}
+	if len(errs) > 0 {
+		respondRegistryError(c, errs, "")
+		return
+	}
+	if id != 0 {
+		existing, err := registryService.Vehicles().GetByID(id, orgID)
+		if err != nil {
+			respondRegistryError(c, err, "Vehículo no encontrado")
+			return
+		}
+		vehicle.CreatedAt = existing.CreatedAt
+	}
+
+	if err := registryService.SaveVehicle(&vehicle); err != nil {
+		respondRegistryError(c, err, "Vehículo no encontrado")
+		return
+	}
+
+	status := http.StatusOK
+	if id == 0 {
+		status = http.StatusCreated
+	}
+	c.JSON(status, vehicle)
+}
+
+// ListDrivers lista los conductores de la organización
+// @Summary Listar conductores
+// @Tags registry
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/drivers [get]
+func ListDrivers(c *gin.Context) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+	drivers, err := registryService.Drivers().List(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo conductores",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"drivers": drivers, "total": len(drivers)})
+}
+
+// GetDriver obtiene un conductor de la organización
+// @Summary Obtener conductor
+// @Tags registry
+// @Produce json
+// @Param id path int true "ID del conductor"
+// @Success 200 {object} models.Driver
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/drivers/{id} [get]
+func GetDriver(c *gin.Context) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	driver, err := registryService.Drivers().GetByID(id, orgID)
+	if err != nil {
+		respondRegistryError(c, err, "Conductor no encontrado")
+		return
+	}
+	c.JSON(http.StatusOK, driver)
+}
+
+// CreateDriver registra un conductor y lo enlaza al cliente de WhatsApp con su teléfono
+// @Summary Registrar conductor
+// @Tags registry
+// @Accept json
+// @Produce json
+// @Param request body DriverRequest true "Datos del conductor"
+// @Success 201 {object} models.Driver
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/drivers [post]
+func CreateDriver(c *gin.Context) {
+	saveDriver(c, 0)
+}
+
+// UpdateDriver reemplaza los datos de un conductor de la organización
+// @Summary Actualizar conductor
+// @Tags registry
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del conductor"
+// @Param request body DriverRequest true "Datos del conductor"
+// @Success 200 {object} models.Driver
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/drivers/{id} [put]
+func UpdateDriver(c *gin.Context) {
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	saveDriver(c, id)
+}
+
+// DeleteDriver elimina un conductor de la organización
+// @Summary Eliminar conductor
+// @Tags registry
+// @Param id path int true "ID del conductor"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/drivers/{id} [delete]
+func DeleteDriver(c *gin.Context) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	if err := registryService.Drivers().Delete(id, orgID); err != nil {
+		respondRegistryError(c, err, "Conductor no encontrado")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func saveDriver(c *gin.Context, id uint) {
+	orgID, ok := registryOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request DriverRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var errs rndc.ValidationErrors
+	driver := models.Driver{
+		ID:               id,
+		OrganizationID:   orgID,
+		Cedula:           request.Cedula,
+		TipoID:           request.TipoID,
+		Name:             request.Name,
+		LicenseCategory:  request.LicenseCategory,
+		LicenseExpiresAt: registryDate(&errs, "license_expires_at", request.LicenseExpiresAt),
+		Phone:            request.Phone,
+		VehicleID:        request.VehicleID,
+	}
+	if len(errs) > 0 {
+		respondRegistryError(c, errs, "")
+		return
+	}
+	if id != 0 {
+		existing, err := registryService.Drivers().GetByID(id, orgID)
+		if err != nil {
+			respondRegistryError(c, err, "Conductor no encontrado")
+			return
+		}
+		driver.CreatedAt = existing.CreatedAt
+	}
+
+	if err := registryService.SaveDriver(&driver); err != nil {
+		respondRegistryError(c, err, "Conductor no encontrado")
+		return
+	}
+
+	status := http.StatusOK
+	if id == 0 {
+		status = http.StatusCreated
+	}
+	c.JSON(status, driver)
+}
+
+// PrefillManifest devuelve los slots de manifiesto_form que ya se conocen del chat
+// @Summary Datos registrados del chat
+// @Description Llamado por Rasa al iniciar manifiesto_form: si el chat es de un conductor registrado devuelve su licencia y la placa de su vehículo para no preguntarlas.
+// @Tags documents
+// @Produce json
+// @Param X-Internal-Token header string true "Token interno"
+// @Param sender_id query string true "Sender de Rasa (sessionId:chatId)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /internal/manifests/prefill [get]
+func PrefillManifest(c *gin.Context, hub *WebSocketHub) {
+	if registryService == nil {
+		c.JSON(http.StatusOK, gin.H{"slots": gin.H{}})
+		return
+	}
+
+	var request PrefillManifestRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	target, ok := resolveManifestChat(c, hub, request.SenderID)
+	if !ok {
+		return
+	}
+
+	prefill, err := registryService.Prefill(target.OrganizationID, target.ClientID, target.ChatID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error consultando el registro",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, prefill)
+}
+
+func registryOrganization(c *gin.Context) (uint, bool) {
+	if registryService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Registro de vehículos no configurado"})
+		return 0, false
+	}
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return 0, false
+	}
+	return orgIDInterface.(uint), true
+}
+
+func registryID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// registryDate interpreta una fecha opcional del registro (vacía = sin fecha)
+func registryDate(errs *rndc.ValidationErrors, field, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	date, err := rndc.ParseFecha(value)
+	if err != nil {
+		*errs = append(*errs, rndc.FieldError{Field: field, Message: "fecha inválida, use YYYY-MM-DD"})
+		return nil
+	}
+	return &date
+}
+
+// respondRegistryError traduce los errores del registro: 400 con el detalle por campo,
+// 404 si no existe y 409 si la placa o la cédula ya están registradas
+func respondRegistryError(c *gin.Context, err error, notFound string) {
+	var fields rndc.ValidationErrors
+	switch {
+	case errors.As(err, &fields):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+			"fields":  fields,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, services.ErrRegistryConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Registro duplicado",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error guardando el registro",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+var roleService *services.RoleService
+
+// SetRoleService inyecta el servicio de roles y permisos
+func SetRoleService(service *services.RoleService) {
+	roleService = service
+}
+
+// RoleRequest son los datos de un rol personalizado; el nombre no se puede cambiar
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// ListRoles lista los roles asignables (predefinidos y personalizados) y el catálogo de permisos
+// @Summary Listar roles
+// @Tags roles
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/roles [get]
+func ListRoles(c *gin.Context) {
+	actor, ok := roleActor(c)
+	if !ok {
+		return
+	}
+	roles, err := roleService.List(actor.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo roles",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles, "permissions": models.AllPermissions})
+}
+
+// CreateRole crea un rol personalizado en la organización
+// @Summary Crear rol
+// @Description Solo se pueden otorgar permisos que tiene el usuario autenticado.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param request body RoleRequest true "Nombre, descripción y permisos"
+// @Success 201 {object} models.Role
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/roles [post]
+func CreateRole(c *gin.Context) {
+	actor, ok := roleActor(c)
+	if !ok {
+		return
+	}
+	var request RoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	role, err := roleService.Create(actor, services.RoleInput{
+		Name:        request.Name,
+		Description: request.Description,
+		Permissions: request.Permissions,
+	})
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole cambia la descripción y los permisos de un rol personalizado
+// @Summary Actualizar rol
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del rol"
+// @Param request body RoleRequest true "Descripción y permisos"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/roles/{id} [put]
+func UpdateRole(c *gin.Context) {
+	actor, ok := roleActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	var request RoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	role, err := roleService.Update(actor, id, services.RoleInput{
+		Description: request.Description,
+		Permissions: request.Permissions,
+	})
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole elimina un rol personalizado sin usuarios asignados
+// @Summary Eliminar rol
+// @Tags roles
+// @Param id path int true "ID del rol"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/roles/{id} [delete]
+func DeleteRole(c *gin.Context) {
+	actor, ok := roleActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	if err := roleService.Delete(actor, id); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func roleActor(c *gin.Context) (models.SystemUser, bool) {
+	if roleService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Roles no configurados"})
+		return models.SystemUser{}, false
+	}
+	value, exists := c.Get("current_user")
+	actor, ok := value.(models.SystemUser)
+	if !exists || !ok || actor.OrganizationID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return models.SystemUser{}, false
+	}
+	return actor, true
+}
+
+// respondRoleError traduce los errores de los roles personalizados
+func respondRoleError(c *gin.Context, err error) {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+			"fields":  fields,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rol no encontrado"})
+	case errors.Is(err, services.ErrPermissionEscalation):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Permiso insuficiente",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrRoleConflict),
+		errors.Is(err, services.ErrRoleInUse),
+		errors.Is(err, services.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No se puede aplicar el cambio",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error guardando el rol",
+			"details": err.Error(),
+		})
+	}
+}
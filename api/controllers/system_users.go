@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+var systemUserService *services.SystemUserService
+
+// SetSystemUserService inyecta el servicio de usuarios del dashboard
+func SetSystemUserService(service *services.SystemUserService) {
+	systemUserService = service
+}
+
+// SystemUserRequest son los datos editables de un usuario del dashboard
+type SystemUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Role     string `json:"role"` // Rol predefinido o personalizado (por defecto agent)
+}
+
+// CreateSystemUserRequest agrega la contraseña inicial
+type CreateSystemUserRequest struct {
+	SystemUserRequest
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetSystemUserPasswordRequest asigna una contraseña; vacía genera una temporal
+type ResetSystemUserPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// ListSystemUsers lista los usuarios del dashboard de la organización
+// @Summary Listar usuarios del sistema
+// @Tags system-users
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/system-users [get]
+func ListSystemUsers(c *gin.Context) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	users, err := systemUserService.List(actor.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error obteniendo usuarios",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users, "total": len(users)})
+}
+
+// GetSystemUser obtiene un usuario de la organización
+// @Summary Obtener usuario del sistema
+// @Tags system-users
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} models.SystemUser
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/system-users/{id} [get]
+func GetSystemUser(c *gin.Context) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	user, err := systemUserService.Get(id, actor.OrganizationID)
+	if err != nil {
+		respondSystemUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// CreateSystemUser crea un usuario activo en la organización
+// @Summary Crear usuario del sistema
+// @Tags system-users
+// @Accept json
+// @Produce json
+// @Param request body CreateSystemUserRequest true "Datos del usuario"
+// @Success 201 {object} models.SystemUser
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/system-users [post]
+func CreateSystemUser(c *gin.Context) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	var request CreateSystemUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	user, err := systemUserService.Create(actor, request.input(), request.Password)
+	if err != nil {
+		respondSystemUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateSystemUser cambia el username, el email y el rol de un usuario
+// @Summary Actualizar usuario del sistema
+// @Tags system-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Param request body SystemUserRequest true "Datos del usuario"
+// @Success 200 {object} models.SystemUser
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/system-users/{id} [put]
+func UpdateSystemUser(c *gin.Context) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	var request SystemUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+	user, err := systemUserService.Update(actor, id, request.input())
+	if err != nil {
+		respondSystemUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// ActivateSystemUser reactiva un usuario
+// @Summary Activar usuario del sistema
+// @Tags system-users
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} models.SystemUser
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/system-users/{id}/activate [post]
+func ActivateSystemUser(c *gin.Context) {
+	setSystemUserActive(c, true)
+}
+
+// DeactivateSystemUser desactiva un usuario; no puede iniciar sesión ni usar sus tokens
+// @Summary Desactivar usuario del sistema
+// @Tags system-users
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} models.SystemUser
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/system-users/{id}/deactivate [post]
+func DeactivateSystemUser(c *gin.Context) {
+	setSystemUserActive(c, false)
+}
+
+// ResetSystemUserPassword asigna una nueva contraseña a un usuario
+// @Summary Restablecer contraseña
+// @Description Si no se envía password se genera una temporal. La respuesta incluye la contraseña asignada para compartirla con el usuario.
+// @Tags system-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Param request body ResetSystemUserPasswordRequest false "Nueva contraseña"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/system-users/{id}/reset-password [post]
+func ResetSystemUserPassword(c *gin.Context) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	var request ResetSystemUserPasswordRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Datos inválidos",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	password, err := systemUserService.ResetPassword(actor, id, request.Password)
+	if err != nil {
+		respondSystemUserError(c, err)
+		return
+	}
+	response := gin.H{"message": "Contraseña actualizada"}
+	if request.Password == "" {
+		response["temporary_password"] = password
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteSystemUser elimina un usuario de la organización
+// @Summary Eliminar usuario del sistema
+// @Tags system-users
+// @Param id path int true "ID del usuario"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/system-users/{id} [delete]
+func DeleteSystemUser(c *gin.Context) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	if err := systemUserService.Delete(actor, id); err != nil {
+		respondSystemUserError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func setSystemUserActive(c *gin.Context, active bool) {
+	actor, ok := systemUserActor(c)
+	if !ok {
+		return
+	}
+	id, ok := registryID(c)
+	if !ok {
+		return
+	}
+	user, err := systemUserService.SetActive(actor, id, active)
+	if err != nil {
+		respondSystemUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (r SystemUserRequest) input() services.SystemUserInput {
+	return services.SystemUserInput{Username: r.Username, Email: r.Email, Role: r.Role}
+}
+
+// systemUserActor devuelve el administrador autenticado; su organización limita todas
+// las operaciones
+func systemUserActor(c *gin.Context) (models.SystemUser, bool) {
+	if systemUserService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Gestión de usuarios no configurada"})
+		return models.SystemUser{}, false
+	}
+	value, exists := c.Get("current_user")
+	actor, ok := value.(models.SystemUser)
+	if !exists || !ok || actor.OrganizationID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return models.SystemUser{}, false
+	}
+	return actor, true
+}
+
+// respondSystemUserError traduce los errores de la gestión de usuarios: 400 con el detalle
+// por campo, 403 si el rol tiene más permisos que el actor, 404 si no es de la
+// organización y 409 si el cambio no se permite
+func respondSystemUserError(c *gin.Context, err error) {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Datos inválidos",
+			"details": err.Error(),
+			"fields":  fields,
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	case errors.Is(err, services.ErrSystemUserConflict),
+		errors.Is(err, services.ErrSystemUserSelf),
+		errors.Is(err, services.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "No se puede aplicar el cambio",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrPermissionEscalation):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Permiso insuficiente",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error guardando el usuario",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// tenantConversationRepo guarda documentos por organización como lo hace Mongo con el filtro
+type tenantConversationRepo struct {
+	repositories.ConversationRepository
+	documents []models.Document
+}
+
+func (r *tenantConversationRepo) SaveDocument(ctx context.Context, document models.Document) error {
+	r.documents = append(r.documents, document)
+	return nil
+}
+
+func (r *tenantConversationRepo) GetDocumentsByClientID(ctx context.Context, clientID uint, orgID uint) ([]models.Document, error) {
+	out := []models.Document{}
+	for _, document := range r.documents {
+		if document.ClientID == clientID && document.OrganizationID == orgID {
+			out = append(out, document)
+		}
+	}
+	return out, nil
+}
+
+func (r *tenantConversationRepo) ExportConversation(ctx context.Context, clientID uint, sessionID string, chatID string, orgID uint) ([]models.Message, error) {
+	return []models.Message{}, nil
+}
+
+func setupTenantRouter(orgID uint, repo *tenantConversationRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	conversationRepo = repo
+	// El cliente 7 es de la organización 1
+	clientRepo = &mocks.MockClientRepo{
+		GetClientByIDFunc: func(id uint, org uint) (*models.Client, error) {
+			if id == 7 && org == 1 {
+				return &models.Client{ID: 7, OrganizationID: 1}, nil
+			}
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", orgID)
+		c.Next()
+	})
+	r.POST("/documents", SaveDocument)
+	r.GET("/clients/:clientId/documents", GetClientDocuments)
+	r.GET("/conversations/:clientId/export", ExportConversation)
+	r.POST("/chats/mode", UpdateChatMode)
+	return r
+}
+
+func TestMongoDataIsScopedByOrganization(t *testing.T) {
+	previousConversations, previousClients := conversationRepo, clientRepo
+	defer func() { conversationRepo, clientRepo = previousConversations, previousClients }()
+
+	repo := &tenantConversationRepo{}
+	owner := setupTenantRouter(1, repo)
+	w := httptest.NewRecorder()
+	owner.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/documents",
+		strings.NewReader(`{"client_id":7,"type":"manifiesto","file_name":"m.pdf"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint(1), repo.documents[0].OrganizationID)
+
+	w = httptest.NewRecorder()
+	owner.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clients/7/documents", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+
+	// Otra organización no puede leer ni escribir datos del cliente 7
+	other := setupTenantRouter(2, repo)
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/clients/7/documents", nil),
+		httptest.NewRequest(http.MethodGet, "/conversations/7/export?sessionId=bot-1&chatId=573001234567", nil),
+		httptest.NewRequest(http.MethodPost, "/documents",
+			strings.NewReader(`{"client_id":7,"type":"manifiesto","file_name":"x.pdf"}`)),
+		httptest.NewRequest(http.MethodPost, "/chats/mode",
+			strings.NewReader(`{"client_id":7,"session_id":"bot-1","chat_id":"573001234567","bot_mode":false}`)),
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, "%s %s", req.Method, req.URL)
+	}
+	assert.Len(t, repo.documents, 1)
+
+	docs, _ := repo.GetDocumentsByClientID(context.Background(), 7, 2)
+	assert.Empty(t, docs)
+}
+
+func TestRasaConversationsAreScopedByOrganization(t *testing.T) {
+	fake := setupRasaRouter(t)
+	fake.SetSlots("bot-1:573001234567@s.whatsapp.net", "manifiesto_form", map[string]interface{}{"origen": "Bogotá"})
+	claimSession(t, 1, "bot-1")
+
+	// La organización 2 no puede leer ni reiniciar la conversación de una sesión de la 1
+	other := rasaRouter(2)
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/chats/573001234567/rasa?sessionId=bot-1", nil),
+		httptest.NewRequest(http.MethodPost, "/chats/573001234567/rasa/reset?sessionId=bot-1", nil),
+	}
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		other.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, "%s %s", req.Method, req.URL)
+		assert.NotContains(t, w.Body.String(), "Bogotá")
+	}
+	assert.Equal(t, "Bogotá", fake.Tracker("bot-1:573001234567@s.whatsapp.net").Slots["origen"])
+}
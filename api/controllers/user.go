@@ -179,7 +179,7 @@ func GetOrCreateClient(c *gin.Context) {
 
 // GetCurrentUser obtiene el usuario autenticado desde el token
 // @Summary Obtener usuario actual
-// @Description Retorna el perfil del usuario autenticado
+// @Description Retorna el perfil del usuario autenticado; con una API key devuelve la key, su organización y sus permisos
 // @Tags usuarios
 // @Produce json
 // @Success 200 {object} models.Client
@@ -187,6 +187,16 @@ func GetOrCreateClient(c *gin.Context) {
 // @Failure 404 {object} map[string]string
 // @Router /api/v1/users/me [get]
 func GetCurrentUser(c *gin.Context) {
+	// Una API key no tiene usuario: se describe la credencial con que se autenticó
+	if apiKeyID := c.GetUint("api_key_id"); apiKeyID != 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"api_key_id":      apiKeyID,
+			"organization_id": c.GetUint("organization_id"),
+			"permissions":     c.GetStringSlice("current_user_permissions"),
+		})
+		return
+	}
+
 	// Obtener ID del usuario desde el middleware de autenticación
 	userID, exists := c.Get("current_user_id")
 	if !exists {
@@ -333,21 +343,21 @@ func calculateDashboardStats(ctx context.Context, startDate, endDate time.Time,
 	stats["clients"] = clientStats
 
 	// 2. Estadísticas de documentos
-	documentStats, err := getDocumentStats(ctx, startDate, endDate)
+	documentStats, err := getDocumentStats(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
 	stats["documents"] = documentStats
 
 	// 3. Estadísticas de conversaciones
-	conversationStats, err := getConversationStats(ctx, startDate, endDate)
+	conversationStats, err := getConversationStats(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
 	stats["conversations"] = conversationStats
 
 	// 4. Estadísticas de chatbot
-	chatbotStats, err := getChatbotStats(ctx, startDate, endDate)
+	chatbotStats, err := getChatbotStats(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -372,7 +382,7 @@ func getClientStats(ctx context.Context, startDate, endDate time.Time, orgID uin
 	}
 
 	// Clientes activos (que han tenido conversaciones)
-	activeClients, err := getActiveClientsCount(ctx, startDate, endDate)
+	activeClients, err := getActiveClientsCount(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -386,21 +396,21 @@ func getClientStats(ctx context.Context, startDate, endDate time.Time, orgID uin
 }
 
 // getDocumentStats obtiene estadísticas de documentos
-func getDocumentStats(ctx context.Context, startDate, endDate time.Time) (map[string]interface{}, error) {
+func getDocumentStats(ctx context.Context, startDate, endDate time.Time, orgID uint) (map[string]interface{}, error) {
 	// Total de documentos
-	totalDocuments, err := conversationRepo.GetTotalDocuments(ctx)
+	totalDocuments, err := conversationRepo.GetTotalDocuments(ctx, orgID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Documentos generados en el período
-	documentsInPeriod, err := conversationRepo.GetDocumentsCreatedBetween(ctx, startDate, endDate)
+	documentsInPeriod, err := conversationRepo.GetDocumentsCreatedBetween(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Documentos por tipo
-	documentsByType, err := conversationRepo.GetDocumentsByType(ctx, startDate, endDate)
+	documentsByType, err := conversationRepo.GetDocumentsByType(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -427,21 +437,21 @@ func getDocumentStats(ctx context.Context, startDate, endDate time.Time) (map[st
 }
 
 // getConversationStats obtiene estadísticas de conversaciones
-func getConversationStats(ctx context.Context, startDate, endDate time.Time) (map[string]interface{}, error) {
+func getConversationStats(ctx context.Context, startDate, endDate time.Time, orgID uint) (map[string]interface{}, error) {
 	// Total de mensajes
-	totalMessages, err := conversationRepo.GetTotalMessages(ctx)
+	totalMessages, err := conversationRepo.GetTotalMessages(ctx, orgID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Mensajes en el período
-	messagesInPeriod, err := conversationRepo.GetMessagesBetween(ctx, startDate, endDate)
+	messagesInPeriod, err := conversationRepo.GetMessagesBetween(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Conversaciones activas
-	activeConversations, err := conversationRepo.GetActiveConversations(ctx, startDate, endDate)
+	activeConversations, err := conversationRepo.GetActiveConversations(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -455,14 +465,14 @@ func getConversationStats(ctx context.Context, startDate, endDate time.Time) (ma
 }
 
 // getChatbotStats obtiene estadísticas del chatbot
-func getChatbotStats(ctx context.Context, startDate, endDate time.Time) (map[string]interface{}, error) {
+func getChatbotStats(ctx context.Context, startDate, endDate time.Time, orgID uint) (map[string]interface{}, error) {
 	// Chats en modo bot vs modo usuario
-	botModeChats, err := conversationRepo.GetChatsByMode(ctx, true, startDate, endDate)
+	botModeChats, err := conversationRepo.GetChatsByMode(ctx, true, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
 
-	userModeChats, err := conversationRepo.GetChatsByMode(ctx, false, startDate, endDate)
+	userModeChats, err := conversationRepo.GetChatsByMode(ctx, false, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -477,8 +487,8 @@ func getChatbotStats(ctx context.Context, startDate, endDate time.Time) (map[str
 // Funciones auxiliares adicionales
 
 // getActiveClientsCount cuenta clientes que han tenido actividad
-func getActiveClientsCount(ctx context.Context, startDate, endDate time.Time) (int, error) {
-	activeConversations, err := conversationRepo.GetActiveConversations(ctx, startDate, endDate)
+func getActiveClientsCount(ctx context.Context, startDate, endDate time.Time, orgID uint) (int, error) {
+	activeConversations, err := conversationRepo.GetActiveConversations(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return 0, err
 	}
@@ -486,36 +496,21 @@ func getActiveClientsCount(ctx context.Context, startDate, endDate time.Time) (i
 	// Contar clientes únicos
 	uniqueClients := make(map[uint]bool)
 	for _, conv := range activeConversations {
-		// Type assertion to convert interface{} to map
-		convMap, ok := conv.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		
-		// Extract client_id from the map
-		clientID, ok := convMap["_id"].(uint)
-		if !ok {
-			// Try to extract from other possible field names
-			if clientIDFloat, ok := convMap["client_id"].(float64); ok {
-				clientID = uint(clientIDFloat)
-			} else {
-				continue
-			}
-		}
-		uniqueClients[clientID] = true
+		uniqueClients[conv.ClientID] = true
 	}
 
 	return len(uniqueClients), nil
 }
 
 // calculateAverageMessages calcula el promedio de mensajes por conversación
-func calculateAverageMessages(conversations []interface{}) float64 {
+func calculateAverageMessages(conversations []models.ActiveConversation) float64 {
 	if len(conversations) == 0 {
 		return 0
 	}
 
-	// Since we don't have direct access to messages in the aggregated data,
-	// we'll return a default value or count based on available data
-	// For now, return the number of active conversations as a proxy
-	return float64(len(conversations))
+	total := 0
+	for _, conv := range conversations {
+		total += conv.MessageCount
+	}
+	return float64(total) / float64(len(conversations))
 }
\ No newline at end of file
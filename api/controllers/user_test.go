@@ -24,21 +24,21 @@ func setupMockRouter() *gin.Engine {
 			user.ID = 1
 			return nil
 		},
-		GetClientByIDFunc: func(id uint) (*models.Client, error) {
+		GetClientByIDFunc: func(id uint, orgID uint) (*models.Client, error) {
 			return &models.Client{
 				ID:    1,
 				Name:  "John Doe",
 				Email: &johnEmail, // Ahora es un puntero
 			}, nil
 		},
-		GetClientByPhoneFunc: func(phone string) (*models.Client, error) {
+		GetClientByPhoneFunc: func(phone string, orgID uint) (*models.Client, error) {
 			return &models.Client{
 				ID:    2,
 				Name:  "Jane Doe",
 				Email: &janeEmail, // Ahora es un puntero
 			}, nil
 		},
-		GetOrCreateClientFunc: func(phone, name, email string) (*models.Client, error) {
+		GetOrCreateClientFunc: func(phone, name, email string, orgID uint) (*models.Client, error) {
 			var emailPtr *string
 			if email != "" {
 				emailPtr = &email
@@ -53,8 +53,14 @@ func setupMockRouter() *gin.Engine {
 	SetClientRepo(mock)
 
 	r := gin.Default()
+	// Simula el contexto que deja PasetoAuthMiddleware
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", uint(1))
+		c.Set("current_user_id", uint(1))
+		c.Next()
+	})
 	r.POST("/users", CreateClient)
-	r.GET("/users/profile", GetClientByID)
+	r.GET("/users/profile/:id", GetClientByID)
 	r.POST("/users/get-or-create", GetOrCreateClient)
 	r.GET("/users/:phone", GetClientByPhone)
 	return r
@@ -86,7 +92,7 @@ func TestGetProfile(t *testing.T) {
 	r := setupMockRouter()
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/users/profile", nil)
+	req, _ := http.NewRequest("GET", "/users/profile/1", nil)
 
 	r.ServeHTTP(w, req)
 
@@ -124,3 +130,30 @@ func TestGetClientByPhone(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Jane Doe")
 }
+
+func TestGetCurrentUserWithAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// Contexto que deja PasetoAuthMiddleware para una API key: sin current_user_id
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", uint(7))
+		c.Set("api_key_id", uint(3))
+		c.Set("current_user_permissions", []string{models.PermissionClientsRead})
+		c.Next()
+	})
+	r.GET("/users/me", GetCurrentUser)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		APIKeyID       uint     `json:"api_key_id"`
+		OrganizationID uint     `json:"organization_id"`
+		Permissions    []string `json:"permissions"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, uint(3), resp.APIKeyID)
+	assert.Equal(t, uint(7), resp.OrganizationID)
+	assert.Equal(t, []string{models.PermissionClientsRead}, resp.Permissions)
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+)
+
+var documentVerification *services.DocumentVerification
+
+// SetDocumentVerification inyecta el servicio de verificación pública de documentos
+func SetDocumentVerification(verification *services.DocumentVerification) {
+	documentVerification = verification
+}
+
+// Colombia no tiene horario de verano: la zona fija evita depender de tzdata
+var verificationLocation = time.FixedZone("COT", -5*60*60)
+
+var verificationPage = template.Must(template.New("verify").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Verificación de documento - DocuBot</title>
+<style>
+body{font-family:system-ui,sans-serif;max-width:32rem;margin:2rem auto;padding:0 1rem;color:#222}
+.status{padding:1rem;border-radius:.5rem;font-weight:600}
+.valid{background:#e6f4ea;color:#1e6b34}.invalid{background:#fdecea;color:#a3261b}
+dt{font-size:.85rem;color:#666;margin-top:1rem}dd{margin:0;word-break:break-all}
+</style>
+</head>
+<body>
+<h1>Verificación de documento</h1>
+{{if .Valid}}<p class="status valid">✅ Documento auténtico emitido por DocuBot</p>
+{{else}}<p class="status invalid">⚠️ Este documento no está vigente ({{if .Stage}}{{.Stage}}{{else}}{{.Status}}{{end}})</p>{{end}}
+<dl>
+<dt>Código</dt><dd>{{.Code}}</dd>
+<dt>Tipo</dt><dd>{{.DocumentType}}{{if .Number}} N.º {{.Number}}{{end}}</dd>
+<dt>Emitido por</dt><dd>{{.Organization}}</dd>
+<dt>Fecha de emisión</dt><dd>{{.Issued}}</dd>
+{{if .SHA256}}<dt>Huella SHA-256 del archivo</dt><dd><code>{{.SHA256}}</code></dd>{{end}}
+</dl>
+<p><small>Compare la huella con la del archivo recibido (por ejemplo, <code>sha256sum archivo.pdf</code>): si no coinciden, el archivo fue modificado.</small></p>
+</body>
+</html>
+`))
+
+// VerifyDocument comprueba un documento por su código de verificación (ruta pública).
+// Responde HTML a los navegadores (el QR abre esta ruta) y JSON a los demás clientes.
+// @Summary Verificar documento
+// @Description Devuelve tipo, fecha de emisión, organización y hash del archivo. No expone datos del cliente.
+// @Tags verification
+// @Produce json,html
+// @Param code path string true "Código de verificación (XXXX-XXXX-XXXX)"
+// @Success 200 {object} services.DocumentVerificationResult
+// @Failure 404 {object} map[string]interface{}
+// @Router /verify/{code} [get]
+func VerifyDocument(c *gin.Context) {
+	document, ok := loadVerifiedDocument(c)
+	if !ok {
+		return
+	}
+
+	result := documentVerification.Result(*document, verificationOrganization(document.OrganizationID))
+	c.Header("Cache-Control", "no-store")
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	var page bytes.Buffer
+	err := verificationPage.Execute(&page, struct {
+		services.DocumentVerificationResult
+		Issued string
+	}{result, result.IssuedAt.In(verificationLocation).Format("02/01/2006 15:04")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error generando página de verificación",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// GetVerificationQR devuelve el QR (PNG) que abre la página de verificación (ruta pública)
+// @Summary QR de verificación
+// @Tags verification
+// @Produce png
+// @Param code path string true "Código de verificación"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Router /verify/{code}/qr [get]
+func GetVerificationQR(c *gin.Context) {
+	document, ok := loadVerifiedDocument(c)
+	if !ok {
+		return
+	}
+
+	png, err := documentVerification.QR(document.VerificationCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error generando QR",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// loadVerifiedDocument busca el documento del código; responde 404 si no existe
+func loadVerifiedDocument(c *gin.Context) (*models.Document, bool) {
+	if documentVerification == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verificación de documentos no configurada"})
+		return nil, false
+	}
+
+	code, valid := models.NormalizeVerificationCode(c.Param("code"))
+	if !valid {
+		c.JSON(http.StatusNotFound, gin.H{"valid": false, "error": "Código de verificación no encontrado"})
+		return nil, false
+	}
+
+	document, err := conversationRepo.GetDocumentByVerificationCode(c.Request.Context(), code)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"valid": false, "error": "Código de verificación no encontrado"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Error verificando documento",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return document, true
+}
+
+// verificationOrganization devuelve el nombre de la organización que emitió el documento
+func verificationOrganization(orgID uint) string {
+	if organizationRepo == nil {
+		return ""
+	}
+	org, err := organizationRepo.GetByID(orgID)
+	if err != nil {
+		log.Printf("⚠️ Organización %d del documento verificado no encontrada: %v", orgID, err)
+		return ""
+	}
+	return org.Name
+}
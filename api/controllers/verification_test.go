@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
+)
+
+func TestVerifyDocument(t *testing.T) {
+	document := models.Document{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: 1,
+		ClientID:       55,
+		ChatID:         "573001234567",
+		Type:           "manifiesto",
+		Metadata:       map[string]interface{}{"consecutivo_manifiesto": "MF-100"},
+		Entities:       map[string]interface{}{"conductor_nombre": "Pedro Pérez"},
+		Checksum:       strings.Repeat("ab", 32),
+		Size:           2048,
+	}
+	document.Finish(models.DocumentStatusCompleted, "", time.Now())
+	require.NotEmpty(t, document.VerificationCode)
+
+	r, _ := setupDocumentsRouter(t, document)
+	r.GET("/verify/:code", VerifyDocument)
+	r.GET("/verify/:code/qr", GetVerificationQR)
+	previous := documentVerification
+	documentVerification = services.NewDocumentVerification("https://api.docubot.test")
+	t.Cleanup(func() { documentVerification = previous })
+
+	// El código se acepta en minúsculas y sin guiones
+	typed := strings.ToLower(strings.ReplaceAll(document.VerificationCode, "-", ""))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify/"+typed, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result services.DocumentVerificationResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.Valid)
+	assert.Equal(t, document.VerificationCode, result.Code)
+	assert.Equal(t, "manifiesto", result.DocumentType)
+	assert.Equal(t, "MF-100", result.Number)
+	assert.Equal(t, document.Checksum, result.SHA256)
+	// Sin datos personales del cliente
+	assert.NotContains(t, w.Body.String(), "Pedro")
+	assert.NotContains(t, w.Body.String(), document.ChatID)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/verify/"+document.VerificationCode, nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "MF-100")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify/"+document.VerificationCode+"/qr", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")))
+
+	for _, code := range []string{"AAAA-AAAA-AAAA", "no-es-un-codigo"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify/"+code, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, code)
+	}
+}
+
+func TestVerifyAnnulledManifestIsNotValid(t *testing.T) {
+	document := models.Document{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: 1,
+		Type:           "manifiesto",
+		Metadata:       map[string]interface{}{"consecutivo_manifiesto": "MF-101"},
+	}
+	document.Finish(models.DocumentStatusCompleted, "", time.Now())
+	document.Stage = models.ManifestStageAnnulled
+
+	r, _ := setupDocumentsRouter(t, document)
+	r.GET("/verify/:code", VerifyDocument)
+	previous := documentVerification
+	documentVerification = services.NewDocumentVerification("https://api.docubot.test")
+	t.Cleanup(func() { documentVerification = previous })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify/"+document.VerificationCode, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result services.DocumentVerificationResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.False(t, result.Valid)
+	assert.Equal(t, models.DocumentStatusCompleted, result.Status)
+	assert.Equal(t, models.ManifestStageAnnulled, result.Stage)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/verify/"+document.VerificationCode, nil)
+	req.Header.Set("Accept", "text/html")
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "no está vigente (annulled)")
+}
@@ -1,18 +1,130 @@
 package controllers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// BotCredentialVerifier valida la credencial con la que una sesión de baileys-ws se conecta
+type BotCredentialVerifier interface {
+	Verify(sessionID, token string) (*models.BotSessionKey, error)
+}
+
+var (
+	errBotCredentialVerifierMissing = errors.New("verificador de credenciales de bot no configurado")
+	errBotNotFound                  = errors.New("bot not found")
+)
+
+// HubConfig controla keepalive, colas y reintentos de las conexiones del hub
+type HubConfig struct {
+	PingInterval        time.Duration // Cada cuánto se envía ping (debe ser menor que PongWait)
+	PongWait            time.Duration // Tiempo máximo sin pong antes de dar la conexión por muerta
+	WriteWait           time.Duration // Deadline de cada escritura
+	MaxMessageSize      int64         // Tamaño máximo de un mensaje entrante
+	SendQueueSize       int           // Mensajes en cola por conexión
+	AckTimeout          time.Duration // Espera del ack de baileys-ws antes de reintentar
+	MaxDeliveryAttempts int           // Intentos antes de marcar un mensaje como fallido
+	ReconnectWait       time.Duration // Espera a que el bot se reconecte antes de dar por fallido un pendiente
+}
+
+// DefaultHubConfig devuelve la configuración por defecto del hub
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		PingInterval:        30 * time.Second,
+		PongWait:            60 * time.Second,
+		WriteWait:           10 * time.Second,
+		MaxMessageSize:      1 << 20,
+		SendQueueSize:       256,
+		AckTimeout:          15 * time.Second,
+		MaxDeliveryAttempts: 3,
+		ReconnectWait:       2 * time.Minute,
+	}
+}
+
+// Tipos de frame entre el hub y baileys-ws
+const (
+	BotFrameMessage = "message" // hub → bot: mensaje a enviar por WhatsApp
+	BotFrameAck     = "ack"     // bot → hub: mensaje entregado a WhatsApp
+	BotFrameNack    = "nack"    // bot → hub: no se pudo entregar
+
+	BotFrameIncomingNack = "incoming_nack" // hub → bot: mensaje entrante rechazado por saturación, reenviarlo
+)
+
+// OutboundMessage es un mensaje que baileys-ws debe enviar por WhatsApp
+type OutboundMessage struct {
+	To          string `json:"to"`
+	Message     string `json:"message"`
+	SessionID   string `json:"sessionId,omitempty"`
+	MessageType string `json:"messageType,omitempty"` // text (por defecto), image, document, buttons, location
+
+	Media    *models.MessageMedia    `json:"media,omitempty"`
+	Buttons  []models.MessageButton  `json:"buttons,omitempty"`
+	Location *models.MessageLocation `json:"location,omitempty"`
+}
+
+// BotEnvelope es el frame enviado al bot; el ID se devuelve en el ack/nack
+type BotEnvelope struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Attempt int    `json:"attempt"`
+	OutboundMessage
+}
+
+// DeliveryStatus es el resultado final de un mensaje enviado a un bot
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
 )
 
+// DeliveryResult se reporta cuando un mensaje se confirma o se agotan los reintentos
+type DeliveryResult struct {
+	MessageID      string
+	BotKey         string
+	OrganizationID uint
+	Message        OutboundMessage
+	Status         DeliveryStatus
+	Attempts       int
+	Error          string
+}
+
+type pendingDelivery struct {
+	botKey      string
+	orgID       uint
+	envelope    BotEnvelope
+	sentAt      time.Time
+	lastError   string
+	unreachable time.Time // Desde cuándo no se le puede escribir al bot (cero si se le escribió)
+}
+
 type WebSocketHub struct {
-	mu      sync.RWMutex
-	bots    map[string]*websocket.Conn // Conexiones de bots (key: sessionId:botPhone o botPhone)
-	clients map[string]*websocket.Conn // Conexiones de clientes (key: client phone number)
+	mu             sync.RWMutex
+	config         HubConfig
+	bots           map[string]*wsConn                   // Conexiones de bots (key: sessionId:botPhone)
+	botCredentials map[string]*models.BotSessionKey     // Credencial con la que se registró cada bot
+	clients        map[string]*websocket.Conn           // Conexiones de clientes (key: client phone number)
+	agents         map[uint]map[*websocket.Conn]*wsConn // Conexiones de agentes del dashboard (key: organization_id)
+	verifier       BotCredentialVerifier
+	onRegistered   func(botKey string)
+
+	pendingMu  sync.Mutex
+	pending    map[string]*pendingDelivery // Mensajes esperando ack (key: ID del envelope)
+	onDelivery func(DeliveryResult)
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 type Client struct {
@@ -21,44 +133,382 @@ type Client struct {
 }
 
 func NewWebSocketHub() *WebSocketHub {
-	return &WebSocketHub{
-		bots:    make(map[string]*websocket.Conn),
-		clients: make(map[string]*websocket.Conn),
+	return NewWebSocketHubWithConfig(DefaultHubConfig())
+}
+
+// NewWebSocketHubWithConfig crea el hub e inicia el monitor de acks
+func NewWebSocketHubWithConfig(config HubConfig) *WebSocketHub {
+	h := &WebSocketHub{
+		config:         config,
+		bots:           make(map[string]*wsConn),
+		botCredentials: make(map[string]*models.BotSessionKey),
+		clients:        make(map[string]*websocket.Conn),
+		agents:         make(map[uint]map[*websocket.Conn]*wsConn),
+		pending:        make(map[string]*pendingDelivery),
+		stop:           make(chan struct{}),
 	}
+	go h.monitorDeliveries()
+	return h
 }
 
-// Métodos para Bots
-func (h *WebSocketHub) RegisterBot(botPhone string, conn *websocket.Conn) {
+// Close detiene el monitor de acks
+func (h *WebSocketHub) Close() {
+	h.stopOnce.Do(func() { close(h.stop) })
+}
+
+// SetCredentialVerifier configura el verificador de credenciales de bots
+func (h *WebSocketHub) SetCredentialVerifier(verifier BotCredentialVerifier) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	h.verifier = verifier
+}
+
+// SetDeliveryHandler registra un callback para el resultado final de cada mensaje
+func (h *WebSocketHub) SetDeliveryHandler(handler func(DeliveryResult)) {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	h.onDelivery = handler
+}
+
+// SetBotRegisteredHandler registra un callback que se ejecuta cada vez que un bot se (re)conecta
+func (h *WebSocketHub) SetBotRegisteredHandler(handler func(botKey string)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onRegistered = handler
+}
+
+// Métodos para Bots
+
+// AuthenticateBot valida la credencial de una sesión sin registrar la conexión
+func (h *WebSocketHub) AuthenticateBot(sessionID, token string) (*models.BotSessionKey, error) {
+	h.mu.RLock()
+	verifier := h.verifier
+	h.mu.RUnlock()
 
-	if existing, exists := h.bots[botPhone]; exists {
-		log.Printf("Conexión existente para bot %s, cerrando...", botPhone)
-		existing.Close()
+	if verifier == nil {
+		return nil, errBotCredentialVerifierMissing
 	}
-	print("REGISTRANDO")
-	println(botPhone)
-	h.bots[botPhone] = conn
-	log.Printf("Bot %s registrado exitosamente", botPhone)
+	return verifier.Verify(sessionID, token)
 }
 
-func (h *WebSocketHub) UnregisterBot(botPhone string) {
+// RegisterBot registra la conexión de un bot si su credencial es válida y no está revocada
+func (h *WebSocketHub) RegisterBot(botKey, sessionID, token string, conn *websocket.Conn) (*models.BotSessionKey, error) {
+	credential, err := h.AuthenticateBot(sessionID, token)
+	if err != nil {
+		log.Printf("🔒 Registro de bot %s rechazado: %v", botKey, err)
+		return nil, err
+	}
+
+	h.mu.Lock()
+	if existing, exists := h.bots[botKey]; exists {
+		log.Printf("Conexión existente para bot %s, cerrando...", botKey)
+		existing.close()
+	}
+	h.bots[botKey] = newWSConn(conn, h.config)
+	h.botCredentials[botKey] = credential
+	onRegistered := h.onRegistered
+	h.mu.Unlock()
+
+	log.Printf("Bot %s registrado exitosamente (org: %d, key: %s)", botKey, credential.OrganizationID, credential.KeyID)
+
+	// Reenviar lo que quedó sin confirmar mientras el bot estaba desconectado
+	h.retryPendingFor(botKey)
+	if onRegistered != nil {
+		go onRegistered(botKey)
+	}
+	return credential, nil
+}
+
+// UnregisterBot elimina el bot solo si la conexión registrada es conn; una conexión
+// reemplazada (p. ej. tras rotar la credencial) no debe borrar a su sucesora
+func (h *WebSocketHub) UnregisterBot(botKey string, conn *websocket.Conn) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if conn, ok := h.bots[botPhone]; ok {
-		conn.Close()
-		delete(h.bots, botPhone)
+	if current, ok := h.bots[botKey]; ok && current.conn == conn {
+		current.close()
+		delete(h.bots, botKey)
+		delete(h.botCredentials, botKey)
+		return
+	}
+	conn.Close()
+}
+
+// DisconnectBotsByKeyIDs cierra las conexiones registradas con credenciales revocadas
+func (h *WebSocketHub) DisconnectBotsByKeyIDs(keyIDs []string) int {
+	revoked := make(map[string]bool, len(keyIDs))
+	for _, keyID := range keyIDs {
+		revoked[keyID] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for botKey, credential := range h.botCredentials {
+		if !revoked[credential.KeyID] {
+			continue
+		}
+		if conn, ok := h.bots[botKey]; ok {
+			conn.closeWithReason(websocket.ClosePolicyViolation, "credencial revocada")
+			delete(h.bots, botKey)
+		}
+		delete(h.botCredentials, botKey)
+		closed++
+		log.Printf("🔒 Bot %s desconectado: credencial %s revocada", botKey, credential.KeyID)
 	}
+	return closed
 }
 
-func (h *WebSocketHub) SendToBot(botPhone string, message interface{}) error {
+// GetBotOrganization devuelve la organización a la que está ligada la credencial del bot
+func (h *WebSocketHub) GetBotOrganization(botKey string) (uint, bool) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	println(botPhone)
-	if conn, ok := h.bots[botPhone]; ok {
-		return conn.WriteJSON(message)
+	if credential, ok := h.botCredentials[botKey]; ok {
+		return credential.OrganizationID, true
+	}
+	return 0, false
+}
+
+// SendToBot encola un mensaje para el bot y devuelve el ID del envelope. El mensaje
+// queda pendiente hasta el ack; sin ack se reintenta y al final se reporta como fallido.
+func (h *WebSocketHub) SendToBot(botKey string, message OutboundMessage) (string, error) {
+	id, err := newEnvelopeID()
+	if err != nil {
+		return "", err
+	}
+	return id, h.sendEnvelope(botKey, id, message)
+}
+
+// sendEnvelope envía el mensaje con un ID de envelope ya reservado (la cola de salida
+// lo guarda en la fila antes de enviar)
+func (h *WebSocketHub) sendEnvelope(botKey, id string, message OutboundMessage) error {
+	h.mu.RLock()
+	conn, ok := h.bots[botKey]
+	var orgID uint
+	if credential, exists := h.botCredentials[botKey]; exists {
+		orgID = credential.OrganizationID
+	}
+	h.mu.RUnlock()
+	if !ok {
+		return errBotNotFound
+	}
+
+	envelope := BotEnvelope{ID: id, Type: BotFrameMessage, Attempt: 1, OutboundMessage: message}
+
+	h.pendingMu.Lock()
+	h.pending[id] = &pendingDelivery{botKey: botKey, orgID: orgID, envelope: envelope, sentAt: time.Now()}
+	h.pendingMu.Unlock()
+
+	if err := conn.enqueue(envelope); err != nil {
+		h.pendingMu.Lock()
+		delete(h.pending, id)
+		h.pendingMu.Unlock()
+		return fmt.Errorf("error encolando mensaje para bot %s: %w", botKey, err)
+	}
+	return nil
+}
+
+// RejectIncoming avisa al bot que un mensaje entrante no se aceptó para que lo reenvíe;
+// messageID es el ID que el bot puso en el mensaje
+func (h *WebSocketHub) RejectIncoming(botKey, messageID, reason string) error {
+	h.mu.RLock()
+	conn, ok := h.bots[botKey]
+	h.mu.RUnlock()
+	if !ok {
+		return errBotNotFound
+	}
+	return conn.enqueue(botFrame{Type: BotFrameIncomingNack, ID: messageID, Error: reason})
+}
+
+// HandleBotAck confirma la entrega de un mensaje
+func (h *WebSocketHub) HandleBotAck(botKey, messageID string) {
+	h.pendingMu.Lock()
+	p, ok := h.pending[messageID]
+	if !ok || p.botKey != botKey {
+		h.pendingMu.Unlock()
+		return
+	}
+	delete(h.pending, messageID)
+	handler := h.onDelivery
+	h.pendingMu.Unlock()
+
+	if handler != nil {
+		handler(p.result(DeliveryDelivered))
+	}
+}
+
+// HandleBotNack registra un fallo reportado por el bot y reintenta si quedan intentos
+func (h *WebSocketHub) HandleBotNack(botKey, messageID, reason string) {
+	h.pendingMu.Lock()
+	p, ok := h.pending[messageID]
+	if !ok || p.botKey != botKey {
+		h.pendingMu.Unlock()
+		return
+	}
+	p.lastError = reason
+	failed := h.retryLocked(messageID, p)
+	h.pendingMu.Unlock()
+
+	h.reportFailures(failed)
+}
+
+// PendingCount devuelve cuántos mensajes esperan ack
+func (h *WebSocketHub) PendingCount() int {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+	return len(h.pending)
+}
+
+// monitorDeliveries reintenta los mensajes cuyo ack no llegó a tiempo
+func (h *WebSocketHub) monitorDeliveries() {
+	interval := h.config.AckTimeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.retryExpired(time.Now())
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+func (h *WebSocketHub) retryExpired(now time.Time) {
+	var failed []DeliveryResult
+
+	h.pendingMu.Lock()
+	for id, p := range h.pending {
+		if now.Sub(p.sentAt) < h.config.AckTimeout {
+			continue
+		}
+		if p.lastError == "" {
+			p.lastError = "sin ack del bot"
+		}
+		failed = append(failed, h.retryLocked(id, p)...)
+	}
+	h.pendingMu.Unlock()
+
+	h.reportFailures(failed)
+}
+
+// retryPendingFor reenvía de inmediato los mensajes pendientes de un bot que se reconectó
+func (h *WebSocketHub) retryPendingFor(botKey string) {
+	var failed []DeliveryResult
+
+	h.pendingMu.Lock()
+	for id, p := range h.pending {
+		if p.botKey == botKey {
+			failed = append(failed, h.retryLocked(id, p)...)
+		}
+	}
+	h.pendingMu.Unlock()
+
+	h.reportFailures(failed)
+}
+
+// retryLocked reenvía un pendiente o lo da por fallido si agotó los intentos. Solo cuenta
+// un intento cuando el frame queda en la cola de escritura del bot: con el bot desconectado
+// el pendiente se conserva para reenviarlo al reconectarse (retryPendingFor) y solo se da
+// por fallido si no vuelve en ReconnectWait.
+// Se llama con pendingMu tomado (orden de locks: pendingMu → mu).
+func (h *WebSocketHub) retryLocked(id string, p *pendingDelivery) []DeliveryResult {
+	now := time.Now()
+	p.sentAt = now
+
+	h.mu.RLock()
+	conn, ok := h.bots[p.botKey]
+	h.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = errBotNotFound
+	} else if p.envelope.Attempt >= h.config.MaxDeliveryAttempts {
+		delete(h.pending, id)
+		return []DeliveryResult{p.result(DeliveryFailed)}
+	} else {
+		next := p.envelope
+		next.Attempt++
+		if err = conn.enqueue(next); err == nil {
+			p.envelope = next
+			p.unreachable = time.Time{}
+			return nil
+		}
+	}
+
+	if p.unreachable.IsZero() {
+		p.unreachable = now
+	}
+	if errors.Is(err, errBotNotFound) {
+		p.lastError = "bot desconectado"
+	} else {
+		p.lastError = err.Error()
+	}
+	if now.Sub(p.unreachable) >= h.config.ReconnectWait {
+		delete(h.pending, id)
+		return []DeliveryResult{p.result(DeliveryFailed)}
+	}
+	return nil
+}
+
+// reportFailures avisa al callback, o a los agentes si no hay callback, de los mensajes que no se pudieron entregar
+func (h *WebSocketHub) reportFailures(failed []DeliveryResult) {
+	if len(failed) == 0 {
+		return
+	}
+
+	h.pendingMu.Lock()
+	handler := h.onDelivery
+	h.pendingMu.Unlock()
+
+	for _, result := range failed {
+		log.Printf("❌ Mensaje %s a %s no entregado por bot %s tras %d intentos: %s",
+			result.MessageID, result.Message.To, result.BotKey, result.Attempts, result.Error)
+
+		// Con un callback registrado (cola de salida) el fallo no es definitivo: avisa el callback
+		if handler != nil {
+			handler(result)
+			continue
+		}
+		if result.OrganizationID != 0 {
+			h.BroadcastToAgents(result.OrganizationID, map[string]interface{}{
+				"type":       "delivery_failed",
+				"message_id": result.MessageID,
+				"session_id": result.Message.SessionID,
+				"chat_id":    result.Message.To,
+				"message":    result.Message.Message,
+				"attempts":   result.Attempts,
+				"error":      result.Error,
+			})
+		}
+	}
+}
+
+func (p *pendingDelivery) result(status DeliveryStatus) DeliveryResult {
+	result := DeliveryResult{
+		MessageID:      p.envelope.ID,
+		BotKey:         p.botKey,
+		OrganizationID: p.orgID,
+		Message:        p.envelope.OutboundMessage,
+		Status:         status,
+		Attempts:       p.envelope.Attempt,
+	}
+	if status == DeliveryFailed {
+		result.Error = p.lastError
+	}
+	return result
+}
+
+func newEnvelopeID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return fmt.Errorf("bot not found")
+	return hex.EncodeToString(b), nil
 }
 
 func (h *WebSocketHub) ListBots() []string {
@@ -71,6 +521,21 @@ func (h *WebSocketHub) ListBots() []string {
 	return bots
 }
 
+// GetBotsByOrganization devuelve las claves (sessionId:número) de los bots conectados de
+// la organización, ordenadas para que la elección sea estable
+func (h *WebSocketHub) GetBotsByOrganization(orgID uint) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var bots []string
+	for botKey := range h.bots {
+		if credential, ok := h.botCredentials[botKey]; ok && credential.OrganizationID == orgID {
+			bots = append(bots, botKey)
+		}
+	}
+	sort.Strings(bots)
+	return bots
+}
+
 // GetBotsBySession obtiene todos los bots de una sesión específica
 func (h *WebSocketHub) GetBotsBySession(sessionId string) []string {
 	h.mu.RLock()
@@ -95,7 +560,50 @@ func (h *WebSocketHub) GetBotConnection(botPhone string) (*websocket.Conn, error
 	defer h.mu.RUnlock()
 
 	if conn, ok := h.bots[botPhone]; ok {
-		return conn, nil
+		return conn.conn, nil
+	}
+	return nil, errBotNotFound
+}
+
+// Métodos para agentes del dashboard
+
+// RegisterAgent registra la conexión de un agente de una organización
+func (h *WebSocketHub) RegisterAgent(orgID uint, conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.agents[orgID] == nil {
+		h.agents[orgID] = make(map[*websocket.Conn]*wsConn)
+	}
+	h.agents[orgID][conn] = newWSConn(conn, h.config)
+	log.Printf("Agente registrado para organización %d (%d conectados)", orgID, len(h.agents[orgID]))
+}
+
+// UnregisterAgent elimina la conexión de un agente
+func (h *WebSocketHub) UnregisterAgent(orgID uint, conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if conns, ok := h.agents[orgID]; ok {
+		if agent, ok := conns[conn]; ok {
+			agent.close()
+			delete(conns, conn)
+		}
+		if len(conns) == 0 {
+			delete(h.agents, orgID)
+		}
+	}
+	conn.Close()
+}
+
+// BroadcastToAgents encola un evento para todos los agentes conectados de una organización
+func (h *WebSocketHub) BroadcastToAgents(orgID uint, event interface{}) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, agent := range h.agents[orgID] {
+		if err := agent.enqueue(event); err != nil {
+			log.Printf("Error enviando evento a agente (org %d): %v", orgID, err)
+		}
 	}
-	return nil, fmt.Errorf("bot not found")
 }
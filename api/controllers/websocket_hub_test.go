@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/services"
+)
+
+func setupBotWebSocketServer(t *testing.T) (*httptest.Server, *WebSocketHub, *services.BotCredentialService) {
+	return setupBotWebSocketServerWithConfig(t, DefaultHubConfig())
+}
+
+func setupBotWebSocketServerWithConfig(t *testing.T, config HubConfig) (*httptest.Server, *WebSocketHub, *services.BotCredentialService) {
+	gin.SetMode(gin.TestMode)
+	credentials, err := services.NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, "test-signing-key-0123456789abcdef")
+	require.NoError(t, err)
+
+	hub := NewWebSocketHubWithConfig(config)
+	hub.SetCredentialVerifier(credentials)
+	t.Cleanup(hub.Close)
+
+	r := gin.New()
+	r.GET("/ws", func(c *gin.Context) {
+		HandleWebSocket(c, hub, websocket.Upgrader{})
+	})
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, hub, credentials
+}
+
+func botWebSocketURL(server *httptest.Server, sessionID, token string) string {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?phone=573001234567&sessionId=" + sessionID
+	if token != "" {
+		url += "&token=" + token
+	}
+	return url
+}
+
+func TestBotWebSocketRejectsMissingCredential(t *testing.T) {
+	server, hub, _ := setupBotWebSocketServer(t)
+
+	_, resp, err := websocket.DefaultDialer.Dial(botWebSocketURL(server, "bot-1", ""), nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Empty(t, hub.ListBots())
+}
+
+func TestBotWebSocketRejectsCredentialOfAnotherSession(t *testing.T) {
+	server, _, credentials := setupBotWebSocketServer(t)
+	token, _, _, err := credentials.Issue(1, "bot-1", 0)
+	require.NoError(t, err)
+
+	_, resp, err := websocket.DefaultDialer.Dial(botWebSocketURL(server, "bot-2", token), nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestBotWebSocketRegistersAndDisconnectsOnRevoke(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServer(t)
+	token, key, _, err := credentials.Issue(7, "bot-1", 0)
+	require.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial(botWebSocketURL(server, "bot-1", token), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	orgID, ok := hub.GetBotOrganization("bot-1:573001234567")
+	require.True(t, ok)
+	assert.Equal(t, uint(7), orgID)
+
+	// Revocar la credencial cierra la conexión existente
+	require.NoError(t, credentials.Revoke(7, "bot-1", key.KeyID))
+	assert.Equal(t, 1, hub.DisconnectBotsByKeyIDs([]string{key.KeyID}))
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+	assert.Empty(t, hub.ListBots())
+
+	// Y no puede volver a conectarse
+	_, resp, err := websocket.DefaultDialer.Dial(botWebSocketURL(server, "bot-1", token), nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// connectTestBot conecta un bot con una credencial válida para la sesión bot-1
+func connectTestBot(t *testing.T, server *httptest.Server, credentials *services.BotCredentialService) *websocket.Conn {
+	token, err := credentials.EnsureToken(1, "bot-1", 0)
+	require.NoError(t, err)
+	conn, _, err := websocket.DefaultDialer.Dial(botWebSocketURL(server, "bot-1", token), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readEnvelope(t *testing.T, conn *websocket.Conn) BotEnvelope {
+	var envelope BotEnvelope
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	require.NoError(t, conn.ReadJSON(&envelope))
+	return envelope
+}
+
+func testHubConfig() HubConfig {
+	config := DefaultHubConfig()
+	config.AckTimeout = 100 * time.Millisecond
+	config.MaxDeliveryAttempts = 2
+	return config
+}
+
+func TestSendToBotAck(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	results := make(chan DeliveryResult, 1)
+	hub.SetDeliveryHandler(func(r DeliveryResult) { results <- r })
+	conn := connectTestBot(t, server, credentials)
+
+	id, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+
+	envelope := readEnvelope(t, conn)
+	assert.Equal(t, id, envelope.ID)
+	assert.Equal(t, BotFrameMessage, envelope.Type)
+	assert.Equal(t, 1, envelope.Attempt)
+	assert.Equal(t, "573009999999", envelope.To)
+
+	require.NoError(t, conn.WriteJSON(botFrame{Type: BotFrameAck, ID: id}))
+
+	select {
+	case r := <-results:
+		assert.Equal(t, DeliveryDelivered, r.Status)
+		assert.Equal(t, uint(1), r.OrganizationID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("sin resultado de entrega")
+	}
+	assert.Equal(t, 0, hub.PendingCount())
+}
+
+func TestSendToBotRetriesWithoutAckThenFails(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, testHubConfig())
+	results := make(chan DeliveryResult, 1)
+	hub.SetDeliveryHandler(func(r DeliveryResult) { results <- r })
+	conn := connectTestBot(t, server, credentials)
+
+	id, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+
+	first := readEnvelope(t, conn)
+	retry := readEnvelope(t, conn)
+	assert.Equal(t, id, retry.ID)
+	assert.Equal(t, first.Attempt+1, retry.Attempt)
+
+	select {
+	case r := <-results:
+		assert.Equal(t, DeliveryFailed, r.Status)
+		assert.Equal(t, 2, r.Attempts)
+		assert.NotEmpty(t, r.Error)
+	case <-time.After(2 * time.Second):
+		t.Fatal("el mensaje no se reportó como fallido")
+	}
+}
+
+func TestSendToBotNackExhaustsAttempts(t *testing.T) {
+	config := testHubConfig()
+	config.AckTimeout = time.Minute // solo cuentan los nacks
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, config)
+	results := make(chan DeliveryResult, 1)
+	hub.SetDeliveryHandler(func(r DeliveryResult) { results <- r })
+	conn := connectTestBot(t, server, credentials)
+
+	id, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+
+	readEnvelope(t, conn)
+	require.NoError(t, conn.WriteJSON(botFrame{Type: BotFrameNack, ID: id, Error: "número inválido"}))
+	retry := readEnvelope(t, conn)
+	assert.Equal(t, 2, retry.Attempt)
+	require.NoError(t, conn.WriteJSON(botFrame{Type: BotFrameNack, ID: id, Error: "número inválido"}))
+
+	select {
+	case r := <-results:
+		assert.Equal(t, DeliveryFailed, r.Status)
+		assert.Equal(t, "número inválido", r.Error)
+	case <-time.After(2 * time.Second):
+		t.Fatal("el mensaje no se reportó como fallido")
+	}
+}
+
+func TestSendToBotKeepsAttemptsWhileBotIsDisconnected(t *testing.T) {
+	config := testHubConfig()
+	config.AckTimeout = 300 * time.Millisecond // El bot se desconecta antes del primer reintento
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, config)
+	results := make(chan DeliveryResult, 1)
+	hub.SetDeliveryHandler(func(r DeliveryResult) { results <- r })
+	conn := connectTestBot(t, server, credentials)
+
+	id, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, readEnvelope(t, conn).Attempt)
+
+	// Una reconexión corta no gasta los intentos de entrega
+	conn.Close()
+	require.Eventually(t, func() bool { return len(hub.ListBots()) == 0 }, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(3 * config.AckTimeout)
+	select {
+	case r := <-results:
+		t.Fatalf("el mensaje se reportó como %s sin reenviarse", r.Status)
+	default:
+	}
+	assert.Equal(t, 1, hub.PendingCount())
+
+	conn = connectTestBot(t, server, credentials)
+	retry := readEnvelope(t, conn)
+	assert.Equal(t, id, retry.ID)
+	assert.Equal(t, 2, retry.Attempt)
+}
+
+func TestSendToBotFailsWhenBotDoesNotReconnect(t *testing.T) {
+	config := testHubConfig()
+	config.ReconnectWait = 200 * time.Millisecond
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, config)
+	results := make(chan DeliveryResult, 1)
+	hub.SetDeliveryHandler(func(r DeliveryResult) { results <- r })
+	conn := connectTestBot(t, server, credentials)
+
+	_, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: "hola"})
+	require.NoError(t, err)
+	readEnvelope(t, conn)
+	conn.Close()
+
+	select {
+	case r := <-results:
+		assert.Equal(t, DeliveryFailed, r.Status)
+		assert.Equal(t, 1, r.Attempts)
+		assert.Equal(t, "bot desconectado", r.Error)
+	case <-time.After(2 * time.Second):
+		t.Fatal("el mensaje no se reportó como fallido")
+	}
+	assert.Equal(t, 0, hub.PendingCount())
+}
+
+func TestSendToBotConcurrentWriters(t *testing.T) {
+	server, hub, credentials := setupBotWebSocketServer(t)
+	conn := connectTestBot(t, server, credentials)
+
+	const total = 50
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: fmt.Sprint(i)})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i := 0; i < total; i++ {
+		seen[readEnvelope(t, conn).Message] = true
+	}
+	assert.Len(t, seen, total)
+}
+
+func TestSendToBotUnknownBot(t *testing.T) {
+	hub := NewWebSocketHub()
+	defer hub.Close()
+
+	_, err := hub.SendToBot("bot-1:573001234567", OutboundMessage{To: "573009999999", Message: "hola"})
+	assert.ErrorIs(t, err, errBotNotFound)
+}
+
+func TestBotWithoutPongIsDisconnected(t *testing.T) {
+	config := DefaultHubConfig()
+	config.PingInterval = 20 * time.Millisecond
+	config.PongWait = 100 * time.Millisecond
+	server, hub, credentials := setupBotWebSocketServerWithConfig(t, config)
+
+	conn := connectTestBot(t, server, credentials)
+	// Un cliente que nunca lee no responde los pings
+	conn.SetPingHandler(func(string) error { return nil })
+
+	assert.Eventually(t, func() bool { return len(hub.ListBots()) == 0 }, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestBotFramesAreBoundToTheConnectionSession(t *testing.T) {
+	server, _, credentials := setupBotWebSocketServer(t)
+	received := make(chan IncomingMessageRequest, 4)
+	previous := messageDispatcher
+	messageDispatcher = newMessageDispatcher(DispatcherConfig{Workers: 1, MaxPending: 10}, func(msg IncomingMessageRequest) error {
+		received <- msg
+		return nil
+	})
+	t.Cleanup(func() {
+		messageDispatcher.Close()
+		messageDispatcher = previous
+	})
+	conn := connectTestBot(t, server, credentials)
+
+	// Frames que intentan hablar por otra sesión u otro bot se descartan
+	require.NoError(t, conn.WriteJSON(IncomingMessageRequest{Phone: "573009999999", Message: "x", SessionID: "bot-2"}))
+	require.NoError(t, conn.WriteJSON(IncomingMessageRequest{Phone: "573009999999", Message: "x", BotNumber: "573000000000"}))
+	require.NoError(t, conn.WriteJSON(IncomingMessageRequest{Phone: "573009999999", Message: "hola"}))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "hola", msg.Message)
+		assert.Equal(t, "bot-1", msg.SessionID)
+		assert.Equal(t, "bot-1:573001234567", msg.BotKey)
+		assert.Equal(t, uint(1), msg.OrganizationID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("el mensaje válido no llegó al dispatcher")
+	}
+	select {
+	case msg := <-received:
+		t.Fatalf("se procesó un frame de otra sesión: %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBindToConnection(t *testing.T) {
+	msg := IncomingMessageRequest{BotNumber: "573001234567@s.whatsapp.net"}
+	require.NoError(t, msg.bindToConnection("bot-1", "573001234567"))
+	assert.Equal(t, "bot-1:573001234567", msg.BotKey)
+
+	foreign := IncomingMessageRequest{SessionID: "bot-2"}
+	assert.ErrorIs(t, foreign.bindToConnection("bot-1", "573001234567"), ErrFrameSessionMismatch)
+}
+
+func TestFullDispatcherNacksIncomingMessage(t *testing.T) {
+	release := make(chan struct{})
+	previous := messageDispatcher
+	dispatcher := newMessageDispatcher(DispatcherConfig{Workers: 1, MaxPending: 1}, func(msg IncomingMessageRequest) error {
+		<-release
+		return nil
+	})
+	SetMessageDispatcher(dispatcher)
+	t.Cleanup(func() {
+		close(release)
+		dispatcher.Close()
+		SetMessageDispatcher(previous)
+	})
+
+	server, _, credentials := setupBotWebSocketServer(t)
+	conn := connectTestBot(t, server, credentials)
+
+	require.NoError(t, conn.WriteJSON(map[string]string{"id": "wa-1", "phone": "573009999999", "message": "hola"}))
+	require.NoError(t, conn.WriteJSON(map[string]string{"id": "wa-2", "phone": "573008888888", "message": "hola"}))
+
+	// La cola sigue llena: el hub pide reenviar el segundo en vez de dejar de leer
+	var frame botFrame
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	require.NoError(t, conn.ReadJSON(&frame))
+	assert.Equal(t, BotFrameIncomingNack, frame.Type)
+	assert.Equal(t, "wa-2", frame.ID)
+	assert.Equal(t, uint64(1), dispatcher.Stats().Rejected)
+}
@@ -2,15 +2,17 @@
 package controllers
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/brando1998/docubot-api/baileys"
 	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/services"
 )
 
 // WhatsAppQRResponse estructura para la respuesta del QR
@@ -50,9 +52,35 @@ type SendMessageRequest struct {
 	Message   string `json:"message" binding:"required"`
 }
 
-// BaileysRequest estructura para comunicación con Baileys
-type BaileysRequest struct {
-	Action string `json:"action"`
+var baileysClient baileys.Client
+
+// SetBaileysClient inyecta el cliente del servicio baileys-ws
+func SetBaileysClient(client baileys.Client) {
+	baileysClient = client
+}
+
+// respondBaileysError traduce un error del cliente de Baileys a una respuesta HTTP
+func respondBaileysError(c *gin.Context, message string, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, baileys.ErrSessionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrBotSessionTaken):
+		status = http.StatusForbidden
+	case errors.Is(err, baileys.ErrSessionNotConnected):
+		status = http.StatusConflict
+	case errors.Is(err, baileys.ErrInvalidRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, baileys.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
 }
 
 // GetWhatsAppQR obtiene el código QR o estado de sesión
@@ -66,76 +94,45 @@ type BaileysRequest struct {
 // @Router /api/v1/whatsapp/qr [get]
 func GetWhatsAppQR(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-
-	// URL del servicio Baileys con soporte para sesiones
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/qr", sessionId)
-
-	// Hacer solicitud a Baileys para obtener QR o estado
-	resp, err := http.Get(baileysURL)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error conectando con servicio de WhatsApp",
-			"details": err.Error(),
-		})
+	if !requireSessionOwner(c, sessionId, true) {
 		return
 	}
-	defer resp.Body.Close()
-
-	// Si la sesión no existe (404), intentar crearla automáticamente
-	if resp.StatusCode == http.StatusNotFound {
-		// Intentar crear la sesión
-		createURL := fmt.Sprintf("http://baileys:3000/sessions/%s", sessionId)
-		createResp, createErr := http.Post(createURL, "application/json", nil)
-		if createErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Error creando sesión de WhatsApp",
-				"details": createErr.Error(),
-			})
-			return
-		}
-		createResp.Body.Close()
+	ctx := c.Request.Context()
 
-		// Reintentar obtener el QR después de crear la sesión
-		resp, err = http.Get(baileysURL)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Error conectando con servicio de WhatsApp después de crear sesión",
-				"details": err.Error(),
-			})
+	qr, err := baileysClient.GetQR(ctx, sessionId)
+	// Si la sesión no existe, intentar crearla automáticamente
+	if errors.Is(err, baileys.ErrSessionNotFound) {
+		if _, createErr := createBaileysSession(c, sessionId); createErr != nil {
+			respondBaileysError(c, "Error creando sesión de WhatsApp", createErr)
 			return
 		}
-		defer resp.Body.Close()
+		qr, err = baileysClient.GetQR(ctx, sessionId)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp",
-		})
+	if err != nil {
+		respondBaileysError(c, "Error obteniendo QR de WhatsApp", err)
 		return
 	}
 
-	// Decodificar respuesta de Baileys
-	var baileysResponse WhatsAppQRResponse
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta de WhatsApp",
-			"details": err.Error(),
-		})
-		return
+	response := WhatsAppQRResponse{
+		Status:    qr.Status,
+		Message:   qr.Message,
+		QRCode:    qr.QRCode,
+		QRImage:   qr.QRImage,
+		Connected: qr.Connected,
 	}
-
-	// Agregar session_id a la respuesta si no está presente
-	if baileysResponse.SessionInfo.SessionID == "" {
-		baileysResponse.SessionInfo.SessionID = sessionId
+	response.SessionInfo.SessionID = sessionId
+	if qr.SessionInfo != nil {
+		response.SessionInfo.Number = qr.SessionInfo.Number
+		response.SessionInfo.Name = qr.SessionInfo.Name
+		response.SessionInfo.LastSeen = qr.SessionInfo.LastActivity
 	}
 
-	// Retornar respuesta de Baileys tal como la recibimos
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, response)
 }
 
 // DisconnectWhatsApp termina la sesión actual
 // @Summary Desconectar sesión de WhatsApp
-// @Description Termina la sesión activa de WhatsApp
+// @Description Termina la sesión activa de WhatsApp (logout en baileys-ws)
 // @Tags whatsapp
 // @Produce json
 // @Param sessionId query string false "ID de la sesión (opcional, usa 'default' si no se especifica)"
@@ -144,43 +141,18 @@ func GetWhatsAppQR(c *gin.Context) {
 // @Router /api/v1/whatsapp/disconnect [post]
 func DisconnectWhatsApp(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-
-	// URL del servicio Baileys para desconectar
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s", sessionId)
-
-	// Crear payload con sessionId
-	payload := map[string]string{"sessionId": sessionId}
-	jsonData, _ := json.Marshal(payload)
-
-	// Hacer solicitud POST a Baileys
-	resp, err := http.Post(baileysURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error conectando con servicio de WhatsApp",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error desconectando WhatsApp",
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
 
-	// Decodificar respuesta de Baileys
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta",
-			"details": err.Error(),
-		})
+	// baileys-ws cierra la sesión (logout) al eliminarla
+	result, err := baileysClient.DeleteSession(c.Request.Context(), sessionId)
+	if err != nil {
+		respondBaileysError(c, "Error desconectando WhatsApp", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, result)
 }
 
 // GetSessionStatus obtiene el estado detallado de la sesión
@@ -194,69 +166,39 @@ func DisconnectWhatsApp(c *gin.Context) {
 // @Router /api/v1/whatsapp/status [get]
 func GetSessionStatus(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-
-	// URL del servicio Baileys para obtener estado
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/status", sessionId)
-
-	resp, err := http.Get(baileysURL)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error conectando con servicio de WhatsApp",
-			"details": err.Error(),
-		})
+	if !requireSessionOwner(c, sessionId, true) {
 		return
 	}
-	defer resp.Body.Close()
+	ctx := c.Request.Context()
 
+	status, err := baileysClient.GetStatus(ctx, sessionId)
 	// Si la sesión no existe, crear automáticamente
-	if resp.StatusCode == http.StatusNotFound {
-		// Crear la sesión
-		createURL := fmt.Sprintf("http://baileys:3000/sessions/%s", sessionId)
-		createResp, createErr := http.Post(createURL, "application/json", nil)
-		if createErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Error creando sesión",
-				"details": createErr.Error(),
-			})
-			return
-		}
-		createResp.Body.Close()
-
-		// Reintentar obtener estado
-		resp, err = http.Get(baileysURL)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Error conectando después de crear sesión",
-				"details": err.Error(),
-			})
+	if errors.Is(err, baileys.ErrSessionNotFound) {
+		if _, createErr := createBaileysSession(c, sessionId); createErr != nil {
+			respondBaileysError(c, "Error creando sesión", createErr)
 			return
 		}
-		defer resp.Body.Close()
+		status, err = baileysClient.GetStatus(ctx, sessionId)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":       "Error obteniendo estado de WhatsApp",
-			"status_code": resp.StatusCode,
-		})
+	if err != nil {
+		respondBaileysError(c, "Error obteniendo estado de WhatsApp", err)
 		return
 	}
 
-	var baileysResponse WhatsAppStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta",
-			"details": err.Error(),
-		})
-		return
+	response := WhatsAppStatusResponse{
+		Status:    "disconnected",
+		Message:   status.Status.LastDisconnectReason,
+		Connected: status.Status.Connected,
+		BotNumber: status.Status.Number,
+		LastSeen:  status.Status.LastActivity,
 	}
-
-	// Agregar session_id si no está presente
-	if baileysResponse.SessionInfo.SessionID == "" {
-		baileysResponse.SessionInfo.SessionID = sessionId
+	if status.Status.Connected {
+		response.Status = "connected"
 	}
+	response.SessionInfo.SessionID = sessionId
+	response.SessionInfo.Name = status.Status.Name
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, response)
 }
 
 // GetChatList obtiene la lista de chats de una sesión
@@ -270,37 +212,17 @@ func GetSessionStatus(c *gin.Context) {
 // @Router /api/v1/whatsapp/chats [get]
 func GetChatList(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-
-	// URL del servicio Baileys para obtener chats
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/chats", sessionId)
-
-	resp, err := http.Get(baileysURL)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error conectando con servicio de WhatsApp",
-			"details": err.Error(),
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error obteniendo lista de chats",
-		})
-		return
-	}
-
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta de chats",
-			"details": err.Error(),
-		})
+	chats, err := baileysClient.GetChats(c.Request.Context(), sessionId)
+	if err != nil {
+		respondBaileysError(c, "Error obteniendo lista de chats", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, chats)
 }
 
 // GetChatMessages obtiene los mensajes de un chat específico
@@ -316,39 +238,23 @@ func GetChatList(c *gin.Context) {
 // @Router /api/v1/whatsapp/chats/{chatId}/messages [get]
 func GetChatMessages(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-	chatId := c.Param("chatId")
-	limit := c.DefaultQuery("limit", "50")
-
-	// URL del servicio Baileys para obtener mensajes
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/chats/%s/messages?limit=%s", sessionId, chatId, limit)
-
-	resp, err := http.Get(baileysURL)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error conectando con servicio de WhatsApp",
-			"details": err.Error(),
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error obteniendo mensajes del chat",
-		})
+	chatId := c.Param("chatId")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
 		return
 	}
 
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta de mensajes",
-			"details": err.Error(),
-		})
+	messages, err := baileysClient.GetMessages(c.Request.Context(), sessionId, chatId, limit)
+	if err != nil {
+		respondBaileysError(c, "Error obteniendo mensajes del chat", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, messages)
 }
 
 // SaveDocument guarda un documento generado
@@ -363,6 +269,13 @@ func GetChatMessages(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/documents [post]
 func SaveDocument(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
 	var requestData map[string]interface{}
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -381,6 +294,9 @@ func SaveDocument(c *gin.Context) {
 		return
 	}
 	clientID := uint(clientIDFloat)
+	if !clientBelongsToOrg(c, clientID, orgID) {
+		return
+	}
 
 	docType, ok := requestData["type"].(string)
 	if !ok {
@@ -400,14 +316,22 @@ func SaveDocument(c *gin.Context) {
 
 	// Crear documento
 	document := models.Document{
-		ClientID: clientID,
-		FileName: fileName,
-		Type:     docType,
-		Status:   "completed",
+		OrganizationID: orgID,
+		ClientID:       clientID,
+		FileName:       fileName,
+		Type:           docType,
+		Status:         models.DocumentStatusCompleted,
 	}
 
 	// Agregar campos opcionales
-	if url, ok := requestData["url"].(string); ok {
+	if url, ok := requestData["url"].(string); ok && url != "" {
+		if err := validateDocumentURL(url); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "url inválida",
+				"details": err.Error(),
+			})
+			return
+		}
 		document.URL = url
 	}
 
@@ -427,6 +351,23 @@ func SaveDocument(c *gin.Context) {
 		document.BotID = uint(botIDFloat)
 	}
 
+	// Estado opcional: por defecto el documento llega ya generado
+	if status, ok := requestData["status"].(string); ok && status != "" {
+		if !models.IsValidDocumentStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "status inválido",
+			})
+			return
+		}
+		document.Status = status
+	}
+	errMessage, _ := requestData["error"].(string)
+	if document.Status == models.DocumentStatusGenerating {
+		document.StartAttempt("api", time.Now())
+	} else {
+		document.Finish(document.Status, errMessage, time.Now())
+	}
+
 	// Guardar en base de datos
 	if err := conversationRepo.SaveDocument(c.Request.Context(), document); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -453,6 +394,13 @@ func SaveDocument(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/clients/{clientId}/documents [get]
 func GetClientDocuments(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
 	clientIDStr := c.Param("clientId")
 	clientID := parseUint(clientIDStr)
 	if clientID == 0 {
@@ -461,8 +409,11 @@ func GetClientDocuments(c *gin.Context) {
 		})
 		return
 	}
+	if !clientBelongsToOrg(c, clientID, orgID) {
+		return
+	}
 
-	documents, err := conversationRepo.GetDocumentsByClientID(c.Request.Context(), clientID)
+	documents, err := conversationRepo.GetDocumentsByClientID(c.Request.Context(), clientID, orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Error obteniendo documentos",
@@ -489,6 +440,13 @@ func GetClientDocuments(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/chats/mode [post]
 func UpdateChatMode(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
 	var requestData map[string]interface{}
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -507,6 +465,9 @@ func UpdateChatMode(c *gin.Context) {
 		return
 	}
 	clientID := uint(clientIDFloat)
+	if !clientBelongsToOrg(c, clientID, orgID) {
+		return
+	}
 
 	sessionID, ok := requestData["session_id"].(string)
 	if !ok {
@@ -534,10 +495,11 @@ func UpdateChatMode(c *gin.Context) {
 
 	// Crear o actualizar modo de chat
 	chatMode := models.ChatMode{
-		ClientID:  clientID,
-		SessionID: sessionID,
-		ChatID:    chatID,
-		BotMode:   botMode,
+		OrganizationID: orgID,
+		ClientID:       clientID,
+		SessionID:      sessionID,
+		ChatID:         chatID,
+		BotMode:        botMode,
 	}
 
 	if botIDFloat, ok := requestData["bot_id"].(float64); ok {
@@ -572,6 +534,13 @@ func UpdateChatMode(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/chats/archive [post]
 func ArchiveChat(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
 	var requestData map[string]interface{}
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -590,6 +559,9 @@ func ArchiveChat(c *gin.Context) {
 		return
 	}
 	clientID := uint(clientIDFloat)
+	if !clientBelongsToOrg(c, clientID, orgID) {
+		return
+	}
 
 	chatID, ok := requestData["chat_id"].(string)
 	if !ok {
@@ -609,10 +581,11 @@ func ArchiveChat(c *gin.Context) {
 
 	// Crear registro de archivo
 	archive := models.ChatArchive{
-		ClientID: clientID,
-		ChatID:   chatID,
-		ChatName: chatName,
-		IsGroup:  false, // Por defecto, cambiar si es necesario
+		OrganizationID: orgID,
+		ClientID:       clientID,
+		ChatID:         chatID,
+		ChatName:       chatName,
+		IsGroup:        false, // Por defecto, cambiar si es necesario
 	}
 
 	if sessionID, ok := requestData["session_id"].(string); ok {
@@ -656,6 +629,13 @@ func ArchiveChat(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/conversations/{clientId}/export [get]
 func ExportConversation(c *gin.Context) {
+	orgIDInterface, exists := c.Get("organization_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Organización no encontrada"})
+		return
+	}
+	orgID := orgIDInterface.(uint)
+
 	clientIDStr := c.Param("clientId")
 	clientID := parseUint(clientIDStr)
 	if clientID == 0 {
@@ -664,6 +644,9 @@ func ExportConversation(c *gin.Context) {
 		})
 		return
 	}
+	if !clientBelongsToOrg(c, clientID, orgID) {
+		return
+	}
 
 	sessionID := c.Query("sessionId")
 	if sessionID == "" {
@@ -681,7 +664,7 @@ func ExportConversation(c *gin.Context) {
 		return
 	}
 
-	messages, err := conversationRepo.ExportConversation(c.Request.Context(), clientID, sessionID, chatID)
+	messages, err := conversationRepo.ExportConversation(c.Request.Context(), clientID, sessionID, chatID, orgID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Error exportando conversación",
@@ -699,6 +682,15 @@ func ExportConversation(c *gin.Context) {
 	})
 }
 
+// clientBelongsToOrg verifica que el cliente sea de la organización; si no, responde 404
+func clientBelongsToOrg(c *gin.Context, clientID uint, orgID uint) bool {
+	if _, err := clientRepo.GetClientByID(clientID, orgID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cliente no encontrado"})
+		return false
+	}
+	return true
+}
+
 // Función auxiliar para convertir string a uint
 func parseUint(s string) uint {
 	// Implementación simple, en producción usar strconv.ParseUint
@@ -741,50 +733,24 @@ func SendChatMessage(c *gin.Context) {
 	if sessionId == "" {
 		sessionId = "default"
 	}
-
-	// Preparar mensaje para Baileys
-	messagePayload := map[string]string{
-		"number":  chatId,
-		"message": request.Message,
-	}
-
-	jsonData, err := json.Marshal(messagePayload)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error preparando mensaje",
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
 
 	// Enviar a Baileys con sesión específica
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/send", sessionId)
-	resp, err := http.Post(baileysURL, "application/json", bytes.NewBuffer(jsonData))
+	result, err := baileysClient.SendMessage(c.Request.Context(), sessionId, baileys.SendRequest{
+		Number:  chatId,
+		Message: request.Message,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error enviando mensaje",
-			"details": err.Error(),
-		})
+		respondBaileysError(c, "Error enviando mensaje", err)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp",
-		})
-		return
-	}
+	// El agente sigue atendiendo el chat
+	touchChatMode(c.Request.Context(), c.GetUint("organization_id"), sessionId, chatId)
 
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, result)
 }
 
 // SendWhatsAppMessage envía un mensaje por WhatsApp
@@ -813,50 +779,24 @@ func SendWhatsAppMessage(c *gin.Context) {
 	if sessionId == "" {
 		sessionId = "default"
 	}
-
-	// Preparar mensaje para Baileys
-	messagePayload := map[string]string{
-		"number":  request.To,
-		"message": request.Message,
-	}
-
-	jsonData, err := json.Marshal(messagePayload)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error preparando mensaje",
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
 
 	// Enviar a Baileys con sesión específica
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/send", sessionId)
-	resp, err := http.Post(baileysURL, "application/json", bytes.NewBuffer(jsonData))
+	result, err := baileysClient.SendMessage(c.Request.Context(), sessionId, baileys.SendRequest{
+		Number:  request.To,
+		Message: request.Message,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error enviando mensaje",
-			"details": err.Error(),
-		})
+		respondBaileysError(c, "Error enviando mensaje", err)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp",
-		})
-		return
-	}
-
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta",
-			"details": err.Error(),
-		})
-		return
-	}
+	// El agente sigue atendiendo el chat
+	touchChatMode(c.Request.Context(), c.GetUint("organization_id"), sessionId, request.To)
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, result)
 }
 
 // RestartWhatsAppSession reinicia la sesión completa de WhatsApp
@@ -870,38 +810,17 @@ func SendWhatsAppMessage(c *gin.Context) {
 // @Router /api/v1/whatsapp/restart [post]
 func RestartWhatsAppSession(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-
-	// URL para reiniciar sesión en Baileys
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s/restart", sessionId)
-
-	resp, err := http.Post(baileysURL, "application/json", nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error reiniciando sesión de WhatsApp",
-			"details": err.Error(),
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp al reiniciar",
-		})
-		return
-	}
-
-	// Decodificar respuesta de Baileys
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta de reinicio",
-			"details": err.Error(),
-		})
+	result, err := baileysClient.RestartSession(c.Request.Context(), sessionId)
+	if err != nil {
+		respondBaileysError(c, "Error reiniciando sesión de WhatsApp", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, result)
 }
 
 // ClearWhatsAppSession limpia las credenciales de WhatsApp
@@ -915,49 +834,17 @@ func RestartWhatsAppSession(c *gin.Context) {
 // @Router /api/v1/whatsapp/clear-session [post]
 func ClearWhatsAppSession(c *gin.Context) {
 	sessionId := c.DefaultQuery("sessionId", "default")
-
-	// URL para limpiar credenciales en Baileys
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s", sessionId)
-
-	// Crear request DELETE
-	req, err := http.NewRequest("DELETE", baileysURL, nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error creando solicitud",
-			"details": err.Error(),
-		})
+	if !requireSessionOwner(c, sessionId, false) {
 		return
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	result, err := baileysClient.DeleteSession(c.Request.Context(), sessionId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error limpiando credenciales de WhatsApp",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp al limpiar credenciales",
-		})
+		respondBaileysError(c, "Error limpiando credenciales de WhatsApp", err)
 		return
 	}
 
-	// Decodificar respuesta de Baileys
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta de limpieza",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, result)
 }
 
 // CreateWhatsAppSession crea una nueva sesión de WhatsApp
@@ -988,37 +875,17 @@ func CreateWhatsAppSession(c *gin.Context) {
 		})
 		return
 	}
-
-	// URL para crear sesión en Baileys
-	baileysURL := fmt.Sprintf("http://baileys:3000/sessions/%s", sessionId)
-
-	resp, err := http.Post(baileysURL, "application/json", nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error creando sesión de WhatsApp",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp al crear sesión",
-		})
+	if !requireSessionOwner(c, sessionId, true) {
 		return
 	}
 
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta",
-			"details": err.Error(),
-		})
+	result, err := createBaileysSession(c, sessionId)
+	if err != nil {
+		respondBaileysError(c, "Error creando sesión de WhatsApp", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, result)
 }
 
 // ListWhatsAppSessions lista todas las sesiones activas
@@ -1030,34 +897,11 @@ func CreateWhatsAppSession(c *gin.Context) {
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/whatsapp/sessions [get]
 func ListWhatsAppSessions(c *gin.Context) {
-	// URL para listar sesiones en Baileys
-	baileysURL := "http://baileys:3000/sessions"
-
-	resp, err := http.Get(baileysURL)
+	sessions, err := baileysClient.ListSessions(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error obteniendo lista de sesiones",
-			"details": err.Error(),
-		})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Error en el servicio de WhatsApp",
-		})
-		return
-	}
-
-	var baileysResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&baileysResponse); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error procesando respuesta",
-			"details": err.Error(),
-		})
+		respondBaileysError(c, "Error obteniendo lista de sesiones", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, baileysResponse)
+	c.JSON(http.StatusOK, sessions)
 }
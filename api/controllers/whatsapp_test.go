@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/baileys"
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/services"
+)
+
+// touchOnlyConversationRepo registra las llamadas a TouchChatMode
+type touchOnlyConversationRepo struct {
+	repositories.ConversationRepository
+	touched []string
+}
+
+func (r *touchOnlyConversationRepo) TouchChatMode(ctx context.Context, sessionID string, chatIDs []string, orgID uint) error {
+	r.touched = append(r.touched, fmt.Sprintf("%d|%s|%s", orgID, sessionID, chatIDs[0]))
+	return nil
+}
+
+func setupWhatsAppRouter(fake *baileys.Fake) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	SetBaileysClient(fake)
+	credentials, _ := services.NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, "test-signing-key-0123456789abcdef")
+	SetBotCredentialService(credentials)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("organization_id", uint(1))
+		c.Set("current_user_id", uint(1))
+		c.Next()
+	})
+	r.GET("/whatsapp/qr", GetWhatsAppQR)
+	r.GET("/whatsapp/status", GetSessionStatus)
+	r.GET("/whatsapp/chats", GetChatList)
+	r.POST("/whatsapp/send", SendWhatsAppMessage)
+	r.POST("/whatsapp/disconnect", DisconnectWhatsApp)
+	r.POST("/whatsapp/restart", RestartWhatsAppSession)
+	r.POST("/whatsapp/clear-session", ClearWhatsAppSession)
+	r.POST("/whatsapp/sessions", CreateWhatsAppSession)
+	return r
+}
+
+// claimSession emite la credencial de la sesión para la organización, como al crearla
+func claimSession(t *testing.T, orgID uint, sessionID string) {
+	_, _, _, err := botCredentialService.Issue(orgID, sessionID, 1)
+	require.NoError(t, err)
+}
+
+func TestGetWhatsAppQRCreatesMissingSession(t *testing.T) {
+	fake := baileys.NewFake()
+	r := setupWhatsAppRouter(fake)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whatsapp/qr?sessionId=bot-1", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp WhatsAppQRResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, baileys.QRStatusWaitingForScan, resp.Status)
+	assert.NotEmpty(t, resp.QRCode)
+	assert.Equal(t, "bot-1", resp.SessionInfo.SessionID)
+
+	sessions, err := fake.ListSessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sessions.Total)
+
+	// La sesión recibe la credencial para conectarse a /ws
+	_, err = botCredentialService.Verify("bot-1", fake.WSToken("bot-1"))
+	assert.NoError(t, err)
+}
+
+func TestGetSessionStatusConnected(t *testing.T) {
+	fake := baileys.NewFake()
+	fake.AddSession("bot-1", true, "573001234567")
+	r := setupWhatsAppRouter(fake)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whatsapp/status?sessionId=bot-1", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp WhatsAppStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "connected", resp.Status)
+	assert.True(t, resp.Connected)
+	assert.Equal(t, "573001234567", resp.BotNumber)
+}
+
+func TestGetChatListSessionNotConnected(t *testing.T) {
+	fake := baileys.NewFake()
+	fake.AddSession("bot-1", false, "")
+	r := setupWhatsAppRouter(fake)
+	claimSession(t, 1, "bot-1")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whatsapp/chats?sessionId=bot-1", nil))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSendWhatsAppMessage(t *testing.T) {
+	repo := &touchOnlyConversationRepo{}
+	previous := conversationRepo
+	conversationRepo = repo
+	defer func() { conversationRepo = previous }()
+
+	fake := baileys.NewFake()
+	fake.AddSession("bot-1", true, "573001234567")
+	r := setupWhatsAppRouter(fake)
+	claimSession(t, 1, "bot-1")
+
+	body := `{"session_id":"bot-1","to":"573009999999","message":"hola"}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/whatsapp/send", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, fake.Sent, 1)
+	assert.Equal(t, "bot-1", fake.Sent[0].SessionID)
+	assert.Equal(t, "573009999999", fake.Sent[0].Number)
+	assert.Equal(t, []string{"1|bot-1|573009999999"}, repo.touched)
+}
+
+func TestSendWhatsAppMessageBaileysUnavailable(t *testing.T) {
+	fake := baileys.NewFake()
+	fake.Err = baileys.ErrUnavailable
+	r := setupWhatsAppRouter(fake)
+	claimSession(t, 1, "default")
+
+	body := `{"to":"573009999999","message":"hola"}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/whatsapp/send", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestWhatsAppSessionHandlersRejectOtherOrganization(t *testing.T) {
+	fake := baileys.NewFake()
+	fake.AddSession("bot-2", true, "573007654321")
+	r := setupWhatsAppRouter(fake)
+	claimSession(t, 2, "bot-2")
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/whatsapp/qr?sessionId=bot-2", ""},
+		{http.MethodGet, "/whatsapp/status?sessionId=bot-2", ""},
+		{http.MethodGet, "/whatsapp/chats?sessionId=bot-2", ""},
+		{http.MethodPost, "/whatsapp/disconnect?sessionId=bot-2", ""},
+		{http.MethodPost, "/whatsapp/restart?sessionId=bot-2", ""},
+		{http.MethodPost, "/whatsapp/clear-session?sessionId=bot-2", ""},
+		{http.MethodPost, "/whatsapp/sessions", `{"sessionId":"bot-2"}`},
+		{http.MethodPost, "/whatsapp/send", `{"session_id":"bot-2","to":"573009999999","message":"hola"}`},
+	}
+	for _, tc := range requests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, "%s %s", tc.method, tc.path)
+	}
+
+	// La sesión de la otra organización sigue intacta
+	assert.Empty(t, fake.Sent)
+	sessions, err := fake.ListSessions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sessions.Total)
+}
+
+func TestWhatsAppSessionHandlersRequireClaimedSession(t *testing.T) {
+	fake := baileys.NewFake()
+	fake.AddSession("bot-1", true, "573001234567")
+	r := setupWhatsAppRouter(fake)
+
+	// Una sesión que ninguna organización ha reclamado no se puede operar
+	for _, path := range []string{"/whatsapp/disconnect?sessionId=bot-1", "/whatsapp/restart?sessionId=bot-1", "/whatsapp/clear-session?sessionId=bot-1"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+
+	claimSession(t, 1, "bot-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/whatsapp/restart?sessionId=bot-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	errConnectionClosed = errors.New("conexión cerrada")
+	errSendQueueFull    = errors.New("cola de envío llena")
+)
+
+// wsConn envuelve una conexión de gorilla/websocket con una cola de salida y una
+// única goroutine escritora: gorilla no permite escrituras concurrentes
+type wsConn struct {
+	conn   *websocket.Conn
+	config HubConfig
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newWSConn(conn *websocket.Conn, config HubConfig) *wsConn {
+	c := &wsConn{
+		conn:   conn,
+		config: config,
+		send:   make(chan []byte, config.SendQueueSize),
+		done:   make(chan struct{}),
+	}
+
+	// Keepalive: cada pong extiende el deadline de lectura; sin pong la lectura falla
+	conn.SetReadLimit(config.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	})
+
+	go c.writePump()
+	return c
+}
+
+// enqueue serializa el mensaje y lo deja en la cola sin bloquear
+func (c *wsConn) enqueue(message interface{}) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+		return errConnectionClosed
+	default:
+	}
+
+	select {
+	case c.send <- payload:
+		return nil
+	case <-c.done:
+		return errConnectionClosed
+	default:
+		return errSendQueueFull
+	}
+}
+
+// writePump es el único escritor de la conexión: mensajes de la cola y pings periódicos
+func (c *wsConn) writePump() {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				log.Printf("Error escribiendo en WebSocket: %v", err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.config.WriteWait)); err != nil {
+				log.Printf("Ping fallido, cerrando conexión: %v", err)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// closeWithReason envía un frame de cierre antes de cerrar (WriteControl admite concurrencia)
+func (c *wsConn) closeWithReason(code int, reason string) {
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+	c.close()
+}
+
+func (c *wsConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -41,3 +43,21 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// InternalTokenMiddleware protege las rutas que llaman otros servicios (p. ej. las acciones
+// de Rasa) con el token compartido INTERNAL_API_TOKEN en el header X-Internal-Token.
+// Sin token configurado las rutas quedan deshabilitadas.
+func InternalTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "API interna deshabilitada"})
+			return
+		}
+		received := c.GetHeader("X-Internal-Token")
+		if subtle.ConstantTimeCompare([]byte(received), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token interno inválido"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -3,15 +3,14 @@ package middleware
 import (
 	"errors"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/o1egl/paseto"
+	"github.com/gorilla/websocket"
 
 	database "github.com/brando1998/docubot-api/databases"
 	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/tokens"
 )
 
 var (
@@ -20,16 +19,51 @@ var (
 	ErrUnsupportedTokenType = errors.New("tipo de token no soportado")
 )
 
-// PasetoAuthMiddleware verifica tokens PASETO para usuarios del sistema
+var tokenManager *tokens.Manager
+
+// SetTokenManager inyecta las llaves con que se verifican los access tokens
+func SetTokenManager(manager *tokens.Manager) {
+	tokenManager = manager
+}
+
+// APIKeyAuthenticator valida las API keys de los clientes máquina
+type APIKeyAuthenticator interface {
+	Authenticate(raw, ip string) (*models.APIKey, error)
+}
+
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// SetAPIKeyAuthenticator inyecta el validador de API keys; sin él solo se aceptan tokens de usuario
+func SetAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// PasetoAuthMiddleware verifica tokens PASETO para usuarios del sistema. También acepta
+// API keys (Authorization: Bearer dbk_... o X-API-Key): la petición queda limitada a la
+// organización y los permisos (scopes) de la key, sin usuario.
 func PasetoAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+		if token, err := extractToken(c); err == nil && strings.HasPrefix(token, models.APIKeyPrefix) {
+			authenticateAPIKey(c, token)
+			return
+		}
+
+		if tokenManager == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "autenticación no configurada"})
+			return
+		}
+
 		token, err := extractToken(c)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
 
-		payload, err := verifyPasetoToken(token)
+		payload, err := tokenManager.Verify(token)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
@@ -48,6 +82,12 @@ func PasetoAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Los tokens emitidos antes de cerrar sesión en todos los dispositivos ya no sirven
+		if payload.TokenVersion != user.TokenVersion {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "sesión revocada"})
+			return
+		}
+
 		// Verificar que el usuario esté activo
 		if !user.IsActive {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "usuario inactivo"})
@@ -60,39 +100,94 @@ func PasetoAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		permissions, err := resolvePermissions(user)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error obteniendo permisos"})
+			return
+		}
+
 		// Almacenar datos en el contexto
 		c.Set("current_user_id", payload.UserID)
-		c.Set("current_user_role", payload.Role)
+		c.Set("current_user_role", user.Role)
 		c.Set("organization_id", user.OrganizationID) // 🆕 Incluir organization_id
 		c.Set("current_user", user)
+		c.Set("current_user_permissions", permissions)
 		c.Next()
 	}
 }
 
-// verifyPasetoToken verifica y decodifica el token usando models.PasetoPayload
-func verifyPasetoToken(token string) (*models.PasetoPayload, error) {
-	v2 := paseto.NewV2()
-	var payload models.PasetoPayload
-
-	secretKey := []byte(os.Getenv("PASETO_SECRET_KEY"))
-	if len(secretKey) == 0 {
-		secretKey = []byte("default-secret-key-change-in-production-32-chars")
+// authenticateAPIKey autentica la petición con una API key y deja en el contexto su
+// organización y sus scopes como permisos
+func authenticateAPIKey(c *gin.Context, raw string) {
+	if apiKeyAuthenticator == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API keys no habilitadas"})
+		return
 	}
-
-	if err := v2.Decrypt(token, secretKey, &payload, nil); err != nil {
-		return nil, ErrInvalidToken
+	key, err := apiKeyAuthenticator.Authenticate(raw, c.ClientIP())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key inválida, revocada o vencida"})
+		return
+	}
+	if !key.Organization.IsActive {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "organización inactiva"})
+		return
 	}
 
-	if time.Now().After(payload.ExpiresAt) {
-		return nil, errors.New("token expirado")
+	c.Set("organization_id", key.OrganizationID)
+	c.Set("api_key_id", key.ID)
+	c.Set("current_user_permissions", key.Scopes)
+	c.Next()
+}
+
+// PermissionResolver devuelve los permisos de un rol de la organización
+type PermissionResolver interface {
+	Permissions(orgID uint, role string) ([]string, error)
+}
+
+var permissionResolver PermissionResolver
+
+// SetPermissionResolver inyecta el resolvedor de roles personalizados; sin él solo se
+// conocen los roles predefinidos
+func SetPermissionResolver(resolver PermissionResolver) {
+	permissionResolver = resolver
+}
+
+func resolvePermissions(user models.SystemUser) ([]string, error) {
+	if permissionResolver == nil {
+		return models.BuiltInRoles[user.Role], nil
 	}
+	return permissionResolver.Permissions(user.OrganizationID, user.Role)
+}
 
-	return &payload, nil
+// RequirePermission deja pasar solo a los usuarios cuyo rol incluye el permiso. Usa los
+// permisos que resolvió PasetoAuthMiddleware con el rol guardado en la base de datos,
+// así un cambio de rol aplica de inmediato.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("current_user_permissions")
+		permissions, ok := value.([]string)
+		if !exists || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+			return
+		}
+		if !models.HasPermission(permissions, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Permiso insuficiente",
+				"details": "se requiere " + permission,
+			})
+			return
+		}
+		c.Next()
+	}
 }
 
 func extractToken(c *gin.Context) (string, error) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
+		// Los navegadores no pueden enviar cabeceras al abrir un WebSocket
+		if websocket.IsWebSocketUpgrade(c.Request) && c.Query("token") != "" {
+			return c.Query("token"), nil
+		}
 		return "", errors.New("cabecera de autorización no proporcionada")
 	}
 
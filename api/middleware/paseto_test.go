@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+const testAPIKey = models.APIKeyPrefix + "abc123_secreto"
+
+// fakeAPIKeys acepta solo testAPIKey, de la organización 7 con permiso de lectura de clientes
+type fakeAPIKeys struct{}
+
+func (fakeAPIKeys) Authenticate(raw, ip string) (*models.APIKey, error) {
+	if raw != testAPIKey {
+		return nil, errors.New("API key desconocida")
+	}
+	return &models.APIKey{
+		ID:             3,
+		OrganizationID: 7,
+		Organization:   models.Organization{IsActive: true},
+		Scopes:         []string{models.PermissionClientsRead},
+	}, nil
+}
+
+func setupAPIKeyRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	previous := apiKeyAuthenticator
+	SetAPIKeyAuthenticator(fakeAPIKeys{})
+	t.Cleanup(func() { SetAPIKeyAuthenticator(previous) })
+
+	r := gin.New()
+	r.Use(PasetoAuthMiddleware())
+	handler := func(c *gin.Context) {
+		_, hasUser := c.Get("current_user_id")
+		c.JSON(http.StatusOK, gin.H{
+			"organization_id": c.GetUint("organization_id"),
+			"api_key_id":      c.GetUint("api_key_id"),
+			"has_user":        hasUser,
+		})
+	}
+	r.GET("/clients", RequirePermission(models.PermissionClientsRead), handler)
+	r.GET("/users", RequirePermission(models.PermissionUsersManage), handler)
+	return r
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	r := setupAPIKeyRouter(t)
+
+	headers := map[string][2]string{
+		"X-API-Key":     {"X-API-Key", testAPIKey},
+		"Bearer dbk_":   {"Authorization", "Bearer " + testAPIKey},
+		"bearer (case)": {"Authorization", "bearer " + testAPIKey},
+	}
+	for name, header := range headers {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/clients", nil)
+		req.Header.Set(header[0], header[1])
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, name)
+
+		var resp struct {
+			OrganizationID uint `json:"organization_id"`
+			APIKeyID       uint `json:"api_key_id"`
+			HasUser        bool `json:"has_user"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, uint(7), resp.OrganizationID, name)
+		assert.Equal(t, uint(3), resp.APIKeyID, name)
+		assert.False(t, resp.HasUser, "%s: una API key no tiene usuario", name)
+	}
+}
+
+func TestAPIKeyAuthenticationRejections(t *testing.T) {
+	r := setupAPIKeyRouter(t)
+
+	cases := []struct {
+		name, path, header, value string
+		status                    int
+	}{
+		{"key inválida en X-API-Key", "/clients", "X-API-Key", models.APIKeyPrefix + "otra", http.StatusUnauthorized},
+		{"key inválida en Bearer", "/clients", "Authorization", "Bearer " + models.APIKeyPrefix + "otra", http.StatusUnauthorized},
+		{"scope no asignado", "/users", "X-API-Key", testAPIKey, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set(tc.header, tc.value)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code, tc.name)
+	}
+
+	// Sin validador configurado las API keys no se aceptan
+	SetAPIKeyAuthenticator(nil)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/clients", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,99 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockAPIKeyRepo es una implementación en memoria de APIKeyRepository; GetByPrefix no
+// carga la organización
+type MockAPIKeyRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	Keys   []*models.APIKey
+}
+
+func (m *MockAPIKeyRepo) Create(key *models.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.Prefix == key.Prefix {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	m.nextID++
+	key.ID = m.nextID
+	key.CreatedAt = time.Now()
+	key.UpdatedAt = key.CreatedAt
+	stored := *key
+	m.Keys = append(m.Keys, &stored)
+	return nil
+}
+
+func (m *MockAPIKeyRepo) List(orgID uint) ([]models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []models.APIKey
+	for _, k := range m.Keys {
+		if k.OrganizationID == orgID {
+			keys = append(keys, *k)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].ID > keys[j].ID })
+	return keys, nil
+}
+
+func (m *MockAPIKeyRepo) GetByID(id, orgID uint) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.ID == id && k.OrganizationID == orgID {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockAPIKeyRepo) GetByPrefix(prefix string) (*models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.Prefix == prefix {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockAPIKeyRepo) Revoke(id, orgID uint, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.ID == id && k.OrganizationID == orgID && k.RevokedAt == nil {
+			k.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *MockAPIKeyRepo) TouchLastUsed(id uint, at time.Time, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.ID == id {
+			k.LastUsedAt = &at
+			k.LastUsedIP = ip
+		}
+	}
+	return nil
+}
+
+var _ repositories.APIKeyRepository = (*MockAPIKeyRepo)(nil)
@@ -0,0 +1,121 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockBotSessionKeyRepo es una implementación en memoria de BotSessionKeyRepository
+type MockBotSessionKeyRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	Keys   []*models.BotSessionKey
+}
+
+func (m *MockBotSessionKeyRepo) Create(key *models.BotSessionKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	key.ID = m.nextID
+	key.CreatedAt = time.Now()
+	stored := *key
+	m.Keys = append(m.Keys, &stored)
+	return nil
+}
+
+func (m *MockBotSessionKeyRepo) GetByKeyID(keyID string) (*models.BotSessionKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.KeyID == keyID {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockBotSessionKeyRepo) GetBySessionID(sessionID string, orgID uint) ([]models.BotSessionKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []models.BotSessionKey
+	for i := len(m.Keys) - 1; i >= 0; i-- {
+		if k := m.Keys[i]; k.SessionID == sessionID && k.OrganizationID == orgID {
+			keys = append(keys, *k)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MockBotSessionKeyRepo) GetSessionOrganization(sessionID string) (uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.SessionID == sessionID {
+			return k.OrganizationID, nil
+		}
+	}
+	return 0, gorm.ErrRecordNotFound
+}
+
+func (m *MockBotSessionKeyRepo) ListSessions(orgID uint) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := map[string]bool{}
+	var sessions []string
+	for _, k := range m.Keys {
+		if k.OrganizationID == orgID && k.IsActive() && !seen[k.SessionID] {
+			seen[k.SessionID] = true
+			sessions = append(sessions, k.SessionID)
+		}
+	}
+	sort.Strings(sessions)
+	return sessions, nil
+}
+
+func (m *MockBotSessionKeyRepo) Revoke(keyID, sessionID string, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.KeyID == keyID && k.SessionID == sessionID && k.OrganizationID == orgID && k.RevokedAt == nil {
+			now := time.Now()
+			k.RevokedAt = &now
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockBotSessionKeyRepo) RevokeBySessionID(sessionID string, orgID uint) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var revoked []string
+	now := time.Now()
+	for _, k := range m.Keys {
+		if k.SessionID == sessionID && k.OrganizationID == orgID && k.RevokedAt == nil {
+			k.RevokedAt = &now
+			revoked = append(revoked, k.KeyID)
+		}
+	}
+	return revoked, nil
+}
+
+func (m *MockBotSessionKeyRepo) TouchLastUsed(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.Keys {
+		if k.ID == id {
+			now := time.Now()
+			k.LastUsedAt = &now
+		}
+	}
+	return nil
+}
+
+var _ repositories.BotSessionKeyRepository = (*MockBotSessionKeyRepo)(nil)
@@ -10,34 +10,42 @@ import (
 
 type MockClientRepo struct {
 	CreateClientFunc      func(user *models.Client) error
-	GetClientByIDFunc     func(id uint) (*models.Client, error)
-	GetClientByPhoneFunc  func(phone string) (*models.Client, error)
-	GetOrCreateClientFunc func(phone, name, email string) (*models.Client, error)
+	GetClientByIDFunc     func(id uint, orgID uint) (*models.Client, error)
+	GetClientByPhoneFunc  func(phone string, orgID uint) (*models.Client, error)
+	GetOrCreateClientFunc func(phone, name, email string, orgID uint) (*models.Client, error)
+	GetAllClientsFunc     func(orgID uint) ([]models.Client, error)
 }
 
 func (m *MockClientRepo) CreateClient(user *models.Client) error {
 	return m.CreateClientFunc(user)
 }
 
-func (m *MockClientRepo) GetClientByID(id uint) (*models.Client, error) {
-	return m.GetClientByIDFunc(id)
+func (m *MockClientRepo) GetClientByID(id uint, orgID uint) (*models.Client, error) {
+	return m.GetClientByIDFunc(id, orgID)
 }
 
-func (m *MockClientRepo) GetClientByPhone(phone string) (*models.Client, error) {
-	return m.GetClientByPhone(phone)
+func (m *MockClientRepo) GetClientByPhone(phone string, orgID uint) (*models.Client, error) {
+	return m.GetClientByPhoneFunc(phone, orgID)
 }
 
-func (m *MockClientRepo) GetOrCreateClient(phone, name, email string) (*models.Client, error) {
-	return m.GetOrCreateClient(phone, name, email)
+func (m *MockClientRepo) GetOrCreateClient(phone, name, email string, orgID uint) (*models.Client, error) {
+	return m.GetOrCreateClientFunc(phone, name, email, orgID)
+}
+
+func (m *MockClientRepo) GetAllClients(orgID uint) ([]models.Client, error) {
+	if m.GetAllClientsFunc == nil {
+		return []models.Client{}, nil
+	}
+	return m.GetAllClientsFunc(orgID)
 }
 
 var _ repositories.ClientRepository = &MockClientRepo{} // asegura que implementa la interfaz
 
 // Implementación de los nuevos métodos para estadísticas
-func (m *MockClientRepo) GetTotalClients(ctx context.Context) (int64, error) {
+func (m *MockClientRepo) GetTotalClients(ctx context.Context, orgID uint) (int64, error) {
 	return 10, nil
 }
 
-func (m *MockClientRepo) GetClientsCreatedBetween(ctx context.Context, startDate, endDate time.Time) ([]models.Client, error) {
+func (m *MockClientRepo) GetClientsCreatedBetween(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.Client, error) {
 	return []models.Client{}, nil
 }
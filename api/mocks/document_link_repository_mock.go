@@ -0,0 +1,114 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockDocumentLinkRepo es una implementación en memoria de DocumentLinkRepository
+type MockDocumentLinkRepo struct {
+	mu        sync.Mutex
+	nextID    uint
+	Links     []*models.DocumentLink
+	Downloads []models.DocumentDownload
+}
+
+func (m *MockDocumentLinkRepo) Create(link *models.DocumentLink) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	link.ID = m.nextID
+	link.CreatedAt = time.Now()
+	stored := *link
+	m.Links = append(m.Links, &stored)
+	return nil
+}
+
+func (m *MockDocumentLinkRepo) GetByLinkID(linkID string) (*models.DocumentLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.Links {
+		if l.LinkID == linkID {
+			copied := *l
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockDocumentLinkRepo) ListByDocument(documentID string, orgID uint) ([]models.DocumentLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var links []models.DocumentLink
+	for i := len(m.Links) - 1; i >= 0; i-- {
+		if l := m.Links[i]; l.DocumentID == documentID && l.OrganizationID == orgID {
+			links = append(links, *l)
+		}
+	}
+	return links, nil
+}
+
+func (m *MockDocumentLinkRepo) Revoke(linkID, documentID string, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.Links {
+		if l.LinkID == linkID && l.DocumentID == documentID && l.OrganizationID == orgID && l.RevokedAt == nil {
+			now := time.Now()
+			l.RevokedAt = &now
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockDocumentLinkRepo) Consume(linkID string, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.Links {
+		if l.LinkID == linkID && l.RevokedAt == nil && l.ExpiresAt.After(now) && l.Downloads < l.MaxDownloads {
+			l.Downloads++
+			l.LastDownloadAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockDocumentLinkRepo) Release(linkID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.Links {
+		if l.LinkID == linkID && l.Downloads > 0 {
+			l.Downloads--
+		}
+	}
+	return nil
+}
+
+func (m *MockDocumentLinkRepo) LogDownload(download *models.DocumentDownload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	download.ID = uint(len(m.Downloads) + 1)
+	download.CreatedAt = time.Now()
+	m.Downloads = append(m.Downloads, *download)
+	return nil
+}
+
+func (m *MockDocumentLinkRepo) ListDownloads(documentID string, orgID uint) ([]models.DocumentDownload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var downloads []models.DocumentDownload
+	for i := len(m.Downloads) - 1; i >= 0; i-- {
+		if d := m.Downloads[i]; d.DocumentID == documentID && d.OrganizationID == orgID {
+			downloads = append(downloads, d)
+		}
+	}
+	return downloads, nil
+}
+
+var _ repositories.DocumentLinkRepository = (*MockDocumentLinkRepo)(nil)
@@ -0,0 +1,58 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockExpiryReminderRepo es una implementación en memoria de ExpiryReminderRepository
+type MockExpiryReminderRepo struct {
+	mu        sync.Mutex
+	nextID    uint
+	Reminders []*models.ExpiryReminder
+}
+
+func (m *MockExpiryReminderRepo) Claim(reminder *models.ExpiryReminder) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.Reminders {
+		if r.OrganizationID == reminder.OrganizationID && r.Kind == reminder.Kind && r.SubjectID == reminder.SubjectID &&
+			r.DriverID == reminder.DriverID && r.ExpiresAt.Equal(reminder.ExpiresAt) {
+			return false, nil
+		}
+	}
+	m.nextID++
+	reminder.ID = m.nextID
+	stored := *reminder
+	m.Reminders = append(m.Reminders, &stored)
+	return true, nil
+}
+
+func (m *MockExpiryReminderRepo) Release(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.Reminders {
+		if r.ID == id {
+			m.Reminders = append(m.Reminders[:i], m.Reminders[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *MockExpiryReminderRepo) ListSince(orgID uint, since time.Time) ([]models.ExpiryReminder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reminders := []models.ExpiryReminder{}
+	for _, r := range m.Reminders {
+		if r.OrganizationID == orgID && !r.ExpiresAt.Before(since) {
+			reminders = append(reminders, *r)
+		}
+	}
+	return reminders, nil
+}
+
+var _ repositories.ExpiryReminderRepository = (*MockExpiryReminderRepo)(nil)
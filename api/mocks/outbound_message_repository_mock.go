@@ -0,0 +1,163 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockOutboundMessageRepo es una implementación en memoria de OutboundMessageRepository
+type MockOutboundMessageRepo struct {
+	mu       sync.Mutex
+	nextID   uint
+	messages map[uint]models.OutboundMessage
+}
+
+func (m *MockOutboundMessageRepo) Create(message *models.OutboundMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.messages == nil {
+		m.messages = make(map[uint]models.OutboundMessage)
+	}
+	m.nextID++
+	message.ID = m.nextID
+	message.CreatedAt = time.Now()
+	message.UpdatedAt = message.CreatedAt
+	m.messages[message.ID] = *message
+	return nil
+}
+
+func (m *MockOutboundMessageRepo) UpdateDispatch(message *models.OutboundMessage, expectedStatus, expectedEnvelopeID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.messages[message.ID]
+	if !ok || stored.Status != expectedStatus || stored.EnvelopeID != expectedEnvelopeID {
+		return false, nil
+	}
+	message.UpdatedAt = time.Now()
+	m.messages[message.ID] = *message
+	return true, nil
+}
+
+func (m *MockOutboundMessageRepo) GetByID(id uint, orgID uint) (*models.OutboundMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if message, ok := m.messages[id]; ok && message.OrganizationID == orgID {
+		return &message, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockOutboundMessageRepo) GetByEnvelopeID(envelopeID string) (*models.OutboundMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, message := range m.messages {
+		if envelopeID != "" && message.EnvelopeID == envelopeID {
+			return &message, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockOutboundMessageRepo) GetDispatchable(botKeys []string, dueBefore time.Time, limit int) ([]models.OutboundMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.OutboundMessage
+	for _, message := range m.sorted() {
+		if message.Status == models.OutboundStatusPending && message.EnvelopeID == "" &&
+			!message.NextAttemptAt.After(dueBefore) && containsKey(botKeys, message.BotKey) {
+			out = append(out, message)
+		}
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (m *MockOutboundMessageRepo) GetStalePending(idleBefore time.Time, limit int) ([]models.OutboundMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []models.OutboundMessage
+	for _, message := range m.sorted() {
+		if message.Status == models.OutboundStatusPending && message.EnvelopeID == "" && message.UpdatedAt.Before(idleBefore) {
+			out = append(out, message)
+		}
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (m *MockOutboundMessageRepo) ResetInFlight(instanceID string, staleBefore time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, message := range m.messages {
+		if message.Status != models.OutboundStatusPending || message.EnvelopeID == "" {
+			continue
+		}
+		if (instanceID != "" && message.DispatchedBy == instanceID) || message.UpdatedAt.Before(staleBefore) {
+			message.EnvelopeID = ""
+			message.DispatchedBy = ""
+			message.NextAttemptAt = time.Now()
+			m.messages[id] = message
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MockOutboundMessageRepo) List(orgID uint, status string, page, pageSize int) ([]models.OutboundMessage, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var filtered []models.OutboundMessage
+	for _, message := range m.sorted() {
+		if message.OrganizationID == orgID && (status == "" || message.Status == status) {
+			filtered = append(filtered, message)
+		}
+	}
+	total := int64(len(filtered))
+	start := (page - 1) * pageSize
+	if start >= len(filtered) {
+		return []models.OutboundMessage{}, total, nil
+	}
+	end := start + pageSize
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	return filtered[start:end], total, nil
+}
+
+// Get devuelve la fila tal como está guardada
+func (m *MockOutboundMessageRepo) Get(id uint) models.OutboundMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.messages[id]
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockOutboundMessageRepo) sorted() []models.OutboundMessage {
+	out := make([]models.OutboundMessage, 0, len(m.messages))
+	for _, message := range m.messages {
+		out = append(out, message)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+var _ repositories.OutboundMessageRepository = (*MockOutboundMessageRepo)(nil)
@@ -0,0 +1,89 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockRefreshTokenRepo es una implementación en memoria de RefreshTokenRepository
+type MockRefreshTokenRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	Tokens []*models.RefreshToken
+}
+
+func (m *MockRefreshTokenRepo) Create(token *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	stored := *token
+	m.Tokens = append(m.Tokens, &stored)
+	return nil
+}
+
+func (m *MockRefreshTokenRepo) GetByHash(hash string) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.Tokens {
+		if t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockRefreshTokenRepo) MarkUsed(id uint, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.Tokens {
+		if t.ID == id && t.UsedAt == nil && t.RevokedAt == nil {
+			t.UsedAt = &at
+			return nil
+		}
+	}
+	return repositories.ErrRefreshTokenUsed
+}
+
+func (m *MockRefreshTokenRepo) revoke(match func(*models.RefreshToken) bool, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.Tokens {
+		if t.RevokedAt == nil && match(t) {
+			t.RevokedAt = &at
+		}
+	}
+}
+
+func (m *MockRefreshTokenRepo) RevokeFamily(familyID string, at time.Time) error {
+	m.revoke(func(t *models.RefreshToken) bool { return t.FamilyID == familyID }, at)
+	return nil
+}
+
+func (m *MockRefreshTokenRepo) RevokeByUser(userID uint, at time.Time) error {
+	m.revoke(func(t *models.RefreshToken) bool { return t.UserID == userID }, at)
+	return nil
+}
+
+func (m *MockRefreshTokenRepo) DeleteExpired(before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []*models.RefreshToken
+	for _, t := range m.Tokens {
+		if !t.ExpiresAt.Before(before) {
+			kept = append(kept, t)
+		}
+	}
+	deleted := int64(len(m.Tokens) - len(kept))
+	m.Tokens = kept
+	return deleted, nil
+}
+
+var _ repositories.RefreshTokenRepository = (*MockRefreshTokenRepo)(nil)
@@ -0,0 +1,202 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockVehicleRepo es una implementación en memoria de VehicleRepository
+type MockVehicleRepo struct {
+	mu       sync.Mutex
+	nextID   uint
+	Vehicles []*models.Vehicle
+	// Drivers, si se asigna, recibe el desenlace de los conductores al borrar un vehículo
+	Drivers *MockDriverRepo
+}
+
+func (m *MockVehicleRepo) Create(vehicle *models.Vehicle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	vehicle.ID = m.nextID
+	vehicle.CreatedAt = time.Now()
+	vehicle.UpdatedAt = vehicle.CreatedAt
+	stored := *vehicle
+	m.Vehicles = append(m.Vehicles, &stored)
+	return nil
+}
+
+func (m *MockVehicleRepo) Update(vehicle *models.Vehicle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range m.Vehicles {
+		if v.ID == vehicle.ID && v.OrganizationID == vehicle.OrganizationID {
+			vehicle.CreatedAt = v.CreatedAt
+			vehicle.UpdatedAt = time.Now()
+			stored := *vehicle
+			m.Vehicles[i] = &stored
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockVehicleRepo) find(match func(*models.Vehicle) bool) (*models.Vehicle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.Vehicles {
+		if match(v) {
+			copied := *v
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockVehicleRepo) GetByID(id uint, orgID uint) (*models.Vehicle, error) {
+	return m.find(func(v *models.Vehicle) bool { return v.ID == id && v.OrganizationID == orgID })
+}
+
+func (m *MockVehicleRepo) GetByPlaca(placa string, orgID uint) (*models.Vehicle, error) {
+	return m.find(func(v *models.Vehicle) bool { return v.Placa == placa && v.OrganizationID == orgID })
+}
+
+func (m *MockVehicleRepo) List(orgID uint) ([]models.Vehicle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vehicles := []models.Vehicle{}
+	for _, v := range m.Vehicles {
+		if v.OrganizationID == orgID {
+			vehicles = append(vehicles, *v)
+		}
+	}
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].Placa < vehicles[j].Placa })
+	return vehicles, nil
+}
+
+func (m *MockVehicleRepo) Delete(id uint, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range m.Vehicles {
+		if v.ID == id && v.OrganizationID == orgID {
+			m.Vehicles = append(m.Vehicles[:i], m.Vehicles[i+1:]...)
+			if m.Drivers != nil {
+				m.Drivers.unlinkVehicle(id, orgID)
+			}
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// MockDriverRepo es una implementación en memoria de DriverRepository
+type MockDriverRepo struct {
+	mu      sync.Mutex
+	nextID  uint
+	Drivers []*models.Driver
+}
+
+func (m *MockDriverRepo) Create(driver *models.Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	driver.ID = m.nextID
+	driver.CreatedAt = time.Now()
+	driver.UpdatedAt = driver.CreatedAt
+	stored := *driver
+	m.Drivers = append(m.Drivers, &stored)
+	return nil
+}
+
+func (m *MockDriverRepo) Update(driver *models.Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.Drivers {
+		if d.ID == driver.ID && d.OrganizationID == driver.OrganizationID {
+			driver.CreatedAt = d.CreatedAt
+			driver.UpdatedAt = time.Now()
+			stored := *driver
+			m.Drivers[i] = &stored
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockDriverRepo) find(match func(*models.Driver) bool) (*models.Driver, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.Drivers {
+		if match(d) {
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockDriverRepo) GetByID(id uint, orgID uint) (*models.Driver, error) {
+	return m.find(func(d *models.Driver) bool { return d.ID == id && d.OrganizationID == orgID })
+}
+
+func (m *MockDriverRepo) GetByCedula(cedula string, orgID uint) (*models.Driver, error) {
+	return m.find(func(d *models.Driver) bool { return d.Cedula == cedula && d.OrganizationID == orgID })
+}
+
+func (m *MockDriverRepo) GetByClient(clientID uint, phone string, orgID uint) (*models.Driver, error) {
+	// Primero el enlazado al cliente, igual que el ORDER BY del repositorio real
+	if driver, err := m.find(func(d *models.Driver) bool {
+		return d.OrganizationID == orgID && d.ClientID != nil && *d.ClientID == clientID
+	}); err == nil {
+		return driver, nil
+	}
+	return m.find(func(d *models.Driver) bool {
+		return d.OrganizationID == orgID && d.ClientID == nil && d.Phone == phone
+	})
+}
+
+func (m *MockDriverRepo) List(orgID uint) ([]models.Driver, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	drivers := []models.Driver{}
+	for _, d := range m.Drivers {
+		if d.OrganizationID == orgID {
+			drivers = append(drivers, *d)
+		}
+	}
+	sort.Slice(drivers, func(i, j int) bool { return drivers[i].Name < drivers[j].Name })
+	return drivers, nil
+}
+
+func (m *MockDriverRepo) Delete(id uint, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.Drivers {
+		if d.ID == id && d.OrganizationID == orgID {
+			m.Drivers = append(m.Drivers[:i], m.Drivers[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockDriverRepo) unlinkVehicle(vehicleID uint, orgID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.Drivers {
+		if d.OrganizationID == orgID && d.VehicleID != nil && *d.VehicleID == vehicleID {
+			d.VehicleID = nil
+		}
+	}
+}
+
+var (
+	_ repositories.VehicleRepository = (*MockVehicleRepo)(nil)
+	_ repositories.DriverRepository  = (*MockDriverRepo)(nil)
+)
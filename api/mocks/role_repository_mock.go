@@ -0,0 +1,97 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockRoleRepo es una implementación en memoria de RoleRepository
+type MockRoleRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	Roles  []*models.Role
+}
+
+func (m *MockRoleRepo) Create(role *models.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.Roles {
+		if r.OrganizationID == role.OrganizationID && r.Name == role.Name {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	m.nextID++
+	role.ID = m.nextID
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = role.CreatedAt
+	stored := *role
+	m.Roles = append(m.Roles, &stored)
+	return nil
+}
+
+func (m *MockRoleRepo) Update(role *models.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.Roles {
+		if r.ID == role.ID && r.OrganizationID == role.OrganizationID {
+			r.Description = role.Description
+			r.Permissions = append([]string(nil), role.Permissions...)
+			r.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockRoleRepo) find(match func(*models.Role) bool) (*models.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.Roles {
+		if match(r) {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockRoleRepo) GetByID(id uint, orgID uint) (*models.Role, error) {
+	return m.find(func(r *models.Role) bool { return r.ID == id && r.OrganizationID == orgID })
+}
+
+func (m *MockRoleRepo) GetByName(name string, orgID uint) (*models.Role, error) {
+	return m.find(func(r *models.Role) bool { return r.Name == name && r.OrganizationID == orgID })
+}
+
+func (m *MockRoleRepo) List(orgID uint) ([]models.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var roles []models.Role
+	for _, r := range m.Roles {
+		if r.OrganizationID == orgID {
+			roles = append(roles, *r)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (m *MockRoleRepo) Delete(id uint, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.Roles {
+		if r.ID == id && r.OrganizationID == orgID {
+			m.Roles = append(m.Roles[:i], m.Roles[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+var _ repositories.RoleRepository = (*MockRoleRepo)(nil)
@@ -0,0 +1,82 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockRouteTemplateRepo es una implementación en memoria de RouteTemplateRepository
+type MockRouteTemplateRepo struct {
+	mu     sync.Mutex
+	nextID uint
+	Routes []*models.RouteTemplate
+}
+
+func (m *MockRouteTemplateRepo) Create(template *models.RouteTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	template.ID = m.nextID
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = template.CreatedAt
+	stored := *template
+	m.Routes = append(m.Routes, &stored)
+	return nil
+}
+
+func (m *MockRouteTemplateRepo) Update(template *models.RouteTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.Routes {
+		if r.ID == template.ID && r.ClientID == template.ClientID && r.OrganizationID == template.OrganizationID {
+			template.UpdatedAt = time.Now()
+			stored := *template
+			m.Routes[i] = &stored
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockRouteTemplateRepo) GetByID(id, clientID, orgID uint) (*models.RouteTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.Routes {
+		if r.ID == id && r.ClientID == clientID && r.OrganizationID == orgID {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockRouteTemplateRepo) ListByClient(clientID, orgID uint) ([]models.RouteTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := []models.RouteTemplate{}
+	for i := len(m.Routes) - 1; i >= 0; i-- {
+		if r := m.Routes[i]; r.ClientID == clientID && r.OrganizationID == orgID {
+			routes = append(routes, *r)
+		}
+	}
+	return routes, nil
+}
+
+func (m *MockRouteTemplateRepo) Delete(id, clientID, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.Routes {
+		if r.ID == id && r.ClientID == clientID && r.OrganizationID == orgID {
+			m.Routes = append(m.Routes[:i], m.Routes[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+var _ repositories.RouteTemplateRepository = (*MockRouteTemplateRepo)(nil)
@@ -0,0 +1,145 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// MockSystemUserRepo es una implementación en memoria de SystemUserRepository. Los
+// usuarios eliminados se quitan de Users pero siguen ocupando su username y email.
+type MockSystemUserRepo struct {
+	mu      sync.Mutex
+	nextID  uint
+	Users   []*models.SystemUser
+	deleted []*models.SystemUser
+}
+
+func (m *MockSystemUserRepo) Create(user *models.SystemUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	user.ID = m.nextID
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	stored := *user
+	m.Users = append(m.Users, &stored)
+	return nil
+}
+
+func (m *MockSystemUserRepo) Update(user *models.SystemUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.Users {
+		if u.ID == user.ID && u.OrganizationID == user.OrganizationID {
+			u.Username = user.Username
+			u.Email = user.Email
+			u.PasswordHash = user.PasswordHash
+			u.Role = user.Role
+			u.IsActive = user.IsActive
+			u.TokenVersion = user.TokenVersion
+			u.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockSystemUserRepo) GetByID(id uint, orgID uint) (*models.SystemUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.Users {
+		if u.ID == id && u.OrganizationID == orgID {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockSystemUserRepo) GetByLogin(username, email string) (*models.SystemUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range append(append([]*models.SystemUser{}, m.Users...), m.deleted...) {
+		if u.Username == username || u.Email == email {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MockSystemUserRepo) List(orgID uint) ([]models.SystemUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []models.SystemUser
+	for _, u := range m.Users {
+		if u.OrganizationID == orgID {
+			users = append(users, *u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (m *MockSystemUserRepo) IncrementTokenVersion(id uint, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.Users {
+		if u.ID == id && u.OrganizationID == orgID {
+			u.TokenVersion++
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockSystemUserRepo) CountActive(orgID uint, roles []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var count int64
+	for _, u := range m.Users {
+		if u.OrganizationID != orgID || !u.IsActive {
+			continue
+		}
+		for _, role := range roles {
+			if u.Role == role {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+func (m *MockSystemUserRepo) CountByRole(orgID uint, role string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var count int64
+	for _, u := range m.Users {
+		if u.OrganizationID == orgID && u.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockSystemUserRepo) Delete(id uint, orgID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, u := range m.Users {
+		if u.ID == id && u.OrganizationID == orgID {
+			m.deleted = append(m.deleted, u)
+			m.Users = append(m.Users[:i], m.Users[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+var _ repositories.SystemUserRepository = (*MockSystemUserRepo)(nil)
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// APIKeyPrefix identifica las API keys en la cabecera Authorization
+const APIKeyPrefix = "dbk_"
+
+// APIKeyScopes son los permisos que se pueden dar a una API key. Las tareas de
+// administración (usuarios, roles, API keys, organizaciones) quedan para los usuarios.
+var APIKeyScopes = []string{
+	PermissionWhatsAppSend,
+	PermissionWhatsAppManage,
+	PermissionChatsRead,
+	PermissionChatsTakeover,
+	PermissionDocumentsRead,
+	PermissionDocumentsManage,
+	PermissionClientsRead,
+	PermissionClientsManage,
+	PermissionRegistryRead,
+	PermissionRegistryManage,
+	PermissionDashboardRead,
+	PermissionBotInstancesManage,
+}
+
+// APIKey es la credencial de un cliente máquina (acciones de Rasa, playwright-bot,
+// integraciones) ligada a una organización. La key completa es
+// "dbk_<Prefix>_<secreto>": Prefix sirve para buscarla y solo se guarda el SHA-256.
+type APIKey struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	OrganizationID uint         `json:"organization_id" gorm:"not null;index"`
+	Organization   Organization `json:"-" gorm:"foreignKey:OrganizationID"`
+	Name           string       `json:"name" gorm:"not null"`
+	Prefix         string       `json:"prefix" gorm:"uniqueIndex;not null"`
+	KeyHash        string       `json:"-" gorm:"not null"`
+	Scopes         []string     `json:"scopes" gorm:"serializer:json;not null"`
+	CreatedByID    uint         `json:"created_by_id"`
+	ExpiresAt      *time.Time   `json:"expires_at"`
+	LastUsedAt     *time.Time   `json:"last_used_at"`
+	LastUsedIP     string       `json:"last_used_ip"`
+	RevokedAt      *time.Time   `json:"revoked_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// IsActive indica si la key no está revocada ni vencida
+func (k APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -11,7 +11,8 @@ type BotInstance struct {
 	Status         string    `json:"status"` // running, stopped, error
 	BasedOnBotID   uint      `json:"based_on_bot_id"`
 	WhatsAppNumber string    `json:"whatsapp_number" gorm:"index"`
-	CreatedBy      uint      `json:"created_by"`
+	CreatedBy      uint      `json:"created_by"`                  // 0 si la creó una API key
+	CreatedByKeyID uint      `json:"created_by_key_id,omitempty"` // API key que la creó
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// BotSessionKey es la credencial con la que una sesión de baileys-ws se
+// autentica en /ws. Solo se guarda el identificador; la firma se calcula con
+// la llave del servidor y nunca se persiste.
+type BotSessionKey struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null;index:idx_bot_session_keys_org_session"`
+	SessionID      string     `json:"session_id" gorm:"not null;index:idx_bot_session_keys_org_session"`
+	KeyID          string     `json:"key_id" gorm:"uniqueIndex;not null"`
+	CreatedBy      uint       `json:"created_by"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// IsActive indica si la credencial no ha sido revocada
+func (k *BotSessionKey) IsActive() bool {
+	return k.RevokedAt == nil
+}
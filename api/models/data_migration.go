@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// DataMigration registra una migración de datos que solo debe aplicarse una vez (a
+// diferencia de las que son idempotentes y corren en cada arranque)
+type DataMigration struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	AppliedAt time.Time `json:"applied_at" gorm:"not null"`
+}
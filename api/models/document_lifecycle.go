@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DocumentTransitions define los cambios de estado permitidos de un documento.
+// completed es final; failed y cancelled pueden volver a generating con un reintento.
+var DocumentTransitions = map[string][]string{
+	DocumentStatusGenerating: {DocumentStatusCompleted, DocumentStatusFailed, DocumentStatusCancelled},
+	DocumentStatusFailed:     {DocumentStatusGenerating, DocumentStatusCancelled},
+	DocumentStatusCancelled:  {DocumentStatusGenerating},
+}
+
+// Subestados de un manifiesto completado. Después de expedido se le pide al conductor que
+// confirme la entrega y se radica el cumplido, o se anula si se expidió por error.
+const (
+	ManifestStageIssued           = "issued"
+	ManifestStageAwaitingDelivery = "awaiting_delivery"
+	ManifestStageFulfilling       = "fulfilling"
+	ManifestStageFulfilled        = "fulfilled"
+	ManifestStageAnnulling        = "annulling"
+	ManifestStageAnnulled         = "annulled"
+)
+
+// ManifestStageTransitions define los cambios de subestado permitidos. fulfilled y
+// annulled son finales; si el RNDC rechaza un cumplido o una anulación el manifiesto
+// vuelve a issued para corregir los datos e intentarlo otra vez.
+var ManifestStageTransitions = map[string][]string{
+	ManifestStageIssued:           {ManifestStageAwaitingDelivery, ManifestStageFulfilling, ManifestStageAnnulling},
+	ManifestStageAwaitingDelivery: {ManifestStageAwaitingDelivery, ManifestStageFulfilling, ManifestStageAnnulling},
+	ManifestStageFulfilling:       {ManifestStageFulfilled, ManifestStageIssued},
+	ManifestStageAnnulling:        {ManifestStageAnnulled, ManifestStageIssued},
+}
+
+// DocumentAttempt registra un intento de generación de un documento
+type DocumentAttempt struct {
+	Number      int        `bson:"number" json:"number"`
+	Status      string     `bson:"status" json:"status"`
+	TriggeredBy string     `bson:"triggered_by,omitempty" json:"triggered_by,omitempty"` // "bot", "api" o usuario:ID
+	StartedAt   time.Time  `bson:"started_at" json:"started_at"`
+	FinishedAt  *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	Error       string     `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// IsValidDocumentStatus indica si el estado es uno de los conocidos
+func IsValidDocumentStatus(status string) bool {
+	switch status {
+	case DocumentStatusGenerating, DocumentStatusCompleted, DocumentStatusFailed, DocumentStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsValidManifestStage indica si el subestado es uno de los conocidos
+func IsValidManifestStage(stage string) bool {
+	switch stage {
+	case ManifestStageIssued, ManifestStageAwaitingDelivery, ManifestStageFulfilling,
+		ManifestStageFulfilled, ManifestStageAnnulling, ManifestStageAnnulled:
+		return true
+	}
+	return false
+}
+
+// CanTransition indica si el documento puede pasar al estado indicado
+func (d *Document) CanTransition(to string) bool {
+	for _, allowed := range DocumentTransitions[d.Status] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ManifestStage devuelve el subestado de un manifiesto completado ("" en otros casos). Los
+// manifiestos completados antes de existir los subestados se consideran issued.
+func (d *Document) ManifestStage() string {
+	if d.Status != DocumentStatusCompleted || d.ParentID != primitive.NilObjectID {
+		return ""
+	}
+	if d.Stage == "" {
+		return ManifestStageIssued
+	}
+	return d.Stage
+}
+
+// CanAdvanceStage indica si el manifiesto puede pasar al subestado indicado
+func (d *Document) CanAdvanceStage(to string) bool {
+	for _, allowed := range ManifestStageTransitions[d.ManifestStage()] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StartAttempt pasa el documento a generating y abre un nuevo intento
+func (d *Document) StartAttempt(triggeredBy string, now time.Time) {
+	d.Status = DocumentStatusGenerating
+	d.Error = ""
+	d.CompletedAt = nil
+	d.Attempts = append(d.Attempts, DocumentAttempt{
+		Number:      len(d.Attempts) + 1,
+		Status:      DocumentStatusGenerating,
+		TriggeredBy: triggeredBy,
+		StartedAt:   now,
+	})
+}
+
+// Finish deja el documento en un estado final y cierra el intento en curso
+func (d *Document) Finish(status string, errMessage string, now time.Time) {
+	d.Status = status
+	d.Error = errMessage
+	if status == DocumentStatusCompleted {
+		d.CompletedAt = &now
+		if d.VerificationCode == "" {
+			d.VerificationCode = NewVerificationCode()
+		}
+	}
+
+	if len(d.Attempts) == 0 {
+		// Documentos registrados sin intentos (p. ej. generados fuera de la API)
+		d.Attempts = append(d.Attempts, DocumentAttempt{Number: 1, StartedAt: now})
+	}
+	attempt := &d.Attempts[len(d.Attempts)-1]
+	if attempt.FinishedAt != nil {
+		// Cancelar un documento fallido no abre un intento nuevo
+		return
+	}
+	attempt.Status = status
+	attempt.Error = errMessage
+	attempt.FinishedAt = &now
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// DocumentLink es un enlace firmado para descargar un documento sin iniciar sesión
+// (p. ej. el manifiesto que se le envía al conductor). Como en BotSessionKey, solo se
+// guarda el identificador: la firma se calcula con la llave del servidor.
+type DocumentLink struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null;index:idx_document_links_org_document"`
+	DocumentID     string     `json:"document_id" gorm:"not null;index:idx_document_links_org_document"` // ObjectID (hex) en Mongo
+	ClientID       uint       `json:"client_id"`
+	LinkID         string     `json:"link_id" gorm:"uniqueIndex;not null"`
+	MaxDownloads   int        `json:"max_downloads" gorm:"not null;default:1"` // 1 = un solo uso
+	Downloads      int        `json:"downloads" gorm:"not null;default:0"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	CreatedBy      uint       `json:"created_by"` // 0 si lo emitió el sistema
+	LastDownloadAt *time.Time `json:"last_download_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Resultados de un intento de descarga
+const (
+	DownloadResultOK        = "ok"
+	DownloadResultInvalid   = "invalid"
+	DownloadResultExpired   = "expired"
+	DownloadResultExhausted = "exhausted"
+	DownloadResultRevoked   = "revoked"
+	DownloadResultError     = "error"
+)
+
+// DocumentDownload registra cada intento de descarga de un documento
+type DocumentDownload struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"index:idx_document_downloads_org_document"`
+	DocumentID     string    `json:"document_id" gorm:"index:idx_document_downloads_org_document"`
+	LinkID         string    `json:"link_id,omitempty" gorm:"index"` // Vacío en descargas autenticadas
+	UserID         uint      `json:"user_id,omitempty"`              // Usuario del dashboard, si aplica
+	Result         string    `json:"result" gorm:"not null"`
+	IP             string    `json:"ip"`
+	UserAgent      string    `json:"user_agent"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// IsActive indica si el enlace no ha sido revocado
+func (l *DocumentLink) IsActive() bool {
+	return l.RevokedAt == nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// verificationAlphabet no incluye 0/O, 1/I/L para que el código se pueda dictar o
+// copiar a mano sin confusiones
+const verificationAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const verificationCodeLength = 12
+
+// NewVerificationCode genera un código aleatorio con el formato XXXX-XXXX-XXXX
+// (~59 bits, no se puede adivinar ni recorrer)
+func NewVerificationCode() string {
+	// Se descartan los bytes desde el mayor múltiplo del alfabeto que cabe en un byte
+	// (248): con ellos los primeros símbolos saldrían más seguido que el resto
+	limit := 256 - 256%len(verificationAlphabet)
+	code := make([]byte, 0, verificationCodeLength)
+	buf := make([]byte, verificationCodeLength)
+	for len(code) < verificationCodeLength {
+		rand.Read(buf) // Desde Go 1.24 nunca devuelve error
+		for _, v := range buf {
+			if int(v) < limit && len(code) < verificationCodeLength {
+				code = append(code, verificationAlphabet[int(v)%len(verificationAlphabet)])
+			}
+		}
+	}
+	return formatVerificationCode(string(code))
+}
+
+// NormalizeVerificationCode acepta el código como lo escriba una persona (minúsculas,
+// espacios, sin guiones) y lo devuelve en el formato guardado. El segundo valor es
+// false si no puede ser un código válido.
+func NormalizeVerificationCode(code string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		switch {
+		case r == '-' || r == ' ':
+			continue
+		case r < 128 && strings.IndexByte(verificationAlphabet, byte(r)) >= 0:
+			b.WriteRune(r)
+		default:
+			return "", false
+		}
+	}
+	if b.Len() != verificationCodeLength {
+		return "", false
+	}
+	return formatVerificationCode(b.String()), true
+}
+
+func formatVerificationCode(raw string) string {
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12]
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Documentos del registro que vencen y por los que se multa al conductor
+const (
+	ExpiryKindLicense       = "license"       // Licencia de conducción del conductor
+	ExpiryKindSOAT          = "soat"          // SOAT del vehículo
+	ExpiryKindTecnomecanica = "tecnomecanica" // Revisión técnico-mecánica del vehículo
+)
+
+// ExpiryReminder registra un recordatorio de vencimiento enviado a un conductor. El índice
+// único evita repetirlo: solo se vuelve a enviar si la fecha de vencimiento cambia.
+type ExpiryReminder struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;uniqueIndex:idx_expiry_reminder_once"`
+	Kind           string    `json:"kind" gorm:"size:20;not null;uniqueIndex:idx_expiry_reminder_once"`
+	SubjectID      uint      `json:"subject_id" gorm:"not null;uniqueIndex:idx_expiry_reminder_once"` // ID del conductor o del vehículo según Kind
+	DriverID       uint      `json:"driver_id" gorm:"not null;uniqueIndex:idx_expiry_reminder_once"`  // Conductor que recibe el recordatorio
+	ExpiresAt      time.Time `json:"expires_at" gorm:"not null;uniqueIndex:idx_expiry_reminder_once"`
+	Phone          string    `json:"phone"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// JobLease es el turno de una réplica para ejecutar un trabajo programado. Quien tenga el
+// lease vigente es el líder; si deja de renovarlo otra réplica lo toma al vencer.
+type JobLease struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	Holder    string    `json:"holder" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -6,57 +6,153 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Dirección, tipo y estado de los mensajes de la colección messages
+const (
+	MessageDirectionInbound  = "inbound"  // Del cliente hacia el bot
+	MessageDirectionOutbound = "outbound" // Del bot o un agente hacia el cliente
+
+	MessageTypeText     = "text"
+	MessageTypeImage    = "image"
+	MessageTypeDocument = "document"
+	MessageTypeButtons  = "buttons"  // Texto con opciones de respuesta
+	MessageTypeLocation = "location" // Ubicación (payload custom de Rasa)
+
+	MessageStatusReceived = "received" // Mensaje entrante guardado
+	MessageStatusQueued   = "queued"   // Entregado a la cola de salida
+	MessageStatusFailed   = "failed"   // No se pudo encolar el envío
+)
+
+// Message es un mensaje de WhatsApp; cada uno es un documento de la colección messages
 type Message struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	ClientID  uint               `bson:"client_id"`
-	BotID     uint               `bson:"bot_id"`
-	Sender    string             `bson:"sender"` // "user" o "bot"
-	Text      string             `bson:"text"`
-	Timestamp time.Time          `bson:"timestamp"`
-	SessionID string             `bson:"session_id,omitempty"`
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID uint               `bson:"organization_id"`
+	ClientID       uint               `bson:"client_id"`
+	BotID          uint               `bson:"bot_id"`
+	SessionID      string             `bson:"session_id,omitempty"`
+	ChatID         string             `bson:"chat_id"`   // Número del cliente sin sufijo (@s.whatsapp.net)
+	Direction      string             `bson:"direction"` // "inbound" o "outbound"
+	Type           string             `bson:"type"`      // text, audio, image, ...
+	Status         string             `bson:"status"`
+	Sender         string             `bson:"sender"` // JID del cliente o "bot"
+	Text           string             `bson:"text"`
+	Timestamp      time.Time          `bson:"timestamp"`
+
+	// Contenido enriquecido de las respuestas del bot, según Type
+	Media    *MessageMedia    `bson:"media,omitempty"`
+	Buttons  []MessageButton  `bson:"buttons,omitempty"`
+	Location *MessageLocation `bson:"location,omitempty"`
+
+	// Origen del mensaje cuando viene de la migración del formato anterior
+	LegacyConversationID *primitive.ObjectID `bson:"legacy_conversation_id,omitempty"`
+}
+
+// MessageMedia es la imagen o documento adjunto a un mensaje
+type MessageMedia struct {
+	URL      string `bson:"url,omitempty" json:"url,omitempty"`
+	Data     string `bson:"-" json:"data,omitempty"` // Contenido en base64 cuando no hay URL pública; no se guarda en el historial
+	FileName string `bson:"file_name,omitempty" json:"fileName,omitempty"`
+	MimeType string `bson:"mime_type,omitempty" json:"mimeType,omitempty"`
+	Caption  string `bson:"caption,omitempty" json:"caption,omitempty"`
+}
+
+// MessageButton es una opción de respuesta; Payload es lo que se envía a Rasa al elegirla
+type MessageButton struct {
+	Title   string `bson:"title" json:"title"`
+	Payload string `bson:"payload" json:"payload"`
 }
 
+// MessageLocation es una ubicación enviada al cliente
+type MessageLocation struct {
+	Latitude  float64 `bson:"latitude" json:"latitude"`
+	Longitude float64 `bson:"longitude" json:"longitude"`
+	Name      string  `bson:"name,omitempty" json:"name,omitempty"`
+	Address   string  `bson:"address,omitempty" json:"address,omitempty"`
+}
+
+// Conversation agrupa los mensajes de un cliente. Ya no se guarda como documento:
+// se arma a partir de la colección messages.
 type Conversation struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	UserID    uint               `bson:"client_id"`
-	BotID     uint               `bson:"bot_id"`
-	Messages  []Message          `bson:"messages"`
-	CreatedAt time.Time          `bson:"created_at"`
+	UserID   uint
+	BotID    uint
+	Messages []Message
 }
 
+// ActiveConversation resume la actividad de un chat en un período
+type ActiveConversation struct {
+	ClientID     uint      `bson:"client_id" json:"client_id"`
+	SessionID    string    `bson:"session_id" json:"session_id"`
+	ChatID       string    `bson:"chat_id" json:"chat_id"`
+	MessageCount int       `bson:"message_count" json:"message_count"`
+	LastMessage  time.Time `bson:"last_message" json:"last_message"`
+}
+
+// Estados de un documento
+const (
+	DocumentStatusGenerating = "generating"
+	DocumentStatusCompleted  = "completed"
+	DocumentStatusFailed     = "failed"
+	DocumentStatusCancelled  = "cancelled"
+)
+
 type Document struct {
-	ID        primitive.ObjectID     `bson:"_id,omitempty"`
-	ClientID  uint                   `bson:"client_id"`
-	BotID     uint                   `bson:"bot_id,omitempty"`
-	SessionID string                 `bson:"session_id,omitempty"`
-	FileName  string                 `bson:"file_name"`
-	URL       string                 `bson:"url"`
-	Type      string                 `bson:"type"`               // Ej: "manifiesto", "certificado"
-	Metadata  map[string]interface{} `bson:"metadata,omitempty"` // Datos usados para generar el documento
-	Entities  map[string]interface{} `bson:"entities,omitempty"` // Entidades/slots del formulario
-	Status    string                 `bson:"status"`             // "generating", "completed", "failed"
-	CreatedAt time.Time              `bson:"created_at"`
-	UpdatedAt time.Time              `bson:"updated_at"`
+	ID             primitive.ObjectID     `bson:"_id,omitempty"`
+	OrganizationID uint                   `bson:"organization_id"`
+	ClientID       uint                   `bson:"client_id"`
+	BotID          uint                   `bson:"bot_id,omitempty"`
+	SessionID      string                 `bson:"session_id,omitempty"`
+	FileName       string                 `bson:"file_name"`
+	URL            string                 `bson:"url"`
+	Type           string                 `bson:"type"`                   // Ej: "manifiesto", "certificado"
+	Metadata       map[string]interface{} `bson:"metadata,omitempty"`     // Datos usados para generar el documento
+	Entities       map[string]interface{} `bson:"entities,omitempty"`     // Entidades/slots del formulario
+	Status         string                 `bson:"status"`                 // Ver DocumentTransitions
+	ChatID         string                 `bson:"chat_id,omitempty"`      // Chat al que se entrega el documento
+	BotKey         string                 `bson:"bot_key,omitempty"`      // sessionId:botPhone que entrega el documento
+	StorageKey     string                 `bson:"storage_key,omitempty"`  // Clave del archivo en el almacenamiento de la API
+	ContentType    string                 `bson:"content_type,omitempty"` // Detectado al guardar el archivo
+	Size           int64                  `bson:"size,omitempty"`         // Bytes
+	Checksum       string                 `bson:"checksum,omitempty"`     // SHA-256 (hex) verificado en cada descarga
+	Error          string                 `bson:"error,omitempty"`        // Motivo del fallo
+	CompletedAt    *time.Time             `bson:"completed_at,omitempty"`
+	Attempts       []DocumentAttempt      `bson:"attempts,omitempty"` // Un registro por intento de generación
+	CreatedAt      time.Time              `bson:"created_at"`
+	UpdatedAt      time.Time              `bson:"updated_at"`
+
+	// VerificationCode se asigna al completar el documento; cualquiera lo puede consultar
+	// en /verify/{código} para comprobar que el archivo es auténtico
+	VerificationCode string `bson:"verification_code,omitempty"`
+
+	// Vehículo y conductor del registro de la organización (0 si no estaban registrados)
+	VehicleID uint `bson:"vehicle_id,omitempty"`
+	DriverID  uint `bson:"driver_id,omitempty"`
+
+	// Stage es el subestado de un manifiesto completado (ver ManifestStageTransitions).
+	// Los cumplidos y anulaciones son documentos aparte enlazados con ParentID.
+	Stage    string             `bson:"stage,omitempty"`
+	ParentID primitive.ObjectID `bson:"parent_id,omitempty"`
 }
 
 type ChatMode struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	ClientID  uint               `bson:"client_id"`
-	BotID     uint               `bson:"bot_id,omitempty"`
-	SessionID string             `bson:"session_id,omitempty"`
-	ChatID    string             `bson:"chat_id"`  // ID del chat en WhatsApp
-	BotMode   bool               `bson:"bot_mode"` // true = modo bot, false = modo usuario
-	CreatedAt time.Time          `bson:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at"`
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID uint               `bson:"organization_id"`
+	ClientID       uint               `bson:"client_id"`
+	BotID          uint               `bson:"bot_id,omitempty"`
+	SessionID      string             `bson:"session_id,omitempty"`
+	ChatID         string             `bson:"chat_id"`                    // ID del chat en WhatsApp
+	BotMode        bool               `bson:"bot_mode"`                   // true = modo bot, false = modo usuario
+	LastActivityAt time.Time          `bson:"last_activity_at,omitempty"` // Última actividad del agente (modo usuario)
+	CreatedAt      time.Time          `bson:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at"`
 }
 
 type ChatArchive struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	ClientID   uint               `bson:"client_id"`
-	BotID      uint               `bson:"bot_id,omitempty"`
-	SessionID  string             `bson:"session_id,omitempty"`
-	ChatID     string             `bson:"chat_id"`
-	ChatName   string             `bson:"chat_name"`
-	IsGroup    bool               `bson:"is_group"`
-	ArchivedAt time.Time          `bson:"archived_at"`
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	OrganizationID uint               `bson:"organization_id"`
+	ClientID       uint               `bson:"client_id"`
+	BotID          uint               `bson:"bot_id,omitempty"`
+	SessionID      string             `bson:"session_id,omitempty"`
+	ChatID         string             `bson:"chat_id"`
+	ChatName       string             `bson:"chat_name"`
+	IsGroup        bool               `bson:"is_group"`
+	ArchivedAt     time.Time          `bson:"archived_at"`
 }
@@ -11,6 +11,10 @@ type Organization struct {
 	Name      string         `json:"name" gorm:"not null"`
 	Slug      string         `json:"slug" gorm:"uniqueIndex;not null"` // para URLs amigables
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
+
+	// Días de anticipación de los recordatorios de vencimientos (0 = sin recordatorios)
+	ExpiryReminderDays int `json:"expiry_reminder_days" gorm:"default:7"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Estados de un mensaje en la cola de salida
+const (
+	OutboundStatusPending = "pending" // En cola o esperando ack de baileys-ws
+	OutboundStatusSent    = "sent"    // Confirmado por baileys-ws
+	OutboundStatusFailed  = "failed"  // Agotó los intentos (dead letter)
+)
+
+// OutboundMessage es un mensaje de WhatsApp persistido hasta que baileys-ws confirme su envío
+type OutboundMessage struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID  uint       `json:"organization_id" gorm:"index"`
+	SessionID       string     `json:"session_id"`
+	BotKey          string     `json:"bot_key" gorm:"not null;index:idx_outbound_dispatch"`
+	To              string     `json:"to" gorm:"not null"`
+	Message         string     `json:"message" gorm:"type:text;not null"`
+	MessageType     string     `json:"message_type" gorm:"not null;default:text"`
+	Payload         string     `json:"payload,omitempty" gorm:"type:text"` // JSON con media, botones o ubicación
+	Status          string     `json:"status" gorm:"not null;default:pending;index:idx_outbound_dispatch"`
+	Attempts        int        `json:"attempts"`
+	OfflineAttempts int        `json:"offline_attempts"` // Reintentos seguidos con el bot desconectado
+	NextAttemptAt   time.Time  `json:"next_attempt_at" gorm:"index:idx_outbound_dispatch"`
+	EnvelopeID      string     `json:"envelope_id,omitempty" gorm:"index"` // Envío en curso en el WebSocketHub
+	DispatchedBy    string     `json:"dispatched_by,omitempty"`            // Instancia de la API con el envío en curso
+	LastError       string     `json:"last_error,omitempty"`
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RefreshToken es un token opaco de larga duración con el que el dashboard obtiene nuevos
+// access tokens. Solo se guarda el SHA-256 del token. Cada uso lo rota: se marca UsedAt y
+// se emite otro de la misma familia (FamilyID = un inicio de sesión). Si un token ya
+// usado vuelve a llegar, alguien lo copió y se revoca toda la familia.
+type RefreshToken struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null"`
+	FamilyID       string     `json:"family_id" gorm:"not null;index"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`
+	TokenVersion   uint       `json:"-" gorm:"not null;default:0"` // SystemUser.TokenVersion al emitirlo
+	UserAgent      string     `json:"user_agent"`
+	IP             string     `json:"ip"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt         *time.Time `json:"used_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Vehicle es un vehículo de carga registrado por la organización. La placa es única por
+// organización y se usa como placaVehiculo del manifiesto.
+type Vehicle struct {
+	ID                     uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID         uint       `json:"organization_id" gorm:"not null;uniqueIndex:idx_vehicle_org_placa"`
+	Placa                  string     `json:"placa" gorm:"size:6;not null;uniqueIndex:idx_vehicle_org_placa"` // ABC123
+	Configuracion          string     `json:"configuracion"`                                                  // Configuración RNDC: 2, 3, 3S2, 3S3...
+	OwnerTipoID            string     `json:"owner_tipo_id"`                                                  // Cedula Ciudadania, Nit o Cedula Extranjeria
+	OwnerNumeroID          string     `json:"owner_numero_id"`
+	OwnerName              string     `json:"owner_name"`
+	TarjetaPropiedad       string     `json:"tarjeta_propiedad"` // Número de la licencia de tránsito
+	SOATExpiresAt          *time.Time `json:"soat_expires_at"`
+	TecnomecanicaExpiresAt *time.Time `json:"tecnomecanica_expires_at"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+}
+
+// Driver es un conductor registrado por la organización. Se enlaza al Client de WhatsApp
+// por el teléfono, así sus chats pueden precargar su cédula y su vehículo.
+type Driver struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID   uint       `json:"organization_id" gorm:"not null;uniqueIndex:idx_driver_org_cedula;index:idx_driver_org_phone"`
+	Cedula           string     `json:"cedula" gorm:"size:10;not null;uniqueIndex:idx_driver_org_cedula"` // Es también el número de la licencia
+	TipoID           string     `json:"tipo_id"`                                                          // Cedula Ciudadania o Cedula Extranjeria
+	Name             string     `json:"name"`
+	LicenseCategory  string     `json:"license_category"` // C1, C2, C3...
+	LicenseExpiresAt *time.Time `json:"license_expires_at"`
+	Phone            string     `json:"phone" gorm:"index:idx_driver_org_phone"` // Formato de WhatsApp: 573001234567
+	ClientID         *uint      `json:"client_id" gorm:"index"`
+	VehicleID        *uint      `json:"vehicle_id"` // Vehículo habitual
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
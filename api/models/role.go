@@ -0,0 +1,145 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Permisos de los usuarios del dashboard. Cada ruta protegida exige uno (ver
+// middleware.RequirePermission) y /auth/me los devuelve para ocultar acciones.
+const (
+	PermissionWhatsAppSend        = "whatsapp:send"   // Enviar mensajes a los chats
+	PermissionWhatsAppManage      = "whatsapp:manage" // Sesiones, QR y credenciales de los bots
+	PermissionChatsRead           = "chats:read"
+	PermissionChatsTakeover       = "chats:takeover" // Tomar el chat (modo manual), archivarlo o reiniciar Rasa
+	PermissionDocumentsRead       = "documents:read"
+	PermissionDocumentsManage     = "documents:manage" // Generar, reintentar, cumplir, anular y compartir
+	PermissionClientsRead         = "clients:read"
+	PermissionClientsManage       = "clients:manage"
+	PermissionRegistryRead        = "registry:read" // Vehículos, conductores y vencimientos
+	PermissionRegistryManage      = "registry:manage"
+	PermissionDashboardRead       = "dashboard:read"
+	PermissionBotInstancesManage  = "bot-instances:manage"
+	PermissionUsersManage         = "users:manage"
+	PermissionRolesManage         = "roles:manage"
+	PermissionAPIKeysManage       = "api-keys:manage" // Crear y revocar las API keys de la organización
+	PermissionOrganizationsManage = "organizations:manage"
+)
+
+// AllPermissions es el catálogo de permisos asignables
+var AllPermissions = []string{
+	PermissionWhatsAppSend,
+	PermissionWhatsAppManage,
+	PermissionChatsRead,
+	PermissionChatsTakeover,
+	PermissionDocumentsRead,
+	PermissionDocumentsManage,
+	PermissionClientsRead,
+	PermissionClientsManage,
+	PermissionRegistryRead,
+	PermissionRegistryManage,
+	PermissionDashboardRead,
+	PermissionBotInstancesManage,
+	PermissionUsersManage,
+	PermissionRolesManage,
+	PermissionAPIKeysManage,
+	PermissionOrganizationsManage,
+}
+
+// Roles predefinidos; existen en todas las organizaciones y no se pueden editar
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleAgent  = "agent"
+	RoleViewer = "viewer"
+
+	// RoleLegacyUser es el rol por defecto anterior; se migra a agent
+	RoleLegacyUser = "user"
+)
+
+// BuiltInRoles son los permisos de los roles predefinidos
+var BuiltInRoles = map[string][]string{
+	RoleOwner: AllPermissions,
+	RoleAdmin: {
+		PermissionWhatsAppSend, PermissionWhatsAppManage,
+		PermissionChatsRead, PermissionChatsTakeover,
+		PermissionDocumentsRead, PermissionDocumentsManage,
+		PermissionClientsRead, PermissionClientsManage,
+		PermissionRegistryRead, PermissionRegistryManage,
+		PermissionDashboardRead, PermissionBotInstancesManage,
+		PermissionUsersManage, PermissionAPIKeysManage,
+	},
+	RoleAgent: {
+		PermissionWhatsAppSend,
+		PermissionChatsRead, PermissionChatsTakeover,
+		PermissionDocumentsRead, PermissionDocumentsManage,
+		PermissionClientsRead, PermissionClientsManage,
+		PermissionRegistryRead, PermissionDashboardRead,
+	},
+	RoleViewer: {
+		PermissionChatsRead, PermissionDocumentsRead, PermissionClientsRead,
+		PermissionRegistryRead, PermissionDashboardRead,
+	},
+}
+
+// Role es un rol personalizado de una organización. Name es el valor que se guarda en
+// SystemUser.Role y no puede coincidir con un rol predefinido.
+type Role struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;uniqueIndex:idx_role_org_name"`
+	Name           string    `json:"name" gorm:"not null;uniqueIndex:idx_role_org_name"`
+	Description    string    `json:"description"`
+	Permissions    []string  `json:"permissions" gorm:"serializer:json;not null"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// IsBuiltInRole indica si el nombre es de un rol predefinido
+func IsBuiltInRole(name string) bool {
+	_, ok := BuiltInRoles[name]
+	return ok
+}
+
+// IsValidPermission indica si el permiso está en el catálogo
+func IsValidPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission indica si la lista de permisos incluye permission
+func HasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludesPermissions indica si permissions contiene todos los de subset
+func IncludesPermissions(permissions, subset []string) bool {
+	for _, p := range subset {
+		if !HasPermission(permissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizePermissions quita duplicados y ordena la lista
+func NormalizePermissions(permissions []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, p := range permissions {
+		if !seen[p] {
+			seen[p] = true
+			normalized = append(normalized, p)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// RouteTemplate es una ruta guardada de un cliente (origen, destino y carga habituales)
+// para generar manifiestos sin volver a dictar todos los datos. Los campos usan los
+// mismos nombres y formatos que los slots de manifiesto_form.
+type RouteTemplate struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;uniqueIndex:idx_route_template_client_name"`
+	ClientID       uint      `json:"client_id" gorm:"not null;uniqueIndex:idx_route_template_client_name"`
+	Name           string    `json:"name" gorm:"not null;uniqueIndex:idx_route_template_client_name"`
+	Origen         string    `json:"origen" gorm:"not null"`
+	Destino        string    `json:"destino" gorm:"not null"`
+	Descripcion    string    `json:"descripcion"`
+	Peso           string    `json:"peso"`
+	Flete          string    `json:"flete"`
+	Tarjeta        string    `json:"tarjeta"`  // Placa del vehículo
+	Licencia       string    `json:"licencia"` // Cédula del conductor
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Slots devuelve los datos de la ruta como slots del manifiesto (omite los vacíos)
+func (t RouteTemplate) Slots() map[string]interface{} {
+	slots := map[string]interface{}{}
+	for name, value := range map[string]string{
+		"origen":      t.Origen,
+		"destino":     t.Destino,
+		"descripcion": t.Descripcion,
+		"peso":        t.Peso,
+		"flete":       t.Flete,
+		"tarjeta":     t.Tarjeta,
+		"licencia":    t.Licencia,
+	} {
+		if value != "" {
+			slots[name] = value
+		}
+	}
+	return slots
+}
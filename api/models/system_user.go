@@ -12,11 +12,14 @@ type SystemUser struct {
 	Username       string         `json:"username" gorm:"uniqueIndex;not null"`
 	Email          string         `json:"email" gorm:"uniqueIndex;not null"`
 	PasswordHash   string         `json:"-" gorm:"not null"`        // Nunca exponer esto en JSON
-	Role           string         `json:"role" gorm:"default:user"` // user, admin, etc.
+	Role           string         `json:"role" gorm:"default:agent"` // Rol predefinido (owner, admin, agent, viewer) o personalizado
 	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	// TokenVersion invalida todos los tokens emitidos antes de incrementarlo (cerrar
+	// sesión en todos los dispositivos, desactivación o cambio de contraseña)
+	TokenVersion uint `json:"-" gorm:"not null;default:0"`
 	LastLogin      *time.Time     `json:"last_login"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 	Organization Organization `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
-}
\ No newline at end of file
+}
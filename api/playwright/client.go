@@ -0,0 +1,211 @@
+// Package playwright es el cliente HTTP de playwright-bot (automatización del RNDC)
+package playwright
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "http://playwright:3001"
+	// El bot llena los formularios del RNDC y encola las solicitudes: puede tardar minutos
+	DefaultTimeout = 5 * time.Minute
+
+	maxFileSize = 20 << 20
+
+	// ChecksumHeader trae el SHA-256 (hex) del archivo en /api/download/:fileId
+	ChecksumHeader = "X-Checksum-Sha256"
+)
+
+// Client cubre los endpoints HTTP de playwright-bot (ver playwright-bot/index.js)
+type Client interface {
+	CreateManifiesto(ctx context.Context, req ManifiestoRequest) (*ManifiestoResult, error)
+	// CumplirManifiesto radica el cumplido de la remesa y del manifiesto
+	CumplirManifiesto(ctx context.Context, req CumplidoRequest) (*FollowUpResult, error)
+	AnularManifiesto(ctx context.Context, req AnulacionRequest) (*FollowUpResult, error)
+	// Download descarga el archivo de un downloadUrl devuelto por CreateManifiesto
+	Download(ctx context.Context, downloadURL string) (*File, error)
+	Health(ctx context.Context) (*Health, error)
+}
+
+// Config configura el cliente HTTP
+type Config struct {
+	BaseURL    string
+	Timeout    time.Duration // Timeout por solicitud si el contexto no tiene deadline
+	HTTPClient *http.Client
+}
+
+type httpClient struct {
+	baseURL string
+	timeout time.Duration
+	http    *http.Client
+}
+
+// NewClient crea un cliente para playwright-bot
+func NewClient(cfg Config) Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{}
+	}
+	return &httpClient{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		timeout: cfg.Timeout,
+		http:    cfg.HTTPClient,
+	}
+}
+
+func (c *httpClient) CreateManifiesto(ctx context.Context, req ManifiestoRequest) (*ManifiestoResult, error) {
+	var out ManifiestoResult
+	if err := c.doJSON(ctx, http.MethodPost, "/api/manifiesto", req, &out); err != nil {
+		return nil, err
+	}
+	if out.DownloadURL == "" {
+		return nil, fmt.Errorf("respuesta de playwright sin downloadUrl")
+	}
+	return &out, nil
+}
+
+func (c *httpClient) CumplirManifiesto(ctx context.Context, req CumplidoRequest) (*FollowUpResult, error) {
+	return c.followUp(ctx, "/api/cumplido", req)
+}
+
+func (c *httpClient) AnularManifiesto(ctx context.Context, req AnulacionRequest) (*FollowUpResult, error) {
+	return c.followUp(ctx, "/api/anulacion", req)
+}
+
+func (c *httpClient) followUp(ctx context.Context, path string, req interface{}) (*FollowUpResult, error) {
+	var out FollowUpResult
+	if err := c.doJSON(ctx, http.MethodPost, path, req, &out); err != nil {
+		return nil, err
+	}
+	if !out.Success {
+		return nil, fmt.Errorf("respuesta de playwright sin éxito en %s", path)
+	}
+	return &out, nil
+}
+
+func (c *httpClient) Health(ctx context.Context) (*Health, error) {
+	var out Health
+	if err := c.doJSON(ctx, http.MethodGet, "/health", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Download usa solo la ruta del downloadUrl: playwright-bot lo arma con su BASE_URL, que
+// no siempre es alcanzable desde la API (p. ej. http://localhost:3001 dentro de Docker)
+func (c *httpClient) Download(ctx context.Context, downloadURL string) (*File, error) {
+	parsed, err := url.Parse(downloadURL)
+	if err != nil || !strings.HasPrefix(parsed.Path, "/api/download/") {
+		return nil, fmt.Errorf("downloadUrl inválido: %q", downloadURL)
+	}
+
+	resp, cancel, err := c.send(ctx, http.MethodGet, parsed.EscapedPath(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: error leyendo archivo: %w", ErrUnavailable, err)
+	}
+	if len(content) > maxFileSize {
+		return nil, fmt.Errorf("el archivo supera el tamaño máximo (%d bytes)", maxFileSize)
+	}
+	// Una descarga cortada o alterada se trata como transitoria para que se reintente
+	if resp.ContentLength >= 0 && int64(len(content)) != resp.ContentLength {
+		return nil, fmt.Errorf("%w: archivo incompleto (%d de %d bytes)", ErrUnavailable, len(content), resp.ContentLength)
+	}
+	if expected := resp.Header.Get(ChecksumHeader); expected != "" {
+		sum := sha256.Sum256(content)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), expected) {
+			return nil, fmt.Errorf("%w: el checksum del archivo no coincide", ErrUnavailable)
+		}
+	}
+
+	file := &File{
+		FileName:    path.Base(parsed.Path),
+		ContentType: resp.Header.Get("Content-Type"),
+		Content:     content,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		file.FileName = params["filename"]
+	}
+	return file, nil
+}
+
+// doJSON envía body como JSON y decodifica la respuesta en out
+func (c *httpClient) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error serializando solicitud: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	resp, cancel, err := c.send(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decodificando respuesta de %s: %w", path, err)
+	}
+	return nil
+}
+
+// send ejecuta la solicitud aplicando el timeout por defecto y mapeando los errores. El
+// llamador cierra el cuerpo y luego llama cancel.
+func (c *httpClient) send(ctx context.Context, method, path string, body io.Reader) (*http.Response, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("error creando solicitud: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("%w: %s %s: %w", ErrUnavailable, method, path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer cancel()
+		defer resp.Body.Close()
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		// El cuerpo de error es opcional; si no es JSON queda el status
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(apiErr)
+		return nil, nil, apiErr
+	}
+	return resp, cancel, nil
+}
@@ -0,0 +1,88 @@
+package playwright
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRequest() ManifiestoRequest {
+	return ManifiestoRequest{
+		Remesa: Remesa{
+			Consecutivo:      "DB-1",
+			DescripcionCorta: "Cajas de repuestos",
+			CantidadEstimada: 500,
+			Empresa:          Empresa{NIT: "8600537463", SedeCargue: "SEDE-001", SedeDescargue: "SEDE-002"},
+		},
+		Manifiesto: Manifiesto{
+			MunicipioOrigen:  "Bogotá",
+			MunicipioDestino: "Medellín",
+			TitularNumeroID:  "1234567890",
+			Vehiculo:         Vehiculo{PlacaVehiculo: "ABC123", ConductorNumeroID: "9876543210"},
+			ValorPagar:       "500000",
+			LugarPago:        "Medellín",
+		},
+	}
+}
+
+func TestClientCreateAndDownload(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+
+	client := NewClient(Config{BaseURL: fake.URL + "/"})
+	result, err := client.CreateManifiesto(context.Background(), testRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "DB-1", result.ConsecutivoRemesa)
+	assert.Equal(t, "00000001", result.ConsecutivoManifiesto)
+	require.Len(t, fake.Requested(), 1)
+	assert.Equal(t, "ABC123", fake.Requested()[0].Manifiesto.PlacaVehiculo)
+
+	// El downloadUrl apunta a localhost:3001; se descarga del servidor configurado
+	file, err := client.Download(context.Background(), result.DownloadURL)
+	require.NoError(t, err)
+	assert.Equal(t, "manifiesto_file-1.pdf", file.FileName)
+	assert.Equal(t, "application/pdf", file.ContentType)
+	assert.Equal(t, FakePDF, file.Content)
+
+	_, err = client.Download(context.Background(), "http://localhost:3001/api/download/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.Download(context.Background(), "http://localhost:3001/etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestClientErrorMapping(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	client := NewClient(Config{BaseURL: fake.URL, Timeout: 50 * time.Millisecond})
+
+	fake.Handle = func(req ManifiestoRequest) *APIError {
+		return &APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "La placa no existe en el RNDC",
+			Details:    &ErrorDetails{Type: "VALIDATION_ERROR", PageErrors: []string{"Placa no registrada"}},
+		}
+	}
+	_, err := client.CreateManifiesto(context.Background(), testRequest())
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.False(t, Retryable(err))
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, []string{"Placa no registrada"}, apiErr.Details.PageErrors)
+
+	fake.Handle = func(req ManifiestoRequest) *APIError {
+		return &APIError{StatusCode: http.StatusTooManyRequests, Message: "Too many requests"}
+	}
+	_, err = client.CreateManifiesto(context.Background(), testRequest())
+	assert.True(t, Retryable(err))
+
+	fake.Handle = nil
+	fake.Delay = time.Second
+	_, err = client.CreateManifiesto(context.Background(), testRequest())
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
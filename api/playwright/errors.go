@@ -0,0 +1,60 @@
+package playwright
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrRejected    = errors.New("el RNDC rechazó el documento")        // 400: datos inválidos o error del formulario
+	ErrRateLimited = errors.New("demasiadas solicitudes a playwright") // 429
+	ErrNotFound    = errors.New("archivo no encontrado o expirado")
+	ErrUnavailable = errors.New("servicio de playwright no disponible") // fallos de red, timeout o 5xx
+)
+
+// ErrorDetails es el detalle de un RNDCError de playwright-bot
+type ErrorDetails struct {
+	Type       string   `json:"type,omitempty"`
+	PageErrors []string `json:"pageErrors,omitempty"`
+	Alerts     []string `json:"alerts,omitempty"`
+	Screenshot string   `json:"screenshot,omitempty"`
+}
+
+// APIError representa una respuesta de error de playwright-bot
+type APIError struct {
+	StatusCode int           `json:"-"`
+	Message    string        `json:"error"`
+	Details    *ErrorDetails `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Details != nil && e.Details.Type != "" {
+		return fmt.Sprintf("playwright %d: %s (%s)", e.StatusCode, msg, e.Details.Type)
+	}
+	return fmt.Sprintf("playwright %d: %s", e.StatusCode, msg)
+}
+
+// Unwrap permite usar errors.Is con los errores de la familia
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrRejected
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// Retryable indica si vale la pena reintentar la solicitud
+func Retryable(err error) bool {
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrRateLimited)
+}
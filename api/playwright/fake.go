@@ -0,0 +1,176 @@
+package playwright
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakePDF es el contenido del PDF que devuelve el FakeServer
+var FakePDF = []byte("%PDF-1.4\n% manifiesto de prueba\n%%EOF\n")
+
+// FakeServer reemplaza a playwright-bot en pruebas y desarrollo local: acepta
+// POST /api/manifiesto, /api/cumplido y /api/anulacion y sirve un PDF en /api/download/:fileId.
+type FakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	files    map[string][]byte
+	sequence int
+
+	// Handle decide el resultado de cada solicitud; nil genera el manifiesto. Si devuelve
+	// un *APIError se responde con su status.
+	Handle func(req ManifiestoRequest) *APIError
+	// Delay retrasa la creación del manifiesto (para probar timeouts)
+	Delay time.Duration
+	// Requests registra las solicitudes de creación recibidas
+	Requests []ManifiestoRequest
+
+	// HandleFollowUp decide el resultado de los cumplidos y anulaciones (path es la ruta)
+	HandleFollowUp func(path string) *APIError
+	Cumplidos      []CumplidoRequest
+	Anulaciones    []AnulacionRequest
+}
+
+// NewFakeServer arranca un playwright-bot falso; se cierra con Close
+func NewFakeServer() *FakeServer {
+	f := &FakeServer{files: make(map[string][]byte)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *FakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/manifiesto":
+		f.handleManifiesto(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/cumplido":
+		var req CumplidoRequest
+		f.handleFollowUp(w, r, &req, func() { f.Cumplidos = append(f.Cumplidos, req) }, func() string { return req.ConsecutivoManifiesto })
+	case r.Method == http.MethodPost && r.URL.Path == "/api/anulacion":
+		var req AnulacionRequest
+		f.handleFollowUp(w, r, &req, func() { f.Anulaciones = append(f.Anulaciones, req) }, func() string { return req.ConsecutivoManifiesto })
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/download/"):
+		f.handleDownload(w, strings.TrimPrefix(r.URL.Path, "/api/download/"))
+	case r.Method == http.MethodGet && r.URL.Path == "/health":
+		json.NewEncoder(w).Encode(Health{Status: "ok", BotInitialized: true})
+	default:
+		writeFakeError(w, &APIError{StatusCode: http.StatusNotFound, Message: "Ruta no encontrada"})
+	}
+}
+
+func (f *FakeServer) handleManifiesto(w http.ResponseWriter, r *http.Request) {
+	var req ManifiestoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFakeError(w, &APIError{StatusCode: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	f.mu.Lock()
+	f.Requests = append(f.Requests, req)
+	handle := f.Handle
+	f.mu.Unlock()
+
+	if handle != nil {
+		if apiErr := handle(req); apiErr != nil {
+			writeFakeError(w, apiErr)
+			return
+		}
+	}
+
+	f.mu.Lock()
+	f.sequence++
+	sequence := f.sequence
+	fileID := fmt.Sprintf("file-%d", sequence)
+	f.files[fileID] = FakePDF
+	f.mu.Unlock()
+
+	json.NewEncoder(w).Encode(ManifiestoResult{
+		Success:               true,
+		ConsecutivoRemesa:     req.Remesa.Consecutivo,
+		ConsecutivoManifiesto: fmt.Sprintf("%08d", sequence),
+		// Igual que playwright-bot, la URL usa un host que no es el del servidor
+		DownloadURL: "http://localhost:3001/api/download/" + fileID,
+		ExpiresAt:   time.Now().Add(time.Hour).Format(time.RFC3339),
+	})
+}
+
+// handleFollowUp decodifica la solicitud en req, la registra con record y responde con un radicado
+func (f *FakeServer) handleFollowUp(w http.ResponseWriter, r *http.Request, req interface{}, record func(), consecutivo func() string) {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeFakeError(w, &APIError{StatusCode: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	f.mu.Lock()
+	record()
+	handle := f.HandleFollowUp
+	f.sequence++
+	sequence := f.sequence
+	f.mu.Unlock()
+
+	if handle != nil {
+		if apiErr := handle(r.URL.Path); apiErr != nil {
+			writeFakeError(w, apiErr)
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(FollowUpResult{
+		Success:               true,
+		ConsecutivoManifiesto: consecutivo(),
+		Radicado:              fmt.Sprintf("%010d", sequence),
+	})
+}
+
+func (f *FakeServer) handleDownload(w http.ResponseWriter, fileID string) {
+	f.mu.Lock()
+	content, ok := f.files[fileID]
+	f.mu.Unlock()
+	if !ok {
+		writeFakeError(w, &APIError{StatusCode: http.StatusNotFound, Message: "Archivo no encontrado o expirado"})
+		return
+	}
+
+	sum := sha256.Sum256(content)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set(ChecksumHeader, hex.EncodeToString(sum[:]))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="manifiesto_%s.pdf"`, fileID))
+	w.Write(content)
+}
+
+// Requested devuelve una copia de las solicitudes recibidas
+func (f *FakeServer) Requested() []ManifiestoRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ManifiestoRequest(nil), f.Requests...)
+}
+
+// FollowUps devuelve una copia de los cumplidos y anulaciones recibidos
+func (f *FakeServer) FollowUps() ([]CumplidoRequest, []AnulacionRequest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]CumplidoRequest(nil), f.Cumplidos...), append([]AnulacionRequest(nil), f.Anulaciones...)
+}
+
+func writeFakeError(w http.ResponseWriter, apiErr *APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.StatusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   apiErr.Message,
+		"details": apiErr.Details,
+	})
+}
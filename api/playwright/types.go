@@ -0,0 +1,45 @@
+package playwright
+
+import "github.com/brando1998/docubot-api/rndc"
+
+// Los datos de la solicitud son los del dominio RNDC (ver rndc.Validate); se exponen
+// aquí con los mismos nombres para los clientes del paquete
+type (
+	Empresa           = rndc.Empresa
+	Vehiculo          = rndc.Vehiculo
+	Remesa            = rndc.Remesa
+	Manifiesto        = rndc.Manifiesto
+	ManifiestoRequest = rndc.ManifiestoRequest // Cuerpo de POST /api/manifiesto
+	CumplidoRequest   = rndc.CumplidoRequest   // Cuerpo de POST /api/cumplido
+	AnulacionRequest  = rndc.AnulacionRequest  // Cuerpo de POST /api/anulacion
+)
+
+// ManifiestoResult es la respuesta exitosa de POST /api/manifiesto
+type ManifiestoResult struct {
+	Success               bool   `json:"success"`
+	ConsecutivoRemesa     string `json:"consecutivoRemesa"`
+	ConsecutivoManifiesto string `json:"consecutivoManifiesto"`
+	DownloadURL           string `json:"downloadUrl"`
+	ExpiresAt             string `json:"expiresAt,omitempty"`
+}
+
+// FollowUpResult es la respuesta exitosa de POST /api/cumplido y /api/anulacion. El RNDC
+// no genera un PDF: el radicado es el número de ingreso que asigna al aceptar la solicitud.
+type FollowUpResult struct {
+	Success               bool   `json:"success"`
+	ConsecutivoManifiesto string `json:"consecutivoManifiesto"`
+	Radicado              string `json:"radicado"`
+}
+
+// File es un archivo descargado de /api/download/:fileId
+type File struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// Health es la respuesta de GET /health
+type Health struct {
+	Status         string `json:"status"`
+	BotInitialized bool   `json:"botInitialized"`
+}
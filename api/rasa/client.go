@@ -0,0 +1,239 @@
+// Package rasa es el cliente HTTP de los servidores Rasa (bot base e instancias por organización)
+package rasa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "http://rasa:5005"
+	DefaultTimeout = 10 * time.Second
+)
+
+// Client cubre la API HTTP de un servidor Rasa
+type Client interface {
+	// SendMessage envía un mensaje por el canal REST y devuelve las respuestas del bot
+	SendMessage(ctx context.Context, sender, message string) ([]Response, error)
+	GetTracker(ctx context.Context, conversationID string) (*Tracker, error)
+	AppendEvents(ctx context.Context, conversationID string, events ...Event) (*Tracker, error)
+	// Restart borra slots y formulario activo de la conversación
+	Restart(ctx context.Context, conversationID string) (*Tracker, error)
+	SetSlot(ctx context.Context, conversationID, name string, value interface{}) (*Tracker, error)
+	Parse(ctx context.Context, text string) (*ParseResult, error)
+	Status(ctx context.Context) (*Status, error)
+	BaseURL() string
+}
+
+// Config configura los clientes de Rasa
+type Config struct {
+	BaseURL    string
+	Token      string        // --auth-token del servidor, se envía como ?token=
+	Timeout    time.Duration // Timeout por solicitud si el contexto no tiene deadline
+	HTTPClient *http.Client
+}
+
+type httpClient struct {
+	baseURL string
+	token   string
+	timeout time.Duration
+	http    *http.Client
+}
+
+// NewClient crea un cliente para un servidor Rasa
+func NewClient(cfg Config) Client {
+	cfg = withDefaults(cfg)
+	return &httpClient{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		token:   cfg.Token,
+		timeout: cfg.Timeout,
+		http:    cfg.HTTPClient,
+	}
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{}
+	}
+	return cfg
+}
+
+// Pool entrega un cliente por servidor Rasa. Todos comparten el mismo http.Client
+// (y sus conexiones), el token y el timeout.
+type Pool struct {
+	config  Config
+	mu      sync.Mutex
+	clients map[string]Client
+}
+
+// NewPool crea el pool; config.BaseURL es el servidor por defecto
+func NewPool(cfg Config) *Pool {
+	return &Pool{config: withDefaults(cfg), clients: make(map[string]Client)}
+}
+
+// Default devuelve el cliente del servidor por defecto
+func (p *Pool) Default() Client {
+	return p.For("")
+}
+
+// For devuelve el cliente de baseURL (o el por defecto si está vacío)
+func (p *Pool) For(baseURL string) Client {
+	if baseURL == "" {
+		baseURL = p.config.BaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[baseURL]; ok {
+		return client
+	}
+	cfg := p.config
+	cfg.BaseURL = baseURL
+	client := NewClient(cfg)
+	p.clients[baseURL] = client
+	return client
+}
+
+func (c *httpClient) BaseURL() string {
+	return c.baseURL
+}
+
+func (c *httpClient) SendMessage(ctx context.Context, sender, message string) ([]Response, error) {
+	if sender == "" {
+		return nil, fmt.Errorf("%w: sender es requerido", ErrInvalidRequest)
+	}
+
+	body := map[string]string{"sender": sender, "message": message}
+	out := []Response{}
+	if err := c.do(ctx, http.MethodPost, "/webhooks/rest/webhook", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *httpClient) GetTracker(ctx context.Context, conversationID string) (*Tracker, error) {
+	var out Tracker
+	query := url.Values{"include_events": {"AFTER_RESTART"}}
+	if err := c.do(ctx, http.MethodGet, trackerPath(conversationID, ""), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) AppendEvents(ctx context.Context, conversationID string, events ...Event) (*Tracker, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: se requiere al menos un evento", ErrInvalidRequest)
+	}
+
+	var out Tracker
+	query := url.Values{"include_events": {"NONE"}}
+	if err := c.do(ctx, http.MethodPost, trackerPath(conversationID, "/events"), query, events, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) Restart(ctx context.Context, conversationID string) (*Tracker, error) {
+	return c.AppendEvents(ctx, conversationID, Event{Event: EventRestart})
+}
+
+func (c *httpClient) SetSlot(ctx context.Context, conversationID, name string, value interface{}) (*Tracker, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: el nombre del slot es requerido", ErrInvalidRequest)
+	}
+	return c.AppendEvents(ctx, conversationID, Event{Event: EventSlot, Name: name, Value: value})
+}
+
+func (c *httpClient) Parse(ctx context.Context, text string) (*ParseResult, error) {
+	var out ParseResult
+	if err := c.do(ctx, http.MethodPost, "/model/parse", nil, map[string]string{"text": text}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *httpClient) Status(ctx context.Context) (*Status, error) {
+	var out Status
+	if err := c.do(ctx, http.MethodGet, "/status", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func trackerPath(conversationID, suffix string) string {
+	return "/conversations/" + url.PathEscape(conversationID) + "/tracker" + suffix
+}
+
+// do ejecuta la solicitud aplicando el timeout por defecto y mapeando los errores
+func (c *httpClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.token != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("token", c.token)
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error serializando solicitud: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("error creando solicitud: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s %s: %w", ErrUnavailable, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		// El cuerpo de error es opcional; si no es JSON queda el status
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(apiErr)
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decodificando respuesta de %s: %w", path, err)
+	}
+	return nil
+}
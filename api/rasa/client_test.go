@@ -0,0 +1,114 @@
+package rasa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSendMessage(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.Reply = func(sender, message string) []Response {
+		return []Response{
+			{Text: "¿Cuál es el peso de la carga?"},
+			{Buttons: []Button{{Title: "Sí", Payload: "/affirm"}}},
+		}
+	}
+
+	client := NewClient(Config{BaseURL: fake.URL + "/"})
+	responses, err := client.SendMessage(context.Background(), "bot-1:573001234567@s.whatsapp.net", "hola")
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, "¿Cuál es el peso de la carga?", responses[0].Text)
+	assert.Equal(t, "bot-1:573001234567@s.whatsapp.net", responses[0].RecipientID)
+	assert.Equal(t, "/affirm", responses[1].Buttons[0].Payload)
+	assert.Equal(t, []FakeMessage{{Sender: "bot-1:573001234567@s.whatsapp.net", Message: "hola"}}, fake.Received)
+}
+
+func TestClientTrackerSlotsAndRestart(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	conversationID := "bot-1:573001234567@s.whatsapp.net"
+	fake.SetSlots(conversationID, "manifiesto_form", map[string]interface{}{"origen": "Bogotá", "peso": 1200.0})
+
+	client := NewClient(Config{BaseURL: fake.URL})
+	ctx := context.Background()
+
+	tracker, err := client.GetTracker(ctx, conversationID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bogotá", tracker.Slots["origen"])
+	assert.Equal(t, "manifiesto_form", tracker.ActiveLoop.Name)
+
+	tracker, err = client.SetSlot(ctx, conversationID, "flete", 850000.0)
+	require.NoError(t, err)
+	assert.Equal(t, 850000.0, tracker.Slots["flete"])
+
+	tracker, err = client.Restart(ctx, conversationID)
+	require.NoError(t, err)
+	assert.Empty(t, tracker.Slots)
+	assert.Empty(t, tracker.ActiveLoop.Name)
+	assert.Equal(t, EventRestart, fake.Tracker(conversationID).Events[0].Event)
+}
+
+func TestClientParseAndStatus(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.Token = "secreto"
+	fake.ParseFunc = func(text string) ParseResult {
+		return ParseResult{Text: text, Intent: Intent{Name: "solicitar_manifiesto", Confidence: 0.93}}
+	}
+
+	client := NewClient(Config{BaseURL: fake.URL, Token: "secreto"})
+	result, err := client.Parse(context.Background(), "necesito un manifiesto")
+	require.NoError(t, err)
+	assert.Equal(t, "solicitar_manifiesto", result.Intent.Name)
+
+	status, err := client.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "fake", status.ModelID)
+
+	_, err = NewClient(Config{BaseURL: fake.URL}).Status(context.Background())
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestClientErrors(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+	fake.Delay = 200 * time.Millisecond
+
+	client := NewClient(Config{BaseURL: fake.URL, Timeout: 20 * time.Millisecond})
+	_, err := client.SendMessage(context.Background(), "bot-1:573001", "hola")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = client.AppendEvents(context.Background(), "bot-1:573001")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer server.Close()
+
+	_, err = NewClient(Config{BaseURL: server.URL}).Status(context.Background())
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+}
+
+func TestPoolSharesHTTPClient(t *testing.T) {
+	shared := &http.Client{}
+	pool := NewPool(Config{BaseURL: "http://rasa:5005", HTTPClient: shared})
+
+	assert.Equal(t, "http://rasa:5005", pool.Default().BaseURL())
+	assert.Same(t, pool.For("http://localhost:5006/"), pool.For("http://localhost:5006"))
+	assert.Same(t, shared, pool.For("http://localhost:5006").(*httpClient).http)
+	assert.Same(t, shared, pool.Default().(*httpClient).http)
+}
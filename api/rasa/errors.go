@@ -0,0 +1,47 @@
+package rasa
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrNotFound       = errors.New("recurso de Rasa no encontrado")
+	ErrUnauthorized   = errors.New("token de Rasa inválido")
+	ErrInvalidRequest = errors.New("solicitud inválida para Rasa")
+	ErrUnavailable    = errors.New("servidor Rasa no disponible") // fallos de red o timeout
+)
+
+// APIError representa una respuesta de error de la API HTTP de Rasa
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("rasa %d: %s (%s)", e.StatusCode, msg, e.Reason)
+	}
+	return fmt.Sprintf("rasa %d: %s", e.StatusCode, msg)
+}
+
+// Unwrap permite usar errors.Is con los errores de la familia
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrInvalidRequest
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package rasa
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeServer es un servidor Rasa en memoria para pruebas. Implementa el canal REST,
+// el tracker con inyección de eventos, /model/parse y /status sobre httptest.
+type FakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	trackers map[string]*Tracker
+
+	// Reply arma las respuestas del bot; por defecto no responde nada
+	Reply func(sender, message string) []Response
+	// ParseFunc arma la respuesta de /model/parse; por defecto nlu_fallback
+	ParseFunc func(text string) ParseResult
+	// Token, si no está vacío, se exige en ?token=
+	Token string
+	// Delay retrasa todas las respuestas (para probar timeouts)
+	Delay time.Duration
+	// Received registra los mensajes recibidos por el webhook
+	Received []FakeMessage
+}
+
+// FakeMessage es un mensaje recibido por el webhook del FakeServer
+type FakeMessage struct {
+	Sender  string
+	Message string
+}
+
+// NewFakeServer arranca un servidor Rasa falso; se cierra con Close
+func NewFakeServer() *FakeServer {
+	f := &FakeServer{trackers: make(map[string]*Tracker)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// SetSlots asigna slots a una conversación como si los hubiera llenado un formulario
+func (f *FakeServer) SetSlots(conversationID string, loop string, slots map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tracker := f.tracker(conversationID)
+	for name, value := range slots {
+		tracker.Slots[name] = value
+	}
+	tracker.ActiveLoop = ActiveLoop{Name: loop}
+}
+
+// Tracker devuelve una copia del tracker de la conversación
+func (f *FakeServer) Tracker(conversationID string) Tracker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.copyTracker(f.tracker(conversationID))
+}
+
+func (f *FakeServer) tracker(conversationID string) *Tracker {
+	tracker, ok := f.trackers[conversationID]
+	if !ok {
+		tracker = &Tracker{SenderID: conversationID, Slots: map[string]interface{}{}}
+		f.trackers[conversationID] = tracker
+	}
+	return tracker
+}
+
+func (f *FakeServer) copyTracker(tracker *Tracker) Tracker {
+	out := *tracker
+	out.Slots = make(map[string]interface{}, len(tracker.Slots))
+	for name, value := range tracker.Slots {
+		out.Slots[name] = value
+	}
+	out.Events = append([]Event(nil), tracker.Events...)
+	return out
+}
+
+func (f *FakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if f.Token != "" && r.URL.Query().Get("token") != f.Token {
+		writeFakeError(w, http.StatusUnauthorized, "NotAuthenticated", "User is not authenticated.")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/webhooks/rest/webhook":
+		f.handleWebhook(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/model/parse":
+		f.handleParse(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/status":
+		json.NewEncoder(w).Encode(Status{ModelFile: "models/fake.tar.gz", ModelID: "fake"})
+	case strings.HasPrefix(r.URL.Path, "/conversations/"):
+		f.handleTracker(w, r)
+	default:
+		writeFakeError(w, http.StatusNotFound, "NotFound", "Ruta no encontrada")
+	}
+}
+
+func (f *FakeServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Sender  string `json:"sender"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	f.Received = append(f.Received, FakeMessage{Sender: body.Sender, Message: body.Message})
+	tracker := f.tracker(body.Sender)
+	tracker.Events = append(tracker.Events, Event{Event: "user", Text: body.Message})
+	tracker.LatestMessage = &ParseResult{Text: body.Message, Intent: f.parse(body.Message).Intent}
+	reply := f.Reply
+	f.mu.Unlock()
+
+	responses := []Response{}
+	if reply != nil {
+		responses = reply(body.Sender, body.Message)
+	}
+	for i := range responses {
+		responses[i].RecipientID = body.Sender
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (f *FakeServer) handleParse(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	f.mu.Lock()
+	result := f.parse(body.Text)
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(result)
+}
+
+func (f *FakeServer) parse(text string) ParseResult {
+	if f.ParseFunc != nil {
+		return f.ParseFunc(text)
+	}
+	return ParseResult{Text: text, Intent: Intent{Name: "nlu_fallback", Confidence: 1}, Entities: []Entity{}}
+}
+
+// handleTracker atiende /conversations/{id}/tracker y /conversations/{id}/tracker/events
+func (f *FakeServer) handleTracker(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.EscapedPath(), "/conversations/")
+	parts := strings.SplitN(rest, "/", 2)
+	conversationID, err := url.PathUnescape(parts[0])
+	if err != nil || len(parts) != 2 {
+		writeFakeError(w, http.StatusNotFound, "NotFound", "Ruta no encontrada")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracker := f.tracker(conversationID)
+
+	switch {
+	case r.Method == http.MethodGet && parts[1] == "tracker":
+		json.NewEncoder(w).Encode(f.copyTracker(tracker))
+	case r.Method == http.MethodPost && parts[1] == "tracker/events":
+		var events []Event
+		if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+			writeFakeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		for _, event := range events {
+			switch event.Event {
+			case EventRestart:
+				*tracker = Tracker{SenderID: conversationID, Slots: map[string]interface{}{}}
+			case EventSlot:
+				tracker.Slots[event.Name] = event.Value
+			case EventActiveLoop:
+				tracker.ActiveLoop = ActiveLoop{Name: event.Name}
+			}
+			tracker.Events = append(tracker.Events, event)
+		}
+		out := f.copyTracker(tracker)
+		out.Events = nil
+		json.NewEncoder(w).Encode(out)
+	default:
+		writeFakeError(w, http.StatusNotFound, "NotFound", "Ruta no encontrada")
+	}
+}
+
+func writeFakeError(w http.ResponseWriter, status int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "failure",
+		"reason":  reason,
+		"message": message,
+		"code":    status,
+	})
+}
@@ -0,0 +1,85 @@
+package rasa
+
+import "encoding/json"
+
+// Tipos de evento que se pueden inyectar en el tracker
+const (
+	EventRestart        = "restart"
+	EventSlot           = "slot"
+	EventSessionStarted = "session_started"
+	EventActiveLoop     = "active_loop"
+)
+
+// Button es un botón de respuesta rápida
+type Button struct {
+	Title   string `json:"title"`
+	Payload string `json:"payload"`
+}
+
+// Response es un elemento de la respuesta de POST /webhooks/rest/webhook
+type Response struct {
+	RecipientID string                 `json:"recipient_id"`
+	Text        string                 `json:"text,omitempty"`
+	Image       string                 `json:"image,omitempty"`
+	Buttons     []Button               `json:"buttons,omitempty"`
+	Attachment  json.RawMessage        `json:"attachment,omitempty"` // URL o un objeto, según la acción
+	Custom      map[string]interface{} `json:"custom,omitempty"`
+}
+
+// Intent es la intención detectada por el NLU
+type Intent struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Entity es una entidad extraída del mensaje
+type Entity struct {
+	Entity     string      `json:"entity"`
+	Value      interface{} `json:"value"`
+	Start      int         `json:"start"`
+	End        int         `json:"end"`
+	Confidence float64     `json:"confidence_entity,omitempty"`
+	Extractor  string      `json:"extractor,omitempty"`
+}
+
+// ParseResult es la respuesta de POST /model/parse y el latest_message del tracker
+type ParseResult struct {
+	Text          string   `json:"text"`
+	Intent        Intent   `json:"intent"`
+	Entities      []Entity `json:"entities"`
+	IntentRanking []Intent `json:"intent_ranking,omitempty"`
+}
+
+// Event es un evento del tracker. Solo se tipan los campos que usa la API;
+// el resto de cada evento se ignora.
+type Event struct {
+	Event     string      `json:"event"`
+	Timestamp float64     `json:"timestamp,omitempty"`
+	Name      string      `json:"name,omitempty"`  // Slot, acción o loop
+	Value     interface{} `json:"value,omitempty"` // Valor del slot
+	Text      string      `json:"text,omitempty"`  // Mensajes del usuario o del bot
+}
+
+// ActiveLoop es el formulario activo en la conversación
+type ActiveLoop struct {
+	Name string `json:"name,omitempty"`
+}
+
+// Tracker es el estado de una conversación en GET /conversations/{id}/tracker
+type Tracker struct {
+	SenderID         string                 `json:"sender_id"`
+	Slots            map[string]interface{} `json:"slots"`
+	LatestMessage    *ParseResult           `json:"latest_message,omitempty"`
+	LatestActionName string                 `json:"latest_action_name,omitempty"`
+	ActiveLoop       ActiveLoop             `json:"active_loop"`
+	Paused           bool                   `json:"paused"`
+	Events           []Event                `json:"events,omitempty"`
+}
+
+// Status es la respuesta de GET /status
+type Status struct {
+	ModelFile             string                 `json:"model_file"`
+	ModelID               string                 `json:"model_id"`
+	NumActiveTrainingJobs int                    `json:"num_active_training_jobs"`
+	Fingerprint           map[string]interface{} `json:"fingerprint,omitempty"`
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	List(orgID uint) ([]models.APIKey, error)
+	GetByID(id, orgID uint) (*models.APIKey, error)
+	// GetByPrefix busca la key de cualquier organización e incluye su organización
+	GetByPrefix(prefix string) (*models.APIKey, error)
+	Revoke(id, orgID uint, at time.Time) error
+	TouchLastUsed(id uint, at time.Time, ip string) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) List(orgID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Where("organization_id = ?", orgID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) GetByID(id, orgID uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Preload("Organization").Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Revoke(id, orgID uint, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ? AND organization_id = ? AND revoked_at IS NULL", id, orgID).
+		Update("revoked_at", at).Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id uint, at time.Time, ip string) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
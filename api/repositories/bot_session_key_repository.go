@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type BotSessionKeyRepository interface {
+	Create(key *models.BotSessionKey) error
+	GetByKeyID(keyID string) (*models.BotSessionKey, error)
+	GetBySessionID(sessionID string, orgID uint) ([]models.BotSessionKey, error)
+	GetSessionOrganization(sessionID string) (uint, error)
+	// ListSessions devuelve las sesiones de la organización con alguna credencial activa
+	ListSessions(orgID uint) ([]string, error)
+	Revoke(keyID, sessionID string, orgID uint) error
+	RevokeBySessionID(sessionID string, orgID uint) ([]string, error)
+	TouchLastUsed(id uint) error
+}
+
+type botSessionKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewBotSessionKeyRepository(db *gorm.DB) BotSessionKeyRepository {
+	return &botSessionKeyRepository{db}
+}
+
+func (r *botSessionKeyRepository) Create(key *models.BotSessionKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *botSessionKeyRepository) GetByKeyID(keyID string) (*models.BotSessionKey, error) {
+	var key models.BotSessionKey
+	err := r.db.Where("key_id = ?", keyID).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *botSessionKeyRepository) GetBySessionID(sessionID string, orgID uint) ([]models.BotSessionKey, error) {
+	var keys []models.BotSessionKey
+	err := r.db.Where("session_id = ? AND organization_id = ?", sessionID, orgID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// GetSessionOrganization devuelve la organización dueña de una sesión (gorm.ErrRecordNotFound si nadie la ha reclamado)
+func (r *botSessionKeyRepository) GetSessionOrganization(sessionID string) (uint, error) {
+	var key models.BotSessionKey
+	err := r.db.Select("organization_id").Where("session_id = ?", sessionID).First(&key).Error
+	if err != nil {
+		return 0, err
+	}
+	return key.OrganizationID, nil
+}
+
+func (r *botSessionKeyRepository) ListSessions(orgID uint) ([]string, error) {
+	var sessions []string
+	err := r.db.Model(&models.BotSessionKey{}).
+		Where("organization_id = ? AND revoked_at IS NULL", orgID).
+		Distinct().Order("session_id").
+		Pluck("session_id", &sessions).Error
+	return sessions, err
+}
+
+func (r *botSessionKeyRepository) Revoke(keyID, sessionID string, orgID uint) error {
+	result := r.db.Model(&models.BotSessionKey{}).
+		Where("key_id = ? AND session_id = ? AND organization_id = ? AND revoked_at IS NULL", keyID, sessionID, orgID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeBySessionID revoca todas las credenciales activas de una sesión y devuelve sus key IDs
+func (r *botSessionKeyRepository) RevokeBySessionID(sessionID string, orgID uint) ([]string, error) {
+	var keyIDs []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.BotSessionKey{}).
+			Where("session_id = ? AND organization_id = ? AND revoked_at IS NULL", sessionID, orgID).
+			Pluck("key_id", &keyIDs).Error; err != nil {
+			return err
+		}
+		if len(keyIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.BotSessionKey{}).
+			Where("key_id IN ?", keyIDs).
+			Update("revoked_at", time.Now()).Error
+	})
+	return keyIDs, err
+}
+
+func (r *botSessionKeyRepository) TouchLastUsed(id uint) error {
+	return r.db.Model(&models.BotSessionKey{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error
+}
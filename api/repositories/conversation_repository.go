@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,258 +15,559 @@ import (
 	"github.com/brando1998/docubot-api/models"
 )
 
+// ErrMissingOrganization se devuelve al guardar datos de Mongo sin organization_id
+var ErrMissingOrganization = errors.New("organization_id es requerido")
+
+// ErrDocumentStatusChanged indica que el documento cambió de estado mientras se actualizaba
+var ErrDocumentStatusChanged = errors.New("el estado del documento cambió")
+
+// DocumentFilter son los filtros del listado de documentos; los campos vacíos no filtran
+type DocumentFilter struct {
+	Type     string
+	Status   string
+	Stage    string // Subestado del manifiesto (issued incluye los que no lo tienen guardado)
+	ClientID uint
+	DriverID uint
+	ParentID primitive.ObjectID // Cumplidos y anulaciones de un manifiesto
+	From     time.Time          // created_at >= From
+	To       time.Time          // created_at < To
+	Page     int
+	Limit    int
+}
+
+// ConversationRepository guarda los datos de Mongo. Todas las lecturas y escrituras
+// se limitan a una organización; solo EnsureIndexes, ReleaseIdleChatModes y
+// GetStaleGeneratingDocuments (tareas de mantenimiento del sistema) y
+// GetDocumentByVerificationCode (verificación pública) operan sobre todas.
 type ConversationRepository interface {
-	SaveMessage(ctx context.Context, userID uint, botID uint, message models.Message) error
-	GetConversationByUserID(ctx context.Context, userID uint) (*models.Conversation, error)
+	SaveMessage(ctx context.Context, message models.Message) error
+	GetConversationByUserID(ctx context.Context, userID uint, orgID uint) (*models.Conversation, error)
+	EnsureIndexes(ctx context.Context) error
 
 	// Nuevos métodos para gestión avanzada
 	SaveDocument(ctx context.Context, document models.Document) error
-	GetDocumentsByClientID(ctx context.Context, clientID uint) ([]models.Document, error)
-	GetDocumentByID(ctx context.Context, documentID string) (*models.Document, error)
+	GetDocumentsByClientID(ctx context.Context, clientID uint, orgID uint) ([]models.Document, error)
+	GetDocumentByID(ctx context.Context, documentID string, orgID uint) (*models.Document, error)
+	// UpdateDocument reemplaza el documento guardado (mismo ID y organización). Si
+	// expectedStatus no está vacío solo lo reemplaza si sigue en ese estado.
+	UpdateDocument(ctx context.Context, document models.Document, expectedStatus string) error
+	// UpdateDocumentStage reemplaza un manifiesto completado solo si sigue en expectedStage
+	UpdateDocumentStage(ctx context.Context, document models.Document, expectedStage string) error
+	ListDocuments(ctx context.Context, filter DocumentFilter, orgID uint) ([]models.Document, int64, error)
+	// GetDocumentByVerificationCode busca en todas las organizaciones: el código es
+	// aleatorio y lo usa la ruta pública /verify
+	GetDocumentByVerificationCode(ctx context.Context, code string) (*models.Document, error)
+	// GetStaleGeneratingDocuments devuelve los documentos del tipo que siguen en generating
+	// sin cambios desde startedBefore, de todas las organizaciones
+	GetStaleGeneratingDocuments(ctx context.Context, documentType string, startedBefore time.Time) ([]models.Document, error)
 
 	SaveChatMode(ctx context.Context, chatMode models.ChatMode) error
-	GetChatMode(ctx context.Context, clientID uint, sessionID string, chatID string) (*models.ChatMode, error)
-	UpdateChatMode(ctx context.Context, clientID uint, sessionID string, chatID string, botMode bool) error
+	GetChatMode(ctx context.Context, clientID uint, sessionID string, chatID string, orgID uint) (*models.ChatMode, error)
+	UpdateChatMode(ctx context.Context, clientID uint, sessionID string, chatID string, botMode bool, orgID uint) error
+	FindChatMode(ctx context.Context, sessionID string, chatIDs []string, orgID uint) (*models.ChatMode, error)
+	TouchChatMode(ctx context.Context, sessionID string, chatIDs []string, orgID uint) error
+	ReleaseIdleChatModes(ctx context.Context, idleSince time.Time) (int64, error)
 
 	ArchiveChat(ctx context.Context, archive models.ChatArchive) error
-	GetArchivedChats(ctx context.Context, clientID uint) ([]models.ChatArchive, error)
+	GetArchivedChats(ctx context.Context, clientID uint, orgID uint) ([]models.ChatArchive, error)
 
 	// Exportación de conversaciones
-	ExportConversation(ctx context.Context, clientID uint, sessionID string, chatID string) ([]models.Message, error)
+	ExportConversation(ctx context.Context, clientID uint, sessionID string, chatID string, orgID uint) ([]models.Message, error)
 
 	// Métodos para estadísticas
-	GetTotalDocuments(ctx context.Context) (int64, error)
-	GetDocumentsCreatedBetween(ctx context.Context, startDate, endDate time.Time) ([]models.Document, error)
-	GetDocumentsByType(ctx context.Context, startDate, endDate time.Time) (map[string]int, error)
-	GetTotalMessages(ctx context.Context) (int64, error)
-	GetMessagesBetween(ctx context.Context, startDate, endDate time.Time) ([]models.Message, error)
-	GetActiveConversations(ctx context.Context, startDate, endDate time.Time) ([]interface{}, error)
-	GetChatsByMode(ctx context.Context, botMode bool, startDate, endDate time.Time) ([]interface{}, error)
-	GetChatsInBotMode(ctx context.Context) (int64, error)
-	GetChatsInManualMode(ctx context.Context) (int64, error)
-	GetArchivedChatsCount(ctx context.Context, startDate, endDate time.Time) (int64, error)
+	GetTotalDocuments(ctx context.Context, orgID uint) (int64, error)
+	GetDocumentsCreatedBetween(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.Document, error)
+	GetDocumentsByType(ctx context.Context, startDate, endDate time.Time, orgID uint) (map[string]int, error)
+	GetTotalMessages(ctx context.Context, orgID uint) (int64, error)
+	GetMessagesBetween(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.Message, error)
+	GetActiveConversations(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.ActiveConversation, error)
+	GetChatsByMode(ctx context.Context, botMode bool, startDate, endDate time.Time, orgID uint) ([]interface{}, error)
+	GetChatsInBotMode(ctx context.Context, orgID uint) (int64, error)
+	GetChatsInManualMode(ctx context.Context, orgID uint) (int64, error)
+	GetArchivedChatsCount(ctx context.Context, startDate, endDate time.Time, orgID uint) (int64, error)
 }
 
 type conversationRepository struct {
 	collection *mongo.Collection
+	messages   *mongo.Collection // Un documento por mensaje
 }
 
 // Constructor
 func NewConversationRepository(client *mongo.Client) ConversationRepository {
-	collection := client.Database(os.Getenv("MONGO_DB")).Collection("conversations")
-	return &conversationRepository{collection}
+	return newConversationRepository(client.Database(os.Getenv("MONGO_DB")))
 }
 
-// Implementación de SaveMessage
-func (r *conversationRepository) SaveMessage(ctx context.Context, userID uint, botID uint, message models.Message) error {
-	filter := bson.M{"user_id": userID, "bot_id": botID}
-	update := bson.M{
-		"$push": bson.M{"messages": message},
-		"$setOnInsert": bson.M{
-			"user_id":    userID,
-			"bot_id":     botID,
-			"created_at": time.Now(),
+func newConversationRepository(database *mongo.Database) *conversationRepository {
+	return &conversationRepository{
+		collection: database.Collection("conversations"),
+		messages:   database.Collection("messages"),
+	}
+}
+
+func (r *conversationRepository) documents() *mongo.Collection {
+	return r.collection.Database().Collection("documents")
+}
+
+func (r *conversationRepository) chatModes() *mongo.Collection {
+	return r.collection.Database().Collection("chat_modes")
+}
+
+func (r *conversationRepository) archives() *mongo.Collection {
+	return r.collection.Database().Collection("chat_archives")
+}
+
+// byOrg agrega el filtro de organización a una consulta
+func byOrg(orgID uint, filter bson.M) bson.M {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	filter["organization_id"] = orgID
+	return filter
+}
+
+// EnsureIndexes crea los índices de las colecciones de Mongo (es idempotente)
+func (r *conversationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.messages.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// Historial y exportación de un chat
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "client_id", Value: 1}, {Key: "session_id", Value: 1}, {Key: "chat_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+		// Chats de una organización por sesión
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "session_id", Value: 1}, {Key: "chat_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		// Estadísticas por período
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		// Migración del formato anterior
+		{Keys: bson.D{{Key: "legacy_conversation_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.documents().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "client_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		// Listado filtrado por estado
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		// Manifiestos pendientes de entrega de un conductor y seguimientos de un manifiesto
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "driver_id", Value: 1}, {Key: "stage", Value: 1}}},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Verificación pública; solo los documentos completados tienen código
+		{
+			Keys:    bson.D{{Key: "verification_code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
 		},
+	}); err != nil {
+		return err
 	}
-	opts := options.Update().SetUpsert(true)
-	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
+
+	if _, err := r.chatModes().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "session_id", Value: 1}, {Key: "chat_id", Value: 1}}},
+		{Keys: bson.D{{Key: "bot_mode", Value: 1}, {Key: "last_activity_at", Value: 1}}},
+	}); err != nil {
+		return err
+	}
+
+	_, err = r.archives().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "client_id", Value: 1}}},
+		{Keys: bson.D{{Key: "organization_id", Value: 1}, {Key: "archived_at", Value: -1}}},
+	})
+	return err
+}
+
+// SaveMessage guarda el mensaje como un documento nuevo
+func (r *conversationRepository) SaveMessage(ctx context.Context, message models.Message) error {
+	if message.OrganizationID == 0 {
+		return ErrMissingOrganization
+	}
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+	_, err := r.messages.InsertOne(ctx, message)
 	return err
 }
 
-// Implementación de GetConversationByUserID
-func (r *conversationRepository) GetConversationByUserID(ctx context.Context, userID uint) (*models.Conversation, error) {
-	filter := bson.M{"user_id": userID}
-	var conversation models.Conversation
-	err := r.collection.FindOne(ctx, filter).Decode(&conversation)
+// GetConversationByUserID arma la conversación de un cliente con sus mensajes en orden
+func (r *conversationRepository) GetConversationByUserID(ctx context.Context, userID uint, orgID uint) (*models.Conversation, error) {
+	messages, err := r.findMessages(ctx, byOrg(orgID, bson.M{"client_id": userID}))
 	if err != nil {
 		return nil, err
 	}
-	return &conversation, nil
+	if len(messages) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &models.Conversation{
+		UserID:   userID,
+		BotID:    messages[len(messages)-1].BotID,
+		Messages: messages,
+	}, nil
+}
+
+// findMessages devuelve los mensajes del filtro ordenados por fecha
+func (r *conversationRepository) findMessages(ctx context.Context, filter bson.M) ([]models.Message, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	cursor, err := r.messages.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.Message{}
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
 }
 
 // Implementaciones de los nuevos métodos
 func (r *conversationRepository) SaveDocument(ctx context.Context, document models.Document) error {
+	if document.OrganizationID == 0 {
+		return ErrMissingOrganization
+	}
 	document.CreatedAt = time.Now()
 	document.UpdatedAt = time.Now()
-	document.Status = "generating" // Estado inicial
+	if document.Status == "" {
+		document.Status = models.DocumentStatusGenerating // Estado inicial
+	}
 
-	documentsCollection := r.collection.Database().Collection("documents")
-	_, err := documentsCollection.InsertOne(ctx, document)
+	_, err := r.documents().InsertOne(ctx, document)
 	return err
 }
 
-func (r *conversationRepository) GetDocumentsByClientID(ctx context.Context, clientID uint) ([]models.Document, error) {
-	documentsCollection := r.collection.Database().Collection("documents")
-	filter := bson.M{"client_id": clientID}
+func (r *conversationRepository) GetDocumentsByClientID(ctx context.Context, clientID uint, orgID uint) ([]models.Document, error) {
+	return r.findDocuments(ctx, byOrg(orgID, bson.M{"client_id": clientID}))
+}
 
-	cursor, err := documentsCollection.Find(ctx, filter)
+func (r *conversationRepository) findDocuments(ctx context.Context, filter bson.M) ([]models.Document, error) {
+	cursor, err := r.documents().Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var documents []models.Document
+	documents := []models.Document{}
 	if err = cursor.All(ctx, &documents); err != nil {
 		return nil, err
 	}
 	return documents, nil
 }
 
-func (r *conversationRepository) GetDocumentByID(ctx context.Context, documentID string) (*models.Document, error) {
-	documentsCollection := r.collection.Database().Collection("documents")
+func (r *conversationRepository) GetDocumentByID(ctx context.Context, documentID string, orgID uint) (*models.Document, error) {
 	objID, err := primitive.ObjectIDFromHex(documentID)
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.M{"_id": objID}
 	var document models.Document
-	err = documentsCollection.FindOne(ctx, filter).Decode(&document)
+	err = r.documents().FindOne(ctx, byOrg(orgID, bson.M{"_id": objID})).Decode(&document)
 	if err != nil {
 		return nil, err
 	}
 	return &document, nil
 }
 
+func (r *conversationRepository) GetDocumentByVerificationCode(ctx context.Context, code string) (*models.Document, error) {
+	var document models.Document
+	err := r.documents().FindOne(ctx, bson.M{"verification_code": code}).Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *conversationRepository) GetStaleGeneratingDocuments(ctx context.Context, documentType string, startedBefore time.Time) ([]models.Document, error) {
+	return r.findDocuments(ctx, bson.M{
+		"type":       documentType,
+		"status":     models.DocumentStatusGenerating,
+		"updated_at": bson.M{"$lt": startedBefore},
+	})
+}
+
+func (r *conversationRepository) UpdateDocument(ctx context.Context, document models.Document, expectedStatus string) error {
+	if document.OrganizationID == 0 {
+		return ErrMissingOrganization
+	}
+	document.UpdatedAt = time.Now()
+
+	filter := byOrg(document.OrganizationID, bson.M{"_id": document.ID})
+	if expectedStatus != "" {
+		filter["status"] = expectedStatus
+	}
+	result, err := r.documents().ReplaceOne(ctx, filter, document)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if expectedStatus != "" {
+			return ErrDocumentStatusChanged
+		}
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *conversationRepository) UpdateDocumentStage(ctx context.Context, document models.Document, expectedStage string) error {
+	if document.OrganizationID == 0 {
+		return ErrMissingOrganization
+	}
+	document.UpdatedAt = time.Now()
+
+	filter := byOrg(document.OrganizationID, bson.M{
+		"_id":    document.ID,
+		"status": models.DocumentStatusCompleted,
+		"stage":  stageFilter(expectedStage),
+	})
+	result, err := r.documents().ReplaceOne(ctx, filter, document)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrDocumentStatusChanged
+	}
+	return nil
+}
+
+// stageFilter busca un subestado; los manifiestos sin subestado guardado están en issued
+func stageFilter(stage string) interface{} {
+	if stage == models.ManifestStageIssued {
+		return bson.M{"$in": bson.A{stage, nil}}
+	}
+	return stage
+}
+
+// ListDocuments devuelve una página de documentos (más recientes primero) y el total del filtro
+func (r *conversationRepository) ListDocuments(ctx context.Context, filter DocumentFilter, orgID uint) ([]models.Document, int64, error) {
+	query := byOrg(orgID, nil)
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Stage != "" {
+		query["stage"] = stageFilter(filter.Stage)
+	}
+	if filter.ClientID != 0 {
+		query["client_id"] = filter.ClientID
+	}
+	if filter.DriverID != 0 {
+		query["driver_id"] = filter.DriverID
+	}
+	if !filter.ParentID.IsZero() {
+		query["parent_id"] = filter.ParentID
+	}
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lt"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	total, err := r.documents().CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 20
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((filter.Page - 1) * filter.Limit)).
+		SetLimit(int64(filter.Limit))
+
+	cursor, err := r.documents().Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := []models.Document{}
+	if err = cursor.All(ctx, &documents); err != nil {
+		return nil, 0, err
+	}
+	return documents, total, nil
+}
+
 func (r *conversationRepository) SaveChatMode(ctx context.Context, chatMode models.ChatMode) error {
+	if chatMode.OrganizationID == 0 {
+		return ErrMissingOrganization
+	}
 	chatMode.CreatedAt = time.Now()
 	chatMode.UpdatedAt = time.Now()
 
-	chatModesCollection := r.collection.Database().Collection("chat_modes")
-	filter := bson.M{
+	filter := byOrg(chatMode.OrganizationID, bson.M{
 		"client_id":  chatMode.ClientID,
 		"session_id": chatMode.SessionID,
 		"chat_id":    chatMode.ChatID,
-	}
+	})
 	update := bson.M{
 		"$set": bson.M{
-			"bot_mode":   chatMode.BotMode,
-			"updated_at": time.Now(),
+			"bot_mode":         chatMode.BotMode,
+			"last_activity_at": time.Now(),
+			"updated_at":       time.Now(),
 		},
 		"$setOnInsert": bson.M{
-			"client_id":  chatMode.ClientID,
-			"bot_id":     chatMode.BotID,
-			"session_id": chatMode.SessionID,
-			"chat_id":    chatMode.ChatID,
-			"created_at": chatMode.CreatedAt,
+			"organization_id": chatMode.OrganizationID,
+			"client_id":       chatMode.ClientID,
+			"bot_id":          chatMode.BotID,
+			"session_id":      chatMode.SessionID,
+			"chat_id":         chatMode.ChatID,
+			"created_at":      chatMode.CreatedAt,
 		},
 	}
 	opts := options.Update().SetUpsert(true)
-	_, err := chatModesCollection.UpdateOne(ctx, filter, update, opts)
+	_, err := r.chatModes().UpdateOne(ctx, filter, update, opts)
 	return err
 }
 
-func (r *conversationRepository) GetChatMode(ctx context.Context, clientID uint, sessionID string, chatID string) (*models.ChatMode, error) {
-	chatModesCollection := r.collection.Database().Collection("chat_modes")
-	filter := bson.M{
+func (r *conversationRepository) GetChatMode(ctx context.Context, clientID uint, sessionID string, chatID string, orgID uint) (*models.ChatMode, error) {
+	filter := byOrg(orgID, bson.M{
 		"client_id":  clientID,
 		"session_id": sessionID,
 		"chat_id":    chatID,
-	}
+	})
 
 	var chatMode models.ChatMode
-	err := chatModesCollection.FindOne(ctx, filter).Decode(&chatMode)
+	err := r.chatModes().FindOne(ctx, filter).Decode(&chatMode)
 	if err != nil {
 		return nil, err
 	}
 	return &chatMode, nil
 }
 
-func (r *conversationRepository) UpdateChatMode(ctx context.Context, clientID uint, sessionID string, chatID string, botMode bool) error {
-	chatModesCollection := r.collection.Database().Collection("chat_modes")
-	filter := bson.M{
+func (r *conversationRepository) UpdateChatMode(ctx context.Context, clientID uint, sessionID string, chatID string, botMode bool, orgID uint) error {
+	filter := byOrg(orgID, bson.M{
 		"client_id":  clientID,
 		"session_id": sessionID,
 		"chat_id":    chatID,
+	})
+	update := bson.M{
+		"$set": bson.M{
+			"bot_mode":         botMode,
+			"last_activity_at": time.Now(),
+			"updated_at":       time.Now(),
+		},
+	}
+
+	_, err := r.chatModes().UpdateOne(ctx, filter, update)
+	return err
+}
+
+// FindChatMode busca el modo de un chat por sesión, aceptando varias formas del ID
+// del chat (JID completo o número limpio)
+func (r *conversationRepository) FindChatMode(ctx context.Context, sessionID string, chatIDs []string, orgID uint) (*models.ChatMode, error) {
+	filter := byOrg(orgID, bson.M{
+		"session_id": sessionID,
+		"chat_id":    bson.M{"$in": chatIDs},
+	})
+	opts := options.FindOne().SetSort(bson.M{"updated_at": -1})
+
+	var chatMode models.ChatMode
+	err := r.chatModes().FindOne(ctx, filter, opts).Decode(&chatMode)
+	if err != nil {
+		return nil, err
+	}
+	return &chatMode, nil
+}
+
+// TouchChatMode registra actividad del agente en un chat atendido manualmente
+func (r *conversationRepository) TouchChatMode(ctx context.Context, sessionID string, chatIDs []string, orgID uint) error {
+	filter := byOrg(orgID, bson.M{
+		"session_id": sessionID,
+		"chat_id":    bson.M{"$in": chatIDs},
+		"bot_mode":   false,
+	})
+	update := bson.M{
+		"$set": bson.M{"last_activity_at": time.Now()},
+	}
+
+	_, err := r.chatModes().UpdateMany(ctx, filter, update)
+	return err
+}
+
+// ReleaseIdleChatModes devuelve al modo bot los chats sin actividad del agente desde idleSince.
+// Es una tarea del sistema y aplica a todas las organizaciones. Los documentos anteriores a
+// last_activity_at usan updated_at, la última vez que se cambió el modo.
+func (r *conversationRepository) ReleaseIdleChatModes(ctx context.Context, idleSince time.Time) (int64, error) {
+	filter := bson.M{
+		"bot_mode": false,
+		"$or": bson.A{
+			bson.M{"last_activity_at": bson.M{"$lt": idleSince}},
+			bson.M{"last_activity_at": nil, "updated_at": bson.M{"$lt": idleSince}},
+		},
 	}
 	update := bson.M{
 		"$set": bson.M{
-			"bot_mode":   botMode,
+			"bot_mode":   true,
 			"updated_at": time.Now(),
 		},
 	}
 
-	_, err := chatModesCollection.UpdateOne(ctx, filter, update)
-	return err
+	result, err := r.chatModes().UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
 }
 
 func (r *conversationRepository) ArchiveChat(ctx context.Context, archive models.ChatArchive) error {
+	if archive.OrganizationID == 0 {
+		return ErrMissingOrganization
+	}
 	archive.ArchivedAt = time.Now()
 
-	archivesCollection := r.collection.Database().Collection("chat_archives")
-	_, err := archivesCollection.InsertOne(ctx, archive)
+	_, err := r.archives().InsertOne(ctx, archive)
 	return err
 }
 
-func (r *conversationRepository) GetArchivedChats(ctx context.Context, clientID uint) ([]models.ChatArchive, error) {
-	archivesCollection := r.collection.Database().Collection("chat_archives")
-	filter := bson.M{"client_id": clientID}
-
-	cursor, err := archivesCollection.Find(ctx, filter)
+func (r *conversationRepository) GetArchivedChats(ctx context.Context, clientID uint, orgID uint) ([]models.ChatArchive, error) {
+	cursor, err := r.archives().Find(ctx, byOrg(orgID, bson.M{"client_id": clientID}))
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var archives []models.ChatArchive
+	archives := []models.ChatArchive{}
 	if err = cursor.All(ctx, &archives); err != nil {
 		return nil, err
 	}
 	return archives, nil
 }
 
-func (r *conversationRepository) ExportConversation(ctx context.Context, clientID uint, sessionID string, chatID string) ([]models.Message, error) {
-	// Buscar mensajes por client_id, session_id y que el sender contenga el chatID
-	filter := bson.M{
+func (r *conversationRepository) ExportConversation(ctx context.Context, clientID uint, sessionID string, chatID string, orgID uint) ([]models.Message, error) {
+	// chat_id se guarda sin el sufijo del JID
+	filter := byOrg(orgID, bson.M{
 		"client_id":  clientID,
 		"session_id": sessionID,
-		"sender":     bson.M{"$regex": chatID, "$options": "i"},
-	}
-
-	cursor, err := r.collection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var messages []models.Message
-	if err = cursor.All(ctx, &messages); err != nil {
-		return nil, err
-	}
-	return messages, nil
+		"chat_id":    strings.Split(chatID, "@")[0],
+	})
+	return r.findMessages(ctx, filter)
 }
 
 // Implementaciones de métodos para estadísticas
-func (r *conversationRepository) GetTotalDocuments(ctx context.Context) (int64, error) {
-	documentsCollection := r.collection.Database().Collection("documents")
-	count, err := documentsCollection.CountDocuments(ctx, bson.M{})
+func (r *conversationRepository) GetTotalDocuments(ctx context.Context, orgID uint) (int64, error) {
+	count, err := r.documents().CountDocuments(ctx, byOrg(orgID, nil))
 	return count, err
 }
 
-func (r *conversationRepository) GetDocumentsCreatedBetween(ctx context.Context, startDate, endDate time.Time) ([]models.Document, error) {
-	documentsCollection := r.collection.Database().Collection("documents")
-	filter := bson.M{
+func (r *conversationRepository) GetDocumentsCreatedBetween(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.Document, error) {
+	filter := byOrg(orgID, bson.M{
 		"created_at": bson.M{
 			"$gte": startDate,
 			"$lt":  endDate,
 		},
-	}
-
-	cursor, err := documentsCollection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var documents []models.Document
-	if err = cursor.All(ctx, &documents); err != nil {
-		return nil, err
-	}
-	return documents, nil
+	})
+	return r.findDocuments(ctx, filter)
 }
 
-func (r *conversationRepository) GetDocumentsByType(ctx context.Context, startDate, endDate time.Time) (map[string]int, error) {
-	documents, err := r.GetDocumentsCreatedBetween(ctx, startDate, endDate)
+func (r *conversationRepository) GetDocumentsByType(ctx context.Context, startDate, endDate time.Time, orgID uint) (map[string]int, error) {
+	documents, err := r.GetDocumentsCreatedBetween(ctx, startDate, endDate, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -276,46 +579,39 @@ func (r *conversationRepository) GetDocumentsByType(ctx context.Context, startDa
 	return result, nil
 }
 
-func (r *conversationRepository) GetTotalMessages(ctx context.Context) (int64, error) {
-	count, err := r.collection.CountDocuments(ctx, bson.M{})
+func (r *conversationRepository) GetTotalMessages(ctx context.Context, orgID uint) (int64, error) {
+	count, err := r.messages.CountDocuments(ctx, byOrg(orgID, nil))
 	return count, err
 }
 
-func (r *conversationRepository) GetMessagesBetween(ctx context.Context, startDate, endDate time.Time) ([]models.Message, error) {
-	filter := bson.M{
+func (r *conversationRepository) GetMessagesBetween(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.Message, error) {
+	filter := byOrg(orgID, bson.M{
 		"timestamp": bson.M{
 			"$gte": startDate,
 			"$lt":  endDate,
 		},
-	}
-
-	cursor, err := r.collection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var messages []models.Message
-	if err = cursor.All(ctx, &messages); err != nil {
-		return nil, err
-	}
-	return messages, nil
+	})
+	return r.findMessages(ctx, filter)
 }
 
-func (r *conversationRepository) GetActiveConversations(ctx context.Context, startDate, endDate time.Time) ([]interface{}, error) {
-	// Agrupar por client_id y contar mensajes en el período
+func (r *conversationRepository) GetActiveConversations(ctx context.Context, startDate, endDate time.Time, orgID uint) ([]models.ActiveConversation, error) {
+	// Agrupar por chat y contar mensajes en el período
 	pipeline := []bson.M{
 		{
-			"$match": bson.M{
+			"$match": byOrg(orgID, bson.M{
 				"timestamp": bson.M{
 					"$gte": startDate,
 					"$lt":  endDate,
 				},
-			},
+			}),
 		},
 		{
 			"$group": bson.M{
-				"_id":           "$client_id",
+				"_id": bson.M{
+					"client_id":  "$client_id",
+					"session_id": "$session_id",
+					"chat_id":    "$chat_id",
+				},
 				"message_count": bson.M{"$sum": 1},
 				"last_message":  bson.M{"$max": "$timestamp"},
 			},
@@ -325,56 +621,62 @@ func (r *conversationRepository) GetActiveConversations(ctx context.Context, sta
 				"message_count": bson.M{"$gte": 2}, // Al menos 2 mensajes para considerar conversación activa
 			},
 		},
+		{
+			"$project": bson.M{
+				"_id":           0,
+				"client_id":     "$_id.client_id",
+				"session_id":    "$_id.session_id",
+				"chat_id":       "$_id.chat_id",
+				"message_count": 1,
+				"last_message":  1,
+			},
+		},
 	}
 
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	cursor, err := r.messages.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var results []interface{}
+	results := []models.ActiveConversation{}
 	if err = cursor.All(ctx, &results); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
-func (r *conversationRepository) GetChatsInBotMode(ctx context.Context) (int64, error) {
-	chatModesCollection := r.collection.Database().Collection("chat_modes")
-	count, err := chatModesCollection.CountDocuments(ctx, bson.M{"bot_mode": true})
+func (r *conversationRepository) GetChatsInBotMode(ctx context.Context, orgID uint) (int64, error) {
+	count, err := r.chatModes().CountDocuments(ctx, byOrg(orgID, bson.M{"bot_mode": true}))
 	return count, err
 }
 
-func (r *conversationRepository) GetChatsInManualMode(ctx context.Context) (int64, error) {
-	chatModesCollection := r.collection.Database().Collection("chat_modes")
-	count, err := chatModesCollection.CountDocuments(ctx, bson.M{"bot_mode": false})
+func (r *conversationRepository) GetChatsInManualMode(ctx context.Context, orgID uint) (int64, error) {
+	count, err := r.chatModes().CountDocuments(ctx, byOrg(orgID, bson.M{"bot_mode": false}))
 	return count, err
 }
 
-func (r *conversationRepository) GetArchivedChatsCount(ctx context.Context, startDate, endDate time.Time) (int64, error) {
-	archivesCollection := r.collection.Database().Collection("chat_archives")
-	filter := bson.M{
+func (r *conversationRepository) GetArchivedChatsCount(ctx context.Context, startDate, endDate time.Time, orgID uint) (int64, error) {
+	filter := byOrg(orgID, bson.M{
 		"archived_at": bson.M{
 			"$gte": startDate,
 			"$lt":  endDate,
 		},
-	}
-	count, err := archivesCollection.CountDocuments(ctx, filter)
+	})
+	count, err := r.archives().CountDocuments(ctx, filter)
 	return count, err
 }
 
-func (r *conversationRepository) GetChatsByMode(ctx context.Context, botMode bool, startDate, endDate time.Time) ([]interface{}, error) {
-	chatModesCollection := r.collection.Database().Collection("chat_modes")
-	filter := bson.M{
+func (r *conversationRepository) GetChatsByMode(ctx context.Context, botMode bool, startDate, endDate time.Time, orgID uint) ([]interface{}, error) {
+	filter := byOrg(orgID, bson.M{
 		"bot_mode": botMode,
 		"created_at": bson.M{
 			"$gte": startDate,
 			"$lt":  endDate,
 		},
-	}
+	})
 
-	cursor, err := chatModesCollection.Find(ctx, filter)
+	cursor, err := r.chatModes().Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
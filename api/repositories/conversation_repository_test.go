@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// sentFilter devuelve el filtro de la última consulta enviada a Mongo
+func sentFilter(mt *mtest.T, field string) bson.Raw {
+	started := mt.GetStartedEvent()
+	require.NotNil(mt, started)
+	return started.Command.Lookup(field).Document()
+}
+
+func TestConversationRepositoryScopesReadsByOrganization(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	reads := map[string]func(r *conversationRepository) error{
+		"documents por cliente": func(r *conversationRepository) error {
+			_, err := r.GetDocumentsByClientID(context.Background(), 7, 2)
+			return err
+		},
+		"exportación": func(r *conversationRepository) error {
+			_, err := r.ExportConversation(context.Background(), 7, "bot-1", "573001234567@s.whatsapp.net", 2)
+			return err
+		},
+		"mensajes del período": func(r *conversationRepository) error {
+			_, err := r.GetMessagesBetween(context.Background(), time.Now().Add(-time.Hour), time.Now(), 2)
+			return err
+		},
+		"chats archivados": func(r *conversationRepository) error {
+			_, err := r.GetArchivedChats(context.Background(), 7, 2)
+			return err
+		},
+	}
+
+	for name, read := range reads {
+		mt.Run(name, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "docubot.test", mtest.FirstBatch))
+			require.NoError(mt, read(newConversationRepository(mt.DB)))
+
+			filter := sentFilter(mt, "filter")
+			assert.Equal(mt, int64(2), filter.Lookup("organization_id").AsInt64())
+		})
+	}
+
+	mt.Run("modo del chat", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "docubot.chat_modes", mtest.FirstBatch))
+		_, err := newConversationRepository(mt.DB).FindChatMode(context.Background(), "bot-1", []string{"573001234567"}, 2)
+		assert.Error(mt, err) // Sin resultados para otra organización
+
+		filter := sentFilter(mt, "filter")
+		assert.Equal(mt, int64(2), filter.Lookup("organization_id").AsInt64())
+	})
+
+	mt.Run("conteos", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "docubot.documents", mtest.FirstBatch,
+			bson.D{{Key: "n", Value: 0}}))
+		count, err := newConversationRepository(mt.DB).GetTotalDocuments(context.Background(), 2)
+		require.NoError(mt, err)
+		assert.Equal(mt, int64(0), count)
+
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		match := pipeline.Index(0).Value().Document().Lookup("$match").Document()
+		assert.Equal(mt, int64(2), match.Lookup("organization_id").AsInt64())
+	})
+}
+
+func TestConversationRepositoryRequiresOrganizationOnWrite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("sin organización", func(mt *mtest.T) {
+		r := newConversationRepository(mt.DB)
+		ctx := context.Background()
+
+		assert.ErrorIs(mt, r.SaveMessage(ctx, models.Message{ClientID: 7, Text: "hola"}), ErrMissingOrganization)
+		assert.ErrorIs(mt, r.SaveDocument(ctx, models.Document{ClientID: 7}), ErrMissingOrganization)
+		assert.ErrorIs(mt, r.SaveChatMode(ctx, models.ChatMode{ClientID: 7}), ErrMissingOrganization)
+		assert.ErrorIs(mt, r.ArchiveChat(ctx, models.ChatArchive{ClientID: 7}), ErrMissingOrganization)
+		assert.Nil(mt, mt.GetStartedEvent())
+	})
+
+	mt.Run("con organización", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		err := newConversationRepository(mt.DB).SaveMessage(context.Background(), models.Message{OrganizationID: 2, ClientID: 7, Text: "hola"})
+		require.NoError(mt, err)
+
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(mt, int64(2), inserted.Lookup("organization_id").AsInt64())
+	})
+}
+
+func TestReleaseIdleChatModesIncludesLegacyDocuments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("filtro enviado", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		idleSince := time.Now().Add(-30 * time.Minute).Truncate(time.Millisecond)
+		released, err := newConversationRepository(mt.DB).ReleaseIdleChatModes(context.Background(), idleSince)
+		require.NoError(mt, err)
+		assert.Equal(mt, int64(1), released)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.True(mt, update.Lookup("multi").Boolean())
+		assert.True(mt, update.Lookup("u", "$set", "bot_mode").Boolean())
+
+		query := update.Lookup("q").Document()
+		assert.False(mt, query.Lookup("bot_mode").Boolean())
+		_, err = query.LookupErr("updated_at")
+		assert.Error(mt, err, "updated_at solo debe usarse en la rama de documentos legados")
+		branches, err := query.Lookup("$or").Array().Values()
+		require.NoError(mt, err)
+		require.Len(mt, branches, 2)
+
+		// Con last_activity_at manda la actividad del agente, aunque updated_at sea antiguo
+		current := branches[0].Document()
+		assert.Equal(mt, idleSince, current.Lookup("last_activity_at", "$lt").Time())
+		_, err = current.LookupErr("updated_at")
+		assert.Error(mt, err)
+
+		// Sin él entra por updated_at: en Mongo {last_activity_at: null} también
+		// coincide con los documentos donde el campo no existe
+		fallback := branches[1].Document()
+		assert.Equal(mt, bson.TypeNull, fallback.Lookup("last_activity_at").Type)
+		assert.Equal(mt, idleSince, fallback.Lookup("updated_at", "$lt").Time())
+	})
+
+	mt.Run("error de Mongo", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Message: "interrupted"}))
+		released, err := newConversationRepository(mt.DB).ReleaseIdleChatModes(context.Background(), time.Now())
+		assert.Error(mt, err)
+		assert.Zero(mt, released)
+	})
+}
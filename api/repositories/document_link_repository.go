@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type DocumentLinkRepository interface {
+	Create(link *models.DocumentLink) error
+	GetByLinkID(linkID string) (*models.DocumentLink, error)
+	ListByDocument(documentID string, orgID uint) ([]models.DocumentLink, error)
+	Revoke(linkID, documentID string, orgID uint) error
+	// Consume suma una descarga si el enlace sigue activo, vigente y con descargas disponibles;
+	// devuelve false si no se pudo (otra descarga llegó primero, expiró o fue revocado)
+	Consume(linkID string, now time.Time) (bool, error)
+	// Release devuelve una descarga consumida cuando el archivo no se pudo entregar
+	Release(linkID string) error
+
+	LogDownload(download *models.DocumentDownload) error
+	ListDownloads(documentID string, orgID uint) ([]models.DocumentDownload, error)
+}
+
+type documentLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentLinkRepository(db *gorm.DB) DocumentLinkRepository {
+	return &documentLinkRepository{db}
+}
+
+func (r *documentLinkRepository) Create(link *models.DocumentLink) error {
+	return r.db.Create(link).Error
+}
+
+func (r *documentLinkRepository) GetByLinkID(linkID string) (*models.DocumentLink, error) {
+	var link models.DocumentLink
+	err := r.db.Where("link_id = ?", linkID).First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *documentLinkRepository) ListByDocument(documentID string, orgID uint) ([]models.DocumentLink, error) {
+	var links []models.DocumentLink
+	err := r.db.Where("document_id = ? AND organization_id = ?", documentID, orgID).
+		Order("created_at DESC").
+		Find(&links).Error
+	return links, err
+}
+
+func (r *documentLinkRepository) Revoke(linkID, documentID string, orgID uint) error {
+	result := r.db.Model(&models.DocumentLink{}).
+		Where("link_id = ? AND document_id = ? AND organization_id = ? AND revoked_at IS NULL", linkID, documentID, orgID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Consume hace la verificación y el incremento en un solo UPDATE para que dos descargas
+// simultáneas de un enlace de un solo uso no pasen las dos
+func (r *documentLinkRepository) Consume(linkID string, now time.Time) (bool, error) {
+	result := r.db.Model(&models.DocumentLink{}).
+		Where("link_id = ? AND revoked_at IS NULL AND expires_at > ? AND downloads < max_downloads", linkID, now).
+		Updates(map[string]interface{}{
+			"downloads":        gorm.Expr("downloads + 1"),
+			"last_download_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *documentLinkRepository) Release(linkID string) error {
+	return r.db.Model(&models.DocumentLink{}).
+		Where("link_id = ? AND downloads > 0", linkID).
+		Update("downloads", gorm.Expr("downloads - 1")).Error
+}
+
+func (r *documentLinkRepository) LogDownload(download *models.DocumentDownload) error {
+	return r.db.Create(download).Error
+}
+
+func (r *documentLinkRepository) ListDownloads(documentID string, orgID uint) ([]models.DocumentDownload, error) {
+	var downloads []models.DocumentDownload
+	err := r.db.Where("document_id = ? AND organization_id = ?", documentID, orgID).
+		Order("created_at DESC").
+		Find(&downloads).Error
+	return downloads, err
+}
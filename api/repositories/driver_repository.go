@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type DriverRepository interface {
+	Create(driver *models.Driver) error
+	Update(driver *models.Driver) error
+	GetByID(id uint, orgID uint) (*models.Driver, error)
+	GetByCedula(cedula string, orgID uint) (*models.Driver, error)
+	// GetByClient busca el conductor enlazado al cliente o, si no lo está, con su teléfono
+	GetByClient(clientID uint, phone string, orgID uint) (*models.Driver, error)
+	List(orgID uint) ([]models.Driver, error)
+	Delete(id uint, orgID uint) error
+}
+
+type driverRepository struct {
+	db *gorm.DB
+}
+
+func NewDriverRepository(db *gorm.DB) DriverRepository {
+	return &driverRepository{db}
+}
+
+func (r *driverRepository) Create(driver *models.Driver) error {
+	return r.db.Create(driver).Error
+}
+
+// Update guarda todos los campos; el conductor debe ser de la organización
+func (r *driverRepository) Update(driver *models.Driver) error {
+	result := r.db.Model(driver).Where("organization_id = ?", driver.OrganizationID).
+		Select("*").Omit("id", "organization_id", "created_at").
+		Updates(driver)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *driverRepository) GetByID(id uint, orgID uint) (*models.Driver, error) {
+	var driver models.Driver
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&driver).Error
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (r *driverRepository) GetByCedula(cedula string, orgID uint) (*models.Driver, error) {
+	var driver models.Driver
+	err := r.db.Where("cedula = ? AND organization_id = ?", cedula, orgID).First(&driver).Error
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (r *driverRepository) GetByClient(clientID uint, phone string, orgID uint) (*models.Driver, error) {
+	var driver models.Driver
+	err := r.db.Where("organization_id = ? AND (client_id = ? OR (client_id IS NULL AND phone = ?))", orgID, clientID, phone).
+		Order("client_id IS NULL, updated_at DESC").
+		First(&driver).Error
+	if err != nil {
+		return nil, err
+	}
+	return &driver, nil
+}
+
+func (r *driverRepository) List(orgID uint) ([]models.Driver, error) {
+	var drivers []models.Driver
+	err := r.db.Where("organization_id = ?", orgID).Order("name").Find(&drivers).Error
+	return drivers, err
+}
+
+func (r *driverRepository) Delete(id uint, orgID uint) error {
+	result := r.db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.Driver{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type ExpiryReminderRepository interface {
+	// Claim registra el recordatorio antes de enviarlo. Devuelve false si ya existía (otro
+	// envío lo tomó), así el mismo vencimiento nunca se recuerda dos veces.
+	Claim(reminder *models.ExpiryReminder) (bool, error)
+	// Release borra un recordatorio reclamado cuyo envío falló para reintentarlo después
+	Release(id uint) error
+	ListSince(orgID uint, since time.Time) ([]models.ExpiryReminder, error)
+}
+
+type expiryReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewExpiryReminderRepository(db *gorm.DB) ExpiryReminderRepository {
+	return &expiryReminderRepository{db}
+}
+
+func (r *expiryReminderRepository) Claim(reminder *models.ExpiryReminder) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *expiryReminderRepository) Release(id uint) error {
+	return r.db.Delete(&models.ExpiryReminder{}, id).Error
+}
+
+// ListSince devuelve los recordatorios de vencimientos desde la fecha indicada
+func (r *expiryReminderRepository) ListSince(orgID uint, since time.Time) ([]models.ExpiryReminder, error) {
+	var reminders []models.ExpiryReminder
+	err := r.db.Where("organization_id = ? AND expires_at >= ?", orgID, since).
+		Order("sent_at").
+		Find(&reminders).Error
+	return reminders, err
+}
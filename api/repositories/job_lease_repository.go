@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// JobLeaseRepository implementa la elección de líder de los trabajos programados con una
+// fila por trabajo en Postgres (funciona con varias réplicas y con pgbouncer)
+type JobLeaseRepository interface {
+	// TryAcquire toma o renueva el lease si está libre, vencido o ya es del holder
+	TryAcquire(name, holder string, ttl time.Duration) (bool, error)
+	Release(name, holder string) error
+}
+
+type jobLeaseRepository struct {
+	db *gorm.DB
+}
+
+func NewJobLeaseRepository(db *gorm.DB) JobLeaseRepository {
+	return &jobLeaseRepository{db}
+}
+
+func (r *jobLeaseRepository) TryAcquire(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.JobLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl), "updated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// Primera vez que se ejecuta el trabajo: solo una réplica logra insertar la fila
+	result = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.JobLease{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: now.Add(ttl),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *jobLeaseRepository) Release(name, holder string) error {
+	return r.db.Where("name = ? AND holder = ?", name, holder).Delete(&models.JobLease{}).Error
+}
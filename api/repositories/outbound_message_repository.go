@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type OutboundMessageRepository interface {
+	Create(message *models.OutboundMessage) error
+	// UpdateDispatch guarda el estado de envío solo si la fila sigue en expectedStatus con
+	// expectedEnvelopeID; devuelve false si otra instancia la tomó o la cambió antes
+	UpdateDispatch(message *models.OutboundMessage, expectedStatus, expectedEnvelopeID string) (bool, error)
+	GetByID(id uint, orgID uint) (*models.OutboundMessage, error)
+	GetByEnvelopeID(envelopeID string) (*models.OutboundMessage, error)
+	// GetDispatchable devuelve los pendientes sin envío en curso de los bots indicados
+	GetDispatchable(botKeys []string, dueBefore time.Time, limit int) ([]models.OutboundMessage, error)
+	// GetStalePending devuelve los pendientes sin envío en curso que nadie tocó desde idleBefore
+	GetStalePending(idleBefore time.Time, limit int) ([]models.OutboundMessage, error)
+	// ResetInFlight libera los envíos en curso de la instancia y los de cualquiera que lleven
+	// en curso desde antes de staleBefore (instancias caídas)
+	ResetInFlight(instanceID string, staleBefore time.Time) (int64, error)
+	List(orgID uint, status string, page, pageSize int) ([]models.OutboundMessage, int64, error)
+}
+
+type outboundMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboundMessageRepository(db *gorm.DB) OutboundMessageRepository {
+	return &outboundMessageRepository{db}
+}
+
+func (r *outboundMessageRepository) Create(message *models.OutboundMessage) error {
+	return r.db.Create(message).Error
+}
+
+// outboundDispatchColumns son las columnas que cambia la cola; el contenido del mensaje no se reescribe
+var outboundDispatchColumns = []string{
+	"status", "attempts", "offline_attempts", "next_attempt_at", "envelope_id", "dispatched_by", "last_error", "sent_at",
+}
+
+func (r *outboundMessageRepository) UpdateDispatch(message *models.OutboundMessage, expectedStatus, expectedEnvelopeID string) (bool, error) {
+	result := r.db.Model(message).
+		Where("status = ? AND envelope_id = ?", expectedStatus, expectedEnvelopeID).
+		Select(outboundDispatchColumns).
+		Updates(message)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *outboundMessageRepository) GetByID(id uint, orgID uint) (*models.OutboundMessage, error) {
+	var message models.OutboundMessage
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *outboundMessageRepository) GetByEnvelopeID(envelopeID string) (*models.OutboundMessage, error) {
+	var message models.OutboundMessage
+	err := r.db.Where("envelope_id = ?", envelopeID).First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *outboundMessageRepository) GetDispatchable(botKeys []string, dueBefore time.Time, limit int) ([]models.OutboundMessage, error) {
+	var messages []models.OutboundMessage
+	if len(botKeys) == 0 {
+		return messages, nil
+	}
+	// Orden de creación: los mensajes de un chat salen en el orden en que se generaron
+	err := r.db.Where("status = ? AND envelope_id = ? AND next_attempt_at <= ? AND bot_key IN ?",
+		models.OutboundStatusPending, "", dueBefore, botKeys).
+		Order("created_at ASC, id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *outboundMessageRepository) GetStalePending(idleBefore time.Time, limit int) ([]models.OutboundMessage, error) {
+	var messages []models.OutboundMessage
+	err := r.db.Where("status = ? AND envelope_id = ? AND updated_at < ?", models.OutboundStatusPending, "", idleBefore).
+		Order("id ASC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+func (r *outboundMessageRepository) ResetInFlight(instanceID string, staleBefore time.Time) (int64, error) {
+	query := r.db.Model(&models.OutboundMessage{}).
+		Where("status = ? AND envelope_id <> ?", models.OutboundStatusPending, "")
+	if instanceID != "" {
+		query = query.Where("(dispatched_by = ? OR updated_at < ?)", instanceID, staleBefore)
+	} else {
+		query = query.Where("updated_at < ?", staleBefore)
+	}
+	result := query.Updates(map[string]interface{}{"envelope_id": "", "dispatched_by": "", "next_attempt_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
+
+func (r *outboundMessageRepository) List(orgID uint, status string, page, pageSize int) ([]models.OutboundMessage, int64, error) {
+	query := r.db.Model(&models.OutboundMessage{}).Where("organization_id = ?", orgID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []models.OutboundMessage
+	err := query.Order("updated_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&messages).Error
+	return messages, total, err
+}
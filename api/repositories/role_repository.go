@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type RoleRepository interface {
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	GetByID(id uint, orgID uint) (*models.Role, error)
+	GetByName(name string, orgID uint) (*models.Role, error)
+	List(orgID uint) ([]models.Role, error)
+	Delete(id uint, orgID uint) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db}
+}
+
+func (r *roleRepository) Create(role *models.Role) error {
+	return r.db.Create(role).Error
+}
+
+// Update guarda la descripción y los permisos; el nombre no cambia porque los usuarios
+// lo tienen asignado
+func (r *roleRepository) Update(role *models.Role) error {
+	result := r.db.Model(role).Where("organization_id = ?", role.OrganizationID).
+		Select("description", "permissions").
+		Updates(role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) GetByID(id uint, orgID uint) (*models.Role, error) {
+	var role models.Role
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) GetByName(name string, orgID uint) (*models.Role, error) {
+	var role models.Role
+	err := r.db.Where("name = ? AND organization_id = ?", name, orgID).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) List(orgID uint) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.Where("organization_id = ?", orgID).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) Delete(id uint, orgID uint) error {
+	result := r.db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.Role{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type RouteTemplateRepository interface {
+	Create(template *models.RouteTemplate) error
+	Update(template *models.RouteTemplate) error
+	GetByID(id, clientID, orgID uint) (*models.RouteTemplate, error)
+	ListByClient(clientID, orgID uint) ([]models.RouteTemplate, error)
+	Delete(id, clientID, orgID uint) error
+}
+
+type routeTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewRouteTemplateRepository(db *gorm.DB) RouteTemplateRepository {
+	return &routeTemplateRepository{db}
+}
+
+func (r *routeTemplateRepository) Create(template *models.RouteTemplate) error {
+	return r.db.Create(template).Error
+}
+
+// Update guarda todos los campos; la ruta debe ser del cliente y la organización
+func (r *routeTemplateRepository) Update(template *models.RouteTemplate) error {
+	result := r.db.Model(template).
+		Where("organization_id = ? AND client_id = ?", template.OrganizationID, template.ClientID).
+		Select("*").Omit("id", "organization_id", "client_id", "created_at").
+		Updates(template)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *routeTemplateRepository) GetByID(id, clientID, orgID uint) (*models.RouteTemplate, error) {
+	var template models.RouteTemplate
+	err := r.db.Where("id = ? AND client_id = ? AND organization_id = ?", id, clientID, orgID).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *routeTemplateRepository) ListByClient(clientID, orgID uint) ([]models.RouteTemplate, error) {
+	var templates []models.RouteTemplate
+	err := r.db.Where("client_id = ? AND organization_id = ?", clientID, orgID).
+		Order("updated_at DESC").
+		Find(&templates).Error
+	return templates, err
+}
+
+func (r *routeTemplateRepository) Delete(id, clientID, orgID uint) error {
+	result := r.db.Where("id = ? AND client_id = ? AND organization_id = ?", id, clientID, orgID).
+		Delete(&models.RouteTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
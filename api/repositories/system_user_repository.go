@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type SystemUserRepository interface {
+	Create(user *models.SystemUser) error
+	Update(user *models.SystemUser) error
+	GetByID(id uint, orgID uint) (*models.SystemUser, error)
+	// GetByLogin busca en todas las organizaciones (username y email son únicos globalmente),
+	// incluyendo los usuarios eliminados que siguen ocupando el índice único
+	GetByLogin(username, email string) (*models.SystemUser, error)
+	List(orgID uint) ([]models.SystemUser, error)
+	// IncrementTokenVersion invalida los tokens emitidos al usuario
+	IncrementTokenVersion(id uint, orgID uint) error
+	// CountActive cuenta los usuarios activos de la organización con alguno de los roles
+	CountActive(orgID uint, roles []string) (int64, error)
+	// CountByRole cuenta los usuarios (activos o no) que tienen el rol
+	CountByRole(orgID uint, role string) (int64, error)
+	Delete(id uint, orgID uint) error
+}
+
+type systemUserRepository struct {
+	db *gorm.DB
+}
+
+func NewSystemUserRepository(db *gorm.DB) SystemUserRepository {
+	return &systemUserRepository{db}
+}
+
+func (r *systemUserRepository) Create(user *models.SystemUser) error {
+	return r.db.Create(user).Error
+}
+
+// Update guarda los datos editables; el usuario debe ser de la organización
+func (r *systemUserRepository) Update(user *models.SystemUser) error {
+	result := r.db.Model(user).Where("organization_id = ?", user.OrganizationID).
+		Select("username", "email", "password_hash", "role", "is_active", "token_version").
+		Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *systemUserRepository) GetByID(id uint, orgID uint) (*models.SystemUser, error) {
+	var user models.SystemUser
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *systemUserRepository) GetByLogin(username, email string) (*models.SystemUser, error) {
+	var user models.SystemUser
+	err := r.db.Unscoped().Where("username = ? OR email = ?", username, email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *systemUserRepository) List(orgID uint) ([]models.SystemUser, error) {
+	var users []models.SystemUser
+	err := r.db.Where("organization_id = ?", orgID).Order("username").Find(&users).Error
+	return users, err
+}
+
+func (r *systemUserRepository) IncrementTokenVersion(id uint, orgID uint) error {
+	result := r.db.Model(&models.SystemUser{}).
+		Where("id = ? AND organization_id = ?", id, orgID).
+		Update("token_version", gorm.Expr("token_version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *systemUserRepository) CountActive(orgID uint, roles []string) (int64, error) {
+	var count int64
+	if len(roles) == 0 {
+		return 0, nil
+	}
+	err := r.db.Model(&models.SystemUser{}).
+		Where("organization_id = ? AND role IN ? AND is_active = ?", orgID, roles, true).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *systemUserRepository) CountByRole(orgID uint, role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.SystemUser{}).
+		Where("organization_id = ? AND role = ?", orgID, role).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *systemUserRepository) Delete(id uint, orgID uint) error {
+	result := r.db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.SystemUser{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
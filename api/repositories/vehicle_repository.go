@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+type VehicleRepository interface {
+	Create(vehicle *models.Vehicle) error
+	Update(vehicle *models.Vehicle) error
+	GetByID(id uint, orgID uint) (*models.Vehicle, error)
+	GetByPlaca(placa string, orgID uint) (*models.Vehicle, error)
+	List(orgID uint) ([]models.Vehicle, error)
+	Delete(id uint, orgID uint) error
+}
+
+type vehicleRepository struct {
+	db *gorm.DB
+}
+
+func NewVehicleRepository(db *gorm.DB) VehicleRepository {
+	return &vehicleRepository{db}
+}
+
+func (r *vehicleRepository) Create(vehicle *models.Vehicle) error {
+	return r.db.Create(vehicle).Error
+}
+
+// Update guarda todos los campos; el vehículo debe ser de la organización
+func (r *vehicleRepository) Update(vehicle *models.Vehicle) error {
+	result := r.db.Model(vehicle).Where("organization_id = ?", vehicle.OrganizationID).
+		Select("*").Omit("id", "organization_id", "created_at").
+		Updates(vehicle)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *vehicleRepository) GetByID(id uint, orgID uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := r.db.Where("id = ? AND organization_id = ?", id, orgID).First(&vehicle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+func (r *vehicleRepository) GetByPlaca(placa string, orgID uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := r.db.Where("placa = ? AND organization_id = ?", placa, orgID).First(&vehicle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+func (r *vehicleRepository) List(orgID uint) ([]models.Vehicle, error) {
+	var vehicles []models.Vehicle
+	err := r.db.Where("organization_id = ?", orgID).Order("placa").Find(&vehicles).Error
+	return vehicles, err
+}
+
+func (r *vehicleRepository) Delete(id uint, orgID uint) error {
+	result := r.db.Where("id = ? AND organization_id = ?", id, orgID).Delete(&models.Vehicle{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	// Los conductores que lo tenían como habitual quedan sin vehículo
+	return r.db.Model(&models.Driver{}).
+		Where("vehicle_id = ? AND organization_id = ?", id, orgID).
+		Update("vehicle_id", nil).Error
+}
@@ -0,0 +1,82 @@
+package rndc
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CumplidoRequest es el cumplido de un manifiesto expedido: confirma que la carga llegó y
+// cuánto se entregó (ver cumplidoRequestSchema en playwright-bot)
+type CumplidoRequest struct {
+	ConsecutivoRemesa     string `json:"consecutivoRemesa"`
+	ConsecutivoManifiesto string `json:"consecutivoManifiesto"`
+	FechaLlegada          string `json:"fechaLlegada"`      // ISO 8601
+	CantidadEntregada     int    `json:"cantidadEntregada"` // Peso entregado en kilogramos
+	Novedades             string `json:"novedades,omitempty"`
+}
+
+// AnulacionRequest anula un manifiesto expedido por error (ver anulacionRequestSchema)
+type AnulacionRequest struct {
+	ConsecutivoRemesa     string `json:"consecutivoRemesa"`
+	ConsecutivoManifiesto string `json:"consecutivoManifiesto"`
+	Motivo                string `json:"motivo"`
+}
+
+// Normalize quita los espacios de los datos escritos a mano
+func (r *CumplidoRequest) Normalize() {
+	r.ConsecutivoRemesa = strings.ToUpper(strings.TrimSpace(r.ConsecutivoRemesa))
+	r.ConsecutivoManifiesto = strings.TrimSpace(r.ConsecutivoManifiesto)
+	r.FechaLlegada = strings.TrimSpace(r.FechaLlegada)
+	r.Novedades = strings.TrimSpace(r.Novedades)
+}
+
+// Validate revisa el cumplido; now limita la fecha de llegada (no puede ser futura)
+func (r CumplidoRequest) Validate(now time.Time) error {
+	var errs ValidationErrors
+	validateConsecutivos(&errs, r.ConsecutivoRemesa, r.ConsecutivoManifiesto)
+	if r.FechaLlegada == "" {
+		errs.Add("fechaLlegada", errors.New("falta la fecha de llegada"))
+	} else if llegada, err := ParseFecha(r.FechaLlegada); err != nil {
+		errs.Add("fechaLlegada", err)
+	} else if llegada.After(now) {
+		errs.Add("fechaLlegada", errors.New("la fecha de llegada no puede ser futura"))
+	}
+	errs.Add("cantidadEntregada", ValidatePeso(r.CantidadEntregada))
+	if utf8.RuneCountInString(r.Novedades) > 500 {
+		errs.Add("novedades", errors.New("las novedades no pueden superar 500 caracteres"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Normalize quita los espacios de los datos escritos a mano
+func (r *AnulacionRequest) Normalize() {
+	r.ConsecutivoRemesa = strings.ToUpper(strings.TrimSpace(r.ConsecutivoRemesa))
+	r.ConsecutivoManifiesto = strings.TrimSpace(r.ConsecutivoManifiesto)
+	r.Motivo = strings.TrimSpace(r.Motivo)
+}
+
+// Validate revisa la anulación
+func (r AnulacionRequest) Validate() error {
+	var errs ValidationErrors
+	validateConsecutivos(&errs, r.ConsecutivoRemesa, r.ConsecutivoManifiesto)
+	errs.Add("motivo", length("el motivo de la anulación", r.Motivo, 10, 500))
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateConsecutivos(errs *ValidationErrors, remesa, manifiesto string) {
+	switch {
+	case remesa == "":
+		errs.Add("consecutivoRemesa", errors.New("el consecutivo de la remesa es obligatorio"))
+	case len(remesa) > 50 || !consecutivoPattern.MatchString(remesa):
+		errs.Add("consecutivoRemesa", errors.New("el consecutivo solo admite mayúsculas, números y guiones (máximo 50)"))
+	}
+	errs.Add("consecutivoManifiesto", required("el consecutivo del manifiesto", manifiesto))
+}
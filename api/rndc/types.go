@@ -0,0 +1,66 @@
+// Package rndc define los datos de remesas y manifiestos del RNDC (Registro Nacional de
+// Despachos de Carga) y sus validaciones. Los campos y el JSON son los mismos que recibe
+// playwright-bot (playwright-bot/validation/schemas.js), así que una solicitud que pasa
+// Validate no debería ser rechazada por Joi.
+package rndc
+
+// Valores permitidos de los campos con lista cerrada
+var (
+	TiposOperacion   = []string{"Mercancia Consolidada", "Otro"}
+	TiposEmpaque     = []string{"Varios", "Cajas", "Bultos", "Pallets"}
+	TiposManifiesto  = []string{"General", "Individual"}
+	TiposIDTitular   = []string{"Cedula Ciudadania", "Nit", "Cedula Extranjeria"}
+	TiposIDConductor = []string{"Cedula Ciudadania", "Cedula Extranjeria"}
+)
+
+// Empresa identifica la empresa de transporte y sus sedes registradas en el RNDC
+type Empresa struct {
+	NIT           string `json:"nit"` // 9 dígitos, o 10 si incluye el dígito de verificación
+	SedeCargue    string `json:"sedeCargue"`
+	SedeDescargue string `json:"sedeDescargue"`
+}
+
+// Vehiculo es el vehículo y el conductor del despacho. En el JSON del manifiesto sus
+// campos van al mismo nivel que los demás (placaVehiculo, conductorNumeroId...).
+type Vehiculo struct {
+	PlacaVehiculo     string `json:"placaVehiculo"` // ABC123
+	ConductorTipoID   string `json:"conductorTipoId,omitempty"`
+	ConductorNumeroID string `json:"conductorNumeroId"` // Cédula; es también el número de la licencia de conducción
+}
+
+// Remesa son los datos de la remesa (ver remesaSchema)
+type Remesa struct {
+	Consecutivo      string  `json:"consecutivo"`
+	TipoOperacion    string  `json:"tipoOperacion,omitempty"`
+	TipoEmpaque      string  `json:"tipoEmpaque,omitempty"`
+	DescripcionCorta string  `json:"descripcionCorta"`
+	Capitulo         string  `json:"capitulo,omitempty"`
+	Partida          string  `json:"partida,omitempty"`
+	CantidadEstimada int     `json:"cantidadEstimada"` // Peso de la carga en kilogramos
+	Empresa          Empresa `json:"empresa"`
+	HoraCargue       string  `json:"horaCargue,omitempty"`    // ISO 8601
+	HoraDescargue    string  `json:"horaDescargue,omitempty"` // ISO 8601, no anterior al cargue
+	TiempoCargue     string  `json:"tiempoCargue,omitempty"`  // H:MM
+	TiempoDescargue  string  `json:"tiempoDescargue,omitempty"`
+}
+
+// Manifiesto son los datos del manifiesto (ver manifiestoSchema)
+type Manifiesto struct {
+	TipoManifiesto   string `json:"tipoManifiesto,omitempty"`
+	FechaExpedicion  string `json:"fechaExpedicion,omitempty"`
+	MunicipioOrigen  string `json:"municipioOrigen"`
+	MunicipioDestino string `json:"municipioDestino"`
+	TitularTipoID    string `json:"titularTipoId,omitempty"`
+	TitularNumeroID  string `json:"titularNumeroId"`
+	Vehiculo
+	ValorPagar      string `json:"valorPagar"` // Flete en pesos, solo dígitos
+	LugarPago       string `json:"lugarPago"`
+	FechaPago       string `json:"fechaPago,omitempty"`
+	Recomendaciones string `json:"recomendaciones,omitempty"`
+}
+
+// ManifiestoRequest es una solicitud completa: la remesa y su manifiesto
+type ManifiestoRequest struct {
+	Remesa     Remesa     `json:"remesa"`
+	Manifiesto Manifiesto `json:"manifiesto"`
+}
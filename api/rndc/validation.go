@@ -0,0 +1,311 @@
+package rndc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brando1998/docubot-api/validation"
+)
+
+const (
+	// MaxPesoKg es el peso bruto vehicular máximo permitido en Colombia (tractocamión 3S3)
+	MaxPesoKg = 52000
+	// MaxFlete acota errores de digitación (ceros de más) en el valor a pagar
+	MaxFlete = 1_000_000_000
+)
+
+var (
+	consecutivoPattern = regexp.MustCompile(`^[A-Z0-9-]+$`)
+	placaPattern       = regexp.MustCompile(`^[A-Z]{3}[0-9]{3}$`)
+	tiempoPattern      = regexp.MustCompile(`^[0-9]{1,2}:[0-5][0-9]$`)
+	onlyDigits         = regexp.MustCompile(`^[0-9]+$`)
+	// configuracionPattern: camión rígido (2, 3, 4), tractocamión (3S2) o con remolque (2R2)
+	configuracionPattern = regexp.MustCompile(`^[2-4]([SR][1-4])?$`)
+
+	// nitWeights son los pesos de la DIAN para el dígito de verificación, del último
+	// dígito del NIT hacia el primero
+	nitWeights = []int{3, 7, 13, 17, 19, 23, 29, 37, 41, 43, 47, 53, 59, 67, 71}
+
+	// Colombia no tiene horario de verano
+	colombia = time.FixedZone("COT", -5*60*60)
+
+	fechaLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+)
+
+// FieldError y ValidationErrors son los errores de validación compartidos por la API
+type (
+	FieldError       = validation.FieldError
+	ValidationErrors = validation.Errors
+)
+
+// NITCheckDigit calcula el dígito de verificación (DV) de la DIAN para un NIT sin DV
+func NITCheckDigit(nit string) (int, error) {
+	if !onlyDigits.MatchString(nit) || len(nit) > len(nitWeights) {
+		return 0, errors.New("el NIT solo puede tener dígitos")
+	}
+	sum := 0
+	for i := 0; i < len(nit); i++ {
+		sum += int(nit[len(nit)-1-i]-'0') * nitWeights[i]
+	}
+	remainder := sum % 11
+	if remainder > 1 {
+		return 11 - remainder, nil
+	}
+	return remainder, nil
+}
+
+// NormalizeNIT quita puntos, espacios y el guion del DV: "860.053.746-3" → "8600537463"
+func NormalizeNIT(nit string) string {
+	return strings.NewReplacer(".", "", " ", "", "-", "").Replace(strings.TrimSpace(nit))
+}
+
+// ValidateNIT valida un NIT normalizado: 9 dígitos, o 10 cuando el último es el DV (se verifica)
+func ValidateNIT(nit string) error {
+	if !onlyDigits.MatchString(nit) || len(nit) < 9 || len(nit) > 10 {
+		return errors.New("el NIT debe tener 9 dígitos, o 10 con el dígito de verificación")
+	}
+	if len(nit) == 10 {
+		expected, _ := NITCheckDigit(nit[:9])
+		if int(nit[9]-'0') != expected {
+			return fmt.Errorf("el dígito de verificación del NIT %s no es válido (debería ser %d)", nit[:9], expected)
+		}
+	}
+	return nil
+}
+
+// NormalizePlaca pasa la placa a mayúsculas sin guiones ni espacios
+func NormalizePlaca(placa string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(placa))
+}
+
+// ValidatePlaca valida la placa de un vehículo de carga (tres letras y tres números)
+func ValidatePlaca(placa string) error {
+	if placa == "" {
+		return errors.New("la placa es obligatoria")
+	}
+	if !placaPattern.MatchString(placa) {
+		return fmt.Errorf("la placa %q no es válida; debe tener el formato ABC123", placa)
+	}
+	return nil
+}
+
+// ValidateLicencia valida el número de la licencia de conducción, que en Colombia es el
+// número de cédula del conductor
+func ValidateLicencia(numero string) error {
+	if !onlyDigits.MatchString(numero) || len(numero) < 6 || len(numero) > 10 {
+		return errors.New("la licencia de conducción debe tener entre 6 y 10 dígitos (el número de cédula del conductor)")
+	}
+	return nil
+}
+
+// CategoriasLicencia son las categorías de licencia de conducción vigentes
+var CategoriasLicencia = []string{"A1", "A2", "B1", "B2", "B3", "C1", "C2", "C3"}
+
+// ValidateCategoriaLicencia valida la categoría de la licencia de conducción (los camiones
+// requieren C2 y los tractocamiones C3)
+func ValidateCategoriaLicencia(categoria string) error {
+	return oneOf(categoria, CategoriasLicencia)
+}
+
+// ValidateConfiguracion valida la configuración vehicular del RNDC (2, 3, 3S2, 2R3...)
+func ValidateConfiguracion(configuracion string) error {
+	if configuracion != "" && !configuracionPattern.MatchString(configuracion) {
+		return fmt.Errorf("la configuración %q no es válida; usa la del RNDC (ej. 2, 3, 3S2, 3S3)", configuracion)
+	}
+	return nil
+}
+
+// ValidateTipoIDTitular valida el tipo de identificación de un titular o propietario
+func ValidateTipoIDTitular(tipo string) error {
+	return oneOf(tipo, TiposIDTitular)
+}
+
+// ValidateTipoIDConductor valida el tipo de identificación de un conductor
+func ValidateTipoIDConductor(tipo string) error {
+	return oneOf(tipo, TiposIDConductor)
+}
+
+// ValidateIdentificacion valida un número de identificación según su tipo
+func ValidateIdentificacion(tipo, numero string) error {
+	if tipo == "Nit" {
+		return ValidateNIT(numero)
+	}
+	if !onlyDigits.MatchString(numero) || len(numero) < 6 || len(numero) > 10 {
+		return errors.New("la identificación debe tener entre 6 y 10 dígitos")
+	}
+	return nil
+}
+
+// ValidatePeso valida el peso de la carga en kilogramos
+func ValidatePeso(kilos int) error {
+	if kilos < 1 {
+		return errors.New("el peso debe ser mayor a cero")
+	}
+	if kilos > MaxPesoKg {
+		return fmt.Errorf("el peso (%d kg) supera el máximo permitido de %d kg", kilos, MaxPesoKg)
+	}
+	return nil
+}
+
+// NormalizeFlete quita el signo de pesos y los separadores de miles: "$1.500.000" → "1500000"
+func NormalizeFlete(valor string) string {
+	return strings.NewReplacer("$", "", ".", "", ",", "", "'", "", " ", "").Replace(valor)
+}
+
+// ValidateFlete valida el valor a pagar (pesos, solo dígitos)
+func ValidateFlete(valor string) error {
+	if !onlyDigits.MatchString(valor) {
+		return errors.New("el valor a pagar debe ser numérico, sin decimales")
+	}
+	amount, err := strconv.ParseInt(valor, 10, 64)
+	if err != nil || amount > MaxFlete {
+		return fmt.Errorf("el valor a pagar supera el máximo de $%d", MaxFlete)
+	}
+	if amount < 1 {
+		return errors.New("el valor a pagar debe ser mayor a cero")
+	}
+	return nil
+}
+
+// ParseFecha interpreta una fecha ISO 8601; sin zona horaria se asume la hora de Colombia
+func ParseFecha(value string) (time.Time, error) {
+	for _, layout := range fechaLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, colombia); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("la fecha %q no es válida; usa el formato AAAA-MM-DD o ISO 8601", value)
+}
+
+// Normalize limpia el formato de los datos escritos a mano (espacios, puntos del NIT,
+// placa en minúsculas, flete con signo de pesos) sin cambiar su valor
+func (r *ManifiestoRequest) Normalize() {
+	remesa, manifiesto := &r.Remesa, &r.Manifiesto
+	for _, field := range []*string{
+		&remesa.Consecutivo, &remesa.DescripcionCorta, &remesa.Empresa.SedeCargue, &remesa.Empresa.SedeDescargue,
+		&remesa.HoraCargue, &remesa.HoraDescargue, &remesa.TiempoCargue, &remesa.TiempoDescargue,
+		&manifiesto.FechaExpedicion, &manifiesto.MunicipioOrigen, &manifiesto.MunicipioDestino,
+		&manifiesto.TitularNumeroID, &manifiesto.ConductorNumeroID, &manifiesto.LugarPago,
+		&manifiesto.FechaPago, &manifiesto.Recomendaciones,
+	} {
+		*field = strings.TrimSpace(*field)
+	}
+	remesa.Consecutivo = strings.ToUpper(remesa.Consecutivo)
+	remesa.Empresa.NIT = NormalizeNIT(remesa.Empresa.NIT)
+	if manifiesto.TitularTipoID == "Nit" {
+		manifiesto.TitularNumeroID = NormalizeNIT(manifiesto.TitularNumeroID)
+	}
+	manifiesto.PlacaVehiculo = NormalizePlaca(manifiesto.PlacaVehiculo)
+	manifiesto.ValorPagar = NormalizeFlete(manifiesto.ValorPagar)
+}
+
+// Validate revisa toda la solicitud y devuelve ValidationErrors con todos los campos
+// inválidos, o nil si se puede enviar al RNDC
+func (r ManifiestoRequest) Validate() error {
+	var errs ValidationErrors
+	r.Remesa.validate(&errs)
+	r.Manifiesto.validate(&errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (remesa Remesa) validate(errs *ValidationErrors) {
+	switch {
+	case remesa.Consecutivo == "":
+		errs.Add("remesa.consecutivo", errors.New("el consecutivo es obligatorio"))
+	case len(remesa.Consecutivo) > 50 || !consecutivoPattern.MatchString(remesa.Consecutivo):
+		errs.Add("remesa.consecutivo", errors.New("el consecutivo solo admite mayúsculas, números y guiones (máximo 50)"))
+	}
+	errs.Add("remesa.tipoOperacion", oneOf(remesa.TipoOperacion, TiposOperacion))
+	errs.Add("remesa.tipoEmpaque", oneOf(remesa.TipoEmpaque, TiposEmpaque))
+	errs.Add("remesa.descripcionCorta", length("la descripción de la carga", remesa.DescripcionCorta, 5, 200))
+	errs.Add("remesa.cantidadEstimada", ValidatePeso(remesa.CantidadEstimada))
+
+	errs.Add("remesa.empresa.nit", ValidateNIT(remesa.Empresa.NIT))
+	errs.Add("remesa.empresa.sedeCargue", required("la sede de cargue", remesa.Empresa.SedeCargue))
+	errs.Add("remesa.empresa.sedeDescargue", required("la sede de descargue", remesa.Empresa.SedeDescargue))
+
+	cargue, cargueErr := optionalFecha(remesa.HoraCargue)
+	descargue, descargueErr := optionalFecha(remesa.HoraDescargue)
+	errs.Add("remesa.horaCargue", cargueErr)
+	errs.Add("remesa.horaDescargue", descargueErr)
+	if !cargue.IsZero() && !descargue.IsZero() && descargue.Before(cargue) {
+		errs.Add("remesa.horaDescargue", errors.New("el descargue no puede ser anterior al cargue"))
+	}
+	errs.Add("remesa.tiempoCargue", tiempo(remesa.TiempoCargue))
+	errs.Add("remesa.tiempoDescargue", tiempo(remesa.TiempoDescargue))
+}
+
+func (manifiesto Manifiesto) validate(errs *ValidationErrors) {
+	errs.Add("manifiesto.tipoManifiesto", oneOf(manifiesto.TipoManifiesto, TiposManifiesto))
+	_, err := optionalFecha(manifiesto.FechaExpedicion)
+	errs.Add("manifiesto.fechaExpedicion", err)
+	errs.Add("manifiesto.municipioOrigen", length("el municipio de origen", manifiesto.MunicipioOrigen, 3, 100))
+	errs.Add("manifiesto.municipioDestino", length("el municipio de destino", manifiesto.MunicipioDestino, 3, 100))
+
+	errs.Add("manifiesto.titularTipoId", oneOf(manifiesto.TitularTipoID, TiposIDTitular))
+	errs.Add("manifiesto.titularNumeroId", ValidateIdentificacion(manifiesto.TitularTipoID, manifiesto.TitularNumeroID))
+
+	errs.Add("manifiesto.placaVehiculo", ValidatePlaca(manifiesto.PlacaVehiculo))
+	errs.Add("manifiesto.conductorTipoId", oneOf(manifiesto.ConductorTipoID, TiposIDConductor))
+	errs.Add("manifiesto.conductorNumeroId", ValidateLicencia(manifiesto.ConductorNumeroID))
+
+	errs.Add("manifiesto.valorPagar", ValidateFlete(manifiesto.ValorPagar))
+	errs.Add("manifiesto.lugarPago", length("el lugar de pago", manifiesto.LugarPago, 3, 100))
+	_, err = optionalFecha(manifiesto.FechaPago)
+	errs.Add("manifiesto.fechaPago", err)
+	if utf8.RuneCountInString(manifiesto.Recomendaciones) > 500 {
+		errs.Add("manifiesto.recomendaciones", errors.New("las recomendaciones no pueden superar 500 caracteres"))
+	}
+}
+
+func required(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("falta %s", name)
+	}
+	return nil
+}
+
+func length(name, value string, min, max int) error {
+	switch n := utf8.RuneCountInString(value); {
+	case n == 0:
+		return fmt.Errorf("falta %s", name)
+	case n < min || n > max:
+		return fmt.Errorf("%s debe tener entre %d y %d caracteres", name, min, max)
+	}
+	return nil
+}
+
+// oneOf acepta vacío (playwright-bot usa el valor por defecto) o uno de los permitidos
+func oneOf(value string, allowed []string) error {
+	if value == "" {
+		return nil
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q no es válido; usa uno de: %s", value, strings.Join(allowed, ", "))
+}
+
+func optionalFecha(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return ParseFecha(value)
+}
+
+func tiempo(value string) error {
+	if value != "" && !tiempoPattern.MatchString(value) {
+		return fmt.Errorf("el tiempo %q no es válido; usa el formato H:MM (ej. 1:30)", value)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package rndc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validRequest() ManifiestoRequest {
+	return ManifiestoRequest{
+		Remesa: Remesa{
+			Consecutivo:      "DB-1",
+			DescripcionCorta: "Cajas de repuestos",
+			CantidadEstimada: 4500,
+			Empresa:          Empresa{NIT: "860.053.746-3", SedeCargue: "SEDE-001", SedeDescargue: "SEDE-002"},
+			HoraCargue:       "2026-10-20",
+			HoraDescargue:    "2026-10-21T08:00:00-05:00",
+		},
+		Manifiesto: Manifiesto{
+			MunicipioOrigen:  "Bogotá",
+			MunicipioDestino: "Medellín",
+			TitularNumeroID:  "1020304050",
+			Vehiculo:         Vehiculo{PlacaVehiculo: "abc-123", ConductorNumeroID: "1020304050"},
+			ValorPagar:       "$1.500.000",
+			LugarPago:        "Medellín",
+		},
+	}
+}
+
+func TestNITCheckDigit(t *testing.T) {
+	for nit, expected := range map[string]int{"860053746": 3, "800197268": 4, "899999068": 1} {
+		digit, err := NITCheckDigit(nit)
+		require.NoError(t, err)
+		assert.Equal(t, expected, digit, nit)
+	}
+
+	assert.NoError(t, ValidateNIT("860053746"))
+	assert.NoError(t, ValidateNIT("8600537463"))
+	assert.ErrorContains(t, ValidateNIT("8600537464"), "debería ser 3")
+	assert.Error(t, ValidateNIT("86005"))
+}
+
+func TestValidateManifiestoRequest(t *testing.T) {
+	request := validRequest()
+	request.Normalize()
+	require.NoError(t, request.Validate())
+	assert.Equal(t, "8600537463", request.Remesa.Empresa.NIT)
+	assert.Equal(t, "ABC123", request.Manifiesto.PlacaVehiculo)
+	assert.Equal(t, "1500000", request.Manifiesto.ValorPagar)
+
+	// El vehículo va al mismo nivel que los demás campos del manifiesto, como en Joi
+	body, err := json.Marshal(request.Manifiesto)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"placaVehiculo":"ABC123"`)
+	assert.NotContains(t, string(body), `"Vehiculo"`)
+
+	request.Remesa.HoraDescargue = "2026-10-19"
+	request.Remesa.CantidadEstimada = 80000
+	request.Manifiesto.PlacaVehiculo = "ABC12D"
+	request.Manifiesto.ConductorNumeroID = "12AB"
+	request.Manifiesto.ValorPagar = "0"
+	request.Manifiesto.TipoManifiesto = "Especial"
+
+	var errs ValidationErrors
+	require.ErrorAs(t, request.Validate(), &errs)
+	fields := map[string]string{}
+	for _, fieldError := range errs {
+		fields[fieldError.Field] = fieldError.Message
+	}
+	assert.Equal(t, "el descargue no puede ser anterior al cargue", fields["remesa.horaDescargue"])
+	assert.Contains(t, fields["remesa.cantidadEstimada"], "supera el máximo")
+	assert.Contains(t, fields["manifiesto.placaVehiculo"], "ABC123")
+	assert.Contains(t, fields["manifiesto.conductorNumeroId"], "licencia")
+	assert.Contains(t, fields["manifiesto.valorPagar"], "mayor a cero")
+	assert.Contains(t, fields["manifiesto.tipoManifiesto"], "General, Individual")
+	assert.Len(t, errs, 6)
+}
@@ -9,11 +9,13 @@ import (
 	"github.com/brando1998/docubot-api/controllers"
 	_ "github.com/brando1998/docubot-api/docs"
 	"github.com/brando1998/docubot-api/middleware"
+	"github.com/brando1998/docubot-api/models"
 )
 
 type RouterConfig struct {
-	WSHub    *controllers.WebSocketHub
-	Upgrader *websocket.Upgrader
+	WSHub         *controllers.WebSocketHub
+	Upgrader      *websocket.Upgrader
+	InternalToken string // Token de las rutas /internal (INTERNAL_API_TOKEN)
 }
 
 func SetupRoutes(r *gin.Engine, config *RouterConfig) {
@@ -33,17 +35,39 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		public.GET("/health", controllers.Health)
 		public.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-		// WebSocket para conexión con Baileys
+		// WebSocket para conexión con Baileys (autenticado con la credencial de la sesión)
 		public.GET("/ws", func(c *gin.Context) {
 			controllers.HandleWebSocket(c, config.WSHub, *config.Upgrader)
 		})
 
-		// Debug: listar bots conectados
-		public.GET("/debug/bots", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"bots":  config.WSHub.ListBots(),
-				"total": len(config.WSHub.ListBots()),
-			})
+		// Descarga de documentos con enlace firmado (se comparte por WhatsApp)
+		public.GET("/download/:token", controllers.DownloadSharedDocument)
+
+		// Verificación pública de documentos (la abre el QR del manifiesto)
+		public.GET("/verify/:code", controllers.VerifyDocument)
+		public.GET("/verify/:code/qr", controllers.GetVerificationQR)
+	}
+
+	// =============================================
+	// API interna (Rasa y otros servicios)
+	// =============================================
+	internal := r.Group("/internal")
+	internal.Use(middleware.InternalTokenMiddleware(config.InternalToken))
+	{
+		internal.POST("/manifests", func(c *gin.Context) {
+			controllers.StartManifest(c, config.WSHub)
+		})
+		internal.GET("/manifests/prefill", func(c *gin.Context) {
+			controllers.PrefillManifest(c, config.WSHub)
+		})
+		internal.GET("/manifests/templates", func(c *gin.Context) {
+			controllers.GetChatManifestTemplates(c, config.WSHub)
+		})
+		internal.POST("/manifests/repeat", func(c *gin.Context) {
+			controllers.RepeatChatManifest(c, config.WSHub)
+		})
+		internal.POST("/manifests/delivery", func(c *gin.Context) {
+			controllers.ConfirmManifestDelivery(c, config.WSHub)
 		})
 	}
 
@@ -54,27 +78,70 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 	{
 		authGroup.POST("/login", controllers.LoginWithPaseto)
 		authGroup.POST("/refresh", controllers.RefreshPasetoToken)
+		authGroup.GET("/keys", controllers.GetTokenKeys)
+		authGroup.POST("/logout", controllers.Logout)
+		authGroup.POST("/logout-all", middleware.PasetoAuthMiddleware(), controllers.LogoutAll)
 		authGroup.GET("/me", middleware.PasetoAuthMiddleware(), controllers.GetCurrentSystemUser)
 	}
 
 	// =============================================
 	// Rutas Protegidas con PASETO
+	// Cada ruta exige un permiso del rol del usuario (ver models.BuiltInRoles)
 	// =============================================
 	api := r.Group("/api/v1")
 	api.Use(middleware.PasetoAuthMiddleware())
 	{
+		// Debug: métricas del procesamiento de mensajes entrantes (de todas las organizaciones)
+		api.GET("/debug/dispatcher", middleware.RequirePermission(models.PermissionOrganizationsManage), controllers.GetDispatcherStats)
+		// Debug: bots conectados a esta instancia (de todas las organizaciones)
+		api.GET("/debug/bots", middleware.RequirePermission(models.PermissionOrganizationsManage), func(c *gin.Context) {
+			controllers.GetConnectedBots(c, config.WSHub)
+		})
+
 		// --------------------------
 		// 🆕 Organizaciones
 		// --------------------------
 		orgGroup := api.Group("/organizations")
 		{
 			orgGroup.GET("/me", controllers.GetMyOrganization) // Mi organización
-			// Solo admin puede crear/modificar organizaciones
-			// orgGroup.POST("", middleware.RequireAdmin(), controllers.CreateOrganization)
-			// orgGroup.GET("", middleware.RequireAdmin(), controllers.GetOrganizations)
-			// orgGroup.GET("/:id", middleware.RequireAdmin(), controllers.GetOrganizationByID)
-			// orgGroup.PUT("/:id", middleware.RequireAdmin(), controllers.UpdateOrganization)
-			// orgGroup.DELETE("/:id", middleware.RequireAdmin(), controllers.DeleteOrganization)
+			// Solo owner puede crear/modificar organizaciones
+			// orgGroup.POST("", middleware.RequirePermission(models.PermissionOrganizationsManage), controllers.CreateOrganization)
+			// orgGroup.GET("", middleware.RequirePermission(models.PermissionOrganizationsManage), controllers.GetOrganizations)
+			// orgGroup.GET("/:id", middleware.RequirePermission(models.PermissionOrganizationsManage), controllers.GetOrganizationByID)
+			// orgGroup.PUT("/:id", middleware.RequirePermission(models.PermissionOrganizationsManage), controllers.UpdateOrganization)
+			// orgGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionOrganizationsManage), controllers.DeleteOrganization)
+		}
+
+		// --------------------------
+		// Usuarios del dashboard y roles
+		// --------------------------
+		systemUserGroup := api.Group("/system-users")
+		systemUserGroup.Use(middleware.RequirePermission(models.PermissionUsersManage))
+		{
+			systemUserGroup.GET("", controllers.ListSystemUsers)
+			systemUserGroup.POST("", controllers.CreateSystemUser)
+			systemUserGroup.GET("/:id", controllers.GetSystemUser)
+			systemUserGroup.PUT("/:id", controllers.UpdateSystemUser)
+			systemUserGroup.DELETE("/:id", controllers.DeleteSystemUser)
+			systemUserGroup.POST("/:id/activate", controllers.ActivateSystemUser)
+			systemUserGroup.POST("/:id/deactivate", controllers.DeactivateSystemUser)
+			systemUserGroup.POST("/:id/reset-password", controllers.ResetSystemUserPassword)
+		}
+		roleGroup := api.Group("/roles")
+		{
+			roleGroup.GET("", middleware.RequirePermission(models.PermissionUsersManage), controllers.ListRoles)
+			roleGroup.POST("", middleware.RequirePermission(models.PermissionRolesManage), controllers.CreateRole)
+			roleGroup.PUT("/:id", middleware.RequirePermission(models.PermissionRolesManage), controllers.UpdateRole)
+			roleGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionRolesManage), controllers.DeleteRole)
+		}
+
+		// API keys de los clientes máquina (acciones de Rasa, playwright-bot, integraciones)
+		apiKeyGroup := api.Group("/api-keys")
+		apiKeyGroup.Use(middleware.RequirePermission(models.PermissionAPIKeysManage))
+		{
+			apiKeyGroup.GET("", controllers.ListAPIKeys)
+			apiKeyGroup.POST("", controllers.CreateAPIKey)
+			apiKeyGroup.DELETE("/:id", controllers.RevokeAPIKey)
 		}
 
 		// --------------------------
@@ -82,11 +149,47 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		userGroup := api.Group("/users")
 		{
-			userGroup.GET("/me", controllers.GetCurrentUser)
-			userGroup.POST("", controllers.CreateClient)
-			userGroup.GET("/id/:id", controllers.GetClientByID)
-			userGroup.GET("/phone/:phone", controllers.GetClientByPhone)
-			userGroup.POST("/get-or-create", controllers.GetOrCreateClient)
+			userGroup.GET("/me", middleware.RequirePermission(models.PermissionClientsRead), controllers.GetCurrentUser)
+			userGroup.POST("", middleware.RequirePermission(models.PermissionClientsManage), controllers.CreateClient)
+			userGroup.GET("/id/:id", middleware.RequirePermission(models.PermissionClientsRead), controllers.GetClientByID)
+			userGroup.GET("/phone/:phone", middleware.RequirePermission(models.PermissionClientsRead), controllers.GetClientByPhone)
+			userGroup.POST("/get-or-create", middleware.RequirePermission(models.PermissionClientsManage), controllers.GetOrCreateClient)
+
+			// Plantillas para repetir manifiestos y rutas guardadas del cliente
+			userGroup.GET("/id/:id/manifest-templates", middleware.RequirePermission(models.PermissionClientsRead), controllers.GetClientManifestTemplates)
+			userGroup.POST("/id/:id/manifests/repeat", middleware.RequirePermission(models.PermissionDocumentsManage), func(c *gin.Context) {
+				controllers.RepeatClientManifest(c, config.WSHub)
+			})
+			userGroup.GET("/id/:id/route-templates", middleware.RequirePermission(models.PermissionClientsRead), controllers.ListRouteTemplates)
+			userGroup.POST("/id/:id/route-templates", middleware.RequirePermission(models.PermissionClientsManage), controllers.CreateRouteTemplate)
+			userGroup.PUT("/id/:id/route-templates/:templateId", middleware.RequirePermission(models.PermissionClientsManage), controllers.UpdateRouteTemplate)
+			userGroup.DELETE("/id/:id/route-templates/:templateId", middleware.RequirePermission(models.PermissionClientsManage), controllers.DeleteRouteTemplate)
+		}
+
+		// --------------------------
+		// Registro de vehículos y conductores
+		// --------------------------
+		vehicleGroup := api.Group("/vehicles")
+		{
+			vehicleGroup.GET("", middleware.RequirePermission(models.PermissionRegistryRead), controllers.ListVehicles)
+			vehicleGroup.POST("", middleware.RequirePermission(models.PermissionRegistryManage), controllers.CreateVehicle)
+			vehicleGroup.GET("/:id", middleware.RequirePermission(models.PermissionRegistryRead), controllers.GetVehicle)
+			vehicleGroup.PUT("/:id", middleware.RequirePermission(models.PermissionRegistryManage), controllers.UpdateVehicle)
+			vehicleGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionRegistryManage), controllers.DeleteVehicle)
+		}
+		driverGroup := api.Group("/drivers")
+		{
+			driverGroup.GET("", middleware.RequirePermission(models.PermissionRegistryRead), controllers.ListDrivers)
+			driverGroup.POST("", middleware.RequirePermission(models.PermissionRegistryManage), controllers.CreateDriver)
+			driverGroup.GET("/:id", middleware.RequirePermission(models.PermissionRegistryRead), controllers.GetDriver)
+			driverGroup.PUT("/:id", middleware.RequirePermission(models.PermissionRegistryManage), controllers.UpdateDriver)
+			driverGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionRegistryManage), controllers.DeleteDriver)
+		}
+		expirationGroup := api.Group("/expirations")
+		{
+			expirationGroup.GET("", middleware.RequirePermission(models.PermissionRegistryRead), controllers.ListExpirations)
+			expirationGroup.GET("/settings", middleware.RequirePermission(models.PermissionRegistryRead), controllers.GetExpirySettings)
+			expirationGroup.PUT("/settings", middleware.RequirePermission(models.PermissionRegistryManage), controllers.UpdateExpirySettings)
 		}
 
 		// --------------------------
@@ -94,17 +197,36 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		whatsappGroup := api.Group("/whatsapp")
 		{
-			whatsappGroup.GET("/qr", controllers.GetWhatsAppQR)
-			whatsappGroup.POST("/disconnect", controllers.DisconnectWhatsApp)
-			whatsappGroup.GET("/status", controllers.GetSessionStatus)
-			whatsappGroup.POST("/send", controllers.SendWhatsAppMessage)
-			whatsappGroup.POST("/restart", controllers.RestartWhatsAppSession)
-			whatsappGroup.POST("/clear-session", controllers.ClearWhatsAppSession)
-			whatsappGroup.POST("/sessions", controllers.CreateWhatsAppSession)
-			whatsappGroup.GET("/sessions", controllers.ListWhatsAppSessions)
-			whatsappGroup.GET("/chats", controllers.GetChatList)
-			whatsappGroup.GET("/chats/:chatId/messages", controllers.GetChatMessages)
-			whatsappGroup.POST("/chats/:chatId/send", controllers.SendChatMessage)
+			whatsappGroup.GET("/qr", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.GetWhatsAppQR)
+			whatsappGroup.POST("/disconnect", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.DisconnectWhatsApp)
+			whatsappGroup.GET("/status", middleware.RequirePermission(models.PermissionDashboardRead), controllers.GetSessionStatus)
+			whatsappGroup.POST("/send", middleware.RequirePermission(models.PermissionWhatsAppSend), controllers.SendWhatsAppMessage)
+			whatsappGroup.POST("/restart", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.RestartWhatsAppSession)
+			whatsappGroup.POST("/clear-session", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.ClearWhatsAppSession)
+			whatsappGroup.POST("/sessions", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.CreateWhatsAppSession)
+			whatsappGroup.GET("/sessions", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.ListWhatsAppSessions)
+			whatsappGroup.GET("/sessions/:sessionId/credentials", middleware.RequirePermission(models.PermissionWhatsAppManage), controllers.ListBotSessionCredentials)
+			whatsappGroup.POST("/sessions/:sessionId/credentials/rotate", middleware.RequirePermission(models.PermissionWhatsAppManage), func(c *gin.Context) {
+				controllers.RotateBotSessionCredential(c, config.WSHub)
+			})
+			whatsappGroup.DELETE("/sessions/:sessionId/credentials/:keyId", middleware.RequirePermission(models.PermissionWhatsAppManage), func(c *gin.Context) {
+				controllers.RevokeBotSessionCredential(c, config.WSHub)
+			})
+			whatsappGroup.GET("/chats", middleware.RequirePermission(models.PermissionChatsRead), controllers.GetChatList)
+			whatsappGroup.GET("/chats/:chatId/messages", middleware.RequirePermission(models.PermissionChatsRead), controllers.GetChatMessages)
+			whatsappGroup.POST("/chats/:chatId/send", middleware.RequirePermission(models.PermissionWhatsAppSend), controllers.SendChatMessage)
+			whatsappGroup.GET("/chats/:chatId/rasa", middleware.RequirePermission(models.PermissionChatsRead), controllers.GetChatRasaState)
+			whatsappGroup.POST("/chats/:chatId/rasa/reset", middleware.RequirePermission(models.PermissionChatsTakeover), controllers.ResetChatRasaConversation)
+		}
+
+		// --------------------------
+		// Cola de mensajes salientes
+		// --------------------------
+		outboundGroup := api.Group("/outbound-messages")
+		{
+			outboundGroup.GET("", middleware.RequirePermission(models.PermissionChatsRead), controllers.GetOutboundMessages)
+			outboundGroup.GET("/dead-letter", middleware.RequirePermission(models.PermissionChatsRead), controllers.GetOutboundDeadLetters)
+			outboundGroup.POST("/:id/retry", middleware.RequirePermission(models.PermissionWhatsAppSend), controllers.RetryOutboundMessage)
 		}
 
 		// --------------------------
@@ -112,7 +234,20 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		documentGroup := api.Group("/documents")
 		{
-			documentGroup.POST("", controllers.SaveDocument)
+			documentGroup.POST("", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.SaveDocument)
+			documentGroup.GET("", middleware.RequirePermission(models.PermissionDocumentsRead), controllers.ListDocuments)
+			documentGroup.GET("/:id", middleware.RequirePermission(models.PermissionDocumentsRead), controllers.GetDocument)
+			documentGroup.GET("/:id/download", middleware.RequirePermission(models.PermissionDocumentsRead), controllers.DownloadDocument)
+			documentGroup.PATCH("/:id", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.UpdateDocument)
+			documentGroup.POST("/:id/retry", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.RetryDocument)
+			documentGroup.POST("/:id/cancel", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.CancelDocument)
+			documentGroup.POST("/:id/fulfil", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.FulfilManifest)
+			documentGroup.POST("/:id/annul", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.AnnulManifest)
+			documentGroup.POST("/:id/request-delivery", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.RequestManifestDelivery)
+			documentGroup.POST("/:id/links", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.CreateDocumentLink)
+			documentGroup.GET("/:id/links", middleware.RequirePermission(models.PermissionDocumentsRead), controllers.ListDocumentLinks)
+			documentGroup.DELETE("/:id/links/:linkId", middleware.RequirePermission(models.PermissionDocumentsManage), controllers.RevokeDocumentLink)
+			documentGroup.GET("/:id/downloads", middleware.RequirePermission(models.PermissionDocumentsRead), controllers.GetDocumentDownloads)
 		}
 
 		// --------------------------
@@ -120,8 +255,11 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		chatGroup := api.Group("/chats")
 		{
-			chatGroup.POST("/mode", controllers.UpdateChatMode)
-			chatGroup.POST("/archive", controllers.ArchiveChat)
+			chatGroup.POST("/mode", middleware.RequirePermission(models.PermissionChatsTakeover), controllers.UpdateChatMode)
+			chatGroup.POST("/archive", middleware.RequirePermission(models.PermissionChatsTakeover), controllers.ArchiveChat)
+			chatGroup.GET("/ws", middleware.RequirePermission(models.PermissionChatsRead), func(c *gin.Context) {
+				controllers.HandleAgentWebSocket(c, config.WSHub, *config.Upgrader)
+			})
 		}
 
 		// --------------------------
@@ -129,7 +267,7 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		clientGroup := api.Group("/clients")
 		{
-			clientGroup.GET("/:clientId/documents", controllers.GetClientDocuments)
+			clientGroup.GET("/:clientId/documents", middleware.RequirePermission(models.PermissionDocumentsRead), controllers.GetClientDocuments)
 		}
 
 		// --------------------------
@@ -137,7 +275,7 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		conversationGroup := api.Group("/conversations")
 		{
-			conversationGroup.GET("/:clientId/export", controllers.ExportConversation)
+			conversationGroup.GET("/:clientId/export", middleware.RequirePermission(models.PermissionChatsRead), controllers.ExportConversation)
 		}
 
 		// --------------------------
@@ -145,7 +283,7 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		dashboardGroup := api.Group("/dashboard")
 		{
-			dashboardGroup.GET("/stats", controllers.GetDashboardStats)
+			dashboardGroup.GET("/stats", middleware.RequirePermission(models.PermissionDashboardRead), controllers.GetDashboardStats)
 		}
 
 		// --------------------------
@@ -153,9 +291,9 @@ func SetupRoutes(r *gin.Engine, config *RouterConfig) {
 		// --------------------------
 		instanceGroup := api.Group("/bot-instances")
 		{
-			instanceGroup.POST("", controllers.CreateBotInstance)
-			instanceGroup.GET("", controllers.ListBotInstances)
-			instanceGroup.DELETE("/:id", controllers.DeleteBotInstance)
+			instanceGroup.POST("", middleware.RequirePermission(models.PermissionBotInstancesManage), controllers.CreateBotInstance)
+			instanceGroup.GET("", middleware.RequirePermission(models.PermissionBotInstancesManage), controllers.ListBotInstances)
+			instanceGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionBotInstancesManage), controllers.DeleteBotInstance)
 		}
 	}
 }
\ No newline at end of file
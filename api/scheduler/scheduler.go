@@ -0,0 +1,140 @@
+// Package scheduler ejecuta trabajos periódicos de la API con elección de líder: cada
+// trabajo tiene un lease y solo la réplica que lo tiene lo ejecuta en cada ciclo.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Locker guarda los leases de los trabajos (ver repositories.JobLeaseRepository)
+type Locker interface {
+	TryAcquire(name, holder string, ttl time.Duration) (bool, error)
+	Release(name, holder string) error
+}
+
+// Job es un trabajo que se ejecuta cada Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration // Duración máxima de cada ejecución; por defecto Interval
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler ejecuta los trabajos registrados hasta Stop
+type Scheduler struct {
+	locker Locker
+	holder string
+	jobs   []Job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New crea un scheduler. Con locker nil todos los trabajos corren en esta réplica (útil
+// con una sola instancia de la API).
+func New(locker Locker, holder string) *Scheduler {
+	if holder == "" {
+		holder = HolderID()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{locker: locker, holder: holder, ctx: ctx, cancel: cancel}
+}
+
+// HolderID identifica esta réplica en los leases: hostname-pid-aleatorio
+func HolderID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "api"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// Add registra un trabajo; debe llamarse antes de Start
+func (s *Scheduler) Add(job Job) {
+	if job.Timeout <= 0 || job.Timeout > job.Interval {
+		job.Timeout = job.Interval
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Start ejecuta cada trabajo al arrancar y luego en cada intervalo
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		if job.Interval <= 0 {
+			log.Printf("⏸️  Trabajo %s deshabilitado (sin intervalo)", job.Name)
+			continue
+		}
+		s.wg.Add(1)
+		go s.loop(job)
+		log.Printf("⏰ Trabajo %s programado cada %s", job.Name, job.Interval)
+	}
+}
+
+// Stop detiene los trabajos, espera la ejecución en curso y libera los leases para que
+// otra réplica tome el turno sin esperar a que venzan
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	if s.locker == nil {
+		return
+	}
+	for _, job := range s.jobs {
+		if err := s.locker.Release(leaseName(job), s.holder); err != nil {
+			log.Printf("⚠️ Error liberando el lease de %s: %v", job.Name, err)
+		}
+	}
+}
+
+func (s *Scheduler) loop(job Job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(s.ctx, job); err != nil {
+			log.Printf("⚠️ Error en el trabajo %s: %v", job.Name, err)
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce ejecuta un ciclo del trabajo si esta réplica es la líder. Devuelve si se ejecutó.
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) (bool, error) {
+	if s.locker != nil {
+		// El lease dura dos ciclos: el líder lo renueva en cada uno y, si la réplica muere,
+		// otra lo toma como mucho dos intervalos después
+		acquired, err := s.locker.TryAcquire(leaseName(job), s.holder, 2*job.Interval)
+		if err != nil {
+			return false, fmt.Errorf("error obteniendo el lease: %w", err)
+		}
+		if !acquired {
+			return false, nil
+		}
+	}
+
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = job.Interval
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return true, job.Run(runCtx)
+}
+
+func leaseName(job Job) string {
+	return "job:" + job.Name
+}
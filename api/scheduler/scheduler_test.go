@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryLocker struct {
+	mu     sync.Mutex
+	now    time.Time
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	holder  string
+	expires time.Time
+}
+
+func (l *memoryLocker) TryAcquire(name, holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leases == nil {
+		l.leases = map[string]memoryLease{}
+	}
+	lease, ok := l.leases[name]
+	if ok && lease.holder != holder && lease.expires.After(l.now) {
+		return false, nil
+	}
+	l.leases[name] = memoryLease{holder: holder, expires: l.now.Add(ttl)}
+	return true, nil
+}
+
+func (l *memoryLocker) Release(name, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leases[name].holder == holder {
+		delete(l.leases, name)
+	}
+	return nil
+}
+
+func TestOnlyLeaderRunsJob(t *testing.T) {
+	locker := &memoryLocker{now: time.Now()}
+	runs := map[string]int{}
+	job := func(replica string) Job {
+		return Job{Name: "reminders", Interval: time.Minute, Run: func(ctx context.Context) error {
+			runs[replica]++
+			return nil
+		}}
+	}
+
+	first := New(locker, "replica-a")
+	second := New(locker, "replica-b")
+	for i := 0; i < 3; i++ {
+		ran, err := first.RunOnce(context.Background(), job("a"))
+		require.NoError(t, err)
+		assert.True(t, ran)
+		ran, err = second.RunOnce(context.Background(), job("b"))
+		require.NoError(t, err)
+		assert.False(t, ran)
+	}
+	assert.Equal(t, map[string]int{"a": 3}, runs)
+
+	// Si el líder deja de renovar, la otra réplica toma el turno al vencer el lease
+	locker.now = locker.now.Add(3 * time.Minute)
+	ran, err := second.RunOnce(context.Background(), job("b"))
+	require.NoError(t, err)
+	assert.True(t, ran)
+	ran, _ = first.RunOnce(context.Background(), job("a"))
+	assert.False(t, ran)
+
+	// Al detenerse libera el lease y el otro lo toma de inmediato
+	second.Add(job("b"))
+	second.Stop()
+	ran, _ = first.RunOnce(context.Background(), job("a"))
+	assert.True(t, ran)
+}
@@ -43,7 +43,7 @@ func main() {
 
 	// Listar usuarios administradores existentes
 	var admins []models.SystemUser
-	if err := db.Where("role = ?", "admin").Find(&admins).Error; err != nil {
+	if err := db.Where("role IN ?", []string{models.RoleOwner, models.RoleAdmin}).Find(&admins).Error; err != nil {
 		log.Fatalf("❌ Error al buscar administradores: %v", err)
 	}
 
@@ -143,7 +143,7 @@ func listAdmins() {
 
 	// Listar usuarios administradores existentes
 	var admins []models.SystemUser
-	if err := db.Where("role = ?", "admin").Find(&admins).Error; err != nil {
+	if err := db.Where("role IN ?", []string{models.RoleOwner, models.RoleAdmin}).Find(&admins).Error; err != nil {
 		log.Fatalf("❌ Error al buscar administradores: %v", err)
 	}
 
@@ -52,10 +52,10 @@ func EnsureDefaultAdminUser(db *gorm.DB) error {
 		log.Printf("✅ Organización por defecto ya existe (ID: %d)", defaultOrg.ID)
 	}
 
-	// 2. Verificar si ya existe un usuario con rol admin en esta organización
+	// 2. Verificar si ya existe un owner o admin en esta organización
 	var adminExists int64
 	err = db.Model(&models.SystemUser{}).
-		Where("role = ? AND organization_id = ?", "admin", defaultOrg.ID).
+		Where("role IN ? AND organization_id = ?", []string{models.RoleOwner, models.RoleAdmin}, defaultOrg.ID).
 		Count(&adminExists).Error
 	if err != nil {
 		return err
@@ -91,7 +91,7 @@ func EnsureDefaultAdminUser(db *gorm.DB) error {
 		Username:       creds.Username,
 		Email:          creds.Email,
 		PasswordHash:   string(hashedPassword),
-		Role:           "admin",
+		Role:           models.RoleOwner,
 		IsActive:       true,
 	}
 
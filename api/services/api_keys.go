@@ -0,0 +1,165 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+var (
+	ErrAPIKeyInvalid = errors.New("datos de la API key inválidos")
+	// ErrAPIKeyUnauthorized agrupa las keys desconocidas, revocadas o vencidas
+	ErrAPIKeyUnauthorized = errors.New("API key inválida, revocada o vencida")
+)
+
+// apiKeyTouchInterval limita las escrituras de LastUsedAt a una por minuto por key
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService administra las API keys de los clientes máquina de cada organización.
+// Como con los roles, el actor solo puede dar a una key permisos que él tiene.
+type APIKeyService struct {
+	keys  repositories.APIKeyRepository
+	roles *RoleService
+	now   func() time.Time
+}
+
+func NewAPIKeyService(keys repositories.APIKeyRepository, roles *RoleService) *APIKeyService {
+	return &APIKeyService{keys: keys, roles: roles, now: time.Now}
+}
+
+// APIKeyInput son los datos de una key nueva; ExpiresAt nil no vence
+type APIKeyInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+func (s *APIKeyService) List(orgID uint) ([]models.APIKey, error) {
+	return s.keys.List(orgID)
+}
+
+// Create registra una key en la organización del actor. Devuelve la key completa, que
+// solo se puede ver en este momento.
+func (s *APIKeyService) Create(actor models.SystemUser, input APIKeyInput) (*models.APIKey, string, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	input.Scopes = models.NormalizePermissions(input.Scopes)
+
+	var errs validation.Errors
+	if input.Name == "" || utf8.RuneCountInString(input.Name) > 100 {
+		errs.Add("name", errors.New("de 1 a 100 caracteres"))
+	}
+	if len(input.Scopes) == 0 {
+		errs.Add("scopes", errors.New("indica al menos un permiso"))
+	}
+	for _, scope := range input.Scopes {
+		if !models.HasPermission(models.APIKeyScopes, scope) {
+			errs.Add("scopes", fmt.Errorf("permiso no disponible para API keys: %s", scope))
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.now()) {
+		errs.Add("expires_at", errors.New("debe ser una fecha futura"))
+	}
+	if len(errs) > 0 {
+		return nil, "", fmt.Errorf("%w: %w", ErrAPIKeyInvalid, errs)
+	}
+	if err := s.roles.checkGrant(actor, input.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	key := &models.APIKey{
+		OrganizationID: actor.OrganizationID,
+		Name:           input.Name,
+		Prefix:         prefix,
+		KeyHash:        hashAPIKeySecret(secret),
+		Scopes:         input.Scopes,
+		CreatedByID:    actor.ID,
+		ExpiresAt:      input.ExpiresAt,
+	}
+	if err := s.keys.Create(key); err != nil {
+		return nil, "", err
+	}
+	return key, models.APIKeyPrefix + prefix + "_" + secret, nil
+}
+
+// Revoke desactiva una key de la organización del actor; no se puede reactivar
+func (s *APIKeyService) Revoke(actor models.SystemUser, id uint) error {
+	key, err := s.keys.GetByID(id, actor.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return s.keys.Revoke(key.ID, key.OrganizationID, s.now())
+}
+
+// Authenticate valida una key recibida en una petición y registra su último uso
+func (s *APIKeyService) Authenticate(raw, ip string) (*models.APIKey, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(raw), models.APIKeyPrefix)
+	if !ok {
+		return nil, ErrAPIKeyUnauthorized
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return nil, ErrAPIKeyUnauthorized
+	}
+
+	key, err := s.keys.GetByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashAPIKeySecret(secret))) != 1 {
+		return nil, ErrAPIKeyUnauthorized
+	}
+	now := s.now()
+	if !key.IsActive(now) {
+		return nil, ErrAPIKeyUnauthorized
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		if err := s.keys.TouchLastUsed(key.ID, now, ip); err != nil {
+			log.Printf("⚠️  Error registrando el uso de la API key %d: %v", key.ID, err)
+		} else {
+			key.LastUsedAt = &now
+			key.LastUsedIP = ip
+		}
+	}
+	return key, nil
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+func TestAPIKeys(t *testing.T) {
+	repo := &mocks.MockAPIKeyRepo{}
+	roles := NewRoleService(&mocks.MockRoleRepo{}, &mocks.MockSystemUserRepo{})
+	keys := NewAPIKeyService(repo, roles)
+	owner := models.SystemUser{ID: 1, OrganizationID: 1, Role: models.RoleOwner}
+
+	key, raw, err := keys.Create(owner, APIKeyInput{
+		Name:   " Acciones de Rasa ",
+		Scopes: []string{"documents:manage", "clients:read", "documents:manage"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Acciones de Rasa", key.Name)
+	assert.Equal(t, []string{"clients:read", "documents:manage"}, key.Scopes)
+	assert.True(t, strings.HasPrefix(raw, models.APIKeyPrefix+key.Prefix+"_"))
+	assert.NotContains(t, key.KeyHash, raw, "solo se guarda el hash")
+
+	authenticated, err := keys.Authenticate(raw, "10.0.0.5")
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+	stored, err := repo.GetByID(key.ID, 1)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt)
+	assert.Equal(t, "10.0.0.5", stored.LastUsedIP)
+
+	_, err = keys.Authenticate(raw[:len(raw)-2]+"xx", "10.0.0.5")
+	assert.ErrorIs(t, err, ErrAPIKeyUnauthorized)
+	_, err = keys.Authenticate("dbk_desconocida_secreto", "10.0.0.5")
+	assert.ErrorIs(t, err, ErrAPIKeyUnauthorized)
+
+	// Validación por campo y permisos de administración fuera de alcance
+	_, _, err = keys.Create(owner, APIKeyInput{Scopes: []string{"users:manage"}})
+	require.ErrorIs(t, err, ErrAPIKeyInvalid)
+	var fields validation.Errors
+	require.ErrorAs(t, err, &fields)
+	assert.Len(t, fields, 2)
+
+	// Un agente no crea keys con permisos que no tiene
+	agent := models.SystemUser{ID: 2, OrganizationID: 1, Role: models.RoleAgent}
+	_, _, err = keys.Create(agent, APIKeyInput{Name: "bot", Scopes: []string{"whatsapp:manage"}})
+	assert.ErrorIs(t, err, ErrPermissionEscalation)
+
+	// Otra organización no revoca la key; al revocarla deja de aceptarse
+	assert.ErrorIs(t, keys.Revoke(models.SystemUser{OrganizationID: 2, Role: models.RoleOwner}, key.ID), gorm.ErrRecordNotFound)
+	require.NoError(t, keys.Revoke(owner, key.ID))
+	_, err = keys.Authenticate(raw, "10.0.0.5")
+	assert.ErrorIs(t, err, ErrAPIKeyUnauthorized)
+
+	// Las keys vencidas tampoco se aceptan
+	expiresAt := time.Now().Add(time.Hour)
+	_, expiring, err := keys.Create(owner, APIKeyInput{Name: "temporal", Scopes: []string{"documents:read"}, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	keys.now = func() time.Time { return expiresAt.Add(time.Second) }
+	_, err = keys.Authenticate(expiring, "10.0.0.5")
+	assert.ErrorIs(t, err, ErrAPIKeyUnauthorized)
+}
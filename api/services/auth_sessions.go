@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+const refreshTokenPrefix = "rt_"
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token inválido o vencido")
+	// ErrRefreshTokenReused indica que llegó un refresh token ya rotado: se revoca toda la
+	// familia porque alguien más pudo copiarlo
+	ErrRefreshTokenReused = errors.New("refresh token reutilizado, la sesión fue revocada")
+	// ErrOrganizationInactive impide abrir o renovar sesiones de una organización desactivada
+	ErrOrganizationInactive = errors.New("organización inactiva")
+)
+
+// AccessTokenIssuer emite los access tokens de corta duración de un usuario
+type AccessTokenIssuer interface {
+	IssueAccessToken(user models.SystemUser) (string, time.Time, error)
+}
+
+// AuthSessionConfig configura la duración de los refresh tokens
+type AuthSessionConfig struct {
+	RefreshTTL time.Duration
+	// Retention es el tiempo que se guardan los tokens vencidos para detectar reutilización
+	Retention time.Duration
+}
+
+// DefaultAuthSessionConfig devuelve la configuración por defecto (30 días)
+func DefaultAuthSessionConfig() AuthSessionConfig {
+	return AuthSessionConfig{RefreshTTL: 30 * 24 * time.Hour, Retention: 7 * 24 * time.Hour}
+}
+
+// AuthSessionService maneja las sesiones del dashboard: un access token corto y un refresh
+// token opaco que se rota en cada uso
+type AuthSessionService struct {
+	tokens        repositories.RefreshTokenRepository
+	users         repositories.SystemUserRepository
+	organizations repositories.OrganizationRepository
+	access        AccessTokenIssuer
+	config        AuthSessionConfig
+	now           func() time.Time
+}
+
+func NewAuthSessionService(tokens repositories.RefreshTokenRepository, users repositories.SystemUserRepository,
+	organizations repositories.OrganizationRepository, access AccessTokenIssuer, config AuthSessionConfig) *AuthSessionService {
+	return &AuthSessionService{tokens: tokens, users: users, organizations: organizations, access: access, config: config, now: time.Now}
+}
+
+// SessionClient identifica el dispositivo que inicia o renueva la sesión
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// AuthSession son los tokens que recibe el dashboard
+type AuthSession struct {
+	AccessToken      string    `json:"access_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// Login abre una sesión (una familia de refresh tokens) para un usuario ya autenticado
+func (s *AuthSessionService) Login(user models.SystemUser, client SessionClient) (*AuthSession, error) {
+	if err := s.checkOrganization(user.OrganizationID); err != nil {
+		return nil, err
+	}
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, familyID, client)
+}
+
+// Refresh rota el refresh token: lo marca como usado y emite otro de la misma familia
+// junto con un access token nuevo
+func (s *AuthSessionService) Refresh(raw string, client SessionClient) (*AuthSession, error) {
+	token, err := s.tokens.GetByHash(hashRefreshToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if token.UsedAt != nil {
+		return nil, s.reused(token)
+	}
+
+	user, err := s.users.GetByID(token.UserID, token.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive || user.TokenVersion != token.TokenVersion {
+		if err := s.tokens.RevokeFamily(token.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err := s.checkOrganization(user.OrganizationID); err != nil {
+		if errors.Is(err, ErrOrganizationInactive) {
+			if err := s.tokens.RevokeFamily(token.FamilyID, now); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.tokens.MarkUsed(token.ID, now); err != nil {
+		// Otra petición lo rotó primero con el mismo token
+		if errors.Is(err, repositories.ErrRefreshTokenUsed) {
+			return nil, s.reused(token)
+		}
+		return nil, err
+	}
+	return s.issue(*user, token.FamilyID, client)
+}
+
+// checkOrganization falla con ErrOrganizationInactive si la organización está desactivada,
+// igual que el middleware de autenticación con los access tokens
+func (s *AuthSessionService) checkOrganization(orgID uint) error {
+	org, err := s.organizations.GetByID(orgID)
+	if err != nil {
+		return err
+	}
+	if !org.IsActive {
+		return ErrOrganizationInactive
+	}
+	return nil
+}
+
+// Logout revoca la sesión del refresh token; un token desconocido no es un error
+func (s *AuthSessionService) Logout(raw string) error {
+	token, err := s.tokens.GetByHash(hashRefreshToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.tokens.RevokeFamily(token.FamilyID, s.now())
+}
+
+// LogoutAll cierra todas las sesiones del usuario: revoca sus refresh tokens e invalida
+// los access tokens ya emitidos incrementando su TokenVersion
+func (s *AuthSessionService) LogoutAll(userID, orgID uint) error {
+	if err := s.users.IncrementTokenVersion(userID, orgID); err != nil {
+		return err
+	}
+	return s.tokens.RevokeByUser(userID, s.now())
+}
+
+// Cleanup borra los refresh tokens vencidos hace más de Retention (trabajo programado)
+func (s *AuthSessionService) Cleanup(ctx context.Context) error {
+	deleted, err := s.tokens.DeleteExpired(s.now().Add(-s.config.Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("🧹 %d refresh tokens vencidos eliminados", deleted)
+	}
+	return nil
+}
+
+func (s *AuthSessionService) issue(user models.SystemUser, familyID string, client SessionClient) (*AuthSession, error) {
+	accessToken, accessExpiresAt, err := s.access.IssueAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	raw := refreshTokenPrefix + secret
+
+	token := &models.RefreshToken{
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
+		FamilyID:       familyID,
+		TokenHash:      hashRefreshToken(raw),
+		TokenVersion:   user.TokenVersion,
+		UserAgent:      limitUserAgent(client.UserAgent),
+		IP:             client.IP,
+		ExpiresAt:      s.now().Add(s.config.RefreshTTL),
+	}
+	if err := s.tokens.Create(token); err != nil {
+		return nil, err
+	}
+	return &AuthSession{
+		AccessToken:      accessToken,
+		ExpiresAt:        accessExpiresAt,
+		RefreshToken:     raw,
+		RefreshExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
+func (s *AuthSessionService) reused(token *models.RefreshToken) error {
+	log.Printf("🚨 Refresh token reutilizado (usuario %d, familia %s): se revoca la sesión", token.UserID, token.FamilyID)
+	if err := s.tokens.RevokeFamily(token.FamilyID, s.now()); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func limitUserAgent(userAgent string) string {
+	if len(userAgent) > 255 {
+		return userAgent[:255]
+	}
+	return userAgent
+}
+
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(raw)))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
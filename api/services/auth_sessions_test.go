@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+type fakeAccessIssuer struct{}
+
+func (fakeAccessIssuer) IssueAccessToken(user models.SystemUser) (string, time.Time, error) {
+	return fmt.Sprintf("access-%d-v%d", user.ID, user.TokenVersion), time.Now().Add(15 * time.Minute), nil
+}
+
+// organizationsByID solo implementa GetByID, lo único que usan las sesiones
+type organizationsByID struct {
+	repositories.OrganizationRepository
+	organizations map[uint]*models.Organization
+}
+
+func (r organizationsByID) GetByID(id uint) (*models.Organization, error) {
+	org, ok := r.organizations[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return org, nil
+}
+
+func TestAuthSessionRotation(t *testing.T) {
+	tokens := &mocks.MockRefreshTokenRepo{}
+	users := &mocks.MockSystemUserRepo{}
+	user := models.SystemUser{OrganizationID: 1, Username: "ana", Email: "ana@example.com", Role: models.RoleOwner, IsActive: true}
+	require.NoError(t, users.Create(&user))
+	organizations := organizationsByID{organizations: map[uint]*models.Organization{1: {ID: 1, IsActive: true}}}
+	sessions := NewAuthSessionService(tokens, users, organizations, fakeAccessIssuer{}, DefaultAuthSessionConfig())
+	client := SessionClient{UserAgent: "test", IP: "127.0.0.1"}
+
+	login, err := sessions.Login(user, client)
+	require.NoError(t, err)
+	assert.Contains(t, login.RefreshToken, refreshTokenPrefix)
+
+	rotated, err := sessions.Refresh(login.RefreshToken, client)
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, rotated.RefreshToken)
+
+	// Reusar el token ya rotado revoca toda la familia, incluido el token nuevo
+	_, err = sessions.Refresh(login.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	_, err = sessions.Refresh(rotated.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	_, err = sessions.Refresh("rt_desconocido", client)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+
+	// Logout cierra solo su sesión
+	phone, err := sessions.Login(user, client)
+	require.NoError(t, err)
+	laptop, err := sessions.Login(user, client)
+	require.NoError(t, err)
+	require.NoError(t, sessions.Logout(phone.RefreshToken))
+	_, err = sessions.Refresh(phone.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+	laptop, err = sessions.Refresh(laptop.RefreshToken, client)
+	require.NoError(t, err)
+
+	// LogoutAll revoca los refresh tokens e invalida los access tokens emitidos
+	require.NoError(t, sessions.LogoutAll(user.ID, user.OrganizationID))
+	_, err = sessions.Refresh(laptop.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+	stored, err := users.GetByID(user.ID, user.OrganizationID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), stored.TokenVersion)
+
+	// Los tokens vencidos se borran pasada la retención
+	sessions.now = func() time.Time { return time.Now().Add(40 * 24 * time.Hour) }
+	require.NoError(t, sessions.Cleanup(context.Background()))
+	assert.Empty(t, tokens.Tokens)
+}
+
+func TestAuthSessionRejectsInactiveOrganization(t *testing.T) {
+	tokens := &mocks.MockRefreshTokenRepo{}
+	users := &mocks.MockSystemUserRepo{}
+	user := models.SystemUser{OrganizationID: 1, Username: "ana", Email: "ana@example.com", Role: models.RoleOwner, IsActive: true}
+	require.NoError(t, users.Create(&user))
+	org := &models.Organization{ID: 1, IsActive: true}
+	sessions := NewAuthSessionService(tokens, users, organizationsByID{organizations: map[uint]*models.Organization{1: org}},
+		fakeAccessIssuer{}, DefaultAuthSessionConfig())
+	client := SessionClient{UserAgent: "test", IP: "127.0.0.1"}
+
+	login, err := sessions.Login(user, client)
+	require.NoError(t, err)
+
+	// Desactivar la organización impide renovar la sesión y revoca la familia
+	org.IsActive = false
+	_, err = sessions.Refresh(login.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrOrganizationInactive)
+	_, err = sessions.Login(user, client)
+	assert.ErrorIs(t, err, ErrOrganizationInactive)
+
+	org.IsActive = true
+	_, err = sessions.Refresh(login.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrRefreshTokenInvalid)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+const botCredentialPrefix = "bsk_"
+
+var (
+	ErrBotCredentialInvalid = errors.New("credencial de bot inválida")
+	ErrBotCredentialRevoked = errors.New("credencial de bot revocada")
+	ErrBotSessionTaken      = errors.New("la sesión pertenece a otra organización")
+)
+
+// BotCredentialService emite y verifica las credenciales con las que baileys-ws
+// se conecta a /ws. El token tiene la forma bsk_<keyID>.<firma>, donde la firma
+// es HMAC-SHA256(llave del servidor, keyID:sessionID:organizationID).
+type BotCredentialService struct {
+	repo       repositories.BotSessionKeyRepository
+	signingKey []byte
+}
+
+func NewBotCredentialService(repo repositories.BotSessionKeyRepository, signingKey string) (*BotCredentialService, error) {
+	if len(signingKey) < 32 {
+		return nil, fmt.Errorf("la llave de firma de bots debe tener al menos 32 caracteres")
+	}
+	return &BotCredentialService{repo: repo, signingKey: []byte(signingKey)}, nil
+}
+
+// Issue emite una nueva credencial para la sesión y revoca las anteriores (rotación).
+// Devuelve el token, que solo se muestra una vez, y los key IDs revocados.
+func (s *BotCredentialService) Issue(orgID uint, sessionID string, createdBy uint) (string, *models.BotSessionKey, []string, error) {
+	ownerID, err := s.repo.GetSessionOrganization(sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil, nil, err
+	}
+	if err == nil && ownerID != orgID {
+		return "", nil, nil, ErrBotSessionTaken
+	}
+
+	revoked, err := s.repo.RevokeBySessionID(sessionID, orgID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	keyID, err := randomKeyID()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	key := &models.BotSessionKey{
+		OrganizationID: orgID,
+		SessionID:      sessionID,
+		KeyID:          keyID,
+		CreatedBy:      createdBy,
+	}
+	if err := s.repo.Create(key); err != nil {
+		return "", nil, nil, err
+	}
+
+	return s.token(key), key, revoked, nil
+}
+
+// EnsureToken devuelve el token de la credencial activa de la sesión o emite una nueva.
+// El token se recalcula a partir de la llave del servidor, por eso no se guarda.
+func (s *BotCredentialService) EnsureToken(orgID uint, sessionID string, createdBy uint) (string, error) {
+	ownerID, err := s.repo.GetSessionOrganization(sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if err == nil && ownerID != orgID {
+		return "", ErrBotSessionTaken
+	}
+
+	keys, err := s.repo.GetBySessionID(sessionID, orgID)
+	if err != nil {
+		return "", err
+	}
+	for i := range keys {
+		if keys[i].IsActive() {
+			return s.token(&keys[i]), nil
+		}
+	}
+
+	token, _, _, err := s.Issue(orgID, sessionID, createdBy)
+	return token, err
+}
+
+// CheckSessionOwner devuelve nil si la sesión es de la organización, ErrBotSessionTaken si
+// es de otra y gorm.ErrRecordNotFound si nadie la ha reclamado
+func (s *BotCredentialService) CheckSessionOwner(orgID uint, sessionID string) error {
+	ownerID, err := s.repo.GetSessionOrganization(sessionID)
+	if err != nil {
+		return err
+	}
+	if ownerID != orgID {
+		return ErrBotSessionTaken
+	}
+	return nil
+}
+
+// Verify valida el token presentado por una sesión y devuelve la credencial asociada
+func (s *BotCredentialService) Verify(sessionID, token string) (*models.BotSessionKey, error) {
+	keyID, signature, ok := parseBotCredential(token)
+	if !ok {
+		return nil, ErrBotCredentialInvalid
+	}
+
+	key, err := s.repo.GetByKeyID(keyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBotCredentialInvalid
+		}
+		return nil, err
+	}
+
+	// La firma incluye la sesión y la organización: un token no sirve para otra sesión
+	if key.SessionID != sessionID || !hmac.Equal(signature, s.sign(key)) {
+		return nil, ErrBotCredentialInvalid
+	}
+	if !key.IsActive() {
+		return nil, ErrBotCredentialRevoked
+	}
+
+	if err := s.repo.TouchLastUsed(key.ID); err != nil {
+		log.Printf("⚠️ Error actualizando último uso de credencial %s: %v", key.KeyID, err)
+	}
+	return key, nil
+}
+
+// Revoke revoca una credencial de una sesión de la organización
+func (s *BotCredentialService) Revoke(orgID uint, sessionID, keyID string) error {
+	return s.repo.Revoke(keyID, sessionID, orgID)
+}
+
+// ListBySession lista las credenciales de una sesión (sin tokens)
+func (s *BotCredentialService) ListBySession(orgID uint, sessionID string) ([]models.BotSessionKey, error) {
+	return s.repo.GetBySessionID(sessionID, orgID)
+}
+
+func (s *BotCredentialService) sign(key *models.BotSessionKey) []byte {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s:%s:%d", key.KeyID, key.SessionID, key.OrganizationID)
+	return mac.Sum(nil)
+}
+
+func (s *BotCredentialService) token(key *models.BotSessionKey) string {
+	return botCredentialPrefix + key.KeyID + "." + base64.RawURLEncoding.EncodeToString(s.sign(key))
+}
+
+func parseBotCredential(token string) (string, []byte, bool) {
+	if !strings.HasPrefix(token, botCredentialPrefix) {
+		return "", nil, false
+	}
+	keyID, encoded, found := strings.Cut(strings.TrimPrefix(token, botCredentialPrefix), ".")
+	if !found || keyID == "" {
+		return "", nil, false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, false
+	}
+	return keyID, signature, true
+}
+
+func randomKeyID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+)
+
+const testSigningKey = "test-signing-key-0123456789abcdef"
+
+func TestBotCredentialIssueAndVerify(t *testing.T) {
+	service, err := NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, testSigningKey)
+	require.NoError(t, err)
+
+	token, key, revoked, err := service.Issue(1, "bot-1", 7)
+	require.NoError(t, err)
+	assert.Empty(t, revoked)
+
+	verified, err := service.Verify("bot-1", token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), verified.OrganizationID)
+	assert.Equal(t, key.KeyID, verified.KeyID)
+
+	// El token está ligado a la sesión
+	_, err = service.Verify("bot-2", token)
+	assert.ErrorIs(t, err, ErrBotCredentialInvalid)
+
+	_, err = service.Verify("bot-1", token+"x")
+	assert.ErrorIs(t, err, ErrBotCredentialInvalid)
+
+	_, err = service.Verify("bot-1", "")
+	assert.ErrorIs(t, err, ErrBotCredentialInvalid)
+}
+
+func TestBotCredentialSigningKeyMatters(t *testing.T) {
+	repo := &mocks.MockBotSessionKeyRepo{}
+	service, _ := NewBotCredentialService(repo, testSigningKey)
+	other, _ := NewBotCredentialService(repo, "another-signing-key-0123456789abc")
+
+	token, _, _, err := service.Issue(1, "bot-1", 0)
+	require.NoError(t, err)
+
+	_, err = other.Verify("bot-1", token)
+	assert.ErrorIs(t, err, ErrBotCredentialInvalid)
+}
+
+func TestBotCredentialRotationRevokesPrevious(t *testing.T) {
+	service, _ := NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, testSigningKey)
+
+	oldToken, oldKey, _, err := service.Issue(1, "bot-1", 0)
+	require.NoError(t, err)
+	newToken, _, revoked, err := service.Issue(1, "bot-1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{oldKey.KeyID}, revoked)
+
+	_, err = service.Verify("bot-1", oldToken)
+	assert.ErrorIs(t, err, ErrBotCredentialRevoked)
+	_, err = service.Verify("bot-1", newToken)
+	assert.NoError(t, err)
+
+	// EnsureToken reutiliza la credencial activa
+	ensured, err := service.EnsureToken(1, "bot-1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, newToken, ensured)
+}
+
+func TestBotCredentialSessionBoundToOrganization(t *testing.T) {
+	service, _ := NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, testSigningKey)
+
+	_, _, _, err := service.Issue(1, "bot-1", 0)
+	require.NoError(t, err)
+
+	_, _, _, err = service.Issue(2, "bot-1", 0)
+	assert.ErrorIs(t, err, ErrBotSessionTaken)
+	_, err = service.EnsureToken(2, "bot-1", 0)
+	assert.ErrorIs(t, err, ErrBotSessionTaken)
+}
+
+func TestNewBotCredentialServiceRejectsShortKey(t *testing.T) {
+	_, err := NewBotCredentialService(&mocks.MockBotSessionKeyRepo{}, "short")
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// errLegacyClientMissing indica una conversación cuyo cliente no existe en Postgres: sin
+// él no se sabe a qué organización pertenecen los mensajes
+var errLegacyClientMissing = errors.New("cliente de la conversación no encontrado")
+
+// legacyConversation es el formato anterior: un documento por cliente y bot con
+// todos los mensajes en un arreglo
+type legacyConversation struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	UserID   uint               `bson:"user_id"`
+	BotID    uint               `bson:"bot_id"`
+	Messages []legacyMessage    `bson:"messages"`
+}
+
+type legacyMessage struct {
+	Sender    string    `bson:"sender"` // Número del cliente o "bot"
+	Text      string    `bson:"text"`
+	Timestamp time.Time `bson:"timestamp"`
+	SessionID string    `bson:"session_id,omitempty"`
+}
+
+// MigrateConversationsToMessages pasa los arreglos de mensajes de la colección
+// conversations a documentos individuales en messages. Cada conversación migrada
+// queda marcada con migrated_at; si el proceso se interrumpe, al reintentar se
+// borran los mensajes parciales de esa conversación y se vuelven a insertar. Las
+// conversaciones cuyo cliente no existe se omiten y se reportan; quedan sin marcar
+// para migrarlas cuando se corrija el cliente.
+func MigrateConversationsToMessages(ctx context.Context, mongoDB *mongo.Database, db *gorm.DB) error {
+	conversations := mongoDB.Collection("conversations")
+	messages := mongoDB.Collection("messages")
+
+	filter := bson.M{
+		"migrated_at": bson.M{"$exists": false},
+		"messages.0":  bson.M{"$exists": true},
+	}
+	pending, err := conversations.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if pending == 0 {
+		log.Println("✅ No hay conversaciones por migrar a la colección messages")
+		return nil
+	}
+	log.Printf("🔄 Migrando %d conversaciones a la colección messages...", pending)
+
+	cursor, err := conversations.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	migrated, total := 0, 0
+	var skipped []string
+	for cursor.Next(ctx) {
+		var conv legacyConversation
+		if err := cursor.Decode(&conv); err != nil {
+			return fmt.Errorf("conversación inválida: %w", err)
+		}
+
+		var client models.Client
+		if err := db.First(&client, conv.UserID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		docs, err := legacyToMessages(conv, client)
+		if err != nil {
+			log.Printf("⚠️ Conversación %s omitida (cliente %d): %v", conv.ID.Hex(), conv.UserID, err)
+			skipped = append(skipped, conv.ID.Hex())
+			continue
+		}
+		if _, err := messages.DeleteMany(ctx, bson.M{"legacy_conversation_id": conv.ID}); err != nil {
+			return err
+		}
+		if len(docs) > 0 {
+			if _, err := messages.InsertMany(ctx, docs); err != nil {
+				return fmt.Errorf("error migrando conversación %s: %w", conv.ID.Hex(), err)
+			}
+		}
+		if _, err := conversations.UpdateByID(ctx, conv.ID, bson.M{
+			"$set": bson.M{"migrated_at": time.Now()},
+		}); err != nil {
+			return err
+		}
+
+		migrated++
+		total += len(docs)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	log.Printf("✅ %d conversaciones migradas (%d mensajes)", migrated, total)
+	if len(skipped) > 0 {
+		log.Printf("⚠️ %d conversaciones sin cliente quedaron sin migrar: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	return nil
+}
+
+// legacyToMessages convierte el arreglo de una conversación al formato de la colección
+// messages. La organización y el chat salen del cliente en Postgres.
+func legacyToMessages(conv legacyConversation, client models.Client) ([]interface{}, error) {
+	if client.ID == 0 || client.OrganizationID == 0 {
+		return nil, errLegacyClientMissing
+	}
+
+	chatID := strings.Split(client.Phone, "@")[0]
+	for _, legacy := range conv.Messages {
+		if chatID != "" {
+			break
+		}
+		if legacy.Sender != "bot" {
+			chatID = strings.Split(legacy.Sender, "@")[0]
+		}
+	}
+
+	docs := make([]interface{}, 0, len(conv.Messages))
+
+	for _, legacy := range conv.Messages {
+		direction := models.MessageDirectionInbound
+		status := models.MessageStatusReceived
+		if legacy.Sender == "bot" {
+			direction = models.MessageDirectionOutbound
+			status = models.MessageStatusQueued
+		}
+
+		docs = append(docs, models.Message{
+			OrganizationID:       client.OrganizationID,
+			ClientID:             conv.UserID,
+			BotID:                conv.BotID,
+			SessionID:            legacy.SessionID,
+			ChatID:               chatID,
+			Direction:            direction,
+			Type:                 models.MessageTypeText,
+			Status:               status,
+			Sender:               legacy.Sender,
+			Text:                 legacy.Text,
+			Timestamp:            legacy.Timestamp,
+			LegacyConversationID: &conv.ID,
+		})
+	}
+	return docs, nil
+}
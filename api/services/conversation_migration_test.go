@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+func TestLegacyToMessages(t *testing.T) {
+	now := time.Now()
+	conv := legacyConversation{
+		ID:     primitive.NewObjectID(),
+		UserID: 7,
+		BotID:  3,
+		Messages: []legacyMessage{
+			{Sender: "573001234567@s.whatsapp.net", Text: "hola", Timestamp: now, SessionID: "bot-1"},
+			{Sender: "bot", Text: "¿En qué te ayudo?", Timestamp: now.Add(time.Second), SessionID: "bot-1"},
+		},
+	}
+
+	docs, err := legacyToMessages(conv, models.Client{ID: 7, OrganizationID: 2})
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	inbound := docs[0].(models.Message)
+	assert.Equal(t, uint(2), inbound.OrganizationID)
+	assert.Equal(t, uint(7), inbound.ClientID)
+	assert.Equal(t, "573001234567", inbound.ChatID)
+	assert.Equal(t, models.MessageDirectionInbound, inbound.Direction)
+	assert.Equal(t, models.MessageStatusReceived, inbound.Status)
+	assert.Equal(t, conv.ID, *inbound.LegacyConversationID)
+
+	outbound := docs[1].(models.Message)
+	assert.Equal(t, "573001234567", outbound.ChatID)
+	assert.Equal(t, models.MessageDirectionOutbound, outbound.Direction)
+	assert.Equal(t, "bot-1", outbound.SessionID)
+	assert.Equal(t, now.Add(time.Second), outbound.Timestamp)
+}
+
+func TestLegacyToMessagesWithoutClient(t *testing.T) {
+	conv := legacyConversation{
+		ID:       primitive.NewObjectID(),
+		UserID:   99,
+		Messages: []legacyMessage{{Sender: "573001234567@s.whatsapp.net", Text: "hola", Timestamp: time.Now()}},
+	}
+
+	// Sin cliente no hay organización: no se escriben mensajes con organization_id 0
+	docs, err := legacyToMessages(conv, models.Client{})
+	assert.ErrorIs(t, err, errLegacyClientMissing)
+	assert.Empty(t, docs)
+}
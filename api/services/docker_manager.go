@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"os"
 	"strconv"
 
 	"github.com/docker/docker/api/types"
@@ -35,6 +36,11 @@ func (dm *DockerManager) CreateRasaContainer(instanceName string, port int) (str
 			"5005/tcp": struct{}{},
 			"5055/tcp": struct{}{},
 		},
+		// Las acciones llaman a la API (p. ej. action_generar_manifiesto)
+		Env: []string{
+			"API_URL=" + os.Getenv("API_URL"),
+			"INTERNAL_API_TOKEN=" + os.Getenv("INTERNAL_API_TOKEN"),
+		},
 	}
 
 	hostConfig := &container.HostConfig{
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+const documentLinkPrefix = "dl_"
+
+var (
+	ErrDocumentLinkInvalid   = errors.New("enlace de descarga inválido")
+	ErrDocumentLinkExpired   = errors.New("el enlace de descarga expiró")
+	ErrDocumentLinkExhausted = errors.New("el enlace de descarga ya se usó")
+	ErrDocumentLinkRevoked   = errors.New("el enlace de descarga fue revocado")
+	ErrDocumentLinkOptions   = errors.New("opciones de enlace inválidas")
+)
+
+// DocumentLinkConfig controla la vigencia y los usos de los enlaces
+type DocumentLinkConfig struct {
+	BaseURL           string        // URL pública de la API; los enlaces son {BaseURL}/download/{token}
+	DefaultTTL        time.Duration // Vigencia si no se indica otra
+	MaxTTL            time.Duration // Vigencia máxima permitida
+	MaxDownloadsLimit int           // Máximo de descargas que se puede pedir para un enlace
+}
+
+// DefaultDocumentLinkConfig devuelve la configuración por defecto
+func DefaultDocumentLinkConfig() DocumentLinkConfig {
+	return DocumentLinkConfig{
+		BaseURL:           "http://localhost:8080",
+		DefaultTTL:        24 * time.Hour,
+		MaxTTL:            7 * 24 * time.Hour,
+		MaxDownloadsLimit: 20,
+	}
+}
+
+// DocumentLinkService emite y valida enlaces de descarga firmados. El token tiene la
+// forma dl_<linkID>.<firma>, donde la firma es HMAC-SHA256(llave del servidor,
+// linkID:documentID:organizationID:expiración), así que no se puede adivinar ni
+// extender la vigencia de un enlace.
+type DocumentLinkService struct {
+	repo       repositories.DocumentLinkRepository
+	signingKey []byte
+	config     DocumentLinkConfig
+	now        func() time.Time
+}
+
+func NewDocumentLinkService(repo repositories.DocumentLinkRepository, signingKey string, config DocumentLinkConfig) (*DocumentLinkService, error) {
+	if len(signingKey) < 32 {
+		return nil, fmt.Errorf("la llave de firma de enlaces debe tener al menos 32 caracteres")
+	}
+	defaults := DefaultDocumentLinkConfig()
+	if config.BaseURL == "" {
+		config.BaseURL = defaults.BaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = defaults.DefaultTTL
+	}
+	if config.MaxTTL <= 0 {
+		config.MaxTTL = defaults.MaxTTL
+	}
+	if config.MaxDownloadsLimit <= 0 {
+		config.MaxDownloadsLimit = defaults.MaxDownloadsLimit
+	}
+	return &DocumentLinkService{repo: repo, signingKey: []byte(signingKey), config: config, now: time.Now}, nil
+}
+
+// Issue crea un enlace para el documento. ttl 0 usa la vigencia por defecto y
+// maxDownloads 0 hace el enlace de un solo uso. Devuelve la URL, que solo se muestra una vez.
+func (s *DocumentLinkService) Issue(document models.Document, ttl time.Duration, maxDownloads int, createdBy uint) (string, *models.DocumentLink, error) {
+	if ttl <= 0 {
+		ttl = s.config.DefaultTTL
+	}
+	if ttl > s.config.MaxTTL {
+		return "", nil, fmt.Errorf("%w: la vigencia máxima es %s", ErrDocumentLinkOptions, s.config.MaxTTL)
+	}
+	if maxDownloads <= 0 {
+		maxDownloads = 1
+	}
+	if maxDownloads > s.config.MaxDownloadsLimit {
+		return "", nil, fmt.Errorf("%w: máximo %d descargas por enlace", ErrDocumentLinkOptions, s.config.MaxDownloadsLimit)
+	}
+
+	linkID, err := randomKeyID()
+	if err != nil {
+		return "", nil, err
+	}
+	link := &models.DocumentLink{
+		OrganizationID: document.OrganizationID,
+		DocumentID:     document.ID.Hex(),
+		ClientID:       document.ClientID,
+		LinkID:         linkID,
+		MaxDownloads:   maxDownloads,
+		// La firma usa segundos: se trunca para que coincida con lo que se lee de la base
+		ExpiresAt: s.now().Add(ttl).Truncate(time.Second),
+		CreatedBy: createdBy,
+	}
+	if err := s.repo.Create(link); err != nil {
+		return "", nil, err
+	}
+	return s.URL(link), link, nil
+}
+
+// URL arma el enlace público de descarga
+func (s *DocumentLinkService) URL(link *models.DocumentLink) string {
+	return s.config.BaseURL + "/download/" + s.token(link)
+}
+
+// Verify valida el token y devuelve el enlace si todavía se puede usar
+func (s *DocumentLinkService) Verify(token string) (*models.DocumentLink, error) {
+	linkID, signature, ok := parseDocumentLink(token)
+	if !ok {
+		return nil, ErrDocumentLinkInvalid
+	}
+
+	link, err := s.repo.GetByLinkID(linkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentLinkInvalid
+		}
+		return nil, err
+	}
+	if !hmac.Equal(signature, s.sign(link)) {
+		return nil, ErrDocumentLinkInvalid
+	}
+
+	switch {
+	case !link.IsActive():
+		return link, ErrDocumentLinkRevoked
+	case !s.now().Before(link.ExpiresAt):
+		return link, ErrDocumentLinkExpired
+	case link.Downloads >= link.MaxDownloads:
+		return link, ErrDocumentLinkExhausted
+	}
+	return link, nil
+}
+
+// Consume descuenta una descarga del enlace de forma atómica
+func (s *DocumentLinkService) Consume(link *models.DocumentLink) error {
+	ok, err := s.repo.Consume(link.LinkID, s.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Otra descarga usó el último cupo o el enlace venció entre Verify y Consume
+		if !s.now().Before(link.ExpiresAt) {
+			return ErrDocumentLinkExpired
+		}
+		return ErrDocumentLinkExhausted
+	}
+	return nil
+}
+
+// Release devuelve la descarga consumida cuando el archivo no se pudo entregar
+func (s *DocumentLinkService) Release(link *models.DocumentLink) {
+	if err := s.repo.Release(link.LinkID); err != nil {
+		log.Printf("⚠️ Error devolviendo descarga del enlace %s: %v", link.LinkID, err)
+	}
+}
+
+// LogDownload registra un intento de descarga; los errores solo se registran en el log
+func (s *DocumentLinkService) LogDownload(download models.DocumentDownload) {
+	if err := s.repo.LogDownload(&download); err != nil {
+		log.Printf("⚠️ Error registrando descarga del documento %s: %v", download.DocumentID, err)
+	}
+}
+
+// ListLinks lista los enlaces de un documento (sin tokens)
+func (s *DocumentLinkService) ListLinks(orgID uint, documentID string) ([]models.DocumentLink, error) {
+	return s.repo.ListByDocument(documentID, orgID)
+}
+
+// Revoke revoca un enlace de un documento de la organización
+func (s *DocumentLinkService) Revoke(orgID uint, documentID, linkID string) error {
+	return s.repo.Revoke(linkID, documentID, orgID)
+}
+
+// ListDownloads devuelve el registro de descargas de un documento
+func (s *DocumentLinkService) ListDownloads(orgID uint, documentID string) ([]models.DocumentDownload, error) {
+	return s.repo.ListDownloads(documentID, orgID)
+}
+
+func (s *DocumentLinkService) sign(link *models.DocumentLink) []byte {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s:%s:%d:%d", link.LinkID, link.DocumentID, link.OrganizationID, link.ExpiresAt.Unix())
+	return mac.Sum(nil)
+}
+
+func (s *DocumentLinkService) token(link *models.DocumentLink) string {
+	return documentLinkPrefix + link.LinkID + "." + base64.RawURLEncoding.EncodeToString(s.sign(link))
+}
+
+func parseDocumentLink(token string) (string, []byte, bool) {
+	if !strings.HasPrefix(token, documentLinkPrefix) {
+		return "", nil, false
+	}
+	linkID, encoded, found := strings.Cut(strings.TrimPrefix(token, documentLinkPrefix), ".")
+	if !found || linkID == "" {
+		return "", nil, false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, false
+	}
+	return linkID, signature, true
+}
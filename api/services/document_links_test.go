@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+)
+
+func newTestDocumentLinks(t *testing.T) (*DocumentLinkService, *mocks.MockDocumentLinkRepo) {
+	repo := &mocks.MockDocumentLinkRepo{}
+	config := DefaultDocumentLinkConfig()
+	config.BaseURL = "https://api.docubot.test/"
+	service, err := NewDocumentLinkService(repo, "test-signing-key-0123456789abcdef", config)
+	require.NoError(t, err)
+	return service, repo
+}
+
+func linkToken(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+func TestDocumentLinkSingleUse(t *testing.T) {
+	service, _ := newTestDocumentLinks(t)
+	document := models.Document{ID: primitive.NewObjectID(), OrganizationID: 3, ClientID: 7}
+
+	url, link, err := service.Issue(document, 0, 0, 9)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(url, "https://api.docubot.test/download/dl_"))
+	assert.Equal(t, 1, link.MaxDownloads)
+	assert.Equal(t, document.ID.Hex(), link.DocumentID)
+
+	verified, err := service.Verify(linkToken(url))
+	require.NoError(t, err)
+	require.NoError(t, service.Consume(verified))
+
+	// Segundo uso: el enlace ya no sirve
+	_, err = service.Verify(linkToken(url))
+	assert.ErrorIs(t, err, ErrDocumentLinkExhausted)
+	assert.ErrorIs(t, service.Consume(verified), ErrDocumentLinkExhausted)
+
+	// Si la entrega falla la descarga se devuelve
+	service.Release(verified)
+	_, err = service.Verify(linkToken(url))
+	assert.NoError(t, err)
+}
+
+func TestDocumentLinkRejectsTamperedAndExpired(t *testing.T) {
+	service, _ := newTestDocumentLinks(t)
+	document := models.Document{ID: primitive.NewObjectID(), OrganizationID: 3}
+
+	url, link, err := service.Issue(document, time.Hour, 3, 0)
+	require.NoError(t, err)
+	token := linkToken(url)
+
+	_, err = service.Verify(token[:len(token)-2] + "xx")
+	assert.ErrorIs(t, err, ErrDocumentLinkInvalid)
+	_, err = service.Verify("dl_" + link.LinkID + ".")
+	assert.ErrorIs(t, err, ErrDocumentLinkInvalid)
+
+	// Otra llave no valida los enlaces emitidos
+	other, err := NewDocumentLinkService(&mocks.MockDocumentLinkRepo{Links: []*models.DocumentLink{link}}, "another-signing-key-0123456789abcdef", DefaultDocumentLinkConfig())
+	require.NoError(t, err)
+	_, err = other.Verify(token)
+	assert.ErrorIs(t, err, ErrDocumentLinkInvalid)
+
+	service.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = service.Verify(token)
+	assert.ErrorIs(t, err, ErrDocumentLinkExpired)
+
+	_, _, err = service.Issue(document, 30*24*time.Hour, 1, 0)
+	assert.ErrorIs(t, err, ErrDocumentLinkOptions)
+	_, _, err = service.Issue(document, time.Hour, 500, 0)
+	assert.ErrorIs(t, err, ErrDocumentLinkOptions)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// qrSize es el lado en píxeles del PNG; alcanza para leerlo desde una foto del celular
+const qrSize = 384
+
+// DocumentVerification arma los enlaces públicos de verificación y sus códigos QR
+type DocumentVerification struct {
+	baseURL string
+}
+
+// NewDocumentVerification recibe la URL pública de la API (PUBLIC_API_URL)
+func NewDocumentVerification(baseURL string) *DocumentVerification {
+	if baseURL == "" {
+		baseURL = DefaultDocumentLinkConfig().BaseURL
+	}
+	return &DocumentVerification{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// URL devuelve la página pública de verificación del código
+func (v *DocumentVerification) URL(code string) string {
+	return v.baseURL + "/verify/" + code
+}
+
+// QR genera el PNG con el enlace de verificación del código
+func (v *DocumentVerification) QR(code string) ([]byte, error) {
+	return qrcode.Encode(v.URL(code), qrcode.Medium, qrSize)
+}
+
+// DocumentVerificationResult es lo que ve cualquiera con el código. No incluye datos
+// del cliente (nombre, teléfono, chat, entidades del formulario) ni enlaces al archivo.
+type DocumentVerificationResult struct {
+	Valid        bool      `json:"valid"` // true si el documento sigue vigente (completado y no anulado)
+	Code         string    `json:"code"`
+	Status       string    `json:"status"`
+	Stage        string    `json:"stage,omitempty"` // Subestado del manifiesto (issued, fulfilled, annulled...)
+	DocumentType string    `json:"document_type"`
+	Number       string    `json:"number,omitempty"` // Consecutivo del manifiesto
+	IssuedAt     time.Time `json:"issued_at"`
+	Organization string    `json:"organization"`
+	SHA256       string    `json:"sha256,omitempty"` // Hash del archivo original para compararlo con la copia recibida
+	Size         int64     `json:"size,omitempty"`
+}
+
+// Result arma la respuesta pública de verificación del documento
+func (v *DocumentVerification) Result(document models.Document, organization string) DocumentVerificationResult {
+	issuedAt := document.CreatedAt
+	if document.CompletedAt != nil {
+		issuedAt = *document.CompletedAt
+	}
+	number, _ := document.Metadata["consecutivo_manifiesto"].(string)
+	// Un manifiesto anulado sigue completado: lo que lo invalida es el subestado
+	stage := document.ManifestStage()
+	return DocumentVerificationResult{
+		Valid:        document.Status == models.DocumentStatusCompleted && stage != models.ManifestStageAnnulled,
+		Code:         document.VerificationCode,
+		Status:       document.Status,
+		Stage:        stage,
+		DocumentType: document.Type,
+		Number:       number,
+		IssuedAt:     issuedAt,
+		Organization: organization,
+		SHA256:       document.Checksum,
+		Size:         document.Size,
+	}
+}
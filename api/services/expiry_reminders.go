@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// ReminderSender envía un recordatorio por WhatsApp con algún bot de la organización
+type ReminderSender interface {
+	SendReminder(ctx context.Context, orgID uint, phone, text string) error
+}
+
+// ExpiryReminderConfig controla cuándo y con qué texto se envían los recordatorios
+type ExpiryReminderConfig struct {
+	StartHour int            // Hora local desde la que se envían (incluida)
+	EndHour   int            // Hora local hasta la que se envían (excluida)
+	Location  *time.Location // Zona horaria de las fechas de vencimiento
+	Templates map[string]string
+}
+
+// DefaultExpiryReminderConfig envía entre 8:00 y 20:00 hora de Colombia
+func DefaultExpiryReminderConfig() ExpiryReminderConfig {
+	return ExpiryReminderConfig{
+		StartHour: 8,
+		EndHour:   20,
+		Location:  time.FixedZone("COT", -5*60*60),
+		Templates: map[string]string{
+			models.ExpiryKindLicense: "⚠️ Hola {{.Nombre}}, tu licencia de conducción vence {{.Cuando}} ({{.Fecha}}). " +
+				"Renuévala a tiempo para evitar multas. 🚛",
+			models.ExpiryKindSOAT: "⚠️ Hola {{.Nombre}}, el SOAT del vehículo {{.Placa}} vence {{.Cuando}} ({{.Fecha}}). " +
+				"Renuévalo a tiempo para evitar multas e inmovilizaciones. 🚛",
+			models.ExpiryKindTecnomecanica: "⚠️ Hola {{.Nombre}}, la revisión técnico-mecánica del vehículo {{.Placa}} vence {{.Cuando}} ({{.Fecha}}). " +
+				"Agéndala a tiempo para evitar multas e inmovilizaciones. 🚛",
+		},
+	}
+}
+
+// Expiration es un documento del registro próximo a vencer (o vencido) y el conductor a
+// quien se le recuerda
+type Expiration struct {
+	Kind       string     `json:"kind"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	DaysLeft   int        `json:"days_left"` // Negativo si ya venció
+	VehicleID  uint       `json:"vehicle_id,omitempty"`
+	Placa      string     `json:"placa,omitempty"`
+	DriverID   uint       `json:"driver_id,omitempty"`
+	DriverName string     `json:"driver_name,omitempty"`
+	ClientID   *uint      `json:"client_id,omitempty"`
+	Phone      string     `json:"phone,omitempty"`
+	RemindedAt *time.Time `json:"reminded_at,omitempty"`
+}
+
+// subjectID es el conductor o el vehículo al que pertenece el documento
+func (e Expiration) subjectID() uint {
+	if e.Kind == models.ExpiryKindLicense {
+		return e.DriverID
+	}
+	return e.VehicleID
+}
+
+// ExpiryReminderService busca los vencimientos del registro y recuerda a los conductores
+// con N días de anticipación (Organization.ExpiryReminderDays)
+type ExpiryReminderService struct {
+	organizations repositories.OrganizationRepository
+	vehicles      repositories.VehicleRepository
+	drivers       repositories.DriverRepository
+	reminders     repositories.ExpiryReminderRepository
+	sender        ReminderSender
+	config        ExpiryReminderConfig
+	templates     map[string]*template.Template
+	now           func() time.Time
+}
+
+func NewExpiryReminderService(organizations repositories.OrganizationRepository, vehicles repositories.VehicleRepository,
+	drivers repositories.DriverRepository, reminders repositories.ExpiryReminderRepository, sender ReminderSender,
+	config ExpiryReminderConfig) (*ExpiryReminderService, error) {
+	defaults := DefaultExpiryReminderConfig()
+	if config.Location == nil {
+		config.Location = defaults.Location
+	}
+	if config.EndHour <= config.StartHour {
+		config.StartHour, config.EndHour = defaults.StartHour, defaults.EndHour
+	}
+
+	templates := make(map[string]*template.Template, len(defaults.Templates))
+	for kind, text := range defaults.Templates {
+		if custom, ok := config.Templates[kind]; ok && custom != "" {
+			text = custom
+		}
+		tmpl, err := template.New(kind).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("plantilla de recordatorio %s inválida: %w", kind, err)
+		}
+		templates[kind] = tmpl
+	}
+
+	return &ExpiryReminderService{
+		organizations: organizations,
+		vehicles:      vehicles,
+		drivers:       drivers,
+		reminders:     reminders,
+		sender:        sender,
+		config:        config,
+		templates:     templates,
+		now:           time.Now,
+	}, nil
+}
+
+// Upcoming lista los documentos de la organización que vencen en los próximos días
+// (incluye los ya vencidos), ordenados por fecha
+func (s *ExpiryReminderService) Upcoming(orgID uint, days int) ([]Expiration, error) {
+	today := s.today()
+	limit := today.AddDate(0, 0, days)
+
+	drivers, err := s.drivers.List(orgID)
+	if err != nil {
+		return nil, err
+	}
+	vehicles, err := s.vehicles.List(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	expirations := []Expiration{}
+	add := func(expiration Expiration, expiresAt *time.Time) {
+		if expiresAt == nil {
+			return
+		}
+		expiration.ExpiresAt = s.date(*expiresAt)
+		if expiration.ExpiresAt.After(limit) {
+			return
+		}
+		expiration.DaysLeft = int(expiration.ExpiresAt.Sub(today).Hours() / 24)
+		expirations = append(expirations, expiration)
+	}
+	withDriver := func(expiration Expiration, driver models.Driver) Expiration {
+		expiration.DriverID = driver.ID
+		expiration.DriverName = driver.Name
+		expiration.ClientID = driver.ClientID
+		expiration.Phone = driver.Phone
+		return expiration
+	}
+
+	driversByVehicle := map[uint][]models.Driver{}
+	for _, driver := range drivers {
+		add(withDriver(Expiration{Kind: models.ExpiryKindLicense}, driver), driver.LicenseExpiresAt)
+		if driver.VehicleID != nil {
+			driversByVehicle[*driver.VehicleID] = append(driversByVehicle[*driver.VehicleID], driver)
+		}
+	}
+	for _, vehicle := range vehicles {
+		for kind, expiresAt := range map[string]*time.Time{
+			models.ExpiryKindSOAT:          vehicle.SOATExpiresAt,
+			models.ExpiryKindTecnomecanica: vehicle.TecnomecanicaExpiresAt,
+		} {
+			expiration := Expiration{Kind: kind, VehicleID: vehicle.ID, Placa: vehicle.Placa}
+			if len(driversByVehicle[vehicle.ID]) == 0 {
+				// Sin conductor no hay a quién recordarle, pero se lista igual
+				add(expiration, expiresAt)
+			}
+			for _, driver := range driversByVehicle[vehicle.ID] {
+				add(withDriver(expiration, driver), expiresAt)
+			}
+		}
+	}
+
+	if err := s.markReminded(orgID, expirations); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(expirations, func(i, j int) bool {
+		if !expirations[i].ExpiresAt.Equal(expirations[j].ExpiresAt) {
+			return expirations[i].ExpiresAt.Before(expirations[j].ExpiresAt)
+		}
+		if expirations[i].Kind != expirations[j].Kind {
+			return expirations[i].Kind < expirations[j].Kind
+		}
+		return expirations[i].DriverID < expirations[j].DriverID
+	})
+	return expirations, nil
+}
+
+// markReminded completa RemindedAt con los recordatorios ya enviados
+func (s *ExpiryReminderService) markReminded(orgID uint, expirations []Expiration) error {
+	if len(expirations) == 0 {
+		return nil
+	}
+	since := expirations[0].ExpiresAt
+	for _, expiration := range expirations {
+		if expiration.ExpiresAt.Before(since) {
+			since = expiration.ExpiresAt
+		}
+	}
+	reminders, err := s.reminders.ListSince(orgID, since)
+	if err != nil {
+		return err
+	}
+
+	sent := make(map[string]time.Time, len(reminders))
+	for _, reminder := range reminders {
+		sent[reminderKey(reminder.Kind, reminder.SubjectID, reminder.DriverID, reminder.ExpiresAt)] = reminder.SentAt
+	}
+	for i, expiration := range expirations {
+		key := reminderKey(expiration.Kind, expiration.subjectID(), expiration.DriverID, expiration.ExpiresAt)
+		if sentAt, ok := sent[key]; ok {
+			expirations[i].RemindedAt = &sentAt
+		}
+	}
+	return nil
+}
+
+// Run es el trabajo programado: envía los recordatorios pendientes de todas las
+// organizaciones, solo dentro del horario configurado
+func (s *ExpiryReminderService) Run(ctx context.Context) error {
+	hour := s.now().In(s.config.Location).Hour()
+	if hour < s.config.StartHour || hour >= s.config.EndHour {
+		return nil
+	}
+
+	organizations, err := s.organizations.GetAll()
+	if err != nil {
+		return fmt.Errorf("error obteniendo organizaciones: %w", err)
+	}
+
+	var errs []error
+	for _, organization := range organizations {
+		if !organization.IsActive || organization.ExpiryReminderDays <= 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sent, err := s.RemindOrganization(ctx, organization.ID, organization.ExpiryReminderDays)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("organización %d: %w", organization.ID, err))
+		}
+		if sent > 0 {
+			log.Printf("⏰ %d recordatorios de vencimiento enviados (org %d)", sent, organization.ID)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RemindOrganization envía los recordatorios de lo que vence en los próximos días y
+// devuelve cuántos envió. Cada vencimiento se recuerda una sola vez por conductor.
+func (s *ExpiryReminderService) RemindOrganization(ctx context.Context, orgID uint, days int) (int, error) {
+	expirations, err := s.Upcoming(orgID, days)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, expiration := range expirations {
+		if expiration.DaysLeft < 0 || expiration.Phone == "" || expiration.RemindedAt != nil {
+			continue
+		}
+
+		text, err := s.render(expiration)
+		if err != nil {
+			return sent, err
+		}
+
+		reminder := models.ExpiryReminder{
+			OrganizationID: orgID,
+			Kind:           expiration.Kind,
+			SubjectID:      expiration.subjectID(),
+			DriverID:       expiration.DriverID,
+			ExpiresAt:      expiration.ExpiresAt,
+			Phone:          expiration.Phone,
+			SentAt:         s.now(),
+		}
+		claimed, err := s.reminders.Claim(&reminder)
+		if err != nil {
+			return sent, fmt.Errorf("error registrando recordatorio: %w", err)
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.sender.SendReminder(ctx, orgID, expiration.Phone, text); err != nil {
+			// Se libera para reintentarlo en el próximo ciclo
+			log.Printf("⚠️ No se pudo enviar el recordatorio de %s a %s: %v", expiration.Kind, expiration.Phone, err)
+			if err := s.reminders.Release(reminder.ID); err != nil {
+				log.Printf("⚠️ Error liberando recordatorio %d: %v", reminder.ID, err)
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *ExpiryReminderService) render(expiration Expiration) (string, error) {
+	cuando := fmt.Sprintf("en %d días", expiration.DaysLeft)
+	switch expiration.DaysLeft {
+	case 0:
+		cuando = "hoy"
+	case 1:
+		cuando = "mañana"
+	}
+
+	var text bytes.Buffer
+	err := s.templates[expiration.Kind].Execute(&text, map[string]interface{}{
+		"Nombre": expiration.DriverName,
+		"Placa":  expiration.Placa,
+		"Fecha":  expiration.ExpiresAt.Format("02/01/2006"),
+		"Dias":   expiration.DaysLeft,
+		"Cuando": cuando,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error armando recordatorio %s: %w", expiration.Kind, err)
+	}
+	return text.String(), nil
+}
+
+func (s *ExpiryReminderService) today() time.Time {
+	return s.date(s.now())
+}
+
+// date lleva una fecha a la medianoche de su día en la zona horaria configurada
+func (s *ExpiryReminderService) date(t time.Time) time.Time {
+	year, month, day := t.In(s.config.Location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, s.config.Location)
+}
+
+func reminderKey(kind string, subjectID, driverID uint, expiresAt time.Time) string {
+	return fmt.Sprintf("%s:%d:%d:%d", kind, subjectID, driverID, expiresAt.Unix())
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// staticOrganizations solo implementa GetAll, lo único que usa el trabajo de recordatorios
+type staticOrganizations struct {
+	repositories.OrganizationRepository
+	organizations []models.Organization
+}
+
+func (r staticOrganizations) GetAll() ([]models.Organization, error) {
+	return r.organizations, nil
+}
+
+type recordingReminderSender struct {
+	fail  bool
+	sent  map[string][]string
+	tries int
+}
+
+func (s *recordingReminderSender) SendReminder(ctx context.Context, orgID uint, phone, text string) error {
+	s.tries++
+	if s.fail {
+		return errors.New("bot desconectado")
+	}
+	if s.sent == nil {
+		s.sent = map[string][]string{}
+	}
+	s.sent[phone] = append(s.sent[phone], text)
+	return nil
+}
+
+func TestExpiryReminders(t *testing.T) {
+	config := DefaultExpiryReminderConfig()
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, config.Location)
+	day := func(days int) *time.Time {
+		date := time.Date(2026, 10, 17+days, 0, 0, 0, 0, config.Location)
+		return &date
+	}
+
+	drivers := &mocks.MockDriverRepo{}
+	vehicles := &mocks.MockVehicleRepo{Drivers: drivers}
+	require.NoError(t, vehicles.Create(&models.Vehicle{OrganizationID: 3, Placa: "ABC123", SOATExpiresAt: day(5), TecnomecanicaExpiresAt: day(40)}))
+	require.NoError(t, vehicles.Create(&models.Vehicle{OrganizationID: 3, Placa: "XYZ789", SOATExpiresAt: day(2)}))
+	vehicleID := uint(1)
+	require.NoError(t, drivers.Create(&models.Driver{OrganizationID: 3, Name: "Ana", Phone: "573001112233", LicenseExpiresAt: day(1), VehicleID: &vehicleID}))
+	require.NoError(t, drivers.Create(&models.Driver{OrganizationID: 3, Name: "Luis", Phone: "573004445566", LicenseExpiresAt: day(-3)}))
+
+	reminders := &mocks.MockExpiryReminderRepo{}
+	sender := &recordingReminderSender{}
+	organizations := staticOrganizations{organizations: []models.Organization{
+		{ID: 3, IsActive: true, ExpiryReminderDays: 7},
+		{ID: 4, IsActive: true, ExpiryReminderDays: 0},
+	}}
+	service, err := NewExpiryReminderService(organizations, vehicles, drivers, reminders, sender, config)
+	require.NoError(t, err)
+	service.now = func() time.Time { return now }
+
+	upcoming, err := service.Upcoming(3, 7)
+	require.NoError(t, err)
+	require.Len(t, upcoming, 4)
+	assert.Equal(t, models.ExpiryKindLicense, upcoming[0].Kind)
+	assert.Equal(t, -3, upcoming[0].DaysLeft)
+	assert.Equal(t, "XYZ789", upcoming[2].Placa)
+	assert.Empty(t, upcoming[2].Phone, "sin conductor no hay a quién avisar")
+	assert.Equal(t, "ABC123", upcoming[3].Placa)
+	assert.Equal(t, 5, upcoming[3].DaysLeft)
+
+	// Un envío fallido se libera y se reintenta en el siguiente ciclo
+	sender.fail = true
+	require.NoError(t, service.Run(context.Background()))
+	assert.Empty(t, reminders.Reminders)
+
+	sender.fail = false
+	require.NoError(t, service.Run(context.Background()))
+	require.Len(t, sender.sent["573001112233"], 2)
+	assert.Contains(t, sender.sent["573001112233"][0], "licencia de conducción vence mañana (18/10/2026)")
+	assert.Contains(t, sender.sent["573001112233"][1], "SOAT del vehículo ABC123 vence en 5 días")
+	assert.Empty(t, sender.sent["573004445566"], "lo ya vencido no se recuerda")
+
+	// Cada vencimiento se recuerda una sola vez
+	tries := sender.tries
+	require.NoError(t, service.Run(context.Background()))
+	assert.Equal(t, tries, sender.tries)
+	upcoming, err = service.Upcoming(3, 7)
+	require.NoError(t, err)
+	assert.NotNil(t, upcoming[1].RemindedAt)
+
+	// Fuera del horario no se envía nada
+	reminders.Reminders = nil
+	service.now = func() time.Time { return now.Add(12 * time.Hour) }
+	require.NoError(t, service.Run(context.Background()))
+	assert.Empty(t, reminders.Reminders)
+}
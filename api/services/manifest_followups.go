@@ -0,0 +1,442 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/rndc"
+)
+
+// Tipos de los documentos de seguimiento de un manifiesto (enlazados con ParentID)
+const (
+	DocumentTypeCumplido  = "cumplido"
+	DocumentTypeAnulacion = "anulacion"
+)
+
+var (
+	// ErrManifestStage indica que el manifiesto no admite la operación en su subestado actual
+	ErrManifestStage = errors.New("el manifiesto no admite esta operación")
+	// ErrInvalidFollowUp indica datos del cumplido o de la anulación inválidos (envuelve rndc.ValidationErrors)
+	ErrInvalidFollowUp   = errors.New("datos del cumplido o de la anulación inválidos")
+	ErrNoPendingDelivery = errors.New("no hay manifiestos pendientes de entrega")
+	ErrNoDriverPhone     = errors.New("el manifiesto no tiene un conductor registrado con celular")
+)
+
+// followUpStages son el subestado mientras corre cada seguimiento y el subestado final
+var followUpStages = map[string][2]string{
+	DocumentTypeCumplido:  {models.ManifestStageFulfilling, models.ManifestStageFulfilled},
+	DocumentTypeAnulacion: {models.ManifestStageAnnulling, models.ManifestStageAnnulled},
+}
+
+// ManifestFollowUpRepository es la parte del repositorio de Mongo que usan los seguimientos
+type ManifestFollowUpRepository interface {
+	ManifestDocumentRepository
+	GetDocumentByID(ctx context.Context, documentID string, orgID uint) (*models.Document, error)
+	UpdateDocumentStage(ctx context.Context, document models.Document, expectedStage string) error
+	ListDocuments(ctx context.Context, filter repositories.DocumentFilter, orgID uint) ([]models.Document, int64, error)
+}
+
+// Fulfilment son los datos del cumplido: cuándo llegó la carga, cuánto se entregó y las
+// novedades (faltantes, averías, demoras)
+type Fulfilment struct {
+	ArrivedAt   time.Time
+	DeliveredKg int
+	Novedades   string
+}
+
+// ManifestFollowUpService radica el cumplido o la anulación de un manifiesto completado.
+// Cada uno es un documento nuevo enlazado al manifiesto que se genera en segundo plano
+// con playwright-bot; el manifiesto pasa por los subestados de ManifestStageTransitions.
+type ManifestFollowUpService struct {
+	repo    ManifestFollowUpRepository
+	client  playwright.Client
+	sender  DocumentSender
+	timeout time.Duration
+
+	drivers  repositories.DriverRepository // Opcional: confirmación de entrega por WhatsApp
+	notifier ReminderSender
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	now    func() time.Time
+}
+
+// NewManifestFollowUpService crea el servicio; timeout limita cada solicitud a playwright-bot
+func NewManifestFollowUpService(repo ManifestFollowUpRepository, client playwright.Client, sender DocumentSender, timeout time.Duration) *ManifestFollowUpService {
+	if timeout <= 0 {
+		timeout = DefaultManifestOrchestratorConfig().Timeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ManifestFollowUpService{
+		repo:    repo,
+		client:  client,
+		sender:  sender,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		now:     time.Now,
+	}
+}
+
+// SetDeliveryConfirmation permite pedirle al conductor registrado del manifiesto que
+// confirme la entrega por WhatsApp
+func (s *ManifestFollowUpService) SetDeliveryConfirmation(drivers repositories.DriverRepository, notifier ReminderSender) {
+	s.drivers = drivers
+	s.notifier = notifier
+}
+
+// Fulfil radica el cumplido del manifiesto
+func (s *ManifestFollowUpService) Fulfil(ctx context.Context, manifest models.Document, fulfilment Fulfilment, triggeredBy string) (*models.Document, error) {
+	entities := map[string]interface{}{
+		"fecha_llegada":  fulfilment.ArrivedAt.In(colombiaLocation()).Format(time.RFC3339),
+		"peso_entregado": strconv.Itoa(fulfilment.DeliveredKg),
+	}
+	if novedades := strings.TrimSpace(fulfilment.Novedades); novedades != "" {
+		entities["novedades"] = novedades
+	}
+	return s.start(ctx, manifest, DocumentTypeCumplido, entities, triggeredBy)
+}
+
+// Annul radica la anulación del manifiesto
+func (s *ManifestFollowUpService) Annul(ctx context.Context, manifest models.Document, motivo, triggeredBy string) (*models.Document, error) {
+	return s.start(ctx, manifest, DocumentTypeAnulacion, map[string]interface{}{"motivo": strings.TrimSpace(motivo)}, triggeredBy)
+}
+
+// Retry vuelve a radicar un cumplido o una anulación fallida o cancelada
+func (s *ManifestFollowUpService) Retry(ctx context.Context, document models.Document, triggeredBy string) (*models.Document, error) {
+	stages, ok := followUpStages[document.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: solo se pueden reintentar cumplidos y anulaciones", ErrDocumentNotRetryable)
+	}
+	if !document.CanTransition(models.DocumentStatusGenerating) {
+		return nil, fmt.Errorf("%w: el documento está en estado %s", ErrDocumentNotRetryable, document.Status)
+	}
+	manifest, err := s.repo.GetDocumentByID(ctx, document.ParentID.Hex(), document.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("error obteniendo el manifiesto: %w", err)
+	}
+	request, err := s.prepare(*manifest, document, stages[0])
+	if err != nil {
+		return nil, err
+	}
+
+	previousStage := manifest.ManifestStage()
+	if err := s.moveStage(ctx, *manifest, previousStage, stages[0]); err != nil {
+		return nil, err
+	}
+	previousStatus := document.Status
+	document.StartAttempt(triggeredBy, s.now())
+	if err := s.repo.UpdateDocument(ctx, document, previousStatus); err != nil {
+		s.restoreStage(*manifest, stages[0], previousStage)
+		return nil, fmt.Errorf("error actualizando documento: %w", err)
+	}
+
+	log.Printf("🔁 Reintento %d del %s %s (manifiesto %s)", len(document.Attempts), document.Type, document.ID.Hex(), manifest.ID.Hex())
+	s.launch(*manifest, document, request)
+	return &document, nil
+}
+
+// RequestDelivery le pide al conductor del manifiesto que confirme la entrega por WhatsApp.
+// El manifiesto queda en awaiting_delivery hasta que el conductor responda (ConfirmDelivery).
+func (s *ManifestFollowUpService) RequestDelivery(ctx context.Context, manifest models.Document) error {
+	if err := checkManifestStage(manifest, models.ManifestStageAwaitingDelivery); err != nil {
+		return err
+	}
+	if s.drivers == nil || s.notifier == nil || manifest.DriverID == 0 {
+		return ErrNoDriverPhone
+	}
+	driver, err := s.drivers.GetByID(manifest.DriverID, manifest.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && driver.Phone == "") {
+		return ErrNoDriverPhone
+	}
+	if err != nil {
+		return err
+	}
+
+	previousStage := manifest.ManifestStage()
+	if manifest.Metadata == nil {
+		manifest.Metadata = map[string]interface{}{}
+	}
+	manifest.Metadata["delivery_requested_at"] = s.now()
+	if err := s.moveStage(ctx, manifest, previousStage, models.ManifestStageAwaitingDelivery); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("🚚 Hola %s, ¿ya entregaste la carga del manifiesto %s (%s → %s)?\n\n"+
+		"Cuando la entregues escríbeme *entregué* y te pediré el peso entregado y las novedades para radicar el cumplido.",
+		driver.Name, manifestNumber(manifest), slotString(manifest.Entities, "origen"), slotString(manifest.Entities, "destino"))
+	if err := s.notifier.SendReminder(ctx, manifest.OrganizationID, driver.Phone, text); err != nil {
+		if previousStage != models.ManifestStageAwaitingDelivery {
+			s.restoreStage(manifest, models.ManifestStageAwaitingDelivery, previousStage)
+		}
+		return fmt.Errorf("no se pudo enviar la solicitud al conductor: %w", err)
+	}
+	return nil
+}
+
+// PendingDelivery busca el último manifiesto que espera la confirmación de entrega del
+// conductor que escribe desde el chat
+func (s *ManifestFollowUpService) PendingDelivery(ctx context.Context, target ChatTarget) (*models.Document, error) {
+	if s.drivers == nil {
+		return nil, ErrNoPendingDelivery
+	}
+	driver, err := s.drivers.GetByClient(target.ClientID, strings.Split(target.ChatID, "@")[0], target.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoPendingDelivery
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	documents, _, err := s.repo.ListDocuments(ctx, repositories.DocumentFilter{
+		Type:     DocumentTypeManifiesto,
+		Status:   models.DocumentStatusCompleted,
+		Stage:    models.ManifestStageAwaitingDelivery,
+		DriverID: driver.ID,
+		Limit:    1,
+	}, target.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, ErrNoPendingDelivery
+	}
+	return &documents[0], nil
+}
+
+// ConfirmDelivery radica el cumplido con lo que reporta el conductor por WhatsApp: la
+// llegada es el momento de la confirmación y el peso puede venir como "4,5 toneladas"
+func (s *ManifestFollowUpService) ConfirmDelivery(ctx context.Context, target ChatTarget, peso, novedades string) (*models.Document, error) {
+	manifest, err := s.PendingDelivery(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	kilos, err := parsePeso(peso)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFollowUp, rndc.ValidationErrors{{
+			Field:   "cantidadEntregada",
+			Message: "el peso entregado debe ser numérico (ej. 4500 kg o 4,5 toneladas)",
+		}})
+	}
+	return s.Fulfil(ctx, *manifest, Fulfilment{ArrivedAt: s.now(), DeliveredKg: kilos, Novedades: novedades},
+		"conductor:"+strings.Split(target.ChatID, "@")[0])
+}
+
+// Wait espera a que terminen los seguimientos en curso
+func (s *ManifestFollowUpService) Wait() {
+	s.wg.Wait()
+}
+
+// Stop cancela los seguimientos en curso (quedan como failed) y espera a que terminen
+func (s *ManifestFollowUpService) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// start registra el seguimiento, bloquea el manifiesto en el subestado en curso y lanza la
+// solicitud a playwright-bot
+func (s *ManifestFollowUpService) start(ctx context.Context, manifest models.Document, documentType string, entities map[string]interface{}, triggeredBy string) (*models.Document, error) {
+	stages := followUpStages[documentType]
+	document := models.Document{
+		ID:             primitive.NewObjectID(),
+		OrganizationID: manifest.OrganizationID,
+		ClientID:       manifest.ClientID,
+		SessionID:      manifest.SessionID,
+		ChatID:         manifest.ChatID,
+		BotKey:         manifest.BotKey,
+		Type:           documentType,
+		ParentID:       manifest.ID,
+		VehicleID:      manifest.VehicleID,
+		DriverID:       manifest.DriverID,
+		Entities:       entities,
+		Metadata: map[string]interface{}{
+			"consecutivo_remesa":     manifest.Metadata["consecutivo_remesa"],
+			"consecutivo_manifiesto": manifest.Metadata["consecutivo_manifiesto"],
+		},
+		CreatedAt: s.now(),
+	}
+	request, err := s.prepare(manifest, document, stages[0])
+	if err != nil {
+		return nil, err
+	}
+	document.Metadata["request"] = toMetadata(request)
+
+	previousStage := manifest.ManifestStage()
+	if err := s.moveStage(ctx, manifest, previousStage, stages[0]); err != nil {
+		return nil, err
+	}
+	document.StartAttempt(triggeredBy, document.CreatedAt)
+	if err := s.repo.SaveDocument(ctx, document); err != nil {
+		s.restoreStage(manifest, stages[0], previousStage)
+		return nil, fmt.Errorf("error registrando documento: %w", err)
+	}
+
+	log.Printf("📋 %s del manifiesto %s en cola (org %d)", documentType, manifestNumber(manifest), manifest.OrganizationID)
+	s.launch(manifest, document, request)
+	return &document, nil
+}
+
+// prepare revisa que el manifiesto admita el seguimiento y arma la solicitud con sus consecutivos
+func (s *ManifestFollowUpService) prepare(manifest, document models.Document, stage string) (interface{}, error) {
+	if err := checkManifestStage(manifest, stage); err != nil {
+		return nil, err
+	}
+
+	remesa := slotString(manifest.Metadata, "consecutivo_remesa")
+	numero := slotString(manifest.Metadata, "consecutivo_manifiesto")
+	switch document.Type {
+	case DocumentTypeCumplido:
+		peso, _ := strconv.Atoi(slotString(document.Entities, "peso_entregado"))
+		request := rndc.CumplidoRequest{
+			ConsecutivoRemesa:     remesa,
+			ConsecutivoManifiesto: numero,
+			FechaLlegada:          slotString(document.Entities, "fecha_llegada"),
+			CantidadEntregada:     peso,
+			Novedades:             slotString(document.Entities, "novedades"),
+		}
+		request.Normalize()
+		err := request.Validate(s.now())
+		// La llegada se compara por día: con solo la fecha queda a medianoche
+		issued := manifest.CreatedAt.In(colombiaLocation())
+		issuedDay := time.Date(issued.Year(), issued.Month(), issued.Day(), 0, 0, 0, 0, issued.Location())
+		if llegada, parseErr := rndc.ParseFecha(request.FechaLlegada); err == nil && parseErr == nil && llegada.Before(issuedDay) {
+			err = rndc.ValidationErrors{{Field: "fechaLlegada", Message: "la llegada no puede ser anterior a la expedición del manifiesto"}}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFollowUp, err)
+		}
+		return request, nil
+	case DocumentTypeAnulacion:
+		request := rndc.AnulacionRequest{
+			ConsecutivoRemesa:     remesa,
+			ConsecutivoManifiesto: numero,
+			Motivo:                slotString(document.Entities, "motivo"),
+		}
+		request.Normalize()
+		if err := request.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFollowUp, err)
+		}
+		return request, nil
+	}
+	return nil, fmt.Errorf("tipo de seguimiento desconocido: %s", document.Type)
+}
+
+func (s *ManifestFollowUpService) launch(manifest, document models.Document, request interface{}) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.run(ctx, manifest, document, request)
+	}()
+}
+
+func (s *ManifestFollowUpService) run(ctx context.Context, manifest, document models.Document, request interface{}) {
+	var result *playwright.FollowUpResult
+	var err error
+	switch request := request.(type) {
+	case rndc.CumplidoRequest:
+		result, err = s.client.CumplirManifiesto(ctx, request)
+	case rndc.AnulacionRequest:
+		result, err = s.client.AnularManifiesto(ctx, request)
+	}
+
+	stages := followUpStages[document.Type]
+	stage := stages[1]
+	if err != nil {
+		log.Printf("❌ Error radicando %s del manifiesto %s: %v", document.Type, manifestNumber(manifest), err)
+		document.Finish(models.DocumentStatusFailed, err.Error(), s.now())
+		stage = models.ManifestStageIssued
+	} else {
+		log.Printf("✅ %s del manifiesto %s radicado (%s)", document.Type, manifestNumber(manifest), result.Radicado)
+		document.Metadata["radicado"] = result.Radicado
+		document.Finish(models.DocumentStatusCompleted, "", s.now())
+	}
+
+	// El contexto del seguimiento puede estar vencido; las actualizaciones usan uno propio
+	updateCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.repo.UpdateDocument(updateCtx, document, models.DocumentStatusGenerating); err != nil {
+		// Si se canceló mientras tanto el manifiesto sigue el resultado del RNDC
+		log.Printf("⚠️ Error actualizando documento %s: %v", document.ID.Hex(), err)
+	}
+	s.restoreStage(manifest, stages[0], stage)
+	if err != nil || manifest.BotKey == "" || manifest.ChatID == "" {
+		return
+	}
+
+	text := fmt.Sprintf("✅ Radicamos el cumplido del manifiesto %s en el RNDC (radicado %s).", manifestNumber(manifest), result.Radicado)
+	if document.Type == DocumentTypeAnulacion {
+		text = fmt.Sprintf("🚫 El manifiesto %s fue anulado en el RNDC (radicado %s).\n\nMotivo: %s",
+			manifestNumber(manifest), result.Radicado, slotString(document.Entities, "motivo"))
+	}
+	target := ChatTarget{
+		OrganizationID: manifest.OrganizationID,
+		ClientID:       manifest.ClientID,
+		SessionID:      manifest.SessionID,
+		BotKey:         manifest.BotKey,
+		ChatID:         manifest.ChatID,
+	}
+	if err := s.sender.SendText(updateCtx, target, text); err != nil {
+		log.Printf("⚠️ No se pudo avisar del %s a %s: %v", document.Type, manifest.ChatID, err)
+	}
+}
+
+// moveStage guarda el manifiesto en el subestado to si sigue en from
+func (s *ManifestFollowUpService) moveStage(ctx context.Context, manifest models.Document, from, to string) error {
+	manifest.Stage = to
+	err := s.repo.UpdateDocumentStage(ctx, manifest, from)
+	if errors.Is(err, repositories.ErrDocumentStatusChanged) {
+		return fmt.Errorf("%w: el manifiesto cambió de estado, vuelve a consultarlo", ErrManifestStage)
+	}
+	return err
+}
+
+// restoreStage relee el manifiesto y lo pasa de from a to; los errores solo se registran
+func (s *ManifestFollowUpService) restoreStage(manifest models.Document, from, to string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	current, err := s.repo.GetDocumentByID(ctx, manifest.ID.Hex(), manifest.OrganizationID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return
+	}
+	if err == nil {
+		err = s.moveStage(ctx, *current, from, to)
+	}
+	if err != nil {
+		log.Printf("⚠️ No se pudo pasar el manifiesto %s de %s a %s: %v", manifest.ID.Hex(), from, to, err)
+	}
+}
+
+// checkManifestStage revisa que sea un manifiesto completado que pueda pasar al subestado
+func checkManifestStage(manifest models.Document, stage string) error {
+	if manifest.Type != DocumentTypeManifiesto || manifest.Status != models.DocumentStatusCompleted {
+		return fmt.Errorf("%w: solo aplica a manifiestos completados", ErrManifestStage)
+	}
+	if !manifest.CanAdvanceStage(stage) {
+		return fmt.Errorf("%w: el manifiesto está en %s", ErrManifestStage, manifest.ManifestStage())
+	}
+	return nil
+}
+
+// manifestNumber es el consecutivo del RNDC o, si no lo tiene, el de la remesa
+func manifestNumber(manifest models.Document) string {
+	if numero := slotString(manifest.Metadata, "consecutivo_manifiesto"); numero != "" {
+		return numero
+	}
+	return slotString(manifest.Metadata, "consecutivo_remesa")
+}
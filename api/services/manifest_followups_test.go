@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+)
+
+func TestManifestFulfilmentAndAnnulment(t *testing.T) {
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+
+	repo := &memoryDocumentRepo{}
+	sender := &recordingSender{}
+	client := playwright.NewClient(playwright.Config{BaseURL: fake.URL})
+	orchestrator := NewManifestOrchestrator(repo, client, testStorage(t), sender, testManifestConfig())
+
+	drivers := &mocks.MockDriverRepo{}
+	require.NoError(t, drivers.Create(&models.Driver{OrganizationID: 3, Cedula: "1020304050", Name: "Ana", Phone: "573001112233"}))
+	reminders := &recordingReminderSender{}
+	followUps := NewManifestFollowUpService(repo, client, sender, 0)
+	followUps.SetDeliveryConfirmation(drivers, reminders)
+
+	issue := func() models.Document {
+		document, err := orchestrator.Start(context.Background(), testManifestJob())
+		require.NoError(t, err)
+		orchestrator.Wait()
+		manifest := repo.get(document.ID.Hex())
+		manifest.DriverID = 1
+		require.NoError(t, repo.SaveDocument(context.Background(), manifest))
+		return manifest
+	}
+
+	// Cumplido confirmado por el conductor desde su chat
+	manifest := issue()
+	require.NoError(t, followUps.RequestDelivery(context.Background(), manifest))
+	require.Len(t, reminders.sent["573001112233"], 1)
+	assert.Contains(t, reminders.sent["573001112233"][0], "Hola Ana")
+	assert.Equal(t, models.ManifestStageAwaitingDelivery, repo.get(manifest.ID.Hex()).Stage)
+
+	driverChat := ChatTarget{OrganizationID: 3, ClientID: 99, SessionID: "bot-1", BotKey: "bot-1:573001234567", ChatID: "573001112233@s.whatsapp.net"}
+	_, err := followUps.ConfirmDelivery(context.Background(), driverChat, "mucho", "")
+	assert.ErrorIs(t, err, ErrInvalidFollowUp)
+
+	cumplido, err := followUps.ConfirmDelivery(context.Background(), driverChat, "4,5 toneladas", "Llegó una caja golpeada")
+	require.NoError(t, err)
+	assert.Equal(t, models.ManifestStageFulfilling, repo.get(manifest.ID.Hex()).Stage)
+	followUps.Wait()
+
+	stored := repo.get(cumplido.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCompleted, stored.Status)
+	assert.Equal(t, manifest.ID, stored.ParentID)
+	assert.NotEmpty(t, stored.Metadata["radicado"])
+	assert.Equal(t, models.ManifestStageFulfilled, repo.get(manifest.ID.Hex()).Stage)
+	cumplidos, _ := fake.FollowUps()
+	require.Len(t, cumplidos, 1)
+	assert.Equal(t, 4500, cumplidos[0].CantidadEntregada)
+	assert.Equal(t, manifest.Metadata["consecutivo_manifiesto"], cumplidos[0].ConsecutivoManifiesto)
+	assert.Contains(t, sender.texts[len(sender.texts)-1], "Radicamos el cumplido")
+
+	_, err = followUps.ConfirmDelivery(context.Background(), driverChat, "4500", "")
+	assert.ErrorIs(t, err, ErrNoPendingDelivery)
+	_, err = followUps.Annul(context.Background(), repo.get(manifest.ID.Hex()), "Se expidió con la placa equivocada", "api")
+	assert.ErrorIs(t, err, ErrManifestStage, "un manifiesto cumplido no se anula")
+
+	// Anulación rechazada por el RNDC: el manifiesto vuelve a issued y se reintenta
+	manifest = issue()
+	_, err = followUps.Annul(context.Background(), manifest, "error", "api")
+	assert.ErrorIs(t, err, ErrInvalidFollowUp)
+
+	fake.HandleFollowUp = func(path string) *playwright.APIError {
+		return &playwright.APIError{StatusCode: http.StatusBadRequest, Message: "Manifiesto con cumplido inicial"}
+	}
+	anulacion, err := followUps.Annul(context.Background(), manifest, "Se expidió con la placa equivocada", "usuario:1")
+	require.NoError(t, err)
+	followUps.Wait()
+	assert.Equal(t, models.DocumentStatusFailed, repo.get(anulacion.ID.Hex()).Status)
+	assert.Equal(t, models.ManifestStageIssued, repo.get(manifest.ID.Hex()).Stage)
+
+	fake.HandleFollowUp = nil
+	_, err = followUps.Retry(context.Background(), repo.get(anulacion.ID.Hex()), "usuario:1")
+	require.NoError(t, err)
+	followUps.Wait()
+	retried := repo.get(anulacion.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCompleted, retried.Status)
+	assert.Len(t, retried.Attempts, 2)
+	assert.Equal(t, models.ManifestStageAnnulled, repo.get(manifest.ID.Hex()).Stage)
+	assert.Contains(t, sender.texts[len(sender.texts)-1], "fue anulado")
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/storage"
+)
+
+// DocumentTypeManifiesto es el tipo de los documentos generados por el orquestador
+const DocumentTypeManifiesto = "manifiesto"
+
+// ErrDocumentNotRetryable indica que el documento no se puede volver a generar
+var ErrDocumentNotRetryable = errors.New("el documento no se puede reintentar")
+
+// ChatTarget identifica el chat de WhatsApp al que se entrega un documento
+type ChatTarget struct {
+	OrganizationID uint
+	ClientID       uint
+	SessionID      string
+	BotKey         string // sessionId:botPhone del bot que atiende el chat
+	ChatID         string // JID del cliente
+}
+
+// DocumentSender entrega mensajes y archivos por WhatsApp (lo implementa controllers)
+type DocumentSender interface {
+	SendText(ctx context.Context, target ChatTarget, text string) error
+	SendFile(ctx context.Context, target ChatTarget, file playwright.File, caption string) error
+}
+
+// ManifestDocumentRepository es la parte del repositorio de Mongo que usa el orquestador
+type ManifestDocumentRepository interface {
+	SaveDocument(ctx context.Context, document models.Document) error
+	UpdateDocument(ctx context.Context, document models.Document, expectedStatus string) error
+	GetStaleGeneratingDocuments(ctx context.Context, documentType string, startedBefore time.Time) ([]models.Document, error)
+}
+
+// ManifestJob es una solicitud de manifiesto con los slots recolectados por Rasa
+type ManifestJob struct {
+	ChatTarget
+	Slots        map[string]interface{}
+	RepeatedFrom string // Plantilla usada (ver ManifestTemplateService.Repeat)
+}
+
+// ManifestOrchestratorConfig controla la generación de manifiestos
+type ManifestOrchestratorConfig struct {
+	Defaults      ManifestDefaults
+	MaxConcurrent int           // Manifiestos generándose a la vez (playwright-bot los encola)
+	MaxAttempts   int           // Intentos ante errores transitorios de playwright-bot
+	RetryDelay    time.Duration // Espera antes del segundo intento; crece linealmente
+	Timeout       time.Duration // Tiempo máximo por manifiesto, incluyendo reintentos
+}
+
+// DefaultManifestOrchestratorConfig devuelve la configuración por defecto
+func DefaultManifestOrchestratorConfig() ManifestOrchestratorConfig {
+	return ManifestOrchestratorConfig{
+		MaxConcurrent: 2,
+		MaxAttempts:   3,
+		RetryDelay:    30 * time.Second,
+		Timeout:       15 * time.Minute,
+	}
+}
+
+// ManifestOrchestrator es dueño del ciclo de vida de un manifiesto: arma el payload, lo
+// envía a playwright-bot, sigue el documento (generating → completed/failed), guarda el
+// PDF y se lo entrega al cliente por WhatsApp.
+type ManifestOrchestrator struct {
+	repo    ManifestDocumentRepository
+	client  playwright.Client
+	storage storage.Storage
+	sender  DocumentSender
+	config  ManifestOrchestratorConfig
+
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // Generaciones en curso por ID de documento
+
+	links        *DocumentLinkService  // Opcional: agrega un enlace de descarga al mensaje del PDF
+	verification *DocumentVerification // Opcional: envía el código y el QR de verificación
+	registry     *RegistryService      // Opcional: completa y enlaza el vehículo y el conductor registrados
+}
+
+// NewManifestOrchestrator crea el orquestador
+func NewManifestOrchestrator(repo ManifestDocumentRepository, client playwright.Client, files storage.Storage, sender DocumentSender, config ManifestOrchestratorConfig) *ManifestOrchestrator {
+	defaults := DefaultManifestOrchestratorConfig()
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = defaults.MaxConcurrent
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaults.RetryDelay
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+
+	return &ManifestOrchestrator{
+		repo:    repo,
+		client:  client,
+		storage: files,
+		sender:  sender,
+		config:  config,
+		slots:   make(chan struct{}, config.MaxConcurrent),
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+// Start valida los slots, registra el documento en estado generating y lo genera en
+// segundo plano. Devuelve ErrIncompleteManifest si faltan datos.
+func (o *ManifestOrchestrator) Start(ctx context.Context, job ManifestJob) (*models.Document, error) {
+	id := primitive.NewObjectID()
+	hex := id.Hex()
+	consecutivo := NewConsecutivo(o.config.Defaults.ConsecutivoPrefix, time.Now(), hex[len(hex)-6:])
+
+	if o.registry != nil {
+		job.Slots = o.registry.FillManifestSlots(job.OrganizationID, job.ClientID, job.ChatID, job.Slots)
+	}
+	request, err := BuildManifiestoRequest(job.Slots, o.config.Defaults, consecutivo)
+	if err != nil {
+		return nil, err
+	}
+
+	document := models.Document{
+		ID:             id,
+		OrganizationID: job.OrganizationID,
+		ClientID:       job.ClientID,
+		SessionID:      job.SessionID,
+		ChatID:         job.ChatID,
+		BotKey:         job.BotKey,
+		FileName:       fmt.Sprintf("manifiesto_%s.pdf", consecutivo),
+		Type:           DocumentTypeManifiesto,
+		Entities:       job.Slots,
+		Metadata: map[string]interface{}{
+			"consecutivo_remesa": consecutivo,
+			"request":            toMetadata(request),
+		},
+		CreatedAt: time.Now(),
+	}
+	if job.RepeatedFrom != "" {
+		document.Metadata["repeated_from"] = job.RepeatedFrom
+	}
+	if o.registry != nil {
+		document.VehicleID, document.DriverID = o.registry.ResolveManifest(job.OrganizationID, request)
+	}
+	document.StartAttempt("bot", document.CreatedAt)
+	if err := o.repo.SaveDocument(ctx, document); err != nil {
+		return nil, fmt.Errorf("error registrando documento: %w", err)
+	}
+
+	log.Printf("📋 Manifiesto %s en cola para %s (org %d)", consecutivo, job.ChatID, job.OrganizationID)
+	o.launch(document, request, job.ChatTarget)
+	return &document, nil
+}
+
+// Retry vuelve a generar un manifiesto fallido o cancelado con los slots guardados. Cada
+// reintento usa un consecutivo de remesa nuevo y queda registrado como un intento más.
+func (o *ManifestOrchestrator) Retry(ctx context.Context, document models.Document, triggeredBy string) (*models.Document, error) {
+	if document.Type != DocumentTypeManifiesto {
+		return nil, fmt.Errorf("%w: solo se pueden reintentar manifiestos", ErrDocumentNotRetryable)
+	}
+	if !document.CanTransition(models.DocumentStatusGenerating) {
+		return nil, fmt.Errorf("%w: el documento está en estado %s", ErrDocumentNotRetryable, document.Status)
+	}
+	if document.BotKey == "" || document.ChatID == "" {
+		return nil, fmt.Errorf("%w: el documento no tiene chat de entrega", ErrDocumentNotRetryable)
+	}
+	if o.isRunning(document.ID.Hex()) {
+		return nil, fmt.Errorf("%w: el intento anterior sigue en curso", ErrDocumentNotRetryable)
+	}
+
+	hex := document.ID.Hex()
+	consecutivo := NewConsecutivo(o.config.Defaults.ConsecutivoPrefix, time.Now(), hex[len(hex)-6:])
+	request, err := BuildManifiestoRequest(document.Entities, o.config.Defaults, consecutivo)
+	if err != nil {
+		return nil, err
+	}
+
+	previousStatus := document.Status
+	if document.Metadata == nil {
+		document.Metadata = map[string]interface{}{}
+	}
+	document.Metadata["consecutivo_remesa"] = consecutivo
+	document.Metadata["request"] = toMetadata(request)
+	delete(document.Metadata, "consecutivo_manifiesto")
+	document.FileName = fmt.Sprintf("manifiesto_%s.pdf", consecutivo)
+	if o.registry != nil {
+		document.VehicleID, document.DriverID = o.registry.ResolveManifest(document.OrganizationID, request)
+	}
+	document.StartAttempt(triggeredBy, time.Now())
+	if err := o.repo.UpdateDocument(ctx, document, previousStatus); err != nil {
+		return nil, fmt.Errorf("error actualizando documento: %w", err)
+	}
+
+	log.Printf("🔁 Reintento %d del manifiesto %s (%s)", len(document.Attempts), hex, consecutivo)
+	o.launch(document, request, ChatTarget{
+		OrganizationID: document.OrganizationID,
+		ClientID:       document.ClientID,
+		SessionID:      document.SessionID,
+		BotKey:         document.BotKey,
+		ChatID:         document.ChatID,
+	})
+	return &document, nil
+}
+
+// Abort detiene la generación en curso de un documento (si la hay). El documento ya debe
+// estar cancelado: la generación no sobrescribe un documento que dejó de estar en generating.
+func (o *ManifestOrchestrator) Abort(documentID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	cancel, ok := o.running[documentID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (o *ManifestOrchestrator) isRunning(documentID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, ok := o.running[documentID]
+	return ok
+}
+
+// launch genera el documento en segundo plano y lo registra como en curso
+func (o *ManifestOrchestrator) launch(document models.Document, request playwright.ManifiestoRequest, target ChatTarget) {
+	id := document.ID.Hex()
+	ctx, cancel := context.WithTimeout(context.Background(), o.config.Timeout)
+
+	o.mu.Lock()
+	o.running[id] = cancel
+	o.mu.Unlock()
+
+	o.wg.Add(1)
+	go func() {
+		defer func() {
+			o.mu.Lock()
+			delete(o.running, id)
+			o.mu.Unlock()
+			cancel()
+		}()
+		o.run(ctx, document, request, target)
+	}()
+}
+
+// SetDocumentLinks hace que el mensaje del PDF incluya un enlace firmado de descarga
+// (útil cuando el cliente reenvía el manifiesto al conductor)
+func (o *ManifestOrchestrator) SetDocumentLinks(links *DocumentLinkService) {
+	o.links = links
+}
+
+// SetRegistry hace que los manifiestos completen la placa y la licencia con el registro de
+// la organización cuando el chat no las trae, y que el documento quede enlazado a ellos
+func (o *ManifestOrchestrator) SetRegistry(registry *RegistryService) {
+	o.registry = registry
+}
+
+// SetDocumentVerification hace que el PDF se envíe con su código de verificación y, a
+// continuación, la imagen del QR que abre /verify/{código}
+func (o *ManifestOrchestrator) SetDocumentVerification(verification *DocumentVerification) {
+	o.verification = verification
+}
+
+// Wait espera a que terminen los manifiestos en curso
+func (o *ManifestOrchestrator) Wait() {
+	o.wg.Wait()
+}
+
+// RecoverStale marca como failed los manifiestos que siguen en generating después del
+// tiempo máximo de generación: quedaron así por una caída o un reinicio de la API y ninguna
+// réplica los está generando. Así se pueden reintentar. No se le avisa al cliente.
+func (o *ManifestOrchestrator) RecoverStale(ctx context.Context) error {
+	documents, err := o.repo.GetStaleGeneratingDocuments(ctx, DocumentTypeManifiesto, time.Now().Add(-o.config.Timeout))
+	if err != nil {
+		return fmt.Errorf("error buscando manifiestos en generating: %w", err)
+	}
+	recovered := 0
+	for _, document := range documents {
+		if o.isRunning(document.ID.Hex()) {
+			continue
+		}
+		document.Finish(models.DocumentStatusFailed, "La generación se interrumpió (reinicio de la API); se puede reintentar", time.Now())
+		if err := o.repo.UpdateDocument(ctx, document, models.DocumentStatusGenerating); err != nil {
+			if !errors.Is(err, repositories.ErrDocumentStatusChanged) {
+				log.Printf("⚠️ Error recuperando el manifiesto %s: %v", document.ID.Hex(), err)
+			}
+			continue
+		}
+		recovered++
+	}
+	if recovered > 0 {
+		log.Printf("🧹 %d manifiestos interrumpidos pasaron a failed", recovered)
+	}
+	return nil
+}
+
+func (o *ManifestOrchestrator) run(ctx context.Context, document models.Document, request playwright.ManifiestoRequest, target ChatTarget) {
+	defer o.wg.Done()
+
+	select {
+	case o.slots <- struct{}{}:
+		defer func() { <-o.slots }()
+	case <-ctx.Done():
+		o.fail(document, target, ctx.Err())
+		return
+	}
+
+	var result *playwright.ManifiestoResult
+	err := o.withRetry(ctx, "creación", retryableCreate, func() (err error) {
+		result, err = o.client.CreateManifiesto(ctx, request)
+		return err
+	})
+	if err != nil {
+		o.fail(document, target, err)
+		return
+	}
+
+	var file *playwright.File
+	err = o.withRetry(ctx, "descarga", playwright.Retryable, func() (err error) {
+		file, err = o.client.Download(ctx, result.DownloadURL)
+		return err
+	})
+	if err != nil {
+		o.fail(document, target, fmt.Errorf("manifiesto %s creado pero no se pudo descargar: %w", result.ConsecutivoManifiesto, err))
+		return
+	}
+
+	// playwright-bot borra sus archivos: el PDF se copia al almacenamiento de la API
+	object, err := o.storage.Put(ctx, fmt.Sprintf("%d/%s.pdf", document.OrganizationID, document.ID.Hex()), file.Content, file.ContentType)
+	if err != nil {
+		o.fail(document, target, fmt.Errorf("manifiesto %s creado pero no se pudo guardar: %w", result.ConsecutivoManifiesto, err))
+		return
+	}
+
+	document.URL = DocumentDownloadPath(document.ID.Hex())
+	document.StorageKey = object.Key
+	document.ContentType = object.ContentType
+	document.Size = object.Size
+	document.Checksum = object.Checksum
+	document.Metadata["source_url"] = result.DownloadURL
+	document.FileName = fmt.Sprintf("manifiesto_%s.pdf", result.ConsecutivoManifiesto)
+	document.Metadata["consecutivo_manifiesto"] = result.ConsecutivoManifiesto
+	document.Finish(models.DocumentStatusCompleted, "", time.Now())
+	if errors.Is(o.update(document, models.DocumentStatusGenerating), repositories.ErrDocumentStatusChanged) {
+		log.Printf("⚠️ Manifiesto %s generado pero el documento %s fue cancelado; no se envía (archivo %s)",
+			result.ConsecutivoManifiesto, document.ID.Hex(), object.Key)
+		return
+	}
+	log.Printf("✅ Manifiesto %s generado (documento %s)", result.ConsecutivoManifiesto, document.ID.Hex())
+
+	file.FileName = document.FileName
+	caption := fmt.Sprintf("📋 Manifiesto %s: %s → %s", result.ConsecutivoManifiesto,
+		request.Manifiesto.MunicipioOrigen, request.Manifiesto.MunicipioDestino)
+	if o.links != nil {
+		if url, link, err := o.links.Issue(document, 0, 0, 0); err != nil {
+			log.Printf("⚠️ No se pudo crear el enlace del manifiesto %s: %v", result.ConsecutivoManifiesto, err)
+		} else {
+			caption += fmt.Sprintf("\n\n🔗 Descarga (un solo uso, válido hasta %s):\n%s",
+				link.ExpiresAt.In(colombiaLocation()).Format("02/01/2006 15:04"), url)
+		}
+	}
+	if o.verification != nil {
+		caption += fmt.Sprintf("\n\n✅ Código de verificación: %s\n%s",
+			document.VerificationCode, o.verification.URL(document.VerificationCode))
+	}
+	if err := o.sender.SendFile(ctx, target, *file, caption); err != nil {
+		log.Printf("⚠️ Manifiesto %s generado pero no se pudo enviar a %s: %v", result.ConsecutivoManifiesto, target.ChatID, err)
+		document.Error = "No se pudo enviar por WhatsApp: " + err.Error()
+		o.update(document, models.DocumentStatusCompleted)
+		return
+	}
+	if o.verification != nil {
+		o.sendVerificationQR(ctx, document, target, result.ConsecutivoManifiesto)
+	}
+}
+
+// sendVerificationQR envía la imagen del QR de verificación después del PDF. Es un
+// complemento: si falla el documento sigue completado y el código ya va en el mensaje.
+func (o *ManifestOrchestrator) sendVerificationQR(ctx context.Context, document models.Document, target ChatTarget, consecutivo string) {
+	png, err := o.verification.QR(document.VerificationCode)
+	if err != nil {
+		log.Printf("⚠️ No se pudo generar el QR del manifiesto %s: %v", consecutivo, err)
+		return
+	}
+	qr := playwright.File{
+		FileName:    fmt.Sprintf("verificacion_%s.png", consecutivo),
+		ContentType: "image/png",
+		Content:     png,
+	}
+	caption := fmt.Sprintf("🔎 Escanea este código para verificar el manifiesto %s", consecutivo)
+	if err := o.sender.SendFile(ctx, target, qr, caption); err != nil {
+		log.Printf("⚠️ No se pudo enviar el QR del manifiesto %s a %s: %v", consecutivo, target.ChatID, err)
+	}
+}
+
+// fail marca el documento como fallido y le avisa al cliente. Si el documento se canceló
+// mientras se generaba no se modifica ni se avisa.
+func (o *ManifestOrchestrator) fail(document models.Document, target ChatTarget, cause error) {
+	document.Finish(models.DocumentStatusFailed, cause.Error(), time.Now())
+	if errors.Is(o.update(document, models.DocumentStatusGenerating), repositories.ErrDocumentStatusChanged) {
+		log.Printf("🛑 Generación del manifiesto %s detenida: el documento fue cancelado", document.ID.Hex())
+		return
+	}
+	log.Printf("❌ Error generando manifiesto %s: %v", document.ID.Hex(), cause)
+
+	text := "Lo siento, no pudimos generar tu manifiesto 😔\n\nUn asesor revisará tu solicitud y te contactará."
+	var apiErr *playwright.APIError
+	if errors.As(cause, &apiErr) && errors.Is(cause, playwright.ErrRejected) && apiErr.Message != "" {
+		text = fmt.Sprintf("Lo siento, el RNDC rechazó tu manifiesto 😔\n\n%s\n\nRevisa los datos e intenta nuevamente.", apiErr.Message)
+	}
+	// El contexto de la generación puede estar vencido; el aviso usa uno propio
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := o.sender.SendText(ctx, target, text); err != nil {
+		log.Printf("⚠️ No se pudo avisar del fallo a %s: %v", target.ChatID, err)
+	}
+}
+
+// update guarda el documento si sigue en expectedStatus; los errores solo se registran
+func (o *ManifestOrchestrator) update(document models.Document, expectedStatus string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := o.repo.UpdateDocument(ctx, document, expectedStatus)
+	if err != nil && !errors.Is(err, repositories.ErrDocumentStatusChanged) {
+		log.Printf("⚠️ Error actualizando documento %s: %v", document.ID.Hex(), err)
+	}
+	return err
+}
+
+// DocumentDownloadPath es la ruta autenticada de descarga de un documento
+func DocumentDownloadPath(documentID string) string {
+	return "/api/v1/documents/" + documentID + "/download"
+}
+
+func (o *ManifestOrchestrator) withRetry(ctx context.Context, operation string, retryable func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retryable(err) || attempt >= o.config.MaxAttempts {
+			return err
+		}
+		log.Printf("⚠️ %s del manifiesto falló (intento %d/%d): %v", operation, attempt, o.config.MaxAttempts, err)
+
+		select {
+		case <-time.After(o.config.RetryDelay * time.Duration(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// retryableCreate solo reintenta la creación cuando playwright-bot no la recibió: un timeout
+// puede significar que el manifiesto ya se radicó en el RNDC y reintentar lo duplicaría
+func retryableCreate(err error) bool {
+	if errors.Is(err, playwright.ErrRateLimited) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var apiErr *playwright.APIError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusBadGateway || apiErr.StatusCode == http.StatusServiceUnavailable)
+}
+
+// toMetadata convierte el payload a un mapa para guardarlo con los nombres de campo de la API
+func toMetadata(value interface{}) map[string]interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/storage"
+)
+
+type memoryDocumentRepo struct {
+	mu        sync.Mutex
+	documents map[string]models.Document
+}
+
+func (r *memoryDocumentRepo) SaveDocument(ctx context.Context, document models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.documents == nil {
+		r.documents = make(map[string]models.Document)
+	}
+	r.documents[document.ID.Hex()] = document
+	return nil
+}
+
+func (r *memoryDocumentRepo) UpdateDocument(ctx context.Context, document models.Document, expectedStatus string) error {
+	if expectedStatus != "" && r.get(document.ID.Hex()).Status != expectedStatus {
+		return repositories.ErrDocumentStatusChanged
+	}
+	return r.SaveDocument(ctx, document)
+}
+
+func (r *memoryDocumentRepo) UpdateDocumentStage(ctx context.Context, document models.Document, expectedStage string) error {
+	current := r.get(document.ID.Hex())
+	if current.Status != models.DocumentStatusCompleted || current.ManifestStage() != expectedStage {
+		return repositories.ErrDocumentStatusChanged
+	}
+	return r.SaveDocument(ctx, document)
+}
+
+func (r *memoryDocumentRepo) ListDocuments(ctx context.Context, filter repositories.DocumentFilter, orgID uint) ([]models.Document, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	documents := []models.Document{}
+	for _, document := range r.documents {
+		if document.OrganizationID != orgID ||
+			(filter.Type != "" && document.Type != filter.Type) ||
+			(filter.Status != "" && document.Status != filter.Status) ||
+			(filter.Stage != "" && document.ManifestStage() != filter.Stage) ||
+			(filter.DriverID != 0 && document.DriverID != filter.DriverID) ||
+			(!filter.ParentID.IsZero() && document.ParentID != filter.ParentID) {
+			continue
+		}
+		documents = append(documents, document)
+	}
+	return documents, int64(len(documents)), nil
+}
+
+func (r *memoryDocumentRepo) GetStaleGeneratingDocuments(ctx context.Context, documentType string, startedBefore time.Time) ([]models.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	documents := []models.Document{}
+	for _, document := range r.documents {
+		if document.Type == documentType && document.Status == models.DocumentStatusGenerating && document.UpdatedAt.Before(startedBefore) {
+			documents = append(documents, document)
+		}
+	}
+	return documents, nil
+}
+
+func (r *memoryDocumentRepo) get(id string) models.Document {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.documents[id]
+}
+
+func (r *memoryDocumentRepo) GetDocumentsByClientID(ctx context.Context, clientID uint, orgID uint) ([]models.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	documents := []models.Document{}
+	for _, document := range r.documents {
+		if document.ClientID == clientID && document.OrganizationID == orgID {
+			documents = append(documents, document)
+		}
+	}
+	return documents, nil
+}
+
+func (r *memoryDocumentRepo) GetDocumentByID(ctx context.Context, documentID string, orgID uint) (*models.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	document, ok := r.documents[documentID]
+	if !ok || document.OrganizationID != orgID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &document, nil
+}
+
+type recordingSender struct {
+	mu       sync.Mutex
+	texts    []string
+	files    []playwright.File
+	captions []string
+}
+
+func (s *recordingSender) SendText(ctx context.Context, target ChatTarget, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.texts = append(s.texts, text)
+	return nil
+}
+
+func (s *recordingSender) SendFile(ctx context.Context, target ChatTarget, file playwright.File, caption string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = append(s.files, file)
+	s.captions = append(s.captions, caption)
+	return nil
+}
+
+func testStorage(t *testing.T) storage.Storage {
+	files, err := storage.NewFilesystem(t.TempDir())
+	require.NoError(t, err)
+	return files
+}
+
+func testManifestSlots() map[string]interface{} {
+	return map[string]interface{}{
+		"flete":           "$1.500.000",
+		"descripcion":     "Cajas de repuestos",
+		"peso":            "4,5 toneladas",
+		"fecha_cargue":    "20/10/2026",
+		"fecha_descargue": "21/10/2026",
+		"tarjeta":         "abc-123",
+		"licencia":        "1.020.304.050",
+		"origen":          "Bogotá",
+		"destino":         "Medellín",
+	}
+}
+
+// testManifestConfig incluye los datos de la empresa que exige la validación del RNDC
+func testManifestConfig() ManifestOrchestratorConfig {
+	config := DefaultManifestOrchestratorConfig()
+	config.Defaults = ManifestDefaults{EmpresaNIT: "8600537463", SedeCargue: "SEDE-001", SedeDescargue: "SEDE-002"}
+	return config
+}
+
+func testManifestJob() ManifestJob {
+	return ManifestJob{
+		ChatTarget: ChatTarget{
+			OrganizationID: 3,
+			ClientID:       7,
+			SessionID:      "bot-1",
+			BotKey:         "bot-1:573001234567",
+			ChatID:         "573009999999@s.whatsapp.net",
+		},
+		Slots: testManifestSlots(),
+	}
+}
+
+func TestBuildManifiestoRequest(t *testing.T) {
+	defaults := ManifestDefaults{EmpresaNIT: "8600537463", SedeCargue: "SEDE-001", SedeDescargue: "SEDE-002"}
+
+	request, err := BuildManifiestoRequest(testManifestSlots(), defaults, "DB-1")
+	require.NoError(t, err)
+	assert.Equal(t, "DB-1", request.Remesa.Consecutivo)
+	assert.Equal(t, 4500, request.Remesa.CantidadEstimada)
+	assert.Equal(t, "8600537463", request.Remesa.Empresa.NIT)
+	assert.Equal(t, "2026-10-20T00:00:00-05:00", request.Remesa.HoraCargue)
+	assert.Equal(t, "2026-10-21T00:00:00-05:00", request.Remesa.HoraDescargue)
+	assert.Equal(t, "1500000", request.Manifiesto.ValorPagar)
+	assert.Equal(t, "ABC123", request.Manifiesto.PlacaVehiculo)
+	assert.Equal(t, "1020304050", request.Manifiesto.ConductorNumeroID)
+	assert.Equal(t, "Medellín", request.Manifiesto.LugarPago)
+	// Sin titular configurado el titular es el conductor
+	assert.Equal(t, "1020304050", request.Manifiesto.TitularNumeroID)
+
+	slots := testManifestSlots()
+	delete(slots, "tarjeta")
+	slots["peso"] = "mucho"
+	_, err = BuildManifiestoRequest(slots, defaults, "DB-1")
+	assert.ErrorIs(t, err, ErrIncompleteManifest)
+	assert.Contains(t, err.Error(), "tarjeta")
+
+	assert.Equal(t, "DB-20261017103000-A1B2C3", NewConsecutivo("", time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC), "a1b2c3"))
+}
+
+func TestManifestOrchestratorDeliversPDF(t *testing.T) {
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+
+	repo := &memoryDocumentRepo{}
+	sender := &recordingSender{}
+	config := testManifestConfig()
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: fake.URL}), testStorage(t), sender, config)
+
+	document, err := orchestrator.Start(context.Background(), testManifestJob())
+	require.NoError(t, err)
+	assert.Equal(t, models.DocumentStatusGenerating, document.Status)
+	orchestrator.Wait()
+
+	stored := repo.get(document.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCompleted, stored.Status)
+	assert.Equal(t, uint(3), stored.OrganizationID)
+	assert.Equal(t, "00000001", stored.Metadata["consecutivo_manifiesto"])
+	assert.Equal(t, "manifiesto_00000001.pdf", stored.FileName)
+	require.NotNil(t, stored.CompletedAt)
+
+	assert.Equal(t, DocumentDownloadPath(document.ID.Hex()), stored.URL)
+	assert.Equal(t, "application/pdf", stored.ContentType)
+	content, err := storage.Read(context.Background(), orchestrator.storage, stored.StorageKey, stored.Checksum)
+	require.NoError(t, err)
+	assert.Equal(t, playwright.FakePDF, content)
+
+	require.Len(t, sender.files, 1)
+	assert.Equal(t, "manifiesto_00000001.pdf", sender.files[0].FileName)
+	assert.Equal(t, playwright.FakePDF, sender.files[0].Content)
+	assert.Contains(t, sender.captions[0], "Bogotá → Medellín")
+	assert.Empty(t, sender.texts)
+}
+
+func TestManifestOrchestratorSendsVerificationQR(t *testing.T) {
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+
+	repo := &memoryDocumentRepo{}
+	sender := &recordingSender{}
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: fake.URL}), testStorage(t), sender, testManifestConfig())
+	orchestrator.SetDocumentVerification(NewDocumentVerification("https://api.docubot.test"))
+
+	document, err := orchestrator.Start(context.Background(), testManifestJob())
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	stored := repo.get(document.ID.Hex())
+	code, ok := models.NormalizeVerificationCode(stored.VerificationCode)
+	require.True(t, ok)
+	assert.Equal(t, stored.VerificationCode, code)
+
+	require.Len(t, sender.files, 2)
+	assert.Contains(t, sender.captions[0], "https://api.docubot.test/verify/"+code)
+	assert.Equal(t, "image/png", sender.files[1].ContentType)
+	assert.Equal(t, "verificacion_00000001.png", sender.files[1].FileName)
+}
+
+func TestManifestOrchestratorFailures(t *testing.T) {
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+
+	repo := &memoryDocumentRepo{}
+	sender := &recordingSender{}
+	config := testManifestConfig()
+	config.RetryDelay = time.Millisecond
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: fake.URL}), testStorage(t), sender, config)
+
+	// El rechazo del RNDC es definitivo y se le explica al cliente
+	fake.Handle = func(req playwright.ManifiestoRequest) *playwright.APIError {
+		return &playwright.APIError{StatusCode: http.StatusBadRequest, Message: "La placa ABC123 no está registrada"}
+	}
+	document, err := orchestrator.Start(context.Background(), testManifestJob())
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	stored := repo.get(document.ID.Hex())
+	assert.Equal(t, models.DocumentStatusFailed, stored.Status)
+	assert.Contains(t, stored.Error, "no está registrada")
+	assert.Len(t, fake.Requested(), 1)
+	require.Len(t, sender.texts, 1)
+	assert.Contains(t, sender.texts[0], "La placa ABC123 no está registrada")
+
+	// Los 429 se reintentan hasta MaxAttempts
+	fake.Handle = func(req playwright.ManifiestoRequest) *playwright.APIError {
+		return &playwright.APIError{StatusCode: http.StatusTooManyRequests, Message: "Too many requests"}
+	}
+	document, err = orchestrator.Start(context.Background(), testManifestJob())
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	assert.Equal(t, models.DocumentStatusFailed, repo.get(document.ID.Hex()).Status)
+	assert.Len(t, fake.Requested(), 1+config.MaxAttempts)
+	assert.Empty(t, sender.files)
+}
+
+func TestManifestOrchestratorRetryAndCancel(t *testing.T) {
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+
+	repo := &memoryDocumentRepo{}
+	sender := &recordingSender{}
+	config := testManifestConfig()
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: fake.URL}), testStorage(t), sender, config)
+
+	// Primer intento rechazado por el RNDC
+	fake.Handle = func(req playwright.ManifiestoRequest) *playwright.APIError {
+		return &playwright.APIError{StatusCode: http.StatusBadRequest, Message: "Conductor sin licencia vigente"}
+	}
+	document, err := orchestrator.Start(context.Background(), testManifestJob())
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	failed := repo.get(document.ID.Hex())
+	require.Len(t, failed.Attempts, 1)
+	assert.Equal(t, models.DocumentStatusFailed, failed.Attempts[0].Status)
+	assert.Equal(t, "bot", failed.Attempts[0].TriggeredBy)
+
+	// El reintento genera un intento nuevo que termina bien
+	fake.Handle = nil
+	_, err = orchestrator.Retry(context.Background(), failed, "usuario:9")
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	completed := repo.get(document.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCompleted, completed.Status)
+	assert.Empty(t, completed.Error)
+	require.Len(t, completed.Attempts, 2)
+	assert.Equal(t, models.DocumentStatusCompleted, completed.Attempts[1].Status)
+	assert.Equal(t, "usuario:9", completed.Attempts[1].TriggeredBy)
+	assert.Len(t, sender.files, 1)
+
+	_, err = orchestrator.Retry(context.Background(), completed, "usuario:9")
+	assert.ErrorIs(t, err, ErrDocumentNotRetryable)
+
+	// Cancelar durante la generación no sobrescribe el documento ni avisa al cliente
+	fake.Delay = time.Second
+	document, err = orchestrator.Start(context.Background(), testManifestJob())
+	require.NoError(t, err)
+	cancelled := repo.get(document.ID.Hex())
+	cancelled.Finish(models.DocumentStatusCancelled, "Cancelado por usuario:9", time.Now())
+	require.NoError(t, repo.UpdateDocument(context.Background(), cancelled, models.DocumentStatusGenerating))
+	assert.True(t, orchestrator.Abort(document.ID.Hex()))
+	orchestrator.Wait()
+
+	stored := repo.get(document.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCancelled, stored.Status)
+	assert.Equal(t, models.DocumentStatusCancelled, stored.Attempts[0].Status)
+	assert.Len(t, sender.texts, 1) // Solo el aviso del rechazo inicial
+	assert.Len(t, sender.files, 1)
+}
+
+func TestManifestOrchestratorRecoverStale(t *testing.T) {
+	repo := &memoryDocumentRepo{}
+	sender := &recordingSender{}
+	config := testManifestConfig()
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: "http://127.0.0.1:1"}), testStorage(t), sender, config)
+
+	newDocument := func(documentType string, updatedAt time.Time) models.Document {
+		document := models.Document{
+			ID:             primitive.NewObjectID(),
+			OrganizationID: 1,
+			Type:           documentType,
+			BotKey:         "s1:573001112233",
+			ChatID:         "573009998877@s.whatsapp.net",
+			Entities:       testManifestSlots(),
+		}
+		document.StartAttempt("bot", updatedAt)
+		document.UpdatedAt = updatedAt
+		require.NoError(t, repo.SaveDocument(context.Background(), document))
+		return document
+	}
+	stale := newDocument(DocumentTypeManifiesto, time.Now().Add(-2*config.Timeout))
+	recent := newDocument(DocumentTypeManifiesto, time.Now())
+	otherType := newDocument("cumplido", time.Now().Add(-2*config.Timeout))
+
+	require.NoError(t, orchestrator.RecoverStale(context.Background()))
+
+	recovered := repo.get(stale.ID.Hex())
+	assert.Equal(t, models.DocumentStatusFailed, recovered.Status)
+	assert.Equal(t, models.DocumentStatusFailed, recovered.Attempts[0].Status)
+	assert.Equal(t, models.DocumentStatusGenerating, repo.get(recent.ID.Hex()).Status)
+	assert.Equal(t, models.DocumentStatusGenerating, repo.get(otherType.ID.Hex()).Status)
+	assert.Empty(t, sender.texts) // Un reinicio no se le avisa al cliente
+
+	// El manifiesto recuperado se puede reintentar
+	assert.True(t, recovered.CanTransition(models.DocumentStatusGenerating))
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/rndc"
+)
+
+// ErrIncompleteManifest indica que faltan slots o tienen un formato que no se puede usar.
+// Si los datos no pasan rndc.Validate el error también envuelve rndc.ValidationErrors.
+var ErrIncompleteManifest = errors.New("datos del manifiesto incompletos")
+
+// ManifestDefaults son los datos de la empresa que no se le piden al cliente por WhatsApp
+type ManifestDefaults struct {
+	EmpresaNIT        string
+	SedeCargue        string
+	SedeDescargue     string
+	TitularTipoID     string // Si no hay titular se usa el conductor
+	TitularNumeroID   string
+	ConsecutivoPrefix string
+}
+
+// manifestSlots son los slots de manifiesto_form que se requieren para generar el documento
+var manifestSlots = []string{"descripcion", "peso", "flete", "origen", "destino", "tarjeta", "licencia"}
+
+var (
+	nonDigits   = regexp.MustCompile(`[^0-9]`)
+	numberInput = regexp.MustCompile(`[0-9]+(?:[.,][0-9]+)?`)
+	unsafeChars = regexp.MustCompile(`[^A-Za-z0-9-]`)
+	slotDates   = []string{"02/01/2006", "02-01-2006", "2006-01-02", "2/1/2006"}
+)
+
+// BuildManifiestoRequest arma el payload de POST /api/manifiesto a partir de los slots
+// recolectados por Rasa y los datos de la empresa, y lo valida antes de que se encole
+func BuildManifiestoRequest(slots map[string]interface{}, defaults ManifestDefaults, consecutivo string) (playwright.ManifiestoRequest, error) {
+	var missing []string
+	for _, name := range manifestSlots {
+		if slotString(slots, name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return playwright.ManifiestoRequest{}, fmt.Errorf("%w: faltan %s", ErrIncompleteManifest, strings.Join(missing, ", "))
+	}
+
+	cantidad, err := parsePeso(slotString(slots, "peso"))
+	if err != nil {
+		return playwright.ManifiestoRequest{}, err
+	}
+	flete := nonDigits.ReplaceAllString(slotString(slots, "flete"), "")
+	if flete == "" {
+		return playwright.ManifiestoRequest{}, fmt.Errorf("%w: el flete debe ser numérico", ErrIncompleteManifest)
+	}
+	conductor := nonDigits.ReplaceAllString(slotString(slots, "licencia"), "")
+	if conductor == "" {
+		return playwright.ManifiestoRequest{}, fmt.Errorf("%w: la identificación del conductor debe ser numérica", ErrIncompleteManifest)
+	}
+
+	titularTipo, titularNumero := defaults.TitularTipoID, defaults.TitularNumeroID
+	if titularNumero == "" {
+		titularTipo, titularNumero = "Cedula Ciudadania", conductor
+	}
+
+	destino := slotString(slots, "destino")
+	request := rndc.ManifiestoRequest{
+		Remesa: rndc.Remesa{
+			Consecutivo:      consecutivo,
+			DescripcionCorta: slotString(slots, "descripcion"),
+			CantidadEstimada: cantidad,
+			Empresa: rndc.Empresa{
+				NIT:           defaults.EmpresaNIT,
+				SedeCargue:    defaults.SedeCargue,
+				SedeDescargue: defaults.SedeDescargue,
+			},
+		},
+		Manifiesto: rndc.Manifiesto{
+			MunicipioOrigen:  slotString(slots, "origen"),
+			MunicipioDestino: destino,
+			TitularTipoID:    titularTipo,
+			TitularNumeroID:  titularNumero,
+			Vehiculo: rndc.Vehiculo{
+				PlacaVehiculo:     slotString(slots, "tarjeta"),
+				ConductorNumeroID: conductor,
+			},
+			ValorPagar: flete,
+			LugarPago:  destino,
+		},
+	}
+
+	// Las fechas son opcionales; si el descargue es anterior al cargue lo rechaza Validate
+	if cargue, ok := parseSlotDate(slotString(slots, "fecha_cargue")); ok {
+		request.Remesa.HoraCargue = cargue.Format(time.RFC3339)
+	}
+	if descargue, ok := parseSlotDate(slotString(slots, "fecha_descargue")); ok {
+		request.Remesa.HoraDescargue = descargue.Format(time.RFC3339)
+	}
+
+	request.Normalize()
+	if err := request.Validate(); err != nil {
+		return playwright.ManifiestoRequest{}, fmt.Errorf("%w: %w", ErrIncompleteManifest, err)
+	}
+	return request, nil
+}
+
+// NewConsecutivo genera el consecutivo de la remesa (solo A-Z, 0-9 y guiones)
+func NewConsecutivo(prefix string, now time.Time, suffix string) string {
+	if prefix == "" {
+		prefix = "DB"
+	}
+	value := fmt.Sprintf("%s-%s-%s", prefix, now.Format("20060102150405"), suffix)
+	return strings.ToUpper(unsafeChars.ReplaceAllString(value, ""))
+}
+
+func slotString(slots map[string]interface{}, name string) string {
+	switch value := slots[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return strings.TrimSpace(fmt.Sprint(value))
+	}
+}
+
+// parsePeso convierte "500 kg", "4,5 toneladas" o "2000" a kilogramos
+func parsePeso(value string) (int, error) {
+	lower := strings.ToLower(value)
+	number := numberInput.FindString(lower)
+	if number == "" {
+		return 0, fmt.Errorf("%w: el peso debe ser numérico", ErrIncompleteManifest)
+	}
+	peso, err := strconv.ParseFloat(strings.Replace(number, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: el peso debe ser numérico", ErrIncompleteManifest)
+	}
+	if strings.Contains(lower, "ton") || strings.HasSuffix(strings.TrimSpace(lower), "t") {
+		peso *= 1000
+	}
+	kilos := int(math.Round(peso))
+	if kilos < 1 {
+		return 0, fmt.Errorf("%w: el peso debe ser mayor a cero", ErrIncompleteManifest)
+	}
+	return kilos, nil
+}
+
+func parseSlotDate(value string) (time.Time, bool) {
+	for _, layout := range slotDates {
+		if parsed, err := time.ParseInLocation(layout, value, colombiaLocation()); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func colombiaLocation() *time.Location {
+	if location, err := time.LoadLocation("America/Bogota"); err == nil {
+		return location
+	}
+	return time.FixedZone("COT", -5*60*60)
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+)
+
+// Prefijos de los IDs de plantilla: un manifiesto anterior o una ruta guardada
+const (
+	TemplateSourceDocument = "document"
+	TemplateSourceRoute    = "route"
+)
+
+// MaxRecentManifests limita cuántos manifiestos anteriores se ofrecen como plantilla
+const MaxRecentManifests = 10
+
+var ErrTemplateNotFound = errors.New("plantilla de manifiesto no encontrada")
+
+// ManifestTemplateDocuments es la parte del repositorio de Mongo que usan las plantillas
+type ManifestTemplateDocuments interface {
+	GetDocumentsByClientID(ctx context.Context, clientID uint, orgID uint) ([]models.Document, error)
+	GetDocumentByID(ctx context.Context, documentID string, orgID uint) (*models.Document, error)
+}
+
+// ManifestTemplate son los datos de un manifiesto que se pueden repetir. Las fechas de
+// cargue y descargue no se copian: cambian en cada viaje.
+type ManifestTemplate struct {
+	ID         string                 `json:"id"` // document:<id> o route:<id>
+	Source     string                 `json:"source"`
+	Name       string                 `json:"name"`
+	Slots      map[string]interface{} `json:"slots"`
+	LastUsedAt time.Time              `json:"last_used_at"`
+}
+
+// ManifestTemplateService arma plantillas con los manifiestos anteriores del cliente y sus
+// rutas guardadas, y genera manifiestos nuevos a partir de ellas
+type ManifestTemplateService struct {
+	documents    ManifestTemplateDocuments
+	routes       repositories.RouteTemplateRepository
+	orchestrator *ManifestOrchestrator
+}
+
+func NewManifestTemplateService(documents ManifestTemplateDocuments, routes repositories.RouteTemplateRepository, orchestrator *ManifestOrchestrator) *ManifestTemplateService {
+	return &ManifestTemplateService{documents: documents, routes: routes, orchestrator: orchestrator}
+}
+
+// Routes expone el repositorio de rutas guardadas para el CRUD de los controladores
+func (s *ManifestTemplateService) Routes() repositories.RouteTemplateRepository {
+	return s.routes
+}
+
+// Recent devuelve los últimos manifiestos completados del cliente como plantillas, del más
+// reciente al más antiguo y sin repetir la misma ruta y carga
+func (s *ManifestTemplateService) Recent(ctx context.Context, orgID, clientID uint, limit int) ([]ManifestTemplate, error) {
+	if limit <= 0 || limit > MaxRecentManifests {
+		limit = MaxRecentManifests
+	}
+
+	documents, err := s.documents.GetDocumentsByClientID(ctx, clientID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].CreatedAt.After(documents[j].CreatedAt) })
+
+	templates := []ManifestTemplate{}
+	seen := map[string]bool{}
+	for _, document := range documents {
+		if document.Type != DocumentTypeManifiesto || document.Status != models.DocumentStatusCompleted {
+			continue
+		}
+		template := documentTemplate(document)
+		key := slotsKey(template.Slots)
+		if len(template.Slots) == 0 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		templates = append(templates, template)
+		if len(templates) == limit {
+			break
+		}
+	}
+	return templates, nil
+}
+
+// Saved devuelve las rutas guardadas del cliente como plantillas
+func (s *ManifestTemplateService) Saved(orgID, clientID uint) ([]ManifestTemplate, error) {
+	routes, err := s.routes.ListByClient(clientID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]ManifestTemplate, 0, len(routes))
+	for _, route := range routes {
+		templates = append(templates, routeTemplate(route))
+	}
+	return templates, nil
+}
+
+// Get busca una plantilla del cliente por su ID (document:<id> o route:<id>)
+func (s *ManifestTemplateService) Get(ctx context.Context, orgID, clientID uint, templateID string) (*ManifestTemplate, error) {
+	source, id, _ := strings.Cut(templateID, ":")
+	switch source {
+	case TemplateSourceDocument:
+		if !primitive.IsValidObjectID(id) {
+			return nil, ErrTemplateNotFound
+		}
+		document, err := s.documents.GetDocumentByID(ctx, id, orgID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrTemplateNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if document.ClientID != clientID || document.Type != DocumentTypeManifiesto {
+			return nil, ErrTemplateNotFound
+		}
+		template := documentTemplate(*document)
+		return &template, nil
+	case TemplateSourceRoute:
+		routeID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, ErrTemplateNotFound
+		}
+		route, err := s.routes.GetByID(uint(routeID), clientID, orgID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		template := routeTemplate(*route)
+		return &template, nil
+	}
+	return nil, ErrTemplateNotFound
+}
+
+// Repeat genera un manifiesto nuevo con los datos de la plantilla y los campos que el
+// usuario confirmó o cambió (overrides; un valor vacío quita el de la plantilla)
+func (s *ManifestTemplateService) Repeat(ctx context.Context, target ChatTarget, templateID string, overrides map[string]interface{}) (*models.Document, error) {
+	template, err := s.Get(ctx, target.OrganizationID, target.ClientID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make(map[string]interface{}, len(template.Slots)+len(overrides))
+	for name, value := range template.Slots {
+		slots[name] = value
+	}
+	for name, value := range overrides {
+		if slotString(overrides, name) == "" {
+			delete(slots, name)
+			continue
+		}
+		slots[name] = value
+	}
+
+	return s.orchestrator.Start(ctx, ManifestJob{ChatTarget: target, Slots: slots, RepeatedFrom: template.ID})
+}
+
+func documentTemplate(document models.Document) ManifestTemplate {
+	slots := map[string]interface{}{}
+	for _, name := range manifestSlots {
+		if value := slotString(document.Entities, name); value != "" {
+			slots[name] = value
+		}
+	}
+	return ManifestTemplate{
+		ID:         TemplateSourceDocument + ":" + document.ID.Hex(),
+		Source:     TemplateSourceDocument,
+		Name:       templateName(slots),
+		Slots:      slots,
+		LastUsedAt: document.CreatedAt,
+	}
+}
+
+func routeTemplate(route models.RouteTemplate) ManifestTemplate {
+	return ManifestTemplate{
+		ID:         fmt.Sprintf("%s:%d", TemplateSourceRoute, route.ID),
+		Source:     TemplateSourceRoute,
+		Name:       route.Name,
+		Slots:      route.Slots(),
+		LastUsedAt: route.UpdatedAt,
+	}
+}
+
+// templateName resume la plantilla para mostrarla en el chat: "Bogotá → Medellín (Cajas)"
+func templateName(slots map[string]interface{}) string {
+	name := fmt.Sprintf("%s → %s", slotString(slots, "origen"), slotString(slots, "destino"))
+	if descripcion := slotString(slots, "descripcion"); descripcion != "" {
+		name += " (" + descripcion + ")"
+	}
+	return name
+}
+
+// slotsKey identifica la combinación de datos de una plantilla para no repetirla
+func slotsKey(slots map[string]interface{}) string {
+	parts := make([]string, 0, len(manifestSlots))
+	for _, name := range manifestSlots {
+		parts = append(parts, strings.ToLower(slotString(slots, name)))
+	}
+	return strings.Join(parts, "|")
+}
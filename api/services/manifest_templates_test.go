@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+)
+
+func TestRepeatManifestFromTemplate(t *testing.T) {
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+
+	repo := &memoryDocumentRepo{}
+	routes := &mocks.MockRouteTemplateRepo{}
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: fake.URL}), testStorage(t), &recordingSender{}, testManifestConfig())
+	templates := NewManifestTemplateService(repo, routes, orchestrator)
+
+	// Dos viajes iguales y uno a Cali: las plantillas no repiten la misma ruta y carga
+	for _, destino := range []string{"Medellín", "Medellín", "Cali"} {
+		job := testManifestJob()
+		job.Slots["destino"] = destino
+		_, err := orchestrator.Start(context.Background(), job)
+		require.NoError(t, err)
+		orchestrator.Wait()
+	}
+
+	recent, err := templates.Recent(context.Background(), 3, 7, 3)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	names := []string{recent[0].Name, recent[1].Name}
+	assert.ElementsMatch(t, []string{"Bogotá → Medellín (Cajas de repuestos)", "Bogotá → Cali (Cajas de repuestos)"}, names)
+	assert.NotContains(t, recent[0].Slots, "fecha_cargue", "las fechas se piden en cada viaje")
+
+	// Otro cliente u organización no ve las plantillas
+	other, err := templates.Recent(context.Background(), 4, 7, 3)
+	require.NoError(t, err)
+	assert.Empty(t, other)
+
+	// Repetir con cambios: nuevas fechas y otra placa
+	document, err := templates.Repeat(context.Background(), testManifestJob().ChatTarget, recent[0].ID, map[string]interface{}{
+		"fecha_cargue":    "25/10/2026",
+		"fecha_descargue": "26/10/2026",
+		"tarjeta":         "xyz-789",
+	})
+	require.NoError(t, err)
+	orchestrator.Wait()
+	stored := repo.get(document.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCompleted, stored.Status)
+	assert.Equal(t, recent[0].ID, stored.Metadata["repeated_from"])
+	assert.Equal(t, "xyz-789", stored.Entities["tarjeta"])
+	assert.Equal(t, recent[0].Slots["destino"], stored.Entities["destino"])
+
+	// Ruta guardada: si le falta un dato obligatorio el manifiesto queda incompleto
+	route := models.RouteTemplate{OrganizationID: 3, ClientID: 7, Name: "Puerto", Origen: "Bogotá", Destino: "Buenaventura", Descripcion: "Sacos de café", Peso: "20 toneladas", Flete: "3000000"}
+	require.NoError(t, routes.Create(&route))
+	_, err = templates.Repeat(context.Background(), testManifestJob().ChatTarget, fmt.Sprintf("route:%d", route.ID), nil)
+	assert.ErrorIs(t, err, ErrIncompleteManifest)
+
+	_, err = templates.Repeat(context.Background(), testManifestJob().ChatTarget, fmt.Sprintf("route:%d", route.ID), map[string]interface{}{
+		"tarjeta":  "ABC123",
+		"licencia": "1020304050",
+	})
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	_, err = templates.Get(context.Background(), 3, 8, fmt.Sprintf("route:%d", route.ID))
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	_, err = templates.Get(context.Background(), 3, 7, "document:nope")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+)
+
+// mongoTenantCollections son las colecciones de Mongo que se filtran por organización
+var mongoTenantCollections = []string{"messages", "documents", "chat_modes", "chat_archives"}
+
+// BackfillMongoOrganizations asigna organization_id a los documentos de Mongo que no lo
+// tienen, usando la organización del cliente en Postgres. Es idempotente: solo toca
+// documentos sin organización. Los que apuntan a clientes inexistentes se reportan y
+// quedan sin organización, por lo que ninguna consulta los devuelve.
+func BackfillMongoOrganizations(ctx context.Context, mongoDB *mongo.Database, db *gorm.DB) error {
+	missing := bson.M{"$or": []bson.M{
+		{"organization_id": bson.M{"$exists": false}},
+		{"organization_id": 0},
+	}}
+
+	for _, name := range mongoTenantCollections {
+		collection := mongoDB.Collection(name)
+
+		rawIDs, err := collection.Distinct(ctx, "client_id", missing)
+		if err != nil {
+			return err
+		}
+		if len(rawIDs) == 0 {
+			continue
+		}
+
+		clientIDs := make([]uint, 0, len(rawIDs))
+		for _, raw := range rawIDs {
+			if id, ok := toUint(raw); ok {
+				clientIDs = append(clientIDs, id)
+			}
+		}
+
+		var clients []models.Client
+		if len(clientIDs) > 0 {
+			if err := db.Select("id", "organization_id").Where("id IN ?", clientIDs).Find(&clients).Error; err != nil {
+				return err
+			}
+		}
+
+		var updated int64
+		for _, client := range clients {
+			filter := bson.M{"client_id": client.ID, "$or": missing["$or"]}
+			result, err := collection.UpdateMany(ctx, filter, bson.M{
+				"$set": bson.M{"organization_id": client.OrganizationID},
+			})
+			if err != nil {
+				return err
+			}
+			updated += result.ModifiedCount
+		}
+
+		log.Printf("🏢 %s: %d documentos asignados a su organización", name, updated)
+		if orphans := len(rawIDs) - len(clients); orphans > 0 {
+			log.Printf("⚠️  %s: %d clientes sin registro en Postgres, sus documentos quedan sin organización", name, orphans)
+		}
+	}
+	return nil
+}
+
+// toUint convierte los enteros que devuelve Mongo (int32/int64) a uint
+func toUint(value interface{}) (uint, bool) {
+	switch v := value.(type) {
+	case int32:
+		return uint(v), v > 0
+	case int64:
+		return uint(v), v > 0
+	case float64:
+		return uint(v), v > 0
+	}
+	return 0, false
+}
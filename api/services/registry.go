@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/rndc"
+)
+
+var (
+	ErrRegistryInvalid  = errors.New("datos del registro inválidos")
+	ErrRegistryConflict = errors.New("ya existe en el registro de la organización")
+)
+
+// RegistryService administra los vehículos y conductores de cada organización y los usa
+// para precargar el formulario del manifiesto
+type RegistryService struct {
+	vehicles repositories.VehicleRepository
+	drivers  repositories.DriverRepository
+	clients  repositories.ClientRepository
+}
+
+func NewRegistryService(vehicles repositories.VehicleRepository, drivers repositories.DriverRepository, clients repositories.ClientRepository) *RegistryService {
+	return &RegistryService{vehicles: vehicles, drivers: drivers, clients: clients}
+}
+
+// ManifestPrefill son los datos registrados del chat que se pueden usar en el manifiesto
+type ManifestPrefill struct {
+	Driver  *models.Driver    `json:"driver,omitempty"`
+	Vehicle *models.Vehicle   `json:"vehicle,omitempty"`
+	Slots   map[string]string `json:"slots"` // Slots de manifiesto_form que ya no hay que preguntar
+}
+
+// SaveVehicle valida y guarda (crea si no tiene ID) un vehículo de la organización
+func (s *RegistryService) SaveVehicle(vehicle *models.Vehicle) error {
+	vehicle.Placa = rndc.NormalizePlaca(vehicle.Placa)
+	vehicle.Configuracion = strings.ToUpper(strings.TrimSpace(vehicle.Configuracion))
+	vehicle.OwnerNumeroID = rndc.NormalizeNIT(vehicle.OwnerNumeroID)
+	vehicle.TarjetaPropiedad = strings.ToUpper(strings.TrimSpace(vehicle.TarjetaPropiedad))
+
+	var errs rndc.ValidationErrors
+	errs.Add("placa", rndc.ValidatePlaca(vehicle.Placa))
+	errs.Add("configuracion", rndc.ValidateConfiguracion(vehicle.Configuracion))
+	errs.Add("owner_tipo_id", rndc.ValidateTipoIDTitular(vehicle.OwnerTipoID))
+	if vehicle.OwnerNumeroID != "" {
+		errs.Add("owner_numero_id", rndc.ValidateIdentificacion(vehicle.OwnerTipoID, vehicle.OwnerNumeroID))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrRegistryInvalid, errs)
+	}
+
+	existing, err := s.vehicles.GetByPlaca(vehicle.Placa, vehicle.OrganizationID)
+	if err == nil && existing.ID != vehicle.ID {
+		return fmt.Errorf("%w: la placa %s", ErrRegistryConflict, vehicle.Placa)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if vehicle.ID == 0 {
+		return s.vehicles.Create(vehicle)
+	}
+	return s.vehicles.Update(vehicle)
+}
+
+// SaveDriver valida y guarda (crea si no tiene ID) un conductor de la organización y lo
+// enlaza con el cliente de WhatsApp que tenga su teléfono
+func (s *RegistryService) SaveDriver(driver *models.Driver) error {
+	driver.Cedula = strings.NewReplacer(".", "", " ", "").Replace(driver.Cedula)
+	driver.LicenseCategory = strings.ToUpper(strings.TrimSpace(driver.LicenseCategory))
+	driver.Phone = normalizePhone(driver.Phone)
+
+	var errs rndc.ValidationErrors
+	errs.Add("cedula", rndc.ValidateLicencia(driver.Cedula))
+	errs.Add("tipo_id", rndc.ValidateTipoIDConductor(driver.TipoID))
+	errs.Add("license_category", rndc.ValidateCategoriaLicencia(driver.LicenseCategory))
+	if driver.Phone != "" && (len(driver.Phone) < 10 || len(driver.Phone) > 15) {
+		errs.Add("phone", errors.New("el teléfono debe tener entre 10 y 15 dígitos"))
+	}
+	if driver.VehicleID != nil {
+		if _, err := s.vehicles.GetByID(*driver.VehicleID, driver.OrganizationID); err != nil {
+			errs.Add("vehicle_id", errors.New("el vehículo no está registrado en la organización"))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrRegistryInvalid, errs)
+	}
+
+	existing, err := s.drivers.GetByCedula(driver.Cedula, driver.OrganizationID)
+	if err == nil && existing.ID != driver.ID {
+		return fmt.Errorf("%w: la cédula %s", ErrRegistryConflict, driver.Cedula)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	driver.ClientID = nil
+	if driver.Phone != "" {
+		if client, err := s.clients.GetClientByPhone(driver.Phone, driver.OrganizationID); err == nil {
+			driver.ClientID = &client.ID
+		}
+	}
+
+	if driver.ID == 0 {
+		return s.drivers.Create(driver)
+	}
+	return s.drivers.Update(driver)
+}
+
+// Prefill busca el conductor del chat (por cliente o teléfono) y su vehículo habitual.
+// Si el conductor se encontró por teléfono queda enlazado al cliente.
+func (s *RegistryService) Prefill(orgID, clientID uint, phone string) (*ManifestPrefill, error) {
+	prefill := &ManifestPrefill{Slots: map[string]string{}}
+
+	driver, err := s.drivers.GetByClient(clientID, normalizePhone(phone), orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return prefill, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefill.Driver = driver
+	prefill.Slots["licencia"] = driver.Cedula
+
+	if driver.ClientID == nil && clientID != 0 {
+		driver.ClientID = &clientID
+		if err := s.drivers.Update(driver); err != nil {
+			log.Printf("⚠️ No se pudo enlazar el conductor %d con el cliente %d: %v", driver.ID, clientID, err)
+		}
+	}
+
+	if driver.VehicleID != nil {
+		vehicle, err := s.vehicles.GetByID(*driver.VehicleID, orgID)
+		if err == nil {
+			prefill.Vehicle = vehicle
+			prefill.Slots["tarjeta"] = vehicle.Placa
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return prefill, nil
+}
+
+// FillManifestSlots devuelve una copia de los slots con la placa (tarjeta) y la licencia
+// del registro cuando el formulario no las trae
+func (s *RegistryService) FillManifestSlots(orgID, clientID uint, chatID string, slots map[string]interface{}) map[string]interface{} {
+	prefill, err := s.Prefill(orgID, clientID, chatID)
+	if err != nil {
+		log.Printf("⚠️ No se pudo consultar el registro para el chat %s: %v", chatID, err)
+		return slots
+	}
+
+	filled := make(map[string]interface{}, len(slots)+len(prefill.Slots))
+	for key, value := range slots {
+		filled[key] = value
+	}
+	for key, value := range prefill.Slots {
+		if slotString(filled, key) == "" {
+			filled[key] = value
+		}
+	}
+	return filled
+}
+
+// ResolveManifest devuelve los IDs del vehículo y el conductor registrados con los datos
+// del manifiesto (0 si no están registrados)
+func (s *RegistryService) ResolveManifest(orgID uint, request rndc.ManifiestoRequest) (vehicleID, driverID uint) {
+	if vehicle, err := s.vehicles.GetByPlaca(request.Manifiesto.PlacaVehiculo, orgID); err == nil {
+		vehicleID = vehicle.ID
+	}
+	if driver, err := s.drivers.GetByCedula(request.Manifiesto.ConductorNumeroID, orgID); err == nil {
+		driverID = driver.ID
+	}
+	return vehicleID, driverID
+}
+
+// Vehicles expone el repositorio para las consultas de los controladores
+func (s *RegistryService) Vehicles() repositories.VehicleRepository {
+	return s.vehicles
+}
+
+// Drivers expone el repositorio para las consultas de los controladores
+func (s *RegistryService) Drivers() repositories.DriverRepository {
+	return s.drivers
+}
+
+// normalizePhone deja solo los dígitos con el indicativo de Colombia, como llegan los
+// números de WhatsApp: "300 123 4567" → "573001234567"
+func normalizePhone(phone string) string {
+	digits := nonDigits.ReplaceAllString(strings.Split(phone, "@")[0], "")
+	if len(digits) == 10 && strings.HasPrefix(digits, "3") {
+		return "57" + digits
+	}
+	return digits
+}
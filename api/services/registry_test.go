@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/playwright"
+	"github.com/brando1998/docubot-api/rndc"
+)
+
+func newTestRegistry() (*RegistryService, *mocks.MockVehicleRepo, *mocks.MockDriverRepo) {
+	drivers := &mocks.MockDriverRepo{}
+	vehicles := &mocks.MockVehicleRepo{Drivers: drivers}
+	clients := &mocks.MockClientRepo{
+		GetClientByPhoneFunc: func(phone string, orgID uint) (*models.Client, error) {
+			if phone == "573009999999" && orgID == 3 {
+				return &models.Client{ID: 7, Phone: phone}, nil
+			}
+			return nil, gorm.ErrRecordNotFound
+		},
+	}
+	return NewRegistryService(vehicles, drivers, clients), vehicles, drivers
+}
+
+func TestRegistryValidation(t *testing.T) {
+	registry, _, _ := newTestRegistry()
+
+	vehicle := models.Vehicle{OrganizationID: 3, Placa: "abc-123", Configuracion: "3s2", OwnerTipoID: "Nit", OwnerNumeroID: "860.053.746-3"}
+	require.NoError(t, registry.SaveVehicle(&vehicle))
+	assert.Equal(t, "ABC123", vehicle.Placa)
+	assert.Equal(t, "3S2", vehicle.Configuracion)
+	assert.Equal(t, "8600537463", vehicle.OwnerNumeroID)
+
+	duplicate := models.Vehicle{OrganizationID: 3, Placa: "ABC 123"}
+	assert.ErrorIs(t, registry.SaveVehicle(&duplicate), ErrRegistryConflict)
+	// La misma placa sí se puede registrar en otra organización
+	other := models.Vehicle{OrganizationID: 4, Placa: "ABC123"}
+	assert.NoError(t, registry.SaveVehicle(&other))
+
+	invalid := models.Vehicle{OrganizationID: 3, Placa: "AB1234", Configuracion: "9", OwnerTipoID: "Nit", OwnerNumeroID: "8600537464"}
+	err := registry.SaveVehicle(&invalid)
+	require.ErrorIs(t, err, ErrRegistryInvalid)
+	var fields rndc.ValidationErrors
+	require.ErrorAs(t, err, &fields)
+	assert.Len(t, fields, 3)
+
+	missing := uint(99)
+	driver := models.Driver{OrganizationID: 3, Cedula: "12AB", LicenseCategory: "Z9", VehicleID: &missing}
+	err = registry.SaveDriver(&driver)
+	require.ErrorAs(t, err, &fields)
+	assert.Len(t, fields, 3)
+}
+
+func TestRegistryPrefillsManifest(t *testing.T) {
+	registry, _, drivers := newTestRegistry()
+
+	vehicle := models.Vehicle{OrganizationID: 3, Placa: "XYZ789"}
+	require.NoError(t, registry.SaveVehicle(&vehicle))
+	driver := models.Driver{OrganizationID: 3, Cedula: "1.020.304.050", Name: "Ana", Phone: "300 999 9999", VehicleID: &vehicle.ID}
+	require.NoError(t, registry.SaveDriver(&driver))
+	assert.Equal(t, "1020304050", driver.Cedula)
+	assert.Equal(t, "573009999999", driver.Phone)
+	require.NotNil(t, driver.ClientID, "el teléfono debe enlazar al cliente de WhatsApp")
+	assert.Equal(t, uint(7), *driver.ClientID)
+
+	prefill, err := registry.Prefill(3, 7, "573009999999@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"licencia": "1020304050", "tarjeta": "XYZ789"}, prefill.Slots)
+
+	// Otra organización no ve el conductor
+	prefill, err = registry.Prefill(4, 7, "573009999999@s.whatsapp.net")
+	require.NoError(t, err)
+	assert.Empty(t, prefill.Slots)
+
+	fake := playwright.NewFakeServer()
+	defer fake.Close()
+	repo := &memoryDocumentRepo{}
+	orchestrator := NewManifestOrchestrator(repo, playwright.NewClient(playwright.Config{BaseURL: fake.URL}), testStorage(t), &recordingSender{}, testManifestConfig())
+	orchestrator.SetRegistry(registry)
+
+	job := testManifestJob()
+	delete(job.Slots, "tarjeta")
+	delete(job.Slots, "licencia")
+	document, err := orchestrator.Start(context.Background(), job)
+	require.NoError(t, err)
+	orchestrator.Wait()
+
+	stored := repo.get(document.ID.Hex())
+	assert.Equal(t, models.DocumentStatusCompleted, stored.Status)
+	assert.Equal(t, vehicle.ID, stored.VehicleID)
+	assert.Equal(t, driver.ID, stored.DriverID)
+	assert.Equal(t, "XYZ789", stored.Entities["tarjeta"])
+	_, hasTarjeta := job.Slots["tarjeta"]
+	assert.False(t, hasTarjeta, "los slots del job no se modifican")
+
+	// Al borrar el vehículo el conductor queda sin vehículo habitual
+	require.NoError(t, registry.Vehicles().Delete(vehicle.ID, 3))
+	stillDriver, err := drivers.GetByID(driver.ID, 3)
+	require.NoError(t, err)
+	assert.Nil(t, stillDriver.VehicleID)
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+var (
+	ErrRoleInvalid  = errors.New("datos del rol inválidos")
+	ErrRoleConflict = errors.New("ya existe un rol con ese nombre")
+	ErrRoleInUse    = errors.New("el rol está asignado a usuarios")
+	// ErrPermissionEscalation evita que un usuario otorgue permisos que no tiene o
+	// administre a alguien con más permisos que él
+	ErrPermissionEscalation = errors.New("no puedes otorgar ni administrar permisos que no tienes")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,29}$`)
+
+// RoleService resuelve los permisos de los roles predefinidos y de los roles
+// personalizados de cada organización
+type RoleService struct {
+	roles repositories.RoleRepository
+	users repositories.SystemUserRepository
+}
+
+func NewRoleService(roles repositories.RoleRepository, users repositories.SystemUserRepository) *RoleService {
+	return &RoleService{roles: roles, users: users}
+}
+
+// RoleInfo describe un rol asignable de la organización
+type RoleInfo struct {
+	ID          uint     `json:"id,omitempty"` // Solo los roles personalizados
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"built_in"`
+}
+
+// RoleInput son los datos editables de un rol personalizado
+type RoleInput struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// Permissions devuelve los permisos del rol; un rol que ya no existe no tiene permisos
+func (s *RoleService) Permissions(orgID uint, role string) ([]string, error) {
+	if role == models.RoleLegacyUser {
+		role = models.RoleAgent
+	}
+	if permissions, ok := models.BuiltInRoles[role]; ok {
+		return permissions, nil
+	}
+	custom, err := s.roles.GetByName(role, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return custom.Permissions, nil
+}
+
+// Exists indica si el rol se puede asignar en la organización
+func (s *RoleService) Exists(orgID uint, role string) (bool, error) {
+	if models.IsBuiltInRole(role) {
+		return true, nil
+	}
+	_, err := s.roles.GetByName(role, orgID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RolesWith devuelve los nombres de los roles de la organización que incluyen el permiso
+func (s *RoleService) RolesWith(orgID uint, permission string) ([]string, error) {
+	roles, err := s.List(orgID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, role := range roles {
+		if models.HasPermission(role.Permissions, permission) {
+			names = append(names, role.Name)
+		}
+	}
+	return names, nil
+}
+
+// List devuelve los roles predefinidos seguidos de los personalizados
+func (s *RoleService) List(orgID uint) ([]RoleInfo, error) {
+	custom, err := s.roles.List(orgID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]RoleInfo, 0, len(models.BuiltInRoles)+len(custom))
+	for _, name := range []string{models.RoleOwner, models.RoleAdmin, models.RoleAgent, models.RoleViewer} {
+		roles = append(roles, RoleInfo{Name: name, Permissions: models.BuiltInRoles[name], BuiltIn: true})
+	}
+	for _, role := range custom {
+		roles = append(roles, RoleInfo{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: role.Permissions,
+		})
+	}
+	return roles, nil
+}
+
+// Create registra un rol personalizado; actor solo puede otorgar permisos que tiene
+func (s *RoleService) Create(actor models.SystemUser, input RoleInput) (*models.Role, error) {
+	input.Name = strings.ToLower(strings.TrimSpace(input.Name))
+	input.Description = strings.TrimSpace(input.Description)
+	input.Permissions = models.NormalizePermissions(input.Permissions)
+
+	errs := validateRoleInput(input)
+	if !roleNamePattern.MatchString(input.Name) {
+		errs.Add("name", errors.New("de 2 a 30 caracteres: minúsculas, números, guion o guion bajo"))
+	} else if models.IsBuiltInRole(input.Name) || input.Name == models.RoleLegacyUser {
+		errs.Add("name", fmt.Errorf("%s es un rol predefinido", input.Name))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrRoleInvalid, errs)
+	}
+	if err := s.checkGrant(actor, input.Permissions); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.roles.GetByName(input.Name, actor.OrganizationID); err == nil {
+		return nil, ErrRoleConflict
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	role := &models.Role{
+		OrganizationID: actor.OrganizationID,
+		Name:           input.Name,
+		Description:    input.Description,
+		Permissions:    input.Permissions,
+	}
+	if err := s.roles.Create(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// Update cambia la descripción y los permisos de un rol personalizado. Los usuarios que
+// lo tienen reciben los permisos nuevos en su siguiente petición.
+func (s *RoleService) Update(actor models.SystemUser, id uint, input RoleInput) (*models.Role, error) {
+	input.Description = strings.TrimSpace(input.Description)
+	input.Permissions = models.NormalizePermissions(input.Permissions)
+	if errs := validateRoleInput(input); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrRoleInvalid, errs)
+	}
+
+	role, err := s.roles.GetByID(id, actor.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrant(actor, append(append([]string{}, role.Permissions...), input.Permissions...)); err != nil {
+		return nil, err
+	}
+	if models.HasPermission(role.Permissions, models.PermissionUsersManage) &&
+		!models.HasPermission(input.Permissions, models.PermissionUsersManage) {
+		if err := s.checkOtherManagers(actor.OrganizationID, role.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	role.Description = input.Description
+	role.Permissions = input.Permissions
+	if err := s.roles.Update(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// Delete elimina un rol personalizado que no tenga usuarios asignados
+func (s *RoleService) Delete(actor models.SystemUser, id uint) error {
+	role, err := s.roles.GetByID(id, actor.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkGrant(actor, role.Permissions); err != nil {
+		return err
+	}
+	assigned, err := s.users.CountByRole(actor.OrganizationID, role.Name)
+	if err != nil {
+		return err
+	}
+	if assigned > 0 {
+		return fmt.Errorf("%w: %d usuarios tienen el rol %s", ErrRoleInUse, assigned, role.Name)
+	}
+	return s.roles.Delete(role.ID, role.OrganizationID)
+}
+
+// checkGrant falla si permissions incluye alguno que actor no tiene
+func (s *RoleService) checkGrant(actor models.SystemUser, permissions []string) error {
+	own, err := s.Permissions(actor.OrganizationID, actor.Role)
+	if err != nil {
+		return err
+	}
+	if !models.IncludesPermissions(own, permissions) {
+		return ErrPermissionEscalation
+	}
+	return nil
+}
+
+// checkOtherManagers falla si, sin el rol excluded, nadie activo podría administrar usuarios
+func (s *RoleService) checkOtherManagers(orgID uint, excluded string) error {
+	managers, err := s.RolesWith(orgID, models.PermissionUsersManage)
+	if err != nil {
+		return err
+	}
+	var others []string
+	for _, name := range managers {
+		if name != excluded {
+			others = append(others, name)
+		}
+	}
+	count, err := s.users.CountActive(orgID, others)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func validateRoleInput(input RoleInput) validation.Errors {
+	var errs validation.Errors
+	if utf8.RuneCountInString(input.Description) > 200 {
+		errs.Add("description", errors.New("máximo 200 caracteres"))
+	}
+	if len(input.Permissions) == 0 {
+		errs.Add("permissions", errors.New("el rol debe tener al menos un permiso"))
+	}
+	for _, permission := range input.Permissions {
+		if !models.IsValidPermission(permission) {
+			errs.Add("permissions", fmt.Errorf("permiso desconocido: %s", permission))
+		}
+	}
+	return errs
+}
+
+// ownerPromotionMigration marca la promoción única de un owner por organización
+const ownerPromotionMigration = "roles-owner-promotion"
+
+// MigrateLegacyRoles pasa los usuarios con el rol anterior "user" a agent; es idempotente y
+// se ejecuta en cada arranque. La primera vez también promueve a owner al administrador
+// activo más antiguo de cada organización sin owner activo. Esa promoción queda registrada
+// y no se repite: después, quitar el último owner es una decisión de la organización.
+func MigrateLegacyRoles(db *gorm.DB) error {
+	result := db.Model(&models.SystemUser{}).
+		Where("role = ? OR role = ''", models.RoleLegacyUser).
+		Update("role", models.RoleAgent)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("👤 %d usuarios con el rol user pasaron a agent", result.RowsAffected)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var applied int64
+		if err := tx.Model(&models.DataMigration{}).Where("name = ?", ownerPromotionMigration).Count(&applied).Error; err != nil {
+			return err
+		}
+		if applied > 0 {
+			return nil
+		}
+		if err := promoteLegacyOwners(tx); err != nil {
+			return err
+		}
+		return tx.Create(&models.DataMigration{Name: ownerPromotionMigration, AppliedAt: time.Now()}).Error
+	})
+}
+
+// promoteLegacyOwners promueve a owner al administrador activo más antiguo de cada
+// organización sin owner activo
+func promoteLegacyOwners(db *gorm.DB) error {
+	var orgIDs []uint
+	err := db.Model(&models.SystemUser{}).
+		Where("role = ? AND is_active = ?", models.RoleAdmin, true).
+		Where("organization_id NOT IN (?)", db.Model(&models.SystemUser{}).
+			Select("organization_id").
+			Where("role = ? AND is_active = ?", models.RoleOwner, true)).
+		Distinct().Pluck("organization_id", &orgIDs).Error
+	if err != nil {
+		return err
+	}
+	for _, orgID := range orgIDs {
+		var admin models.SystemUser
+		err := db.Where("organization_id = ? AND role = ? AND is_active = ?", orgID, models.RoleAdmin, true).
+			Order("created_at, id").First(&admin).Error
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&admin).Update("role", models.RoleOwner).Error; err != nil {
+			return err
+		}
+		log.Printf("👑 %s es ahora owner de la organización %d", admin.Username, orgID)
+	}
+	return nil
+}
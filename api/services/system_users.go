@@ -0,0 +1,316 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/repositories"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+var (
+	ErrSystemUserInvalid  = errors.New("datos del usuario inválidos")
+	ErrSystemUserConflict = errors.New("el username o el email ya están en uso")
+	// ErrSystemUserSelf evita que un administrador se desactive, se elimine o se cambie el rol a sí mismo
+	ErrSystemUserSelf = errors.New("no puedes hacer este cambio sobre tu propio usuario")
+	// ErrLastAdmin evita que una organización se quede sin usuarios activos que administren usuarios
+	ErrLastAdmin = errors.New("la organización debe tener al menos un usuario activo que administre usuarios")
+)
+
+const minPasswordLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,50}$`)
+
+// SystemUserService administra los usuarios del dashboard de cada organización. Todas las
+// operaciones reciben al administrador autenticado (actor): se limitan a su organización y
+// no puede asignar ni administrar roles con permisos que él no tiene.
+type SystemUserService struct {
+	users repositories.SystemUserRepository
+	roles *RoleService
+}
+
+func NewSystemUserService(users repositories.SystemUserRepository, roles *RoleService) *SystemUserService {
+	return &SystemUserService{users: users, roles: roles}
+}
+
+// SystemUserInput son los datos editables de un usuario
+type SystemUserInput struct {
+	Username string
+	Email    string
+	Role     string
+}
+
+func (s *SystemUserService) List(orgID uint) ([]models.SystemUser, error) {
+	return s.users.List(orgID)
+}
+
+func (s *SystemUserService) Get(id, orgID uint) (*models.SystemUser, error) {
+	return s.users.GetByID(id, orgID)
+}
+
+// Create registra un usuario activo en la organización del actor
+func (s *SystemUserService) Create(actor models.SystemUser, input SystemUserInput, password string) (*models.SystemUser, error) {
+	input = normalizeSystemUserInput(input)
+	errs, err := s.validateInput(actor.OrganizationID, input)
+	if err != nil {
+		return nil, err
+	}
+	errs.Add("password", validatePassword(password))
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrSystemUserInvalid, errs)
+	}
+	if err := s.checkGrant(actor, input.Role); err != nil {
+		return nil, err
+	}
+	if err := s.checkAvailable(0, input); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.SystemUser{
+		OrganizationID: actor.OrganizationID,
+		Username:       input.Username,
+		Email:          input.Email,
+		PasswordHash:   string(hash),
+		Role:           input.Role,
+		IsActive:       true,
+	}
+	if err := s.users.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Update cambia el username, el email y el rol; actor es el administrador que hace el cambio
+func (s *SystemUserService) Update(actor models.SystemUser, id uint, input SystemUserInput) (*models.SystemUser, error) {
+	input = normalizeSystemUserInput(input)
+	errs, err := s.validateInput(actor.OrganizationID, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrSystemUserInvalid, errs)
+	}
+
+	user, err := s.manageable(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != input.Role {
+		if user.ID == actor.ID {
+			return nil, ErrSystemUserSelf
+		}
+		if err := s.checkGrant(actor, input.Role); err != nil {
+			return nil, err
+		}
+		if err := s.checkRemainingManagers(user, input.Role); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkAvailable(user.ID, input); err != nil {
+		return nil, err
+	}
+
+	user.Username = input.Username
+	user.Email = input.Email
+	user.Role = input.Role
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetActive activa o desactiva un usuario. Un usuario inactivo no puede iniciar sesión y
+// sus tokens dejan de ser aceptados.
+func (s *SystemUserService) SetActive(actor models.SystemUser, id uint, active bool) (*models.SystemUser, error) {
+	user, err := s.manageable(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsActive == active {
+		return user, nil
+	}
+	if !active {
+		if user.ID == actor.ID {
+			return nil, ErrSystemUserSelf
+		}
+		if err := s.checkRemainingManagers(user, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	user.IsActive = active
+	if !active {
+		user.TokenVersion++ // Invalida sus access tokens aunque lo reactiven después
+	}
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetPassword asigna una nueva contraseña; si password viene vacía genera una temporal.
+// Devuelve la contraseña asignada para que el administrador se la comparta al usuario.
+func (s *SystemUserService) ResetPassword(actor models.SystemUser, id uint, password string) (string, error) {
+	user, err := s.manageable(actor, id)
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		if password, err = temporaryPassword(); err != nil {
+			return "", err
+		}
+	} else if err := validatePassword(password); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSystemUserInvalid, validation.Errors{{Field: "password", Message: err.Error()}})
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	user.PasswordHash = string(hash)
+	user.TokenVersion++ // Cierra las sesiones abiertas con la contraseña anterior
+	if err := s.users.Update(user); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// Delete elimina un usuario de la organización (borrado lógico)
+func (s *SystemUserService) Delete(actor models.SystemUser, id uint) error {
+	user, err := s.manageable(actor, id)
+	if err != nil {
+		return err
+	}
+	if user.ID == actor.ID {
+		return ErrSystemUserSelf
+	}
+	if err := s.checkRemainingManagers(user, ""); err != nil {
+		return err
+	}
+	return s.users.Delete(user.ID, user.OrganizationID)
+}
+
+// checkAvailable revisa que el username y el email no los use otro usuario (de cualquier
+// organización, incluidos los eliminados)
+func (s *SystemUserService) checkAvailable(id uint, input SystemUserInput) error {
+	existing, err := s.users.GetByLogin(input.Username, input.Email)
+	if err == nil && existing.ID != id {
+		return ErrSystemUserConflict
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// manageable carga un usuario de la organización del actor y revisa que el actor tenga
+// todos los permisos de su rol (un admin no administra a un owner)
+func (s *SystemUserService) manageable(actor models.SystemUser, id uint) (*models.SystemUser, error) {
+	user, err := s.users.GetByID(id, actor.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGrant(actor, user.Role); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkGrant falla si el rol tiene permisos que el actor no tiene
+func (s *SystemUserService) checkGrant(actor models.SystemUser, role string) error {
+	permissions, err := s.roles.Permissions(actor.OrganizationID, role)
+	if err != nil {
+		return err
+	}
+	return s.roles.checkGrant(actor, permissions)
+}
+
+// checkRemainingManagers falla si user es el último usuario activo que administra
+// usuarios y dejaría de hacerlo: al desactivarlo o eliminarlo (newRole vacío) o al
+// cambiarle el rol por newRole
+func (s *SystemUserService) checkRemainingManagers(user *models.SystemUser, newRole string) error {
+	if !user.IsActive {
+		return nil
+	}
+	current, err := s.roles.Permissions(user.OrganizationID, user.Role)
+	if err != nil || !models.HasPermission(current, models.PermissionUsersManage) {
+		return err
+	}
+	if newRole != "" {
+		next, err := s.roles.Permissions(user.OrganizationID, newRole)
+		if err != nil || models.HasPermission(next, models.PermissionUsersManage) {
+			return err
+		}
+	}
+
+	managers, err := s.roles.RolesWith(user.OrganizationID, models.PermissionUsersManage)
+	if err != nil {
+		return err
+	}
+	count, err := s.users.CountActive(user.OrganizationID, managers)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func normalizeSystemUserInput(input SystemUserInput) SystemUserInput {
+	input.Username = strings.TrimSpace(input.Username)
+	input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+	input.Role = strings.ToLower(strings.TrimSpace(input.Role))
+	if input.Role == "" || input.Role == models.RoleLegacyUser {
+		input.Role = models.RoleAgent
+	}
+	return input
+}
+
+// validateInput revisa los datos y que el rol exista en la organización
+func (s *SystemUserService) validateInput(orgID uint, input SystemUserInput) (validation.Errors, error) {
+	var errs validation.Errors
+	if !usernamePattern.MatchString(input.Username) {
+		errs.Add("username", errors.New("de 3 a 50 caracteres: letras, números, punto, guion o guion bajo"))
+	}
+	if address, err := mail.ParseAddress(input.Email); err != nil || address.Address != input.Email {
+		errs.Add("email", errors.New("email inválido"))
+	}
+	exists, err := s.roles.Exists(orgID, input.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		errs.Add("role", fmt.Errorf("rol desconocido: %s", input.Role))
+	}
+	return errs, nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("la contraseña debe tener al menos %d caracteres", minPasswordLength)
+	}
+	return nil
+}
+
+// temporaryPassword genera una contraseña aleatoria de 16 caracteres
+func temporaryPassword() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/brando1998/docubot-api/mocks"
+	"github.com/brando1998/docubot-api/models"
+	"github.com/brando1998/docubot-api/validation"
+)
+
+func newTestSystemUsers(t *testing.T) (*SystemUserService, *RoleService, *mocks.MockSystemUserRepo, models.SystemUser) {
+	repo := &mocks.MockSystemUserRepo{}
+	roles := NewRoleService(&mocks.MockRoleRepo{}, repo)
+	owner := models.SystemUser{OrganizationID: 1, Username: "ana", Email: "ana@example.com", Role: models.RoleOwner, IsActive: true}
+	require.NoError(t, repo.Create(&owner))
+	return NewSystemUserService(repo, roles), roles, repo, owner
+}
+
+func TestSystemUserManagement(t *testing.T) {
+	users, _, _, owner := newTestSystemUsers(t)
+
+	admin, err := users.Create(owner, SystemUserInput{Username: "luis", Email: "Luis@Example.com", Role: "admin"}, "secreto123")
+	require.NoError(t, err)
+	assert.Equal(t, "luis@example.com", admin.Email)
+	assert.True(t, admin.IsActive)
+
+	agent, err := users.Create(*admin, SystemUserInput{Username: "eva", Email: "eva@example.com"}, "secreto123")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAgent, agent.Role)
+
+	_, err = users.Create(models.SystemUser{OrganizationID: 2, Role: models.RoleOwner}, SystemUserInput{Username: "eva", Email: "otra@example.com"}, "secreto123")
+	assert.ErrorIs(t, err, ErrSystemUserConflict, "el username es único entre organizaciones")
+
+	_, err = users.Create(owner, SystemUserInput{Username: "x", Email: "no-es-email", Role: "root"}, "corta")
+	require.ErrorIs(t, err, ErrSystemUserInvalid)
+	var fields validation.Errors
+	require.ErrorAs(t, err, &fields)
+	assert.Len(t, fields, 4)
+
+	// Otra organización no ve ni modifica los usuarios
+	_, err = users.Get(agent.ID, 2)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	outsider := models.SystemUser{ID: 99, OrganizationID: 2, Role: models.RoleOwner}
+	_, err = users.SetActive(outsider, agent.ID, false)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Un admin no crea owners ni administra al owner
+	_, err = users.Create(*admin, SystemUserInput{Username: "jefe", Email: "jefe@example.com", Role: "owner"}, "secreto123")
+	assert.ErrorIs(t, err, ErrPermissionEscalation)
+	_, err = users.SetActive(*admin, owner.ID, false)
+	assert.ErrorIs(t, err, ErrPermissionEscalation)
+	_, err = users.ResetPassword(*admin, owner.ID, "")
+	assert.ErrorIs(t, err, ErrPermissionEscalation)
+
+	// Nadie se desactiva, se elimina ni se cambia el rol a sí mismo
+	_, err = users.SetActive(owner, owner.ID, false)
+	assert.ErrorIs(t, err, ErrSystemUserSelf)
+	_, err = users.Update(owner, owner.ID, SystemUserInput{Username: "ana", Email: "ana@example.com", Role: "viewer"})
+	assert.ErrorIs(t, err, ErrSystemUserSelf)
+	assert.ErrorIs(t, users.Delete(owner, owner.ID), ErrSystemUserSelf)
+
+	deactivated, err := users.SetActive(owner, admin.ID, false)
+	require.NoError(t, err)
+	assert.False(t, deactivated.IsActive)
+
+	password, err := users.ResetPassword(owner, agent.ID, "")
+	require.NoError(t, err)
+	stored, err := users.Get(agent.ID, 1)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte(password)))
+	_, err = users.ResetPassword(owner, agent.ID, "corta")
+	assert.ErrorIs(t, err, ErrSystemUserInvalid)
+
+	require.NoError(t, users.Delete(owner, agent.ID))
+	_, err = users.Create(owner, SystemUserInput{Username: "eva", Email: "nueva@example.com"}, "secreto123")
+	assert.ErrorIs(t, err, ErrSystemUserConflict, "los usuarios eliminados siguen ocupando su username")
+}
+
+func TestCustomRoles(t *testing.T) {
+	users, roles, repo, owner := newTestSystemUsers(t)
+
+	_, err := roles.Create(owner, RoleInput{Name: "admin", Permissions: []string{"chats:read"}})
+	assert.ErrorIs(t, err, ErrRoleInvalid, "no puede usar el nombre de un rol predefinido")
+	_, err = roles.Create(owner, RoleInput{Name: "despacho", Permissions: []string{"chats:fly"}})
+	assert.ErrorIs(t, err, ErrRoleInvalid)
+
+	role, err := roles.Create(owner, RoleInput{
+		Name:        "Despacho",
+		Permissions: []string{"documents:manage", "chats:read", "documents:read", "chats:read"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "despacho", role.Name)
+	assert.Equal(t, []string{"chats:read", "documents:manage", "documents:read"}, role.Permissions)
+	_, err = roles.Create(owner, RoleInput{Name: "despacho", Permissions: []string{"chats:read"}})
+	assert.ErrorIs(t, err, ErrRoleConflict)
+
+	// El rol solo existe en su organización
+	exists, err := roles.Exists(2, "despacho")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	dispatcher, err := users.Create(owner, SystemUserInput{Username: "pedro", Email: "pedro@example.com", Role: "despacho"}, "secreto123")
+	require.NoError(t, err)
+	permissions, err := roles.Permissions(1, dispatcher.Role)
+	require.NoError(t, err)
+	assert.True(t, models.HasPermission(permissions, models.PermissionDocumentsManage))
+	assert.False(t, models.HasPermission(permissions, models.PermissionWhatsAppSend))
+
+	legacy, err := roles.Permissions(1, models.RoleLegacyUser)
+	require.NoError(t, err)
+	assert.Equal(t, models.BuiltInRoles[models.RoleAgent], legacy)
+
+	// Un admin no otorga permisos que no tiene
+	admin := models.SystemUser{OrganizationID: 1, Role: models.RoleAdmin}
+	_, err = roles.Update(admin, role.ID, RoleInput{Permissions: []string{"roles:manage"}})
+	assert.ErrorIs(t, err, ErrPermissionEscalation)
+
+	assert.ErrorIs(t, roles.Delete(owner, role.ID), ErrRoleInUse)
+	require.NoError(t, users.Delete(owner, dispatcher.ID))
+	assert.NoError(t, roles.Delete(owner, role.ID))
+
+	// Quitar users:manage del rol del único administrador dejaría la organización sin gestión
+	boss := models.SystemUser{OrganizationID: 3, Role: models.RoleOwner}
+	chief, err := roles.Create(boss, RoleInput{Name: "jefe", Permissions: []string{"users:manage", "roles:manage"}})
+	require.NoError(t, err)
+	onlyManager := models.SystemUser{OrganizationID: 3, Username: "jefa", Email: "jefa@example.com", Role: "jefe", IsActive: true}
+	require.NoError(t, repo.Create(&onlyManager))
+	_, err = roles.Update(onlyManager, chief.ID, RoleInput{Permissions: []string{"roles:manage"}})
+	assert.ErrorIs(t, err, ErrLastAdmin)
+}
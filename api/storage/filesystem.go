@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// filesystemStorage guarda los archivos en {root}/{clave}
+type filesystemStorage struct {
+	root string
+}
+
+// NewFilesystem crea el backend en disco local; crea la carpeta si no existe
+func NewFilesystem(root string) (Storage, error) {
+	if root == "" {
+		return nil, errors.New("la carpeta de documentos es requerida")
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("error creando carpeta de documentos: %w", err)
+	}
+	return &filesystemStorage{root: root}, nil
+}
+
+func (s *filesystemStorage) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put escribe en un temporal y lo renombra para no dejar archivos a medias
+func (s *filesystemStorage) Put(ctx context.Context, key string, content []byte, contentType string) (*Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("error creando carpeta: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creando archivo temporal: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No hace nada después del rename
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("error guardando %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("error guardando %s: %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o640); err != nil {
+		return nil, fmt.Errorf("error guardando %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("error guardando %s: %w", key, err)
+	}
+	return newObject(key, content, contentType), nil
+}
+
+func (s *filesystemStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return file, err
+}
+
+func (s *filesystemStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
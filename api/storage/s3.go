@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash es el SHA-256 de un cuerpo vacío (GET y DELETE)
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config configura el backend compatible con S3
+type S3Config struct {
+	Endpoint  string // p. ej. https://s3.us-east-1.amazonaws.com o http://minio:9000
+	Region    string // Por defecto us-east-1 (MinIO lo acepta)
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // Prefijo opcional de las claves dentro del bucket
+	PathStyle bool   // http://endpoint/bucket/clave en lugar de http://bucket.endpoint/clave (MinIO)
+	Timeout   time.Duration
+}
+
+// s3Storage habla la API REST de S3 con firma AWS Signature V4
+type s3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	http     *http.Client
+	now      func() time.Time
+}
+
+// NewS3 crea el backend S3/MinIO
+func NewS3(config S3Config) (Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY y S3_SECRET_KEY son requeridos")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT inválido: %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Minute
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+
+	return &s3Storage{
+		config:   config,
+		endpoint: endpoint,
+		http:     &http.Client{Timeout: config.Timeout},
+		now:      time.Now,
+	}, nil
+}
+
+// Put sube el archivo firmando el SHA-256 del cuerpo: S3 rechaza el objeto si llega distinto
+func (s *s3Storage) Put(ctx context.Context, key string, content []byte, contentType string) (*Object, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	object := newObject(key, content, contentType)
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(content))
+	req.Header.Set("Content-Type", object.ContentType)
+	req.Header.Set("X-Amz-Meta-Sha256", object.Checksum)
+
+	resp, err := s.do(req, object.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return object, nil
+}
+
+func (s *s3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// newRequest arma la URL del objeto (path-style o virtual-hosted)
+func (s *s3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if s.config.Prefix != "" {
+		key = s.config.Prefix + "/" + key
+	}
+
+	target := *s.endpoint
+	if s.config.PathStyle {
+		target.Path = target.Path + "/" + s.config.Bucket + "/" + key
+	} else {
+		target.Host = s.config.Bucket + "." + target.Host
+		target.Path = target.Path + "/" + key
+	}
+	target.RawPath = awsURIEncode(target.Path, false)
+
+	return http.NewRequestWithContext(ctx, method, target.String(), body)
+}
+
+// do firma y envía la solicitud; devuelve la respuesta solo si es 2xx
+func (s *s3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signV4(req, payloadHash, s.config.AccessKey, s.config.SecretKey, s.config.Region, s.now())
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error conectando con S3: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3Err)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, req.URL.Path)
+	}
+	if s3Err.Code == "" {
+		s3Err.Code = resp.Status
+	}
+	return nil, fmt.Errorf("S3 respondió %s a %s %s: %s", s3Err.Code, req.Method, req.URL.Path, s3Err.Message)
+}
+
+// signV4 agrega los encabezados x-amz-* y Authorization de AWS Signature Version 4.
+// Se firman todos los encabezados de la solicitud más host.
+func signV4(req *http.Request, payloadHash, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		sorted := append([]string(nil), values[key]...)
+		sort.Strings(sorted)
+		for _, value := range sorted {
+			parts = append(parts, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode codifica como exige SigV4: solo A-Z, a-z, 0-9, '-', '.', '_' y '~' quedan igual
+func awsURIEncode(value string, encodeSlash bool) string {
+	var out strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '.', b == '_', b == '~':
+			out.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			out.WriteByte(b)
+		default:
+			fmt.Fprintf(&out, "%%%02X", b)
+		}
+	}
+	return out.String()
+}
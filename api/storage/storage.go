@@ -0,0 +1,129 @@
+// Package storage guarda los archivos de los documentos generados (PDF de manifiestos,
+// certificados...) fuera de los contenedores que los producen. Hay un backend en disco
+// local y uno compatible con S3 (AWS S3, MinIO).
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// MaxFileSize es el tamaño máximo de archivo que se lee a memoria
+const MaxFileSize = 50 << 20
+
+var (
+	// ErrNotFound indica que no hay un archivo con esa clave
+	ErrNotFound = errors.New("archivo no encontrado")
+	// ErrChecksumMismatch indica que el contenido leído no coincide con el checksum guardado
+	ErrChecksumMismatch = errors.New("el checksum del archivo no coincide")
+	// ErrInvalidKey indica una clave vacía o que intenta salir del almacenamiento
+	ErrInvalidKey = errors.New("clave de archivo inválida")
+)
+
+// Object describe un archivo guardado
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	Checksum    string // SHA-256 en hexadecimal
+}
+
+// Storage guarda archivos por clave (p. ej. "3/6710f0c2a1b2c3d4e5f60718.pdf")
+type Storage interface {
+	Put(ctx context.Context, key string, content []byte, contentType string) (*Object, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selecciona y configura el backend
+type Config struct {
+	Driver string // "filesystem" (por defecto) o "s3"
+	Dir    string // Carpeta raíz del backend filesystem
+	S3     S3Config
+}
+
+// New crea el backend indicado en la configuración
+func New(config Config) (Storage, error) {
+	switch strings.ToLower(config.Driver) {
+	case "", "filesystem", "local":
+		return NewFilesystem(config.Dir)
+	case "s3", "minio":
+		return NewS3(config.S3)
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER desconocido: %q", config.Driver)
+	}
+}
+
+// Checksum calcula el SHA-256 del contenido en hexadecimal
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// DetectContentType detecta el tipo por el contenido y, si no es concluyente, por la extensión
+func DetectContentType(fileName string, content []byte) string {
+	detected := http.DetectContentType(content)
+	if detected != "application/octet-stream" && !strings.HasPrefix(detected, "text/plain") {
+		return detected
+	}
+	if byExtension := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))); byExtension != "" {
+		return byExtension
+	}
+	return detected
+}
+
+// Read lee el archivo completo y, si se indica checksum, verifica que no haya cambiado
+func Read(ctx context.Context, s Storage, key string, checksum string) ([]byte, error) {
+	reader, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo %s: %w", key, err)
+	}
+	if len(content) > MaxFileSize {
+		return nil, fmt.Errorf("el archivo %s supera el tamaño máximo (%d bytes)", key, MaxFileSize)
+	}
+	if checksum != "" && !strings.EqualFold(Checksum(content), checksum) {
+		return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, key)
+	}
+	return content, nil
+}
+
+// cleanKey normaliza la clave y rechaza rutas absolutas o con ".."
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(strings.ReplaceAll(key, "\\", "/"), "/")
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return key, nil
+}
+
+// newObject arma el Object de un contenido que se va a guardar
+func newObject(key string, content []byte, contentType string) *Object {
+	if contentType == "" {
+		contentType = DetectContentType(key, content)
+	}
+	return &Object{
+		Key:         key,
+		Size:        int64(len(content)),
+		ContentType: contentType,
+		Checksum:    Checksum(content),
+	}
+}
@@ -0,0 +1,266 @@
+// Package tokens emite y verifica los access tokens del dashboard: PASETO v4.public
+// firmados con Ed25519. El footer lleva el ID de la llave (kid) con que se firmó, así se
+// puede rotar la llave sin invalidar los tokens vigentes y otros servicios (acciones de
+// Rasa, playwright-bot) pueden verificarlos solo con la llave pública.
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// Issuer es el claim "iss" de los tokens emitidos por la API
+const Issuer = "docubot-api"
+
+var (
+	ErrInvalidToken = errors.New("token inválido")
+	ErrExpiredToken = errors.New("token expirado")
+	// ErrNoSigningKey indica que el Manager solo verifica (no tiene la llave privada)
+	ErrNoSigningKey = errors.New("no hay llave de firma configurada")
+	// ErrNoKeys evita arrancar sin llaves fuera del modo desarrollo
+	ErrNoKeys = errors.New("configura TOKEN_SIGNING_KEY y TOKEN_SIGNING_KEY_ID para emitir tokens")
+)
+
+// Claims son los datos del usuario que viajan en el access token
+type Claims struct {
+	UserID       uint      `json:"user_id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	TokenVersion uint      `json:"token_version"` // Debe coincidir con SystemUser.TokenVersion
+	IssuedAt     time.Time `json:"issued_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Config son las llaves del Manager. Las llaves van en hexadecimal.
+type Config struct {
+	KeyID      string // kid de la llave de firma
+	PrivateKey string // Ed25519: semilla (32 bytes) o llave privada completa (64 bytes)
+	// VerificationKeys son llaves públicas anteriores que se siguen aceptando durante una
+	// rotación, en formato "kid:publica,kid:publica"
+	VerificationKeys string
+	// AllowEphemeral genera una llave temporal si no hay ninguna (solo desarrollo: los
+	// tokens dejan de servir al reiniciar)
+	AllowEphemeral bool
+}
+
+// PublicKey es una llave de verificación publicada para otros servicios
+type PublicKey struct {
+	KeyID     string `json:"kid"`
+	Version   string `json:"version"`
+	PublicKey string `json:"public_key"` // Ed25519 en hexadecimal
+}
+
+type footer struct {
+	KeyID string `json:"kid"`
+}
+
+// Manager firma tokens con la llave actual y verifica con cualquiera de las llaves activas
+type Manager struct {
+	keyID   string
+	signing *paseto.V4AsymmetricSecretKey
+	keys    map[string]paseto.V4AsymmetricPublicKey
+	now     func() time.Time
+}
+
+// New carga las llaves. Sin llave privada el Manager solo verifica; sin ninguna llave
+// falla, salvo con AllowEphemeral.
+func New(config Config) (*Manager, error) {
+	m := &Manager{keys: map[string]paseto.V4AsymmetricPublicKey{}, now: time.Now}
+
+	verification, err := ParseVerificationKeys(config.VerificationKeys)
+	if err != nil {
+		return nil, err
+	}
+	for kid, key := range verification {
+		m.keys[kid] = key
+	}
+
+	privateKey := strings.TrimSpace(config.PrivateKey)
+	keyID := strings.TrimSpace(config.KeyID)
+	switch {
+	case privateKey != "":
+		if keyID == "" {
+			return nil, errors.New("TOKEN_SIGNING_KEY_ID es obligatorio junto con la llave de firma")
+		}
+		key, err := parsePrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.setSigningKey(keyID, key); err != nil {
+			return nil, err
+		}
+	case len(m.keys) > 0:
+		// Solo verificación
+	case config.AllowEphemeral:
+		log.Println("⚠️  Sin llave de firma de tokens: se usa una temporal (solo desarrollo)")
+		if err := m.setSigningKey("dev-"+strconv.FormatInt(time.Now().Unix(), 10), paseto.NewV4AsymmetricSecretKey()); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrNoKeys
+	}
+	return m, nil
+}
+
+func (m *Manager) setSigningKey(keyID string, key paseto.V4AsymmetricSecretKey) error {
+	public := key.Public()
+	if existing, ok := m.keys[keyID]; ok && existing.ExportHex() != public.ExportHex() {
+		return fmt.Errorf("el kid %q ya está asignado a otra llave de verificación", keyID)
+	}
+	m.keyID = keyID
+	m.signing = &key
+	m.keys[keyID] = public
+	return nil
+}
+
+// Issue firma un token con la llave actual; devuelve el token y su vencimiento
+func (m *Manager) Issue(claims Claims, ttl time.Duration) (string, time.Time, error) {
+	if m.signing == nil {
+		return "", time.Time{}, ErrNoSigningKey
+	}
+	now := m.now()
+	expiresAt := now.Add(ttl)
+
+	token := paseto.NewToken()
+	token.SetIssuer(Issuer)
+	token.SetSubject(strconv.FormatUint(uint64(claims.UserID), 10))
+	token.SetIssuedAt(now)
+	token.SetNotBefore(now)
+	token.SetExpiration(expiresAt)
+	token.SetString("username", claims.Username)
+	token.SetString("role", claims.Role)
+	if err := token.Set("token_version", claims.TokenVersion); err != nil {
+		return "", time.Time{}, err
+	}
+	rawFooter, err := json.Marshal(footer{KeyID: m.keyID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token.SetFooter(rawFooter)
+
+	return token.V4Sign(*m.signing, nil), expiresAt, nil
+}
+
+// Verify valida la firma con la llave indicada en el footer y devuelve los claims
+func (m *Manager) Verify(raw string) (*Claims, error) {
+	parser := paseto.NewParserWithoutExpiryCheck()
+	rawFooter, err := parser.UnsafeParseFooter(paseto.V4Public, raw)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var f footer
+	if err := json.Unmarshal(rawFooter, &f); err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, ok := m.keys[f.KeyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	parser.AddRule(paseto.IssuedBy(Issuer))
+	token, err := parser.ParseV4Public(key, raw, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims, err := claimsFromToken(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !m.now().Before(claims.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+// PublicKeys devuelve las llaves de verificación activas (la de firma primero)
+func (m *Manager) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, 0, len(m.keys))
+	if key, ok := m.keys[m.keyID]; ok {
+		keys = append(keys, PublicKey{KeyID: m.keyID, Version: "v4.public", PublicKey: key.ExportHex()})
+	}
+	for kid, key := range m.keys {
+		if kid != m.keyID {
+			keys = append(keys, PublicKey{KeyID: kid, Version: "v4.public", PublicKey: key.ExportHex()})
+		}
+	}
+	return keys
+}
+
+func claimsFromToken(token *paseto.Token) (*Claims, error) {
+	subject, err := token.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	userID, err := strconv.ParseUint(subject, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{UserID: uint(userID)}
+	if claims.Username, err = token.GetString("username"); err != nil {
+		return nil, err
+	}
+	if claims.Role, err = token.GetString("role"); err != nil {
+		return nil, err
+	}
+	if err := token.Get("token_version", &claims.TokenVersion); err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt, err = token.GetIssuedAt(); err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt, err = token.GetExpiration(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseVerificationKeys lee una lista "kid:publica,kid:publica" de llaves públicas en hexadecimal
+func ParseVerificationKeys(list string) (map[string]paseto.V4AsymmetricPublicKey, error) {
+	keys := map[string]paseto.V4AsymmetricPublicKey{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, hexKey, ok := strings.Cut(entry, ":")
+		kid = strings.TrimSpace(kid)
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("llave de verificación inválida %q: se espera kid:llave", entry)
+		}
+		key, err := paseto.NewV4AsymmetricPublicKeyFromHex(strings.TrimSpace(hexKey))
+		if err != nil {
+			return nil, fmt.Errorf("llave de verificación %q: %w", kid, err)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+func parsePrivateKey(hexKey string) (paseto.V4AsymmetricSecretKey, error) {
+	var (
+		key paseto.V4AsymmetricSecretKey
+		err error
+	)
+	if len(hexKey) == 64 {
+		key, err = paseto.NewV4AsymmetricSecretKeyFromSeed(hexKey)
+	} else {
+		key, err = paseto.NewV4AsymmetricSecretKeyFromHex(hexKey)
+	}
+	if err != nil {
+		return key, fmt.Errorf("llave de firma inválida: %w", err)
+	}
+	return key, nil
+}
+
+// GenerateKey crea un par de llaves nuevo: la semilla privada y la llave pública en hexadecimal
+func GenerateKey() (privateKey, publicKey string) {
+	key := paseto.NewV4AsymmetricSecretKey()
+	return key.ExportSeedHex(), key.Public().ExportHex()
+}
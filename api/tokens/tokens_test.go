@@ -0,0 +1,80 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	private, public := GenerateKey()
+	manager, err := New(Config{KeyID: "2026-10", PrivateKey: private})
+	require.NoError(t, err)
+
+	token, expiresAt, err := manager.Issue(Claims{UserID: 7, Username: "ana", Role: "owner", TokenVersion: 2}, 15*time.Minute)
+	require.NoError(t, err)
+	assert.Contains(t, token, "v4.public.")
+
+	claims, err := manager.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), claims.UserID)
+	assert.Equal(t, "owner", claims.Role)
+	assert.Equal(t, uint(2), claims.TokenVersion)
+	assert.WithinDuration(t, expiresAt, claims.ExpiresAt, time.Second)
+
+	// Otro servicio verifica solo con la llave pública
+	verifier, err := New(Config{VerificationKeys: "2026-10:" + public})
+	require.NoError(t, err)
+	_, err = verifier.Verify(token)
+	assert.NoError(t, err)
+	_, _, err = verifier.Issue(Claims{UserID: 7}, time.Minute)
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+
+	_, err = manager.Verify(token[:len(token)-4] + "AAAA")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	manager.now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, err = manager.Verify(token)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := GenerateKey()
+	old, err := New(Config{KeyID: "k1", PrivateKey: oldPrivate})
+	require.NoError(t, err)
+	oldToken, _, err := old.Issue(Claims{UserID: 1, Username: "ana", Role: "owner"}, time.Hour)
+	require.NoError(t, err)
+
+	newPrivate, _ := GenerateKey()
+	rotated, err := New(Config{KeyID: "k2", PrivateKey: newPrivate, VerificationKeys: "k1:" + oldPublic})
+	require.NoError(t, err)
+	_, err = rotated.Verify(oldToken)
+	assert.NoError(t, err, "los tokens de la llave anterior siguen sirviendo")
+	assert.Len(t, rotated.PublicKeys(), 2)
+	assert.Equal(t, "k2", rotated.PublicKeys()[0].KeyID)
+
+	// Al retirar la llave anterior sus tokens dejan de aceptarse
+	retired, err := New(Config{KeyID: "k2", PrivateKey: newPrivate})
+	require.NoError(t, err)
+	_, err = retired.Verify(oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewRequiresKeys(t *testing.T) {
+	_, err := New(Config{})
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	dev, err := New(Config{AllowEphemeral: true})
+	require.NoError(t, err)
+	token, _, err := dev.Issue(Claims{UserID: 1}, time.Minute)
+	require.NoError(t, err)
+	_, err = dev.Verify(token)
+	assert.NoError(t, err)
+
+	_, err = New(Config{PrivateKey: "abcd"})
+	assert.Error(t, err)
+	_, err = New(Config{VerificationKeys: "sin-llave"})
+	assert.Error(t, err)
+}
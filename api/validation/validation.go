@@ -0,0 +1,29 @@
+// Package validation define los errores de validación por campo que la API devuelve en
+// las respuestas 400 (usuarios, roles, API keys y las reglas del RNDC).
+package validation
+
+import "strings"
+
+// FieldError es un error de validación de un campo (ruta JSON, p. ej. "manifiesto.placaVehiculo")
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors reúne todos los errores de una solicitud
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Field + ": " + fieldError.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add agrega el error del campo si err no es nil
+func (e *Errors) Add(field string, err error) {
+	if err != nil {
+		*e = append(*e, FieldError{Field: field, Message: err.Error()})
+	}
+}
@@ -1,19 +1,58 @@
 import { WebSocket } from 'ws';
 
+// Mensajes enviados al backend por id, para reenviarlos si los rechaza por saturación
+const recentIncoming = new Map<string, { frame: Record<string, string>; retries: number }>();
+const MAX_RECENT_INCOMING = 1000;
+const MAX_INCOMING_RETRIES = 5;
+
 export const handleIncomingMessage = async (
     from: string,
     text: string,
     botNumber: string,
     backendWS: WebSocket,
-    messageType?: string
+    messageType?: string,
+    id?: string
 ) => {
-    // Enviar mensaje al backend Go
-    backendWS.send(JSON.stringify({
+    const frame: Record<string, string> = {
         phone: from,
         message: text,
         botNumber,
         messageType: messageType || 'text'
-    }));
+    };
+    if (id) {
+        frame.id = id;
+        recentIncoming.set(id, { frame, retries: 0 });
+        if (recentIncoming.size > MAX_RECENT_INCOMING) {
+            // Map conserva el orden de inserción: se descarta el más antiguo
+            const oldest = recentIncoming.keys().next().value;
+            if (oldest !== undefined) {
+                recentIncoming.delete(oldest);
+            }
+        }
+    }
+
+    // Enviar mensaje al backend Go
+    backendWS.send(JSON.stringify(frame));
 
     console.log(`Mensaje enviado al backend: ${from} - ${text} (tipo: ${messageType || 'text'})`);
-};
\ No newline at end of file
+};
+
+// Reenvía con espera creciente un mensaje que el backend rechazó (incoming_nack)
+export const retryIncomingMessage = (id: string, backendWS: WebSocket, reason?: string) => {
+    const entry = recentIncoming.get(id);
+    if (!entry) return;
+    if (entry.retries >= MAX_INCOMING_RETRIES) {
+        console.error(`❌ Mensaje ${id} descartado tras ${entry.retries} reintentos: ${reason || 'rechazado por el backend'}`);
+        recentIncoming.delete(id);
+        return;
+    }
+
+    entry.retries++;
+    const delay = 1000 * 2 ** (entry.retries - 1);
+    console.warn(`⏳ Backend saturado, reenviando mensaje ${id} en ${delay / 1000}s (${entry.retries}/${MAX_INCOMING_RETRIES})`);
+    setTimeout(() => {
+        if (backendWS.readyState === backendWS.OPEN) {
+            backendWS.send(JSON.stringify(entry.frame));
+        }
+    }, delay);
+};
@@ -0,0 +1,82 @@
+import type { AnyMessageContent } from '@whiskeysockets/baileys';
+
+// Mensaje que envía el backend Go por el WebSocket (OutboundMessage en controllers/websocket_hub.go)
+export interface BackendMessage {
+    id?: string;
+    to?: string;
+    message?: string;
+    sessionId?: string;
+    messageType?: 'text' | 'image' | 'document' | 'buttons' | 'location';
+    media?: {
+        url?: string;
+        data?: string; // Contenido en base64 (documentos generados por la API)
+        fileName?: string;
+        mimeType?: string;
+        caption?: string;
+    };
+    buttons?: { title: string; payload: string }[];
+    location?: {
+        latitude: number;
+        longitude: number;
+        name?: string;
+        address?: string;
+    };
+}
+
+// Origen del archivo: URL que Baileys descarga o contenido en base64
+const mediaSource = (media: BackendMessage['media']): { url: string } | Buffer => {
+    if (media?.url) return { url: media.url };
+    if (media?.data) return Buffer.from(media.data, 'base64');
+    throw new Error('media.url o media.data es requerido');
+};
+
+// Traduce el mensaje del backend al contenido de Baileys; lanza un error si está incompleto
+export const buildMessageContent = (message: BackendMessage): AnyMessageContent => {
+    if (!message.to) {
+        throw new Error('to es requerido');
+    }
+
+    switch (message.messageType || 'text') {
+        case 'image':
+            return {
+                image: mediaSource(message.media),
+                ...(message.media?.caption ? { caption: message.media.caption } : {})
+            };
+
+        case 'document':
+            return {
+                document: mediaSource(message.media),
+                mimetype: message.media?.mimeType || 'application/octet-stream',
+                fileName: message.media?.fileName || 'documento',
+                ...(message.media?.caption ? { caption: message.media.caption } : {})
+            };
+
+        case 'location':
+            if (!message.location) throw new Error('location es requerido');
+            return {
+                location: {
+                    degreesLatitude: message.location.latitude,
+                    degreesLongitude: message.location.longitude,
+                    ...(message.location.name ? { name: message.location.name } : {}),
+                    ...(message.location.address ? { address: message.location.address } : {})
+                }
+            };
+
+        case 'buttons':
+            if (!message.buttons?.length) throw new Error('buttons es requerido');
+            // La respuesta llega como buttonsResponseMessage con el payload en selectedButtonId
+            return {
+                text: message.message || ' ',
+                buttons: message.buttons.map((button) => ({
+                    buttonId: button.payload,
+                    buttonText: { displayText: button.title },
+                    type: 1
+                })),
+                headerType: 1
+            } as AnyMessageContent;
+
+        default:
+            if (!message.message) throw new Error('message es requerido');
+            return { text: message.message };
+    }
+};
@@ -72,7 +72,10 @@ app.post('/sessions/:sessionId', async (req, res) => {
             });
         }
 
-        const session = await sessionManager.createSession(sessionId);
+        // Credencial emitida por el backend para autenticarse en /ws
+        const wsToken = typeof req.body?.ws_token === 'string' ? req.body.ws_token : '';
+
+        const session = await sessionManager.createSession(sessionId, wsToken);
         
         res.json({
             success: true,
@@ -217,6 +220,40 @@ app.post('/sessions/:sessionId/restart', async (req, res) => {
     }
 });
 
+// Actualizar la credencial de /ws de una sesión (rotación desde el backend)
+app.put('/sessions/:sessionId/ws-token', async (req, res) => {
+    try {
+        const { sessionId } = req.params;
+        const wsToken = req.body?.ws_token;
+
+        if (typeof wsToken !== 'string' || wsToken === '') {
+            return res.status(400).json({
+                error: 'ws_token es requerido'
+            });
+        }
+
+        if (!sessionManager.getSession(sessionId)) {
+            return res.status(404).json({
+                error: 'Sesión no encontrada'
+            });
+        }
+
+        await sessionManager.updateWsToken(sessionId, wsToken);
+
+        res.json({
+            success: true,
+            message: 'Credencial actualizada correctamente',
+            session_id: sessionId
+        });
+    } catch (error: any) {
+        console.error('Error actualizando credencial:', error);
+        res.status(500).json({
+            error: 'Error actualizando credencial',
+            details: error.message
+        });
+    }
+});
+
 // Eliminar una sesión específica
 app.delete('/sessions/:sessionId', async (req, res) => {
     try {
@@ -1,17 +1,21 @@
 // src/managers/SessionManager.ts
 import { makeWASocket, fetchLatestBaileysVersion, DisconnectReason } from "@whiskeysockets/baileys";
-import type { WASocket } from "@whiskeysockets/baileys";
+import type { WASocket, AnyMessageContent } from "@whiskeysockets/baileys";
 import { Boom } from "@hapi/boom";
 import P from "pino";
 import QRCode from "qrcode";
 import { getAuthState, clearAuthState } from "../sessions/auth.js";
 import type { SessionData, SessionConfig, SessionStatus } from "../types/session.types.js";
 import { connectToBackendWS } from "../websocket/client.js";
+import { buildMessageContent } from "../handlers/outboundMessage.js";
 
 export class SessionManager {
     private sessions: Map<string, SessionData>;
     private config: SessionConfig;
     private cleanupInterval?: NodeJS.Timeout;
+    // IDs de envelopes ya enviados, para no duplicar mensajes cuando el backend reintenta
+    private deliveredMessages: Set<string> = new Set();
+    private static readonly MAX_DELIVERED_IDS = 1000;
 
     constructor(config: Partial<SessionConfig> = {}) {
         this.sessions = new Map();
@@ -49,7 +53,7 @@ export class SessionManager {
         }
     }
 
-    async createSession(sessionId: string): Promise<SessionData> {
+    async createSession(sessionId: string, wsToken: string = ''): Promise<SessionData> {
         if (this.sessions.size >= this.config.maxSessions) {
             throw new Error(`Límite de sesiones alcanzado (${this.config.maxSessions})`);
         }
@@ -75,7 +79,8 @@ export class SessionManager {
             qrCodeData: '',
             reconnectAttempts: 0,
             isShuttingDown: false,
-            backendWS: null
+            backendWS: null,
+            wsToken
         };
 
         this.sessions.set(sessionId, sessionData);
@@ -124,6 +129,115 @@ export class SessionManager {
         }
     }
 
+    // Conecta la sesión al WebSocket del backend usando su credencial
+    private async connectBackend(sessionId: string): Promise<void> {
+        const session = this.sessions.get(sessionId);
+        if (!session) return;
+
+        try {
+            console.log(`🔌 [${sessionId}] Conectando al backend WebSocket...`);
+            session.backendWS = await connectToBackendWS(session.status.number, sessionId, session.wsToken);
+            console.log(`✅ [${sessionId}] Conectado al backend WebSocket`);
+
+            // 🔥 ESCUCHAR MENSAJES DEL BACKEND
+            const backendWS = session.backendWS;
+            backendWS.on('message', async (data: any) => {
+                let message: any;
+                try {
+                    message = JSON.parse(data.toString());
+                } catch (error) {
+                    console.error(`❌ [${sessionId}] Error parseando mensaje del backend:`, error);
+                    return;
+                }
+                // El backend no pudo aceptar un mensaje entrante: se reenvía más tarde
+                if (message.type === 'incoming_nack') {
+                    const { retryIncomingMessage } = await import('../handlers/messageHandler.js');
+                    retryIncomingMessage(message.id, backendWS, message.error);
+                    return;
+                }
+
+                // Sin el contenido: puede traer archivos en base64
+                console.log(`📩 [${sessionId}] Mensaje del backend:`, { id: message.id, to: message.to, messageType: message.messageType || 'text' });
+
+                // Confirmar la entrega al backend (ack/nack) usando el id del envelope
+                const reply = (frame: Record<string, string>) => {
+                    if (message.id && backendWS.readyState === backendWS.OPEN) {
+                        backendWS.send(JSON.stringify({ ...frame, id: message.id }));
+                    }
+                };
+
+                // Un reintento de un mensaje ya enviado solo se vuelve a confirmar
+                if (message.id && this.deliveredMessages.has(message.id)) {
+                    reply({ type: 'ack' });
+                    return;
+                }
+
+                let content: AnyMessageContent;
+                try {
+                    content = buildMessageContent(message);
+                } catch (error: any) {
+                    reply({ type: 'nack', error: error.message });
+                    return;
+                }
+                if (!session.socket || !session.status.connected) {
+                    reply({ type: 'nack', error: 'Sesión no conectada' });
+                    return;
+                }
+
+                try {
+                    // Enviar mensaje por WhatsApp
+                    const jid = message.to.includes('@') ? message.to : `${message.to}@s.whatsapp.net`;
+                    await session.socket.sendMessage(jid, content);
+                    console.log(`✅ [${sessionId}] Mensaje (${message.messageType || 'text'}) enviado a ${message.to}`);
+
+                    if (message.id) {
+                        this.rememberDelivered(message.id);
+                    }
+                    reply({ type: 'ack' });
+                } catch (error: any) {
+                    console.error(`❌ [${sessionId}] Error procesando mensaje del backend:`, error);
+                    reply({ type: 'nack', error: error?.message || 'Error enviando mensaje' });
+                }
+            });
+        } catch (error) {
+            console.error(`❌ [${sessionId}] Error conectando al backend:`, error);
+        }
+    }
+
+    private rememberDelivered(id: string): void {
+        this.deliveredMessages.add(id);
+        if (this.deliveredMessages.size > SessionManager.MAX_DELIVERED_IDS) {
+            // Set conserva el orden de inserción: se descarta el más antiguo
+            const oldest = this.deliveredMessages.values().next().value;
+            if (oldest !== undefined) {
+                this.deliveredMessages.delete(oldest);
+            }
+        }
+    }
+
+    // Actualiza la credencial de /ws (rotación) y reconecta al backend si WhatsApp está conectado
+    async updateWsToken(sessionId: string, wsToken: string): Promise<void> {
+        const session = this.sessions.get(sessionId);
+        if (!session) {
+            throw new Error(`Sesión ${sessionId} no encontrada`);
+        }
+
+        session.wsToken = wsToken;
+
+        if (session.backendWS) {
+            try {
+                session.backendWS.close();
+            } catch (error) {
+                console.error(`❌ [${sessionId}] Error cerrando backend WS:`, error);
+            }
+            session.backendWS = null;
+        }
+
+        if (session.status.connected) {
+            await this.connectBackend(sessionId);
+        }
+    }
+
     private setupSocketEvents(sessionId: string, socket: WASocket, saveCreds: any): void {
         const session = this.sessions.get(sessionId);
         if (!session) return;
@@ -165,30 +279,7 @@ export class SessionManager {
                 console.log(`📱 [${sessionId}] Número: ${session.status.number}`);
 
                 // 🔥 CONECTAR AL BACKEND WEBSOCKET AQUÍ
-                try {
-                    console.log(`🔌 [${sessionId}] Conectando al backend WebSocket...`);
-                    session.backendWS = await connectToBackendWS(session.status.number, sessionId);
-                    console.log(`✅ [${sessionId}] Conectado al backend WebSocket`);
-                    
-                    // 🔥 ESCUCHAR MENSAJES DEL BACKEND
-                    session.backendWS.on('message', async (data: any) => {
-                        try {
-                            const message = JSON.parse(data.toString());
-                            console.log(`📩 [${sessionId}] Mensaje del backend:`, message);
-                            
-                            // Enviar mensaje por WhatsApp
-                            if (message.to && message.message && session.socket) {
-                                const jid = message.to.includes('@') ? message.to : `${message.to}@s.whatsapp.net`;
-                                await session.socket.sendMessage(jid, { text: message.message });
-                                console.log(`✅ [${sessionId}] Mensaje enviado a ${message.to}`);
-                            }
-                        } catch (error) {
-                            console.error(`❌ [${sessionId}] Error procesando mensaje del backend:`, error);
-                        }
-                    });
-                } catch (error) {
-                    console.error(`❌ [${sessionId}] Error conectando al backend:`, error);
-                }
+                await this.connectBackend(sessionId);
             }
 
             // Manejar desconexión
@@ -232,7 +323,11 @@ export class SessionManager {
                 let messageType = 'text';
                 let text = '';
 
-                if (msg.message.conversation || msg.message.extendedTextMessage?.text) {
+                if (msg.message.buttonsResponseMessage?.selectedButtonId) {
+                    // Respuesta a botones: se envía el payload del botón elegido
+                    text = msg.message.buttonsResponseMessage.selectedButtonId;
+                    messageType = 'text';
+                } else if (msg.message.conversation || msg.message.extendedTextMessage?.text) {
                     text = msg.message.conversation || msg.message.extendedTextMessage?.text;
                     messageType = 'text';
                 } else if (msg.message.audioMessage) {
@@ -261,7 +356,7 @@ export class SessionManager {
                 if (session.backendWS && session.backendWS.readyState === 1) {
                     try {
                         const { handleIncomingMessage } = await import('../handlers/messageHandler.js');
-                        await handleIncomingMessage(from, text, session.status.number, session.backendWS, messageType);
+                        await handleIncomingMessage(from, text, session.status.number, session.backendWS, messageType, msg.key.id);
                         console.log(`✅ [${sessionId}] Mensaje enviado al backend`);
                     } catch (error) {
                         console.error(`❌ [${sessionId}] Error enviando mensaje al backend:`, error);
@@ -21,6 +21,7 @@ export interface SessionData {
     reconnectAttempts: number;
     isShuttingDown: boolean;
     backendWS: any | null;
+    wsToken: string; // Credencial emitida por el backend para conectarse a /ws
 }
 
 export interface SessionConfig {
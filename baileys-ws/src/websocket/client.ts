@@ -1,12 +1,13 @@
 import WebSocket from 'ws';
 
-export const connectToBackendWS = (phone: string, sessionId: string = 'default'): Promise<WebSocket> => {
+export const connectToBackendWS = (phone: string, sessionId: string = 'default', token: string = ''): Promise<WebSocket> => {
     return new Promise((resolve, reject) => {
         const apiUrl = process.env.API_URL || 'http://localhost:8080';
         const wsUrl = apiUrl.replace('http:', 'ws:').replace('https:', 'wss:');
         
-        // 🔥 Incluir sessionId en la conexión WebSocket
-        const ws = new WebSocket(`${wsUrl}/ws?phone=${phone}&sessionId=${sessionId}`);
+        // 🔥 Incluir sessionId y la credencial de la sesión en la conexión WebSocket
+        const params = new URLSearchParams({ phone, sessionId, token });
+        const ws = new WebSocket(`${wsUrl}/ws?${params.toString()}`);
 
         ws.on('open', () => {
             console.log(`✅ Conectado al backend Go (session: ${sessionId}, phone: ${phone})`);
@@ -49,6 +49,32 @@ class RNDCBot {
       "https://rndc.mintransporte.gov.co/programasRNDC/creardocumento/tabid/69/ctl/Remesa/mid/396/procesoid/3/default.aspx";
     this.manifiestoUrl =
       "https://rndc.mintransporte.gov.co/programasRNDC/creardocumento/tabid/69/ctl/ManifiestoSCD/mid/396/procesoid/4/default.aspx";
+
+    // Formularios de cumplido y anulación (ver SELECTORS.md). Los campos usan los
+    // nombres de las variables del RNDC, igual que los de remesa y manifiesto.
+    this.followUpForms = {
+      cumplidoRemesa: {
+        name: "Cumplido de Remesa",
+        control: "CumplidoRemesa",
+        url:
+          process.env.RNDC_CUMPLIDO_REMESA_URL ||
+          "https://rndc.mintransporte.gov.co/programasRNDC/creardocumento/tabid/69/ctl/CumplidoRemesa/mid/396/procesoid/5/default.aspx",
+      },
+      cumplidoManifiesto: {
+        name: "Cumplido de Manifiesto",
+        control: "CumplidoManifiesto",
+        url:
+          process.env.RNDC_CUMPLIDO_MANIFIESTO_URL ||
+          "https://rndc.mintransporte.gov.co/programasRNDC/creardocumento/tabid/69/ctl/CumplidoManifiesto/mid/396/procesoid/6/default.aspx",
+      },
+      anulacionManifiesto: {
+        name: "Anulación de Manifiesto",
+        control: "AnulacionManifiesto",
+        url:
+          process.env.RNDC_ANULACION_MANIFIESTO_URL ||
+          "https://rndc.mintransporte.gov.co/programasRNDC/creardocumento/tabid/69/ctl/AnulacionManifiesto/mid/396/procesoid/32/default.aspx",
+      },
+    };
   }
 
   async initialize() {
@@ -791,6 +817,130 @@ class RNDCBot {
     }
   }
 
+  /**
+   * Radica el cumplido: primero el de la remesa (peso entregado) y luego el del manifiesto.
+   * Devuelve el número de ingreso del cumplido del manifiesto.
+   */
+  async cumplirManifiesto(data, downloadPath) {
+    return this.withPage("cumplido", downloadPath, async (page) => {
+      const llegada = new Date(data.fechaLlegada);
+      const observaciones = data.novedades || "Sin novedad";
+
+      await this.submitFollowUpForm(page, this.followUpForms.cumplidoRemesa, {
+        CONSECUTIVOREMESA: data.consecutivoRemesa,
+        FECHALLEGADADESCARGUE: this.formatDate(llegada),
+        HORALLEGADADESCARGUE: this.formatTime(llegada),
+        CANTIDADENTREGADA: data.cantidadEntregada,
+        OBSERVACIONES: observaciones,
+      }, downloadPath);
+
+      return this.submitFollowUpForm(page, this.followUpForms.cumplidoManifiesto, {
+        NUMMANIFIESTOCARGA: data.consecutivoManifiesto,
+        FECHAENTREGADOCUMENTOS: this.formatDate(llegada),
+        OBSERVACIONES: observaciones,
+      }, downloadPath);
+    });
+  }
+
+  /**
+   * Radica la anulación del manifiesto y devuelve su número de ingreso
+   */
+  async anularManifiesto(data, downloadPath) {
+    return this.withPage("anulacion", downloadPath, async (page) => {
+      return this.submitFollowUpForm(page, this.followUpForms.anulacionManifiesto, {
+        NUMMANIFIESTOCARGA: data.consecutivoManifiesto,
+        MOTIVOANULACION: data.motivo,
+      }, downloadPath);
+    });
+  }
+
+  /**
+   * Abre una página con sesión iniciada, ejecuta fn y convierte los errores en RNDCError
+   */
+  async withPage(operation, downloadPath, fn) {
+    if (!this.browser) {
+      throw new Error("Bot no inicializado. Llama a initialize() primero.");
+    }
+
+    this.logger.info({ operation }, "Starting RNDC follow-up");
+    const page = await this.browser.newPage();
+    const listeners = this.setupPageListeners(page);
+
+    try {
+      await this.login(page);
+      return await fn(page);
+    } catch (error) {
+      if (error.name === "RNDCError") {
+        if (listeners.alerts.length > 0 && error.alerts.length === 0) {
+          error.alerts = listeners.alerts;
+        }
+        throw error;
+      }
+
+      const screenshot = await this.captureErrorScreenshot(page, downloadPath, `${operation}_failed`);
+      this.logger.error({ operation, error: error.message, stack: error.stack }, "Error in RNDC follow-up");
+      throw new RNDCError(error.message, {
+        type: "execution",
+        pageErrors: listeners.pageErrors,
+        alerts: listeners.alerts,
+        screenshot,
+      });
+    } finally {
+      await page.close();
+    }
+  }
+
+  /**
+   * Llena los campos de un formulario de seguimiento, lo guarda y devuelve el número de
+   * ingreso que asigna el RNDC
+   */
+  async submitFollowUpForm(page, form, fields, downloadPath) {
+    this.logger.info({ form: form.name }, "Filling follow-up form");
+    await page.goto(form.url, { waitUntil: "networkidle" });
+
+    const prefix = `#dnn_ctr396_${form.control}_`;
+    for (const [field, value] of Object.entries(fields)) {
+      if (value !== undefined && value !== null && value !== "") {
+        await page.fill(prefix + field, String(value));
+      }
+    }
+    await page.click(`${prefix}btnGuardar`);
+    await page.waitForTimeout(2000);
+
+    const pageErrors = await this.checkForPageErrors(page);
+    if (pageErrors.length > 0) {
+      const screenshot = await this.captureErrorScreenshot(page, downloadPath, `${form.control}_validation`);
+      throw new RNDCError(`Errores de validación en formulario de ${form.name}`, {
+        type: "validation",
+        pageErrors,
+        screenshot,
+      });
+    }
+
+    const ingresoSelector = `${prefix}lbIngreso`;
+    await page.waitForSelector(ingresoSelector, { timeout: 10000 }).catch(async () => {
+      const pageErrors = await this.checkForPageErrors(page);
+      const screenshot = await this.captureErrorScreenshot(page, downloadPath, `${form.control}_save`);
+      throw new RNDCError(`No se pudo guardar el ${form.name}`, {
+        type: "save_failed",
+        pageErrors,
+        screenshot,
+      });
+    });
+
+    const ingreso = ((await page.textContent(ingresoSelector)) || "").trim();
+    if (!ingreso || ingreso === "0") {
+      const screenshot = await this.captureErrorScreenshot(page, downloadPath, `${form.control}_invalid`);
+      throw new RNDCError(`Número de ingreso inválido en ${form.name}`, {
+        type: "invalid_consecutivo",
+        screenshot,
+      });
+    }
+
+    this.logger.info({ form: form.name, ingreso }, "Follow-up form saved");
+    return ingreso;
+  }
+
   formatDate(date) {
     const day = String(date.getDate()).padStart(2, "0");
     const month = String(date.getMonth() + 1).padStart(2, "0");
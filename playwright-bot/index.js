@@ -7,7 +7,11 @@ const { RNDCBot } = require("./bot/rndcBot");
 const { FileManager } = require("./storage/fileManager");
 const { TaskQueue } = require("./queue/taskQueue");
 const { validateRequest } = require("./middleware/validation");
-const { createManifiestoRequestSchema } = require("./validation/schemas");
+const {
+  createManifiestoRequestSchema,
+  cumplidoRequestSchema,
+  anulacionRequestSchema,
+} = require("./validation/schemas");
 const { logger, createLogger } = require("./utils/logger");
 const { withRetry, isRetryableError } = require("./utils/retry");
 
@@ -194,6 +198,99 @@ app.post(
   }
 );
 
+// Cumplido y anulación de un manifiesto expedido. Ambos radican formularios en el RNDC y
+// devuelven el número de ingreso (radicado); no descargan archivos.
+function followUpHandler(name, run) {
+  return async (req, res) => {
+    const requestLogger = createLogger({
+      component: `${name}Endpoint`,
+      consecutivoManifiesto: req.body.consecutivoManifiesto,
+    });
+
+    try {
+      const requestData = req.body;
+      requestLogger.info(`Received ${name} request`);
+
+      if (!botInitialized) {
+        await initializeBot();
+      }
+
+      const radicado = await taskQueue.add(
+        async () => {
+          const path = require("path");
+          const tempDir = path.join(DOWNLOAD_DIR, "temp");
+
+          return withRetry(async () => await run(requestData, tempDir), {
+            maxRetries: parseInt(process.env.MAX_RETRIES) || 3,
+            shouldRetry: isRetryableError,
+            onRetry: (error, attempt, delay) => {
+              requestLogger.warn(
+                { error: error.message, attempt, delay },
+                `Retrying ${name}`
+              );
+            },
+          });
+        },
+        {
+          consecutivoManifiesto: requestData.consecutivoManifiesto,
+        }
+      );
+
+      requestLogger.info({ radicado }, `${name} radicado successfully`);
+      res.json({
+        success: true,
+        consecutivoManifiesto: requestData.consecutivoManifiesto,
+        radicado,
+      });
+    } catch (error) {
+      requestLogger.error(
+        { error: error.message, stack: error.stack },
+        `Error in ${name}`
+      );
+
+      if (error.name === "RNDCError") {
+        const path = require("path");
+        return res.status(400).json({
+          success: false,
+          error: error.message,
+          details: {
+            type: error.type,
+            pageErrors: error.pageErrors,
+            alerts: error.alerts,
+            screenshot: error.screenshot ? `/downloads/${path.basename(error.screenshot)}` : null,
+            timestamp: error.timestamp,
+          },
+        });
+      }
+
+      res.status(500).json({
+        success: false,
+        error: error.message || "Error interno del servidor",
+      });
+    }
+  };
+}
+
+// Endpoint para radicar el cumplido de un manifiesto
+app.post(
+  "/api/cumplido",
+  limiter,
+  validateRequest(cumplidoRequestSchema),
+  followUpHandler("Cumplido", (data, tempDir) =>
+    bot.cumplirManifiesto(data, tempDir)
+  )
+);
+
+// Endpoint para anular un manifiesto
+app.post(
+  "/api/anulacion",
+  limiter,
+  validateRequest(anulacionRequestSchema),
+  followUpHandler("Anulacion", (data, tempDir) =>
+    bot.anularManifiesto(data, tempDir)
+  )
+);
+
 // Endpoint para descargar archivo
 app.get("/api/download/:fileId", (req, res) => {
   const requestLogger = createLogger({
@@ -222,7 +319,13 @@ app.get("/api/download/:fileId", (req, res) => {
       });
     }
 
-    requestLogger.info("Serving file download");
+    // La API verifica el archivo descargado con este checksum
+    const crypto = require("crypto");
+    const fs = require("fs");
+    const checksum = crypto.createHash("sha256").update(fs.readFileSync(filePath)).digest("hex");
+    res.setHeader("X-Checksum-SHA256", checksum);
+
+    requestLogger.info({ checksum }, "Serving file download");
     res.download(filePath, fileRecord.filename);
   } catch (error) {
     requestLogger.error(
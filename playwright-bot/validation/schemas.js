@@ -167,8 +167,64 @@ const createManifiestoRequestSchema = Joi.object({
   manifiesto: manifiestoSchema.required(),
 });
 
+/**
+ * Schema para el request del endpoint /api/cumplido (cumplido de remesa y manifiesto)
+ */
+const cumplidoRequestSchema = Joi.object({
+  consecutivoRemesa: Joi.string()
+    .required()
+    .pattern(/^[A-Z0-9-]+$/)
+    .max(50)
+    .description("Consecutivo de la remesa del manifiesto"),
+
+  consecutivoManifiesto: Joi.string()
+    .required()
+    .description("Número del manifiesto asignado por el RNDC"),
+
+  fechaLlegada: Joi.date()
+    .iso()
+    .required()
+    .max("now")
+    .description("Fecha y hora de llegada al descargue (ISO 8601)"),
+
+  cantidadEntregada: Joi.number()
+    .integer()
+    .min(1)
+    .required()
+    .description("Peso entregado en kilogramos"),
+
+  novedades: Joi.string()
+    .max(500)
+    .allow("")
+    .optional()
+    .description("Novedades de la entrega (faltantes, averías, demoras)"),
+});
+
+/**
+ * Schema para el request del endpoint /api/anulacion
+ */
+const anulacionRequestSchema = Joi.object({
+  consecutivoRemesa: Joi.string()
+    .required()
+    .pattern(/^[A-Z0-9-]+$/)
+    .max(50)
+    .description("Consecutivo de la remesa del manifiesto"),
+
+  consecutivoManifiesto: Joi.string()
+    .required()
+    .description("Número del manifiesto asignado por el RNDC"),
+
+  motivo: Joi.string()
+    .required()
+    .min(10)
+    .max(500)
+    .description("Motivo de la anulación"),
+});
+
 module.exports = {
   remesaSchema,
   manifiestoSchema,
   createManifiestoRequestSchema,
+  cumplidoRequestSchema,
+  anulacionRequestSchema,
 };
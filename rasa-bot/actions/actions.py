@@ -5,9 +5,13 @@ from rasa_sdk.events import AllSlotsReset, SlotSet, EventType
 from rasa_sdk.types import DomainDict
 import requests
 import logging
+import os
 
 logger = logging.getLogger(__name__)
 
+API_URL = (os.getenv("API_URL") or "http://api:8080").rstrip("/")
+INTERNAL_API_TOKEN = os.getenv("INTERNAL_API_TOKEN", "")
+
 class ActionDefaultFallback(Action):
     """Acción de fallback cuando no se entiende el mensaje del usuario."""
     
@@ -113,8 +117,9 @@ class ActionConfirmarPago(Action):
 
 class ActionGenerarManifiesto(Action):
     """
-    Acción para generar el manifiesto usando Playwright.
-    Llama al bot de Playwright para automatizar la generación del documento.
+    Acción para generar el manifiesto.
+    Envía los slots a la API, que arma el payload de playwright-bot, sigue el documento
+    y le envía el PDF al cliente por WhatsApp cuando esté listo.
     """
     
     def name(self) -> Text:
@@ -137,24 +142,35 @@ class ActionGenerarManifiesto(Action):
             "licencia": tracker.get_slot("licencia"),
             "origen": tracker.get_slot("origen"),
             "destino": tracker.get_slot("destino"),
-            "cliente_id": tracker.sender_id
         }
         
-        logger.info(f"🤖 Generando manifiesto con Playwright para {tracker.sender_id}")
+        logger.info(f"🤖 Solicitando manifiesto a la API para {tracker.sender_id}")
         logger.info(f"  📋 Datos: {datos_manifiesto}")
         
         try:
-            # TODO: LLAMADA A PLAYWRIGHT
-            # playwright_url = "http://playwright:3000/generar-manifiesto"
-            # response = requests.post(playwright_url, json=datos_manifiesto, timeout=30)
-            # if response.status_code == 200:
-            #     resultado = response.json()
-            #     pdf_url = resultado.get("pdf_url")
-            #     enviar_pdf_por_whatsapp(tracker.sender_id, pdf_url)
+            response = requests.post(
+                f"{API_URL}/internal/manifests",
+                json={"sender_id": tracker.sender_id, "slots": datos_manifiesto},
+                headers={"X-Internal-Token": INTERNAL_API_TOKEN},
+                timeout=15,
+            )
+            
+            if response.status_code == 400:
+                detalle = response.json().get("details", "")
+                logger.warning(f"⚠️ Datos incompletos para el manifiesto: {detalle}")
+                dispatcher.utter_message(
+                    text="Faltan datos para generar tu manifiesto. 😔\n\n"
+                         "Por favor revisa la información e intenta nuevamente."
+                )
+                return []
+            
+            response.raise_for_status()
+            resultado = response.json()
+            logger.info(f"📄 Documento {resultado.get('document_id')} en generación")
             
             mensaje = (
-                "✅ ¡Manifiesto generado exitosamente! 📋\n\n"
-                "Tu documento está siendo procesado y te lo enviaré en un momento.\n"
+                "✅ ¡Estamos generando tu manifiesto! 📋\n\n"
+                "Te enviaré el documento por aquí en cuanto esté listo.\n"
                 "Gracias por usar nuestros servicios. 😊"
             )
             
@@ -162,7 +178,7 @@ class ActionGenerarManifiesto(Action):
             return [AllSlotsReset()]
             
         except Exception as e:
-            logger.error(f"❌ Error al generar manifiesto: {str(e)}")
+            logger.error(f"❌ Error al solicitar manifiesto: {str(e)}")
             
             mensaje = (
                 "Lo siento, hubo un error al procesar tu manifiesto. 😔\n\n"
@@ -225,12 +241,166 @@ class ActionSubmitManifiesto(Action):
         return []
 
 
+class ActionRepetirManifiesto(Action):
+    """Precarga el formulario con el último manifiesto (o la ruta guardada) del cliente."""
+
+    # Las fechas cambian en cada viaje: siempre se vuelven a preguntar
+    SLOTS_POR_VIAJE = ["fecha_cargue", "fecha_descargue"]
+
+    def name(self) -> Text:
+        return "action_repetir_manifiesto"
+
+    def run(
+        self,
+        dispatcher: CollectingDispatcher,
+        tracker: Tracker,
+        domain: Dict[Text, Any],
+    ) -> List[EventType]:
+        try:
+            response = requests.get(
+                f"{API_URL}/internal/manifests/templates",
+                params={"sender_id": tracker.sender_id, "limit": 1},
+                headers={"X-Internal-Token": INTERNAL_API_TOKEN},
+                timeout=5,
+            )
+            response.raise_for_status()
+            plantillas = response.json()
+        except Exception as e:
+            logger.warning(f"⚠️ No se pudieron consultar las plantillas de {tracker.sender_id}: {e}")
+            plantillas = {}
+
+        plantilla = next(iter((plantillas.get("recent") or []) + (plantillas.get("routes") or [])), None)
+        if not plantilla:
+            dispatcher.utter_message(
+                text="No encontré manifiestos anteriores tuyos. 🤔\n\nHagamos uno nuevo, te pediré los datos."
+            )
+            return []
+
+        datos = plantilla.get("slots") or {}
+        logger.info(f"🔁 Repitiendo plantilla {plantilla.get('id')} para {tracker.sender_id}")
+        dispatcher.utter_message(
+            text=f"🔁 Tomé los datos de *{plantilla.get('name')}*:\n\n"
+                 f"📦 Carga: {datos.get('descripcion', '-')} ({datos.get('peso', '-')})\n"
+                 f"💰 Flete: {datos.get('flete', '-')}\n"
+                 f"🚗 Placa: {datos.get('tarjeta', '-')}\n"
+                 f"🪪 Conductor: {datos.get('licencia', '-')}\n\n"
+                 f"Solo necesito las fechas de este viaje. Si algo cambió dime "
+                 f"'corregir [campo]' (ej: 'corregir flete a 1800000')."
+        )
+
+        eventos = [SlotSet(slot, valor) for slot, valor in datos.items() if slot not in self.SLOTS_POR_VIAJE]
+        eventos += [SlotSet(slot, None) for slot in self.SLOTS_POR_VIAJE]
+        return eventos
+
+
+def consultar_datos_registrados(sender_id: Text) -> Dict[Text, Any]:
+    """Slots del manifiesto que la API ya conoce del chat (conductor y vehículo registrados)."""
+    try:
+        response = requests.get(
+            f"{API_URL}/internal/manifests/prefill",
+            params={"sender_id": sender_id},
+            headers={"X-Internal-Token": INTERNAL_API_TOKEN},
+            timeout=5,
+        )
+        response.raise_for_status()
+        return response.json().get("slots") or {}
+    except Exception as e:
+        logger.warning(f"⚠️ No se pudo consultar el registro para {sender_id}: {e}")
+        return {}
+
+
+class ActionConfirmarEntrega(Action):
+    """Radica el cumplido del manifiesto que espera la confirmación de entrega del conductor."""
+
+    SIN_NOVEDADES = {"ninguna", "ninguno", "no", "nada", "sin novedad", "sin novedades", "todo bien"}
+
+    def name(self) -> Text:
+        return "action_confirmar_entrega"
+
+    def run(
+        self,
+        dispatcher: CollectingDispatcher,
+        tracker: Tracker,
+        domain: Dict[Text, Any],
+    ) -> List[EventType]:
+        novedades = (tracker.get_slot("novedades") or "").strip()
+        if novedades.lower().strip(".! ") in self.SIN_NOVEDADES:
+            novedades = ""
+        limpiar = [SlotSet("peso_entregado", None), SlotSet("novedades", None)]
+
+        try:
+            response = requests.post(
+                f"{API_URL}/internal/manifests/delivery",
+                json={
+                    "sender_id": tracker.sender_id,
+                    "peso_entregado": tracker.get_slot("peso_entregado"),
+                    "novedades": novedades,
+                },
+                headers={"X-Internal-Token": INTERNAL_API_TOKEN},
+                timeout=15,
+            )
+
+            if response.status_code == 404:
+                dispatcher.utter_message(
+                    text="No encontré manifiestos pendientes de entrega a tu nombre. 🤔\n\n"
+                         "Si crees que es un error, un asesor te ayudará."
+                )
+                return limpiar
+            if response.status_code == 400:
+                logger.warning(f"⚠️ Cumplido inválido: {response.json().get('details', '')}")
+                dispatcher.utter_message(
+                    text="No pude registrar el peso entregado. 😔\n\n"
+                         "Escribe de nuevo *entregué* e indícalo en kilos o toneladas (ej. 4500 kg)."
+                )
+                return limpiar
+
+            response.raise_for_status()
+            resultado = response.json()
+            logger.info(f"📦 Cumplido {resultado.get('document_id')} en radicación")
+            dispatcher.utter_message(
+                text=f"✅ ¡Gracias! Estamos radicando el cumplido del manifiesto {resultado.get('consecutivo') or ''} en el RNDC. 📋"
+            )
+            return limpiar
+
+        except Exception as e:
+            logger.error(f"❌ Error al confirmar la entrega: {str(e)}")
+            dispatcher.utter_message(
+                text="Lo siento, no pude registrar la entrega. 😔\n\nPor favor, intenta nuevamente en unos minutos."
+            )
+            return limpiar
+
+
 class ValidateManifiestoForm(FormValidationAction):
     """Validador para el formulario de manifiesto."""
     
     def name(self) -> Text:
         return "validate_manifiesto_form"
 
+    def _precargar(self, slot: Text, tracker: Tracker) -> Dict[Text, Any]:
+        """Completa el slot con el registro de la organización si el usuario aún no lo dio."""
+        if tracker.get_slot(slot):
+            return {}
+        valor = consultar_datos_registrados(tracker.sender_id).get(slot)
+        return {slot: valor} if valor else {}
+
+    def extract_tarjeta(
+        self,
+        dispatcher: CollectingDispatcher,
+        tracker: Tracker,
+        domain: DomainDict,
+    ) -> Dict[Text, Any]:
+        """Precarga la placa del vehículo habitual del conductor."""
+        return self._precargar("tarjeta", tracker)
+
+    def extract_licencia(
+        self,
+        dispatcher: CollectingDispatcher,
+        tracker: Tracker,
+        domain: DomainDict,
+    ) -> Dict[Text, Any]:
+        """Precarga la licencia (cédula) del conductor registrado."""
+        return self._precargar("licencia", tracker)
+
     def validate_flete(
         self,
         slot_value: Any,
@@ -365,9 +535,9 @@ class ValidateManifiestoForm(FormValidationAction):
     def validate_tarjeta(self, slot_value, dispatcher, tracker, domain):
         """Valida formato de placa colombiana"""
         if slot_value:
-            # Formato: ABC123 o ABC12D
+            # Formato de vehículo de carga: ABC123 (igual que rndc.ValidatePlaca en la API)
             import re
-            pattern = r'^[A-Z]{3}\d{2}[A-Z0-9]$'
+            pattern = r'^[A-Z]{3}\d{3}$'
             clean = str(slot_value).upper().replace("-", "").replace(" ", "")
             
             if re.match(pattern, clean):
@@ -3,11 +3,14 @@ import api from "../services/api";
 
 // ✅ LIMPIO: Solo token, datos del usuario se obtienen cuando se necesiten
 const accessToken = ref<string | null>(localStorage.getItem("accessToken"));
+// Refresh token opaco: cada uso lo rota, por eso se reemplaza en cada renovación
+const refreshToken = ref<string | null>(localStorage.getItem("refreshToken"));
 const currentUser = ref<{
   id: number;
   username: string;
   email: string;
   role: string;
+  permissions: string[]; // p. ej. "whatsapp:send", "documents:read"
   is_active: boolean;
   last_login?: string;
 } | null>(null);
@@ -24,12 +27,7 @@ export function useAuth() {
     try {
       const res = await api.post("/auth/login", { username, password });
 
-      // ✅ LIMPIO: Solo guardar el token
-      accessToken.value = res.data.access_token;
-
-      if (accessToken.value) {
-        localStorage.setItem("accessToken", accessToken.value);
-      }
+      saveTokens(res.data.access_token, res.data.refresh_token);
 
       // ✅ OPCIONAL: Cargar datos del usuario inmediatamente
       await getCurrentUser();
@@ -41,35 +39,59 @@ export function useAuth() {
     }
   };
 
-  const logout = () => {
-    accessToken.value = null;
+  const saveTokens = (access: string | null, refresh: string | null) => {
+    accessToken.value = access;
+    refreshToken.value = refresh;
+    if (access) {
+      localStorage.setItem("accessToken", access);
+    } else {
+      localStorage.removeItem("accessToken");
+    }
+    if (refresh) {
+      localStorage.setItem("refreshToken", refresh);
+    } else {
+      localStorage.removeItem("refreshToken");
+    }
+  };
+
+  const clearSession = () => {
+    saveTokens(null, null);
     currentUser.value = null;
-    localStorage.removeItem("accessToken");
+  };
+
+  // Cierra la sesión en el servidor (revoca el refresh token) y limpia el estado local
+  const logout = () => {
+    const token = refreshToken.value;
+    clearSession();
+    if (token) {
+      api.post("/auth/logout", { refresh_token: token }).catch((error) => {
+        console.error("Error cerrando sesión:", error);
+      });
+    }
+  };
+
+  // Cierra la sesión en todos los dispositivos del usuario
+  const logoutAll = async () => {
+    try {
+      await api.post("/auth/logout-all");
+    } finally {
+      clearSession();
+    }
   };
 
   const refreshTokenFn = async () => {
-    if (!accessToken.value) throw new Error("No hay token para refrescar");
+    if (!refreshToken.value) throw new Error("No hay token para refrescar");
 
     try {
-      const res = await api.post(
-        "/auth/refresh",
-        {},
-        {
-          headers: {
-            Authorization: `Bearer ${accessToken.value}`,
-          },
-        }
-      );
-
-      accessToken.value = res.data.access_token;
-
-      if (accessToken.value) {
-        localStorage.setItem("accessToken", accessToken.value);
-      }
+      const res = await api.post("/auth/refresh", {
+        refresh_token: refreshToken.value,
+      });
+
+      saveTokens(res.data.access_token, res.data.refresh_token);
 
       return res.data;
     } catch (error) {
-      logout();
+      clearSession();
       throw error;
     }
   };
@@ -87,6 +109,7 @@ export function useAuth() {
         username: res.data.username,
         email: res.data.email,
         role: res.data.role,
+        permissions: res.data.permissions || [],
         is_active: res.data.is_active,
         last_login: res.data.last_login,
       };
@@ -119,6 +142,11 @@ export function useAuth() {
     return roles.includes(userRole.value || "");
   };
 
+  // Permisos del rol: sirven para ocultar las acciones que la API rechazaría con 403
+  const can = (permission: string) => {
+    return currentUser.value?.permissions.includes(permission) === true;
+  };
+
   // ✅ NUEVO: Verificar si el usuario está activo
   const isUserActive = computed(() => {
     return currentUser.value?.is_active === true;
@@ -140,10 +168,12 @@ export function useAuth() {
     // Funciones
     login,
     logout,
+    logoutAll,
     refreshTokenFn,
     getCurrentUser,
     ensureUserLoaded,
     hasRole,
     hasAnyRole,
+    can,
   };
 }
@@ -44,7 +44,8 @@ api.interceptors.response.use(
     // ✅ NO intentar refresh en rutas de auth
     if (
       originalRequest?.url?.includes("/auth/login") ||
-      originalRequest?.url?.includes("/auth/refresh")
+      originalRequest?.url?.includes("/auth/refresh") ||
+      originalRequest?.url?.includes("/auth/logout")
     ) {
       return Promise.reject(error);
     }